@@ -0,0 +1,103 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package api
+
+import (
+	"github.com/juju/errors"
+	"github.com/juju/names"
+	"launchpad.net/tomb"
+
+	"github.com/juju/juju/api/base"
+	"github.com/juju/juju/api/watcher"
+	"github.com/juju/juju/apiserver/params"
+)
+
+// ActionResultsWatcher reports the results of actions as they are
+// completed, cancelled or fail. It translates the action ids reported
+// by the API server's watcher into the actions' results, fetched in a
+// single call per event.
+type ActionResultsWatcher struct {
+	tomb   tomb.Tomb
+	facade base.FacadeCaller
+	source watcher.StringsWatcher
+	out    chan []params.ActionResult
+}
+
+func newActionResultsWatcher(client *Client, source watcher.StringsWatcher) *ActionResultsWatcher {
+	w := &ActionResultsWatcher{
+		facade: base.NewFacadeCaller(client.st, "Action"),
+		source: source,
+		out:    make(chan []params.ActionResult),
+	}
+	go func() {
+		defer w.tomb.Done()
+		defer close(w.out)
+		w.tomb.Kill(w.loop())
+	}()
+	return w
+}
+
+func (w *ActionResultsWatcher) loop() error {
+	defer w.source.Stop()
+	var out chan []params.ActionResult
+	var changes []params.ActionResult
+	for {
+		select {
+		case <-w.tomb.Dying():
+			return tomb.ErrDying
+		case ids, ok := <-w.source.Changes():
+			if !ok {
+				if err := w.source.Err(); err != nil {
+					return err
+				}
+				return errors.New("action ids watcher closed unexpectedly")
+			}
+			if len(ids) == 0 {
+				continue
+			}
+			results, err := w.results(ids)
+			if err != nil {
+				return err
+			}
+			changes = append(changes, results...)
+			out = w.out
+		case out <- changes:
+			out = nil
+			changes = nil
+		}
+	}
+}
+
+// results returns the results of the actions with the given ids.
+func (w *ActionResultsWatcher) results(ids []string) ([]params.ActionResult, error) {
+	args := params.Entities{Entities: make([]params.Entity, len(ids))}
+	for i, id := range ids {
+		args.Entities[i].Tag = names.NewActionTag(id).String()
+	}
+	var results params.ActionResults
+	if err := w.facade.FacadeCall("Actions", args, &results); err != nil {
+		return nil, err
+	}
+	if len(results.Results) != len(ids) {
+		return nil, errors.Errorf("expected %d results, got %d", len(ids), len(results.Results))
+	}
+	return results.Results, nil
+}
+
+// Changes returns a channel that receives the results
+// of actions as they finish.
+func (w *ActionResultsWatcher) Changes() <-chan []params.ActionResult {
+	return w.out
+}
+
+// Stop stops the watcher and returns any error it encountered.
+func (w *ActionResultsWatcher) Stop() error {
+	w.tomb.Kill(nil)
+	return w.tomb.Wait()
+}
+
+// Err returns any error encountered by the watcher.
+func (w *ActionResultsWatcher) Err() error {
+	return w.tomb.Err()
+}
@@ -33,3 +33,28 @@ func (watcher *AllWatcher) Stop() error {
 		"AllWatcher", watcher.caller.BestFacadeVersion("AllWatcher"),
 		*watcher.id, "Stop", nil, nil)
 }
+
+// AllEnvWatcher holds information allowing us to get Deltas describing
+// changes to all the environments managed by a state server.
+type AllEnvWatcher struct {
+	caller base.APICaller
+	id     *string
+}
+
+func newAllEnvWatcher(caller base.APICaller, id *string) *AllEnvWatcher {
+	return &AllEnvWatcher{caller, id}
+}
+
+func (watcher *AllEnvWatcher) Next() ([]multiwatcher.EnvironDelta, error) {
+	var info params.AllEnvWatcherNextResults
+	err := watcher.caller.APICall(
+		"AllEnvWatcher", watcher.caller.BestFacadeVersion("AllEnvWatcher"),
+		*watcher.id, "Next", nil, &info)
+	return info.Deltas, err
+}
+
+func (watcher *AllEnvWatcher) Stop() error {
+	return watcher.caller.APICall(
+		"AllEnvWatcher", watcher.caller.BestFacadeVersion("AllEnvWatcher"),
+		*watcher.id, "Stop", nil, nil)
+}
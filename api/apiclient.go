@@ -8,13 +8,15 @@ import (
 	"crypto/x509"
 	"fmt"
 	"io"
+	"math"
+	"math/rand"
 	"strings"
+	"sync"
 	"time"
 
 	"code.google.com/p/go.net/websocket"
 	"github.com/juju/loggo"
 	"github.com/juju/names"
-	"github.com/juju/utils"
 	"github.com/juju/utils/parallel"
 
 	"github.com/juju/juju/apiserver/params"
@@ -113,6 +115,17 @@ type DialOpts struct {
 	// RetryDelay is the amount of time to wait between
 	// unsucssful connection attempts.
 	RetryDelay time.Duration
+
+	// RetryBackoffFactor, if greater than 1, is the factor by which
+	// the delay between connection attempts grows after each
+	// unsuccessful attempt, starting from RetryDelay. The delays
+	// are randomly shortened by up to half, so that many clients
+	// losing their connection at once do not all retry together.
+	RetryBackoffFactor float64
+
+	// MaxRetryDelay, if non-zero, limits the delay between
+	// connection attempts when RetryBackoffFactor is used.
+	MaxRetryDelay time.Duration
 }
 
 // DefaultDialOpts returns a DialOpts representing the default
@@ -122,7 +135,50 @@ func DefaultDialOpts() DialOpts {
 		DialAddressInterval: 50 * time.Millisecond,
 		Timeout:             10 * time.Minute,
 		RetryDelay:          2 * time.Second,
+		RetryBackoffFactor:  2,
+		MaxRetryDelay:       30 * time.Second,
+	}
+}
+
+// retryDelays returns a function that returns the time to wait
+// before each successive connection attempt.
+func (opts DialOpts) retryDelays() func() time.Duration {
+	delay := opts.RetryDelay
+	return func() time.Duration {
+		if opts.RetryBackoffFactor <= 1 {
+			return delay
+		}
+		if opts.MaxRetryDelay > 0 && delay > opts.MaxRetryDelay {
+			delay = opts.MaxRetryDelay
+		}
+		current := delay
+		if next := float64(delay) * opts.RetryBackoffFactor; next >= math.MaxInt64 {
+			delay = math.MaxInt64
+		} else {
+			delay = time.Duration(next)
+		}
+		if opts.MaxRetryDelay > 0 && delay > opts.MaxRetryDelay {
+			delay = opts.MaxRetryDelay
+		}
+		return jitter(current)
+	}
+}
+
+var (
+	jitterMu   sync.Mutex
+	jitterRand = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// jitter returns a random duration between half of d and d. It is a
+// variable so it can be replaced in tests.
+var jitter = func(d time.Duration) time.Duration {
+	half := d / 2
+	if d-half <= 0 {
+		return d
 	}
+	jitterMu.Lock()
+	defer jitterMu.Unlock()
+	return half + time.Duration(jitterRand.Int63n(int64(d-half)))
 }
 
 func Open(info *Info, opts DialOpts) (*State, error) {
@@ -238,12 +294,10 @@ func setUpWebsocket(addr, environUUID string, rootCAs *x509.CertPool) (*websocke
 // newWebsocketDialer returns a function that
 // can be passed to utils/parallel.Try.Start.
 func newWebsocketDialer(cfg *websocket.Config, opts DialOpts) func(<-chan struct{}) (io.Closer, error) {
-	openAttempt := utils.AttemptStrategy{
-		Total: opts.Timeout,
-		Delay: opts.RetryDelay,
-	}
 	return func(stop <-chan struct{}) (io.Closer, error) {
-		for a := openAttempt.Start(); a.Next(); {
+		deadline := time.Now().Add(opts.Timeout)
+		retryDelay := opts.retryDelays()
+		for {
 			select {
 			case <-stop:
 				return nil, parallel.ErrStopped
@@ -254,14 +308,18 @@ func newWebsocketDialer(cfg *websocket.Config, opts DialOpts) func(<-chan struct
 			if err == nil {
 				return conn, nil
 			}
-			if a.HasNext() {
-				logger.Debugf("error dialing %q, will retry: %v", cfg.Location, err)
-			} else {
+			delay := retryDelay()
+			if time.Now().Add(delay).After(deadline) {
 				logger.Infof("error dialing %q: %v", cfg.Location, err)
 				return nil, fmt.Errorf("unable to connect to %q", cfg.Location)
 			}
+			logger.Debugf("error dialing %q, will retry in %v: %v", cfg.Location, delay, err)
+			select {
+			case <-stop:
+				return nil, parallel.ErrStopped
+			case <-time.After(delay):
+			}
 		}
-		panic("unreachable")
 	}
 }
 
@@ -282,6 +340,16 @@ func (s *State) Ping() error {
 	return s.APICall("Pinger", s.BestFacadeVersion("Pinger"), "", "Ping", nil, nil)
 }
 
+// PingLatency pings the API server, returning
+// the round-trip time of the call.
+func (s *State) PingLatency() (time.Duration, error) {
+	start := time.Now()
+	if err := s.Ping(); err != nil {
+		return 0, err
+	}
+	return time.Since(start), nil
+}
+
 // APICall places a call to the remote machine.
 //
 // This fills out the rpc.Request on the given facade, version for a given
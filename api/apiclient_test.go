@@ -8,6 +8,7 @@ import (
 	"io"
 	"net"
 	"strconv"
+	"time"
 
 	"github.com/juju/names"
 	jc "github.com/juju/testing/checkers"
@@ -164,6 +165,91 @@ func (s *apiclientSuite) TestDialWebsocketStopped(c *gc.C) {
 	c.Assert(result, gc.IsNil)
 }
 
+func (s *apiclientSuite) TestRetryDelaysFixed(c *gc.C) {
+	delays := api.RetryDelays(api.DialOpts{RetryDelay: time.Second}, 3)
+	c.Assert(delays, jc.DeepEquals, []time.Duration{time.Second, time.Second, time.Second})
+}
+
+func (s *apiclientSuite) TestRetryDelaysBackoff(c *gc.C) {
+	s.PatchValue(api.Jitter, func(d time.Duration) time.Duration { return d })
+	delays := api.RetryDelays(api.DialOpts{
+		RetryDelay:         time.Second,
+		RetryBackoffFactor: 2,
+		MaxRetryDelay:      5 * time.Second,
+	}, 5)
+	c.Assert(delays, jc.DeepEquals, []time.Duration{
+		time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second,
+	})
+}
+
+func (s *apiclientSuite) TestRetryDelaysJitter(c *gc.C) {
+	delays := api.RetryDelays(api.DialOpts{
+		RetryDelay:         time.Second,
+		RetryBackoffFactor: 3,
+	}, 4)
+	max := time.Second
+	for i, delay := range delays {
+		c.Check(delay >= max/2, jc.IsTrue, gc.Commentf("delay %d: %v", i, delay))
+		c.Check(delay <= max, jc.IsTrue, gc.Commentf("delay %d: %v", i, delay))
+		max *= 3
+	}
+}
+
+func (s *apiclientSuite) TestPingLatency(c *gc.C) {
+	st, err := api.Open(s.APIInfo(c), api.DialOpts{})
+	c.Assert(err, jc.ErrorIsNil)
+	defer st.Close()
+	latency, err := st.PingLatency()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(latency > 0, jc.IsTrue)
+}
+
+func (s *apiclientSuite) TestKeepAliveStop(c *gc.C) {
+	st, err := api.Open(s.APIInfo(c), api.DialOpts{})
+	c.Assert(err, jc.ErrorIsNil)
+	defer st.Close()
+	keepAlive := st.KeepAlive(coretesting.ShortWait / 10)
+	select {
+	case err := <-keepAlive.Failed():
+		c.Fatalf("unexpected keep-alive failure: %v", err)
+	case <-time.After(coretesting.ShortWait):
+	}
+	keepAlive.Stop()
+	keepAlive.Stop()
+}
+
+func (s *apiclientSuite) TestKeepAliveReportsFailure(c *gc.C) {
+	st, err := api.Open(s.APIInfo(c), api.DialOpts{})
+	c.Assert(err, jc.ErrorIsNil)
+	defer st.Close()
+	keepAlive := st.KeepAlive(coretesting.ShortWait / 10)
+	defer keepAlive.Stop()
+
+	// Break the connection without closing the State.
+	err = st.RPCClient().Close()
+	c.Assert(err, jc.ErrorIsNil)
+	select {
+	case err := <-keepAlive.Failed():
+		c.Assert(err, gc.ErrorMatches, "connection is shut down")
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("keep-alive failure not reported")
+	}
+}
+
+func (s *apiclientSuite) TestKeepAliveStopsOnClose(c *gc.C) {
+	st, err := api.Open(s.APIInfo(c), api.DialOpts{})
+	c.Assert(err, jc.ErrorIsNil)
+	keepAlive := st.KeepAlive(coretesting.ShortWait / 10)
+	err = st.Close()
+	c.Assert(err, jc.ErrorIsNil)
+	keepAlive.Stop()
+	select {
+	case err := <-keepAlive.Failed():
+		c.Fatalf("unexpected keep-alive failure: %v", err)
+	default:
+	}
+}
+
 func (*websocketSuite) TestSetUpWebsocketConfig(c *gc.C) {
 	conf, err := api.SetUpWebsocket("0.1.2.3:1234", "", nil)
 	c.Assert(err, jc.ErrorIsNil)
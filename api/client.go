@@ -24,6 +24,9 @@ import (
 	"gopkg.in/juju/charm.v4"
 
 	"github.com/juju/juju/api/base"
+	"github.com/juju/juju/api/charms"
+	"github.com/juju/juju/api/highavailability"
+	"github.com/juju/juju/api/watcher"
 	"github.com/juju/juju/apiserver/params"
 	"github.com/juju/juju/constraints"
 	"github.com/juju/juju/instance"
@@ -163,6 +166,46 @@ func (c *Client) Status(patterns []string) (*Status, error) {
 	return &result, nil
 }
 
+// MachinesStatus returns the status of each of the machines with the
+// given ids, keyed by id, in a single call. Machines that do not exist
+// have an Error for which params.IsCodeNotFound returns true.
+func (c *Client) MachinesStatus(ids []string) (map[string]params.MachineStatusResult, error) {
+	args := params.Entities{Entities: make([]params.Entity, len(ids))}
+	for i, id := range ids {
+		if !names.IsValidMachine(id) {
+			return nil, errors.NotValidf("machine id %q", id)
+		}
+		args.Entities[i].Tag = names.NewMachineTag(id).String()
+	}
+	var results params.MachineStatusResults
+	if err := c.facade.FacadeCall("MachinesStatus", args, &results); err != nil {
+		return nil, err
+	}
+	if len(results.Results) != len(ids) {
+		return nil, errors.Errorf("expected %d results, got %d", len(ids), len(results.Results))
+	}
+	statuses := make(map[string]params.MachineStatusResult, len(ids))
+	for i, id := range ids {
+		statuses[id] = results.Results[i]
+	}
+	return statuses, nil
+}
+
+// UnitStatusHistory returns up to size of the statuses most recently
+// set for the named unit and its agent, most recent first. Fewer are
+// returned if the history is shorter.
+func (c *Client) UnitStatusHistory(unitName string, size int) ([]params.StatusHistoryEntry, error) {
+	if !names.IsValidUnit(unitName) {
+		return nil, errors.NotValidf("unit name %q", unitName)
+	}
+	args := params.UnitStatusHistory{Name: unitName, Size: size}
+	var result params.UnitStatusHistoryResult
+	if err := c.facade.FacadeCall("UnitStatusHistory", args, &result); err != nil {
+		return nil, err
+	}
+	return result.Statuses, nil
+}
+
 // LegacyMachineStatus holds just the instance-id of a machine.
 type LegacyMachineStatus struct {
 	InstanceId string // Not type instance.Id just to match original api.
@@ -194,7 +237,10 @@ func (c *Client) ServiceSet(service string, options map[string]string) error {
 	return c.facade.FacadeCall("NewServiceSetForClientAPI", p, nil)
 }
 
-// ServiceUnset resets configuration options on a service.
+// ServiceUnset resets configuration options on a service, so that
+// they revert to the charm's defaults. If any option is not defined
+// by the service's charm, an error naming each unknown option is
+// returned and no option is reset.
 func (c *Client) ServiceUnset(service string, options []string) error {
 	p := params.ServiceUnset{
 		ServiceName: service,
@@ -212,6 +258,27 @@ func (c *Client) Resolved(unit string, retry bool) error {
 	return c.facade.FacadeCall("Resolved", p, nil)
 }
 
+// ResolveUnits clears errors on each of the given units, and
+// returns the result of doing so for each unit, in order. If
+// retryHooks is true, the failed hooks are run again.
+func (c *Client) ResolveUnits(unitNames []string, retryHooks bool) ([]params.ErrorResult, error) {
+	p := params.ResolveUnits{
+		UnitNames: unitNames,
+		Retry:     retryHooks,
+	}
+	var results params.ErrorResults
+	err := c.facade.FacadeCall("ResolveUnits", p, &results)
+	return results.Results, err
+}
+
+// UnitsInError returns the units in an error state, ordered by
+// name, with the messages describing their errors.
+func (c *Client) UnitsInError() ([]params.UnitError, error) {
+	var results params.UnitsInErrorResults
+	err := c.facade.FacadeCall("UnitsInError", nil, &results)
+	return results.Units, err
+}
+
 // RetryProvisioning updates the provisioning status of a machine allowing the
 // provisioner to retry.
 func (c *Client) RetryProvisioning(machines ...names.MachineTag) ([]params.ErrorResult, error) {
@@ -234,6 +301,91 @@ func (c *Client) PublicAddress(target string) (string, error) {
 	return results.PublicAddress, err
 }
 
+// UnitAddresses holds the addresses of a unit.
+type UnitAddresses struct {
+	result params.UnitAddressesResult
+}
+
+// PublicAddress returns the public address of the
+// unit and whether it is valid.
+func (a *UnitAddresses) PublicAddress() (string, bool) {
+	return a.result.PublicAddress, a.result.PublicAddressSet
+}
+
+// PrivateAddress returns the private address of the
+// unit and whether it is valid.
+func (a *UnitAddresses) PrivateAddress() (string, bool) {
+	return a.result.PrivateAddress, a.result.PrivateAddressSet
+}
+
+// GetMeterStatus returns the meter status code and information of
+// the specified unit. An error satisfying params.IsCodeNotFound is
+// returned if the unit has no meter status set.
+func (c *Client) GetMeterStatus(unitName string) (code, info string, err error) {
+	if !names.IsValidUnit(unitName) {
+		return "", "", errors.NotValidf("unit name %q", unitName)
+	}
+	var result params.MeterStatusResult
+	args := params.Entity{Tag: names.NewUnitTag(unitName).String()}
+	if err := c.facade.FacadeCall("GetMeterStatus", args, &result); err != nil {
+		return "", "", err
+	}
+	return result.Code, result.Info, nil
+}
+
+// SetMeterStatus sets the meter status code and information of
+// the specified unit. It is intended for testing and administration;
+// meter statuses are normally set from the metrics collected for a
+// unit.
+func (c *Client) SetMeterStatus(unitName, code, info string) error {
+	if !names.IsValidUnit(unitName) {
+		return errors.NotValidf("unit name %q", unitName)
+	}
+	args := params.SetMeterStatus{
+		Tag:  names.NewUnitTag(unitName).String(),
+		Code: code,
+		Info: info,
+	}
+	return c.facade.FacadeCall("SetMeterStatus", args, nil)
+}
+
+// TransferLeadership hands leadership of the named service from the
+// unit holding it directly to toUnitName, which must be an alive unit
+// of the same service.
+func (c *Client) TransferLeadership(serviceName, fromUnitName, toUnitName string) error {
+	if !names.IsValidService(serviceName) {
+		return errors.NotValidf("service name %q", serviceName)
+	}
+	for _, unitName := range []string{fromUnitName, toUnitName} {
+		if !names.IsValidUnit(unitName) {
+			return errors.NotValidf("unit name %q", unitName)
+		}
+	}
+	args := params.TransferLeadershipParams{
+		ServiceTag:  names.NewServiceTag(serviceName).String(),
+		FromUnitTag: names.NewUnitTag(fromUnitName).String(),
+		ToUnitTag:   names.NewUnitTag(toUnitName).String(),
+	}
+	return c.facade.FacadeCall("TransferLeadership", args, nil)
+}
+
+// GetUnitAddresses returns the public and private addresses of the
+// specified unit. Unlike PublicAddress and PrivateAddress, a unit
+// without an address is not an error; however an error satisfying
+// params.IsCodeNotAssigned is returned if the unit has not yet been
+// assigned to a machine.
+func (c *Client) GetUnitAddresses(unitName string) (*UnitAddresses, error) {
+	if !names.IsValidUnit(unitName) {
+		return nil, errors.NotValidf("unit name %q", unitName)
+	}
+	var result params.UnitAddressesResult
+	args := params.Entity{Tag: names.NewUnitTag(unitName).String()}
+	if err := c.facade.FacadeCall("UnitAddresses", args, &result); err != nil {
+		return nil, err
+	}
+	return &UnitAddresses{result}, nil
+}
+
 // PrivateAddress returns the private address of the specified
 // machine or unit.
 func (c *Client) PrivateAddress(target string) (string, error) {
@@ -275,6 +427,50 @@ func (c *Client) DestroyRelation(endpoints ...string) error {
 	return c.facade.FacadeCall("DestroyRelation", params, nil)
 }
 
+// GetRelationSettings returns the settings of the named unit within the
+// relation with the given id. If the relation, the unit, or the unit's
+// settings within the relation do not exist, the error satisfies
+// params.IsCodeNotFound.
+func (c *Client) GetRelationSettings(relationId int, unitName string) (map[string]string, error) {
+	var result params.SettingsResult
+	args := params.GetRelationSettings{RelationId: relationId, UnitName: unitName}
+	if err := c.facade.FacadeCall("GetRelationSettings", args, &result); err != nil {
+		return nil, err
+	}
+	return result.Settings, nil
+}
+
+// SetRelationSettings changes the settings of the named unit within the
+// relation with the given id. Settings given empty values are removed.
+// If the relation, the unit, or the unit's settings within the relation
+// do not exist, the error satisfies params.IsCodeNotFound.
+func (c *Client) SetRelationSettings(relationId int, unitName string, settings map[string]string) error {
+	args := params.SetRelationSettings{
+		RelationId: relationId,
+		UnitName:   unitName,
+		Settings:   settings,
+	}
+	return c.facade.FacadeCall("SetRelationSettings", args, nil)
+}
+
+// WatchRelationUnits returns a watcher that reports the units of the
+// relation with the given id, as seen by the named unit, entering and
+// leaving scope, along with the versions of their settings whenever
+// those change. Its first event reports the units already in scope.
+// If the relation or the unit do not exist, the error satisfies
+// params.IsCodeNotFound.
+func (c *Client) WatchRelationUnits(relationId int, unitName string) (watcher.RelationUnitsWatcher, error) {
+	var result params.RelationUnitsWatchResult
+	args := params.WatchRelationUnits{RelationId: relationId, UnitName: unitName}
+	if err := c.facade.FacadeCall("WatchRelationUnits", args, &result); err != nil {
+		return nil, err
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return watcher.NewRelationUnitsWatcher(c.facade.RawAPICaller(), result), nil
+}
+
 // ServiceCharmRelations returns the service's charms relation names.
 func (c *Client) ServiceCharmRelations(service string) ([]string, error) {
 	var results params.ServiceCharmRelationsResults
@@ -331,17 +527,29 @@ func (c *Client) ForceDestroyMachines(machines ...string) error {
 }
 
 // ServiceExpose changes the juju-managed firewall to expose any ports that
-// were also explicitly marked by units as open.
+// were also explicitly marked by units as open. If the service does not
+// exist, an error satisfying errors.IsNotFound is returned.
 func (c *Client) ServiceExpose(service string) error {
-	params := params.ServiceExpose{ServiceName: service}
-	return c.facade.FacadeCall("ServiceExpose", params, nil)
+	p := params.ServiceExpose{ServiceName: service}
+	return serviceError(service, c.facade.FacadeCall("ServiceExpose", p, nil))
 }
 
 // ServiceUnexpose changes the juju-managed firewall to unexpose any ports that
-// were also explicitly marked by units as open.
+// were also explicitly marked by units as open. If the service does not
+// exist, an error satisfying errors.IsNotFound is returned.
 func (c *Client) ServiceUnexpose(service string) error {
-	params := params.ServiceUnexpose{ServiceName: service}
-	return c.facade.FacadeCall("ServiceUnexpose", params, nil)
+	p := params.ServiceUnexpose{ServiceName: service}
+	return serviceError(service, c.facade.FacadeCall("ServiceUnexpose", p, nil))
+}
+
+// serviceError returns an error satisfying errors.IsNotFound in place
+// of a not-found error reported by the API server for the named service.
+// Any other error is returned unchanged.
+func serviceError(service string, err error) error {
+	if params.IsCodeNotFound(err) {
+		return errors.NotFoundf("service %q", service)
+	}
+	return err
 }
 
 // ServiceDeployWithNetworks works exactly like ServiceDeploy, but
@@ -432,16 +640,45 @@ func (c *Client) DestroyServiceUnits(unitNames ...string) error {
 	return c.facade.FacadeCall("DestroyServiceUnits", params, nil)
 }
 
-// ServiceDestroy destroys a given service.
+// ScaleService adds or destroys units of the service so that it has
+// numUnits alive units, and returns their names in the order they were
+// added. Units that are already dying are not counted; when there are
+// too many units, the most recently added are destroyed first. If
+// destroying a unit fails, the names of the units still alive are
+// returned with the error, which names the units already destroyed.
+// If the service does not exist, the error satisfies
+// params.IsCodeNotFound.
+func (c *Client) ScaleService(service string, numUnits int) ([]string, error) {
+	args := params.ScaleService{
+		ServiceName: service,
+		NumUnits:    numUnits,
+	}
+	var result params.ScaleServiceResults
+	if err := c.facade.FacadeCall("ScaleService", args, &result); err != nil {
+		return nil, err
+	}
+	if result.Error != nil {
+		return result.Units, result.Error
+	}
+	return result.Units, nil
+}
+
+// ServiceDestroy marks the given service for removal. If the service
+// still has units or relations, it is removed once they have gone. If
+// the service does not exist, the error satisfies params.IsCodeNotFound.
 func (c *Client) ServiceDestroy(service string) error {
-	params := params.ServiceDestroy{
+	p := params.ServiceDestroy{
 		ServiceName: service,
 	}
-	return c.facade.FacadeCall("ServiceDestroy", params, nil)
+	return c.facade.FacadeCall("ServiceDestroy", p, nil)
 }
 
 // GetServiceConstraints returns the constraints for the given service.
+// If the service does not exist, the error satisfies params.IsCodeNotFound.
 func (c *Client) GetServiceConstraints(service string) (constraints.Value, error) {
+	if !names.IsValidService(service) {
+		return constraints.Value{}, errors.NotValidf("service name %q", service)
+	}
 	results := new(params.GetConstraintsResults)
 	err := c.facade.FacadeCall("GetServiceConstraints", params.GetServiceConstraints{service}, results)
 	return results.Constraints, err
@@ -455,10 +692,19 @@ func (c *Client) GetEnvironmentConstraints() (constraints.Value, error) {
 }
 
 // SetServiceConstraints specifies the constraints for the given service.
-func (c *Client) SetServiceConstraints(service string, constraints constraints.Value) error {
+// The constraints are checked as they would be when parsed before they
+// are sent. If the service does not exist, the error satisfies
+// params.IsCodeNotFound.
+func (c *Client) SetServiceConstraints(service string, cons constraints.Value) error {
+	if !names.IsValidService(service) {
+		return errors.NotValidf("service name %q", service)
+	}
+	if _, err := constraints.Parse(cons.String()); err != nil {
+		return errors.Annotatef(err, "invalid constraints for service %q", service)
+	}
 	params := params.SetConstraints{
 		ServiceName: service,
-		Constraints: constraints,
+		Constraints: cons,
 	}
 	return c.facade.FacadeCall("SetServiceConstraints", params, nil)
 }
@@ -471,6 +717,65 @@ func (c *Client) SetEnvironmentConstraints(constraints constraints.Value) error
 	return c.facade.FacadeCall("SetEnvironmentConstraints", params, nil)
 }
 
+// constraintNames holds the supported constraint attribute names.
+var constraintNames = []string{
+	constraints.Arch,
+	constraints.Container,
+	constraints.CpuCores,
+	constraints.CpuPower,
+	constraints.Mem,
+	constraints.RootDisk,
+	constraints.Tags,
+	constraints.InstanceType,
+	constraints.Networks,
+}
+
+// ParseConstraints parses a constraints string, such as
+// "mem=4G cpu-cores=2", into the value accepted by AddMachines,
+// ServiceDeploy and the constraints setters, so that mistakes can
+// be reported before making any request. If the string names an
+// unknown attribute that differs from a supported one only in case
+// or separators, the error suggests the supported name.
+func ParseConstraints(cons string) (constraints.Value, error) {
+	value, err := constraints.Parse(cons)
+	if err == nil {
+		return value, nil
+	}
+	for _, raw := range strings.Fields(cons) {
+		name := raw
+		if eq := strings.Index(raw, "="); eq > 0 {
+			name = raw[:eq]
+		}
+		if suggestion := suggestConstraintName(name); suggestion != "" {
+			return constraints.Value{}, errors.Errorf(
+				"invalid constraints %q: unknown constraint %q (did you mean %q?)",
+				cons, name, suggestion,
+			)
+		}
+	}
+	return constraints.Value{}, errors.Annotatef(err, "invalid constraints %q", cons)
+}
+
+// suggestConstraintName returns the supported constraint attribute
+// name that the given unknown name was likely intended to be, or ""
+// if the name is supported or there is no likely candidate.
+func suggestConstraintName(name string) string {
+	normalise := func(s string) string {
+		return strings.Replace(strings.ToLower(s), "_", "-", -1)
+	}
+	for _, known := range constraintNames {
+		if name == known {
+			return ""
+		}
+	}
+	for _, known := range constraintNames {
+		if normalise(name) == known {
+			return known
+		}
+	}
+	return ""
+}
+
 // CharmInfo holds information about a charm.
 type CharmInfo struct {
 	Revision int
@@ -490,6 +795,36 @@ func (c *Client) CharmInfo(charmURL string) (*CharmInfo, error) {
 	return info, nil
 }
 
+// GetCharmInfo returns information about the charm with the given URL,
+// including its metadata, configuration schema and actions. The request
+// is made on the Charms facade.
+func (c *Client) GetCharmInfo(curl *charm.URL) (*CharmInfo, error) {
+	info, err := charms.NewClient(c.st).CharmInfo(curl.String())
+	if err != nil {
+		return nil, err
+	}
+	return (*CharmInfo)(info), nil
+}
+
+// ListCharms returns the URLs of the charms in the environment.
+// If any names are supplied, only charms with those names are
+// returned. The request is made on the Charms facade.
+func (c *Client) ListCharms(names ...string) ([]*charm.URL, error) {
+	urls, err := charms.NewClient(c.st).List(names)
+	if err != nil {
+		return nil, err
+	}
+	curls := make([]*charm.URL, len(urls))
+	for i, url := range urls {
+		curl, err := charm.ParseURL(url)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		curls[i] = curl
+	}
+	return curls, nil
+}
+
 // EnvironmentInfo holds information about the Juju environment.
 type EnvironmentInfo struct {
 	DefaultSeries string
@@ -505,6 +840,16 @@ func (c *Client) EnvironmentInfo() (*EnvironmentInfo, error) {
 	return info, err
 }
 
+// EnvironCredential reports the cloud and region hosting the
+// environment, and the names of the secret attributes it uses to
+// authenticate with the cloud. If the environment uses no
+// credentials, no attributes are reported; this is not an error.
+func (c *Client) EnvironCredential() (params.EnvironCredential, error) {
+	var result params.EnvironCredential
+	err := c.facade.FacadeCall("EnvironCredential", nil, &result)
+	return result, err
+}
+
 // EnvironmentUUID returns the environment UUID from the client connection.
 func (c *Client) EnvironmentUUID() string {
 	tag, err := c.st.EnvironTag()
@@ -570,6 +915,130 @@ func (c *Client) WatchAll() (*AllWatcher, error) {
 	return newAllWatcher(c.st, &info.AllWatcherId), nil
 }
 
+// WatchAllEnvs returns an AllEnvWatcher, from which you can request
+// the Next collection of Deltas for all the environments managed by
+// the state server. It may only be used when connected to the state
+// server's own environment.
+func (c *Client) WatchAllEnvs() (*AllEnvWatcher, error) {
+	info := new(WatchAll)
+	if err := c.facade.FacadeCall("WatchAllEnvs", nil, info); err != nil {
+		return nil, err
+	}
+	return newAllEnvWatcher(c.st, &info.AllWatcherId), nil
+}
+
+// AllSnapshot returns the current state of the entire environment:
+// its machines, services, units, relations and so on. The state is
+// described by the same deltas that an AllWatcher first returns from
+// Next, but no watcher is left to be stopped.
+func (c *Client) AllSnapshot() ([]multiwatcher.Delta, error) {
+	var info params.AllWatcherNextResults
+	if err := c.facade.FacadeCall("AllSnapshot", nil, &info); err != nil {
+		return nil, err
+	}
+	return info.Deltas, nil
+}
+
+// WatchEnvironConfig returns a NotifyWatcher that fires when the
+// environment configuration changes. The current configuration
+// can then be read with EnvironmentGet.
+func (c *Client) WatchEnvironConfig() (watcher.NotifyWatcher, error) {
+	var result params.NotifyWatchResult
+	if err := c.facade.FacadeCall("WatchEnvironConfig", nil, &result); err != nil {
+		return nil, err
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return watcher.NewNotifyWatcher(c.facade.RawAPICaller(), result), nil
+}
+
+// WatchForUpgradeAvailable returns a NotifyWatcher that fires when
+// tools newer than the environment's agent version, with the same
+// major version, become available. The available versions can then
+// be read with FindTools.
+func (c *Client) WatchForUpgradeAvailable() (watcher.NotifyWatcher, error) {
+	var result params.NotifyWatchResult
+	if err := c.facade.FacadeCall("WatchForUpgradeAvailable", nil, &result); err != nil {
+		return nil, err
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return watcher.NewNotifyWatcher(c.facade.RawAPICaller(), result), nil
+}
+
+// WatchService returns a StringsWatcher that notifies of changes to the
+// given service's units. Its changes hold the names of the units that
+// have been added, have changed or have been removed. If the service
+// does not exist, the error satisfies params.IsCodeNotFound.
+func (c *Client) WatchService(service string) (watcher.StringsWatcher, error) {
+	if !names.IsValidService(service) {
+		return nil, errors.NotValidf("service name %q", service)
+	}
+	var result params.StringsWatchResult
+	args := params.Entity{Tag: names.NewServiceTag(service).String()}
+	if err := c.facade.FacadeCall("WatchService", args, &result); err != nil {
+		return nil, err
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return watcher.NewStringsWatcher(c.facade.RawAPICaller(), result), nil
+}
+
+// WatchUnit returns a NotifyWatcher that fires when the given unit
+// changes. If the unit does not exist, the error satisfies
+// params.IsCodeNotFound.
+func (c *Client) WatchUnit(unit string) (watcher.NotifyWatcher, error) {
+	if !names.IsValidUnit(unit) {
+		return nil, errors.NotValidf("unit name %q", unit)
+	}
+	var result params.NotifyWatchResult
+	args := params.Entity{Tag: names.NewUnitTag(unit).String()}
+	if err := c.facade.FacadeCall("WatchUnit", args, &result); err != nil {
+		return nil, err
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return watcher.NewNotifyWatcher(c.facade.RawAPICaller(), result), nil
+}
+
+// WatchMachineAddresses returns a watcher that reports the addresses
+// of machines whenever they change. The first event holds the addresses
+// of all machines in the environment.
+func (c *Client) WatchMachineAddresses() (*MachineAddressesWatcher, error) {
+	var result params.StringsWatchResult
+	if err := c.facade.FacadeCall("WatchMachineAddresses", nil, &result); err != nil {
+		return nil, err
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	w := watcher.NewStringsWatcher(c.facade.RawAPICaller(), result)
+	return newMachineAddressesWatcher(c, w), nil
+}
+
+// WatchActionResults returns a watcher that reports the results of the
+// actions with the given tags as they are completed, cancelled or fail.
+// The results of any that already have are reported at once.
+func (c *Client) WatchActionResults(actionTags []string) (*ActionResultsWatcher, error) {
+	args := params.Entities{Entities: make([]params.Entity, len(actionTags))}
+	for i, tag := range actionTags {
+		args.Entities[i].Tag = tag
+	}
+	var result params.StringsWatchResult
+	if err := c.facade.FacadeCall("WatchActionResults", args, &result); err != nil {
+		return nil, err
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	w := watcher.NewStringsWatcher(c.facade.RawAPICaller(), result)
+	return newActionResultsWatcher(c, w), nil
+}
+
 // GetAnnotations returns annotations that have been set on the given entity.
 // This API is now deprecated - "Annotations" client should be used instead.
 // TODO(anastasiamac) remove for Juju 2.x
@@ -605,6 +1074,16 @@ func (c *Client) EnvironmentGet() (map[string]interface{}, error) {
 	return result.Config, err
 }
 
+// StateServerConfig returns the settings that configure the state
+// servers, rather than the environment: the UUID of the environment
+// they were bootstrapped with, their ports and their CA certificate.
+// Secrets are not included.
+func (c *Client) StateServerConfig() (map[string]interface{}, error) {
+	result := params.EnvironmentConfigResults{}
+	err := c.facade.FacadeCall("StateServerConfig", nil, &result)
+	return result.Config, err
+}
+
 // EnvironmentSet sets the given key-value pairs in the environment.
 func (c *Client) EnvironmentSet(config map[string]interface{}) error {
 	args := params.EnvironmentSet{Config: config}
@@ -624,12 +1103,45 @@ func (c *Client) SetEnvironAgentVersion(version version.Number) error {
 	return c.facade.FacadeCall("SetEnvironAgentVersion", args, nil)
 }
 
+// StageEnvironAgentVersion sets the environment agent-version setting
+// to the given value, as SetEnvironAgentVersion does, but only if the
+// API server can find tools matching the version. If it cannot, the
+// error returned is tools.ErrNoMatches and the setting is unchanged.
+func (c *Client) StageEnvironAgentVersion(version version.Number) error {
+	args := params.SetEnvironAgentVersion{Version: version}
+	err := c.facade.FacadeCall("StageEnvironAgentVersion", args, nil)
+	if params.IsCodeNotFound(err) {
+		return tools.ErrNoMatches
+	}
+	return err
+}
+
+// EnvironAgentVersion reports the agent version configured for the
+// environment. Unlike AgentVersion, which reports the version of the
+// api server binary, this is the version agents are running or are
+// being upgraded to.
+func (c *Client) EnvironAgentVersion() (version.Number, error) {
+	var result params.AgentVersionResult
+	if err := c.facade.FacadeCall("EnvironAgentVersion", nil, &result); err != nil {
+		return version.Number{}, err
+	}
+	return result.Version, nil
+}
+
 // AbortCurrentUpgrade aborts and archives the current upgrade
 // synchronisation record, if any.
 func (c *Client) AbortCurrentUpgrade() error {
 	return c.facade.FacadeCall("AbortCurrentUpgrade", nil, nil)
 }
 
+// RollbackCurrentUpgrade aborts the current upgrade and tells the
+// controller to set the environment's agent version back to the
+// version being upgraded from. An error is returned if no upgrade is
+// in progress.
+func (c *Client) RollbackCurrentUpgrade() error {
+	return c.facade.FacadeCall("RollbackCurrentUpgrade", nil, nil)
+}
+
 // FindTools returns a List containing all tools matching the specified parameters.
 func (c *Client) FindTools(
 	majorVersion, minorVersion int,
@@ -645,6 +1157,26 @@ func (c *Client) FindTools(
 	return result, err
 }
 
+// FindToolsList returns the tools matching the specified parameters,
+// as seen from the API server. If no tools match, the error returned
+// is tools.ErrNoMatches.
+func (c *Client) FindToolsList(
+	majorVersion, minorVersion int,
+	series, arch string,
+) (tools.List, error) {
+	result, err := c.FindTools(majorVersion, minorVersion, series, arch)
+	if err != nil {
+		return nil, err
+	}
+	if result.Error != nil {
+		if params.IsCodeNotFound(result.Error) {
+			return nil, tools.ErrNoMatches
+		}
+		return nil, result.Error
+	}
+	return result.List, nil
+}
+
 // RunOnAllMachines runs the command on all the machines with the specified
 // timeout.
 func (c *Client) RunOnAllMachines(commands string, timeout time.Duration) ([]params.RunResult, error) {
@@ -748,6 +1280,43 @@ func (c *Client) AddLocalCharm(curl *charm.URL, ch charm.Charm) (*charm.URL, err
 	return charm.MustParseURL(jsonResponse.CharmURL), nil
 }
 
+// DeployLocalCharm adds the charm in charmDir to the environment as a
+// local charm for the given series, and deploys it as ServiceDeploy
+// does. If the environment already holds a charm with the same URL,
+// that charm is deployed and nothing is uploaded. It returns the URL
+// of the deployed charm, which may have a different revision to the
+// charm directory if another charm was uploaded with its URL first.
+func (c *Client) DeployLocalCharm(
+	charmDir string,
+	series string,
+	serviceName string,
+	numUnits int,
+	configYAML string,
+	cons constraints.Value,
+	toMachineSpec string,
+) (*charm.URL, error) {
+	ch, err := charm.ReadCharmDir(charmDir)
+	if err != nil {
+		return nil, errors.Annotatef(err, "cannot read charm directory %q", charmDir)
+	}
+	curl, err := charm.ParseURL(fmt.Sprintf("local:%s/%s-%d", series, ch.Meta().Name, ch.Revision()))
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if _, err := c.CharmInfo(curl.String()); params.IsCodeNotFound(err) {
+		if curl, err = c.AddLocalCharm(curl, ch); err != nil {
+			return nil, errors.Trace(err)
+		}
+	} else if err != nil {
+		return nil, errors.Annotatef(err, "cannot get charm %q", curl)
+	}
+	err = c.ServiceDeploy(curl.String(), serviceName, numUnits, configYAML, cons, toMachineSpec)
+	if err != nil {
+		return nil, err
+	}
+	return curl, nil
+}
+
 // AddCharm adds the given charm URL (which must include revision) to
 // the environment, if it does not exist yet. Local charms are not
 // supported, only charm store URLs. See also AddLocalCharm() in the
@@ -757,6 +1326,42 @@ func (c *Client) AddCharm(curl *charm.URL) error {
 	return c.facade.FacadeCall("AddCharm", args, nil)
 }
 
+// AddStoreCharm adds the charm store charm referenced by charmURL to
+// the environment, if it does not exist yet, and returns the URL it
+// was added with. Unlike AddCharm, the URL may omit the series and
+// revision, in which case the best series for the charm and its latest
+// revision are used.
+func (c *Client) AddStoreCharm(charmURL string) (*charm.URL, error) {
+	args := params.CharmURL{URL: charmURL}
+	var result params.CharmURL
+	if err := c.facade.FacadeCall("AddStoreCharm", args, &result); err != nil {
+		return nil, err
+	}
+	return charm.ParseURL(result.URL)
+}
+
+// CollectMetrics triggers the immediate collection of metrics by the
+// named unit, or by every unit of the named service, and returns the
+// actions enqueued to collect them, one per unit. Units whose charm
+// declares no metrics are reported with an error in their result.
+func (c *Client) CollectMetrics(serviceOrUnit string) ([]params.ActionResult, error) {
+	var tag names.Tag
+	switch {
+	case names.IsValidUnit(serviceOrUnit):
+		tag = names.NewUnitTag(serviceOrUnit)
+	case names.IsValidService(serviceOrUnit):
+		tag = names.NewServiceTag(serviceOrUnit)
+	default:
+		return nil, errors.NotValidf("service or unit name %q", serviceOrUnit)
+	}
+	var results params.ActionResults
+	args := params.Entity{Tag: tag.String()}
+	if err := c.facade.FacadeCall("CollectMetrics", args, &results); err != nil {
+		return nil, err
+	}
+	return results.Results, nil
+}
+
 // ResolveCharm resolves the best available charm URLs with series, for charm
 // locations without a series specified.
 func (c *Client) ResolveCharm(ref *charm.Reference) (*charm.URL, error) {
@@ -777,6 +1382,37 @@ func (c *Client) ResolveCharm(ref *charm.Reference) (*charm.URL, error) {
 
 // UploadTools uploads tools at the specified location to the API server over HTTPS.
 func (c *Client) UploadTools(r io.Reader, vers version.Binary, additionalSeries ...string) (*tools.Tools, error) {
+	result, err := c.uploadTools(r, vers, additionalSeries)
+	if err != nil {
+		return nil, err
+	}
+	return result.Tools, nil
+}
+
+// UploadToolsList uploads tools as UploadTools does, additionally
+// registering them for the environment's default series when that
+// differs from the series of vers. It returns the tools stored for
+// each series.
+func (c *Client) UploadToolsList(r io.Reader, vers version.Binary, additionalSeries ...string) (tools.List, error) {
+	cfg, err := c.EnvironmentGet()
+	if err != nil {
+		return nil, errors.Annotate(err, "cannot get environment config")
+	}
+	if series, _ := cfg["default-series"].(string); series != "" && series != vers.Series {
+		additionalSeries = append(additionalSeries, series)
+	}
+	result, err := c.uploadTools(r, vers, additionalSeries)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.ToolsList) == 0 {
+		// Older API servers only report the tools for vers.
+		return tools.List{result.Tools}, nil
+	}
+	return result.ToolsList, nil
+}
+
+func (c *Client) uploadTools(r io.Reader, vers version.Binary, additionalSeries []string) (*params.ToolsResult, error) {
 	// Prepare the upload request.
 	url := fmt.Sprintf(
 		"%s/tools?binaryVersion=%s&series=%s",
@@ -828,7 +1464,7 @@ func (c *Client) UploadTools(r io.Reader, vers version.Binary, additionalSeries
 	if err := jsonResponse.Error; err != nil {
 		return nil, errors.Annotate(err, "error uploading tools")
 	}
-	return jsonResponse.Tools, nil
+	return &jsonResponse, nil
 }
 
 // APIHostPorts returns a slice of network.HostPort for each API server.
@@ -870,6 +1506,25 @@ func (c *Client) EnsureAvailability(numStateServers int, cons constraints.Value,
 	return result.Result, nil
 }
 
+// EnableHA ensures that the environment has numControllers state server
+// machines, adding new machines with the given constraints as necessary.
+// New machines are started according to the given placement directives,
+// in order, until the directives are used up. If numControllers is zero,
+// a default number is used. An error is returned
+// without making any request if numControllers is negative or even, as
+// a majority of an odd number of state servers is needed for quorum.
+// The request is made on the HighAvailability facade.
+func (c *Client) EnableHA(numControllers int, cons constraints.Value, placement []string) (params.StateServersChanges, error) {
+	if numControllers < 0 || (numControllers != 0 && numControllers%2 != 1) {
+		return params.StateServersChanges{}, errors.Errorf(
+			"cannot enable HA with %d state servers: the number must be odd and non-negative",
+			numControllers,
+		)
+	}
+	haClient := highavailability.NewClient(c.st)
+	return haClient.EnsureAvailability(numControllers, cons, "", placement)
+}
+
 // AgentVersion reports the version number of the api server.
 func (c *Client) AgentVersion() (version.Number, error) {
 	var result params.AgentVersionResult
@@ -25,6 +25,7 @@ import (
 
 	"github.com/juju/juju/api"
 	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/constraints"
 	jujutesting "github.com/juju/juju/juju/testing"
 	"github.com/juju/juju/state"
 	"github.com/juju/juju/testcharms"
@@ -49,6 +50,38 @@ func (s *clientSuite) TestCloseMultipleOk(c *gc.C) {
 	c.Assert(client.Close(), gc.IsNil)
 }
 
+func (s *clientSuite) TestParseConstraints(c *gc.C) {
+	cons, err := api.ParseConstraints("mem=4G cpu-cores=2")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(cons, gc.DeepEquals, constraints.MustParse("mem=4G cpu-cores=2"))
+}
+
+func (s *clientSuite) TestParseConstraintsErrors(c *gc.C) {
+	for i, test := range []struct {
+		cons   string
+		expect string
+	}{{
+		cons:   "mem=4G cpu_cores=2",
+		expect: `invalid constraints "mem=4G cpu_cores=2": unknown constraint "cpu_cores" \(did you mean "cpu-cores"\?\)`,
+	}, {
+		cons:   "Mem=4G",
+		expect: `invalid constraints "Mem=4G": unknown constraint "Mem" \(did you mean "mem"\?\)`,
+	}, {
+		cons:   "mem=4G flavour=large",
+		expect: `invalid constraints "mem=4G flavour=large": unknown constraint "flavour"`,
+	}, {
+		cons:   "mem=lots",
+		expect: `invalid constraints "mem=lots": bad "mem" constraint: .*`,
+	}, {
+		cons:   "mem",
+		expect: `invalid constraints "mem": malformed constraint "mem"`,
+	}} {
+		c.Logf("test %d: %q", i, test.cons)
+		_, err := api.ParseConstraints(test.cons)
+		c.Check(err, gc.ErrorMatches, test.expect)
+	}
+}
+
 func (s *clientSuite) TestAddLocalCharm(c *gc.C) {
 	charmArchive := testcharms.Repo.CharmArchive(c.MkDir(), "dummy")
 	curl := charm.MustParseURL(
@@ -103,6 +136,46 @@ func (s *clientSuite) TestAddLocalCharmError(c *gc.C) {
 	c.Assert(err, gc.ErrorMatches, "charm upload failed: 405 \\(Method Not Allowed\\)")
 }
 
+func (s *clientSuite) TestDeployLocalCharm(c *gc.C) {
+	charmDir := testcharms.Repo.ClonedDir(c.MkDir(), "dummy")
+	charmDir.SetDiskRevision(7)
+	client := s.APIState.Client()
+
+	curl, err := client.DeployLocalCharm(charmDir.Path, "quantal", "first", 1, "", constraints.Value{}, "")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(curl.String(), gc.Equals, "local:quantal/dummy-7")
+	service, err := s.State.Service("first")
+	c.Assert(err, jc.ErrorIsNil)
+	serviceURL, _ := service.CharmURL()
+	c.Assert(serviceURL, gc.DeepEquals, curl)
+	units, err := service.AllUnits()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(units, gc.HasLen, 1)
+
+	// The charm is already in the environment, so it is not uploaded
+	// again, which would have bumped its revision.
+	curl, err = client.DeployLocalCharm(charmDir.Path, "quantal", "second", 0, "", constraints.Value{}, "")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(curl.String(), gc.Equals, "local:quantal/dummy-7")
+	service, err = s.State.Service("second")
+	c.Assert(err, jc.ErrorIsNil)
+	serviceURL, _ = service.CharmURL()
+	c.Assert(serviceURL, gc.DeepEquals, curl)
+}
+
+func (s *clientSuite) TestDeployLocalCharmErrors(c *gc.C) {
+	client := s.APIState.Client()
+	missing := c.MkDir()
+	_, err := client.DeployLocalCharm(missing, "quantal", "dummy", 1, "", constraints.Value{}, "")
+	c.Assert(err, gc.ErrorMatches, `cannot read charm directory ".*": .*`)
+
+	charmDir := testcharms.Repo.ClonedDir(c.MkDir(), "dummy")
+	_, err = client.DeployLocalCharm(charmDir.Path, "quantal", "dummy", 1, "", constraints.Value{}, "")
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = client.DeployLocalCharm(charmDir.Path, "quantal", "dummy", 1, "", constraints.Value{}, "")
+	c.Assert(err, gc.ErrorMatches, `.*service already exists`)
+}
+
 func (s *clientSuite) TestClientEnvironmentUUID(c *gc.C) {
 	environ, err := s.State.Environment()
 	c.Assert(err, jc.ErrorIsNil)
@@ -386,6 +459,23 @@ func (s *clientSuite) TestAbortCurrentUpgrade(c *gc.C) {
 	c.Assert(err, gc.Equals, someErr) // Confirms that the correct facade was called
 }
 
+func (s *clientSuite) TestRollbackCurrentUpgrade(c *gc.C) {
+	client := s.APIState.Client()
+	someErr := errors.New("random")
+	cleanup := api.PatchClientFacadeCall(client,
+		func(request string, args interface{}, response interface{}) error {
+			c.Assert(request, gc.Equals, "RollbackCurrentUpgrade")
+			c.Assert(args, gc.IsNil)
+			c.Assert(response, gc.IsNil)
+			return someErr
+		},
+	)
+	defer cleanup()
+
+	err := client.RollbackCurrentUpgrade()
+	c.Assert(err, gc.Equals, someErr)
+}
+
 func (s *clientSuite) TestEnvironmentGet(c *gc.C) {
 	client := s.APIState.Client()
 	env, err := client.EnvironmentGet()
@@ -4,6 +4,8 @@
 package api
 
 import (
+	"time"
+
 	"github.com/juju/juju/api/base"
 	"github.com/juju/juju/network"
 )
@@ -16,8 +18,20 @@ var (
 	BestVersion         = bestVersion
 	FacadeVersions      = &facadeVersions
 	NewHTTPClient       = &newHTTPClient
+	Jitter              = &jitter
 )
 
+// RetryDelays returns the first n delays between connection
+// attempts made with the given options.
+func RetryDelays(opts DialOpts, n int) []time.Duration {
+	next := opts.retryDelays()
+	delays := make([]time.Duration, n)
+	for i := range delays {
+		delays[i] = next()
+	}
+	return delays
+}
+
 // SetServerRoot allows changing the URL to the internal API server
 // that AddLocalCharm uses in order to test NotImplementedError.
 func SetServerRoot(c *Client, root string) {
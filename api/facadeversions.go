@@ -13,6 +13,7 @@ package api
 var facadeVersions = map[string]int{
 	"Action":               0,
 	"Agent":                1,
+	"AllEnvWatcher":        0,
 	"AllWatcher":           0,
 	"Annotations":          1,
 	"Backups":              0,
@@ -0,0 +1,77 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package api
+
+import (
+	"sync"
+	"time"
+)
+
+// KeepAlive pings the API server at regular intervals, so that a dead
+// connection is detected without waiting for the next real call.
+type KeepAlive struct {
+	st       *State
+	interval time.Duration
+	failed   chan error
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// KeepAlive starts pinging the API server every interval, until the
+// returned KeepAlive is stopped, the State is closed or a ping fails.
+// The connection's own health check runs regardless; KeepAlive is
+// for callers that want failures reported promptly.
+func (s *State) KeepAlive(interval time.Duration) *KeepAlive {
+	k := &KeepAlive{
+		st:       s,
+		interval: interval,
+		failed:   make(chan error, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go k.loop()
+	return k
+}
+
+// Failed returns a channel on which the error from the
+// first failed ping is sent. No further pings are made.
+func (k *KeepAlive) Failed() <-chan error {
+	return k.failed
+}
+
+// Stop stops the pinging, and waits for any
+// ping in progress to finish.
+func (k *KeepAlive) Stop() {
+	k.stopOnce.Do(func() {
+		close(k.stop)
+	})
+	<-k.done
+}
+
+func (k *KeepAlive) loop() {
+	defer close(k.done)
+	for {
+		select {
+		case <-time.After(k.interval):
+		case <-k.stop:
+			return
+		case <-k.st.closed:
+			return
+		}
+		latency, err := k.st.PingLatency()
+		if err != nil {
+			select {
+			case <-k.st.closed:
+				// Closing the State is not a failure.
+				return
+			default:
+			}
+			logger.Debugf("keep-alive ping to %q failed: %v", k.st.addr, err)
+			k.failed <- err
+			return
+		}
+		logger.Tracef("keep-alive ping to %q took %v", k.st.addr, latency)
+	}
+}
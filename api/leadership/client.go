@@ -20,6 +20,20 @@ import (
 
 var logger = loggo.GetLogger("juju.api.leadership")
 
+var (
+	// claimSkewMargin is subtracted from the lease time granted by the
+	// server when deciding when a claim must be renewed, so that the
+	// claim is renewed before the server expires it even if the
+	// client's clock runs slow relative to the server's.
+	claimSkewMargin = 5 * time.Second
+
+	// now is used to measure the round trip of a claim. Only the
+	// difference between two readings is ever used, so the absolute
+	// time reported by the client's clock does not matter. Exposed as
+	// a variable so it can be replaced for testing purposes.
+	now = time.Now
+)
+
 type facadeCaller interface {
 	FacadeCall(request string, params, response interface{}) error
 }
@@ -34,9 +48,14 @@ func NewClient(facade base.ClientFacade, caller facadeCaller) LeadershipClient {
 	return &client{facade, caller}
 }
 
-// ClaimLeadership implements LeadershipManager.
+// ClaimLeadership implements LeadershipManager. The returned duration
+// is the interval within which the claim must be renewed; it is
+// derived from the lease time remaining on the server, less the time
+// taken by the call and a margin for clock skew, and never from
+// absolute times.
 func (c *client) ClaimLeadership(serviceId, unitId string) (time.Duration, error) {
 
+	sent := now()
 	results, err := c.bulkClaimLeadership(c.prepareClaimLeadership(serviceId, unitId))
 	if err != nil {
 		return 0, err
@@ -44,7 +63,39 @@ func (c *client) ClaimLeadership(serviceId, unitId string) (time.Duration, error
 
 	// We should have our 1 result. If not, we rightfully panic.
 	result := results.Results[0]
-	return time.Duration(result.ClaimDurationInSec) * time.Second, result.Error
+	if result.Error != nil {
+		if params.IsCodeLeadershipClaimDenied(result.Error) {
+			// Another unit holds leadership.
+			return 0, errors.Wrap(result.Error, LeadershipClaimDeniedErr)
+		}
+		return 0, result.Error
+	}
+	return renewalInterval(result, now().Sub(sent)), nil
+}
+
+// renewalInterval returns how long a client may wait before renewing
+// the claim described by result, given the time elapsed on the client
+// since the claim was sent.
+func renewalInterval(result params.ClaimLeadershipResults, elapsed time.Duration) time.Duration {
+	budget := secondsToDuration(result.LeaseRemainingInSec)
+	if budget == 0 {
+		// Older servers only report the granted duration.
+		budget = secondsToDuration(result.ClaimDurationInSec)
+	}
+	if elapsed < 0 {
+		// The client's clock went backwards; assume the worst
+		// case we can measure, which is no time at all.
+		elapsed = 0
+	}
+	interval := budget - elapsed - claimSkewMargin
+	if interval < 0 {
+		return 0
+	}
+	return interval
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
 }
 
 // ReleaseLeadership implements LeadershipManager.
@@ -58,6 +109,20 @@ func (c *client) ReleaseLeadership(serviceId, unitId string) error {
 	return results.Results[0].Error
 }
 
+// TransferLeadership implements LeadershipManager.
+func (c *client) TransferLeadership(serviceId, fromUnitId, toUnitId string) error {
+	results, err := c.bulkTransferLeadership(c.prepareTransferLeadership(serviceId, fromUnitId, toUnitId))
+	if err != nil {
+		return err
+	}
+
+	// We should have our 1 result. If not, we rightfully panic.
+	if err := results.Results[0].Error; err != nil {
+		return err
+	}
+	return nil
+}
+
 // BlockUntilLeadershipReleased implements LeadershipManager.
 func (c *client) BlockUntilLeadershipReleased(serviceId string) error {
 	const friendlyErrMsg = "error blocking on leadership release"
@@ -93,6 +158,16 @@ func (c *client) prepareReleaseLeadership(serviceId, unitId string) params.Relea
 	}
 }
 
+// prepareTransferLeadership creates a single set of params in
+// preperation for making a bulk call.
+func (c *client) prepareTransferLeadership(serviceId, fromUnitId, toUnitId string) params.TransferLeadershipParams {
+	return params.TransferLeadershipParams{
+		names.NewServiceTag(serviceId).String(),
+		names.NewUnitTag(fromUnitId).String(),
+		names.NewUnitTag(toUnitId).String(),
+	}
+}
+
 //
 // Bulk calls.
 //
@@ -125,3 +200,18 @@ func (c *client) bulkReleaseLeadership(args ...params.ReleaseLeadershipParams) (
 
 	return &results, nil
 }
+
+func (c *client) bulkTransferLeadership(args ...params.TransferLeadershipParams) (*params.TransferLeadershipBulkResults, error) {
+	// Don't make the jump over the network if we don't have to.
+	if len(args) <= 0 {
+		return &params.TransferLeadershipBulkResults{}, nil
+	}
+
+	bulkParams := params.TransferLeadershipBulkParams{args}
+	var results params.TransferLeadershipBulkResults
+	if err := c.FacadeCall("TransferLeadership", bulkParams, &results); err != nil {
+		return nil, errors.Annotate(err, "cannot transfer leadership")
+	}
+
+	return &results, nil
+}
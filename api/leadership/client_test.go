@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/juju/errors"
 	"github.com/juju/names"
 	gc "gopkg.in/check.v1"
 
@@ -42,7 +43,21 @@ func (s *stubFacade) FacadeCall(request string, params, response interface{}) er
 func (s *stubFacade) BestAPIVersion() int { return -1 }
 func (s *stubFacade) Close() error        { return nil }
 
+// patchClock replaces the client's clock with one which starts at
+// start and advances by step each time it is read.
+func patchClock(start time.Time, step time.Duration) (restore func()) {
+	orig := now
+	t := start
+	now = func() time.Time {
+		current := t
+		t = t.Add(step)
+		return current
+	}
+	return func() { now = orig }
+}
+
 func (s *clientSuite) TestClaimLeadershipTranslation(c *gc.C) {
+	defer patchClock(time.Now(), 0)()
 
 	const claimTime = 5 * time.Hour
 	numStubCalls := 0
@@ -75,7 +90,58 @@ func (s *clientSuite) TestClaimLeadershipTranslation(c *gc.C) {
 
 	c.Assert(err, gc.IsNil)
 	c.Check(numStubCalls, gc.Equals, 1)
-	c.Check(claimInterval, gc.Equals, claimTime)
+	c.Check(claimInterval, gc.Equals, claimTime-claimSkewMargin)
+}
+
+func claimStub(durationInSec, remainingInSec float64) *stubFacade {
+	return &stubFacade{
+		FacadeCallFn: func(name string, parameters, response interface{}) error {
+			typedR := response.(*params.ClaimLeadershipBulkResults)
+			typedR.Results = []params.ClaimLeadershipResults{{
+				ClaimDurationInSec:  durationInSec,
+				LeaseRemainingInSec: remainingInSec,
+			}}
+			return nil
+		},
+	}
+}
+
+func (s *clientSuite) TestClaimLeadershipSkewedClock(c *gc.C) {
+	// The server granted a 30s lease, of which 29s remained when it
+	// responded; the call took 2s as measured by the client. However
+	// far the client's clock is from the server's, the claim must be
+	// renewed before the lease runs out on the server.
+	const expected = 29*time.Second - 2*time.Second - 5*time.Second
+	for i, skew := range []time.Duration{0, time.Hour, -time.Hour, 24 * time.Hour} {
+		c.Logf("test %d: skew %v", i, skew)
+		restore := patchClock(time.Now().Add(skew), 2*time.Second)
+		stub := claimStub(30, 29)
+		interval, err := NewClient(stub, stub).ClaimLeadership(StubServiceNm, StubUnitNm)
+		restore()
+		c.Assert(err, gc.IsNil)
+		c.Check(interval, gc.Equals, expected)
+		c.Check(interval < 29*time.Second, gc.Equals, true)
+	}
+}
+
+func (s *clientSuite) TestClaimLeadershipOldServer(c *gc.C) {
+	// Servers which do not report the remaining lease time fall
+	// back to the granted duration.
+	defer patchClock(time.Now(), time.Second)()
+	stub := claimStub(30, 0)
+	interval, err := NewClient(stub, stub).ClaimLeadership(StubServiceNm, StubUnitNm)
+	c.Assert(err, gc.IsNil)
+	c.Check(interval, gc.Equals, 30*time.Second-time.Second-claimSkewMargin)
+}
+
+func (s *clientSuite) TestClaimLeadershipRenewImmediately(c *gc.C) {
+	// When the call takes longer than the lease allows for, the
+	// claim must be renewed straight away.
+	defer patchClock(time.Now(), 10*time.Second)()
+	stub := claimStub(10, 10)
+	interval, err := NewClient(stub, stub).ClaimLeadership(StubServiceNm, StubUnitNm)
+	c.Assert(err, gc.IsNil)
+	c.Check(interval, gc.Equals, time.Duration(0))
 }
 
 func (s *clientSuite) TestClaimLeadershipErrorTranslation(c *gc.C) {
@@ -113,6 +179,25 @@ func (s *clientSuite) TestClaimLeadershipErrorTranslation(c *gc.C) {
 	c.Check(err, gc.ErrorMatches, "error making a leadership claim: "+errMsg)
 }
 
+func (s *clientSuite) TestClaimLeadershipDenied(c *gc.C) {
+	stub := &stubFacade{
+		FacadeCallFn: func(name string, parameters, response interface{}) error {
+			typedR := response.(*params.ClaimLeadershipBulkResults)
+			typedR.Results = []params.ClaimLeadershipResults{{
+				Error: &params.Error{
+					Message: "leadership claim denied",
+					Code:    params.CodeLeadershipClaimDenied,
+				},
+			}}
+			return nil
+		},
+	}
+
+	_, err := NewClient(stub, stub).ClaimLeadership(StubServiceNm, StubUnitNm)
+	c.Check(err, gc.ErrorMatches, "leadership claim denied")
+	c.Check(errors.Cause(err), gc.Equals, LeadershipClaimDeniedErr)
+}
+
 func (s *clientSuite) TestReleaseLeadershipTranslation(c *gc.C) {
 
 	numStubCalls := 0
@@ -144,6 +229,52 @@ func (s *clientSuite) TestReleaseLeadershipTranslation(c *gc.C) {
 	c.Assert(err, gc.IsNil)
 }
 
+func (s *clientSuite) TestTransferLeadershipTranslation(c *gc.C) {
+
+	numStubCalls := 0
+	stub := &stubFacade{
+		FacadeCallFn: func(name string, parameters, response interface{}) error {
+			numStubCalls++
+			c.Check(name, gc.Equals, "TransferLeadership")
+
+			typedP, ok := parameters.(params.TransferLeadershipBulkParams)
+			c.Assert(ok, gc.Equals, true)
+
+			typedR, ok := response.(*params.TransferLeadershipBulkResults)
+			c.Assert(ok, gc.Equals, true)
+			typedR.Results = []params.ErrorResult{{}}
+
+			c.Assert(typedP.Params, gc.HasLen, 1)
+			c.Check(typedP.Params[0].ServiceTag, gc.Equals, names.NewServiceTag(StubServiceNm).String())
+			c.Check(typedP.Params[0].FromUnitTag, gc.Equals, names.NewUnitTag(StubUnitNm).String())
+			c.Check(typedP.Params[0].ToUnitTag, gc.Equals, names.NewUnitTag("stub-unit/1").String())
+
+			return nil
+		},
+	}
+
+	client := NewClient(stub, stub)
+	err := client.TransferLeadership(StubServiceNm, StubUnitNm, "stub-unit/1")
+
+	c.Check(numStubCalls, gc.Equals, 1)
+	c.Assert(err, gc.IsNil)
+}
+
+func (s *clientSuite) TestTransferLeadershipIneligible(c *gc.C) {
+	stub := &stubFacade{
+		FacadeCallFn: func(name string, parameters, response interface{}) error {
+			typedR := response.(*params.TransferLeadershipBulkResults)
+			typedR.Results = []params.ErrorResult{{
+				Error: &params.Error{Message: `unit "stub-unit/1" is not alive`},
+			}}
+			return nil
+		},
+	}
+
+	err := NewClient(stub, stub).TransferLeadership(StubServiceNm, StubUnitNm, "stub-unit/1")
+	c.Assert(err, gc.ErrorMatches, `unit "stub-unit/1" is not alive`)
+}
+
 func (s *clientSuite) TestBlockUntilLeadershipReleasedTranslation(c *gc.C) {
 
 	numStubCalls := 0
@@ -0,0 +1,129 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package api
+
+import (
+	"github.com/juju/errors"
+	"launchpad.net/tomb"
+
+	"github.com/juju/juju/api/watcher"
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/network"
+)
+
+// MachineAddresses holds the addresses of a machine,
+// as reported by a MachineAddressesWatcher.
+type MachineAddresses struct {
+	MachineId string
+
+	// Addresses holds the machine's current addresses. It is
+	// nil if the machine has been removed.
+	Addresses []network.Address
+}
+
+// MachineAddressesWatcher reports changes to the addresses of
+// machines in the environment. It translates the machine ids
+// reported by the API server's watcher into the machines' current
+// addresses, fetched in a single call per event.
+type MachineAddressesWatcher struct {
+	tomb   tomb.Tomb
+	client *Client
+	source watcher.StringsWatcher
+	out    chan []MachineAddresses
+}
+
+func newMachineAddressesWatcher(client *Client, source watcher.StringsWatcher) *MachineAddressesWatcher {
+	w := &MachineAddressesWatcher{
+		client: client,
+		source: source,
+		out:    make(chan []MachineAddresses),
+	}
+	go func() {
+		defer w.tomb.Done()
+		defer close(w.out)
+		w.tomb.Kill(w.loop())
+	}()
+	return w
+}
+
+func (w *MachineAddressesWatcher) loop() error {
+	defer w.source.Stop()
+	var out chan []MachineAddresses
+	var changes []MachineAddresses
+	for {
+		select {
+		case <-w.tomb.Dying():
+			return tomb.ErrDying
+		case ids, ok := <-w.source.Changes():
+			if !ok {
+				if err := w.source.Err(); err != nil {
+					return err
+				}
+				return errors.New("machine ids watcher closed unexpectedly")
+			}
+			latest, err := w.addresses(ids)
+			if err != nil {
+				return err
+			}
+			changes = mergeMachineAddresses(changes, latest)
+			out = w.out
+		case out <- changes:
+			out = nil
+			changes = nil
+		}
+	}
+}
+
+// addresses returns the current addresses of the given machines.
+func (w *MachineAddressesWatcher) addresses(ids []string) ([]MachineAddresses, error) {
+	statuses, err := w.client.MachinesStatus(ids)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]MachineAddresses, len(ids))
+	for i, id := range ids {
+		status := statuses[id]
+		if status.Error != nil && !params.IsCodeNotFound(status.Error) {
+			return nil, status.Error
+		}
+		result[i] = MachineAddresses{MachineId: id, Addresses: status.Addresses}
+	}
+	return result, nil
+}
+
+// mergeMachineAddresses merges the latest addresses into the
+// undelivered changes, replacing any earlier entries for a machine.
+func mergeMachineAddresses(changes, latest []MachineAddresses) []MachineAddresses {
+	for _, l := range latest {
+		replaced := false
+		for i, change := range changes {
+			if change.MachineId == l.MachineId {
+				changes[i] = l
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			changes = append(changes, l)
+		}
+	}
+	return changes
+}
+
+// Changes returns a channel that receives the addresses
+// of machines whose addresses have changed.
+func (w *MachineAddressesWatcher) Changes() <-chan []MachineAddresses {
+	return w.out
+}
+
+// Stop stops the watcher and returns any error it encountered.
+func (w *MachineAddressesWatcher) Stop() error {
+	w.tomb.Kill(nil)
+	return w.tomb.Wait()
+}
+
+// Err returns any error encountered by the watcher.
+func (w *MachineAddressesWatcher) Err() error {
+	return w.tomb.Err()
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/juju/juju/api/watcher"
 	"github.com/juju/juju/apiserver/params"
 	"github.com/juju/juju/instance"
+	"github.com/juju/juju/network"
 )
 
 // Machine represents a juju machine as seen by the provisioner worker.
@@ -328,3 +329,31 @@ func (m *Machine) SetSupportedContainers(containerTypes ...instance.ContainerTyp
 func (m *Machine) SupportsNoContainers() error {
 	return m.SetSupportedContainers([]instance.ContainerType{}...)
 }
+
+// SetProviderAddresses records the addresses reported for the machine
+// by the provider. The addresses observed on the machine itself, as
+// set by SetMachineAddresses, are left unchanged.
+func (m *Machine) SetProviderAddresses(addresses ...network.Address) error {
+	return m.setAddresses("SetProviderAddresses", addresses)
+}
+
+// SetMachineAddresses records the addresses observed on the machine
+// itself. The addresses reported by the provider, as set by
+// SetProviderAddresses, are left unchanged.
+func (m *Machine) SetMachineAddresses(addresses ...network.Address) error {
+	return m.setAddresses("SetMachineAddresses", addresses)
+}
+
+func (m *Machine) setAddresses(method string, addresses []network.Address) error {
+	var results params.ErrorResults
+	args := params.SetMachinesAddresses{
+		MachineAddresses: []params.MachineAddresses{
+			{Tag: m.tag.String(), Addresses: addresses},
+		},
+	}
+	err := m.st.facade.FacadeCall(method, args, &results)
+	if err != nil {
+		return err
+	}
+	return results.OneError()
+}
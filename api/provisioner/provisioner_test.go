@@ -682,6 +682,30 @@ func (s *provisionerSuite) TestSetSupportedContainers(c *gc.C) {
 	c.Assert(containers, gc.DeepEquals, []instance.ContainerType{instance.LXC, instance.KVM})
 }
 
+func (s *provisionerSuite) TestSetProviderAndMachineAddresses(c *gc.C) {
+	apiMachine, err := s.provisioner.Machine(s.machine.Tag().(names.MachineTag))
+	c.Assert(err, jc.ErrorIsNil)
+	providerAddresses := network.NewAddresses("10.0.0.1")
+	machineAddresses := network.NewAddresses("192.168.0.1")
+
+	err = apiMachine.SetProviderAddresses(providerAddresses...)
+	c.Assert(err, jc.ErrorIsNil)
+	err = apiMachine.SetMachineAddresses(machineAddresses...)
+	c.Assert(err, jc.ErrorIsNil)
+
+	// Setting either list leaves the other unchanged.
+	err = s.machine.Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.machine.MachineAddresses(), jc.DeepEquals, machineAddresses)
+	c.Assert(s.machine.Addresses(), jc.DeepEquals, append(providerAddresses, machineAddresses...))
+
+	err = apiMachine.SetProviderAddresses()
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.machine.Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.machine.Addresses(), jc.DeepEquals, machineAddresses)
+}
+
 func (s *provisionerSuite) TestSupportsNoContainers(c *gc.C) {
 	apiMachine, err := s.provisioner.Machine(s.machine.Tag().(names.MachineTag))
 	c.Assert(err, jc.ErrorIsNil)
@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/juju/errors"
@@ -20,15 +22,20 @@ import (
 	"github.com/juju/juju/apiserver/common"
 	"github.com/juju/juju/apiserver/highavailability"
 	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/environs"
 	"github.com/juju/juju/environs/config"
 	"github.com/juju/juju/environs/manual"
+	"github.com/juju/juju/environs/simplestreams"
 	"github.com/juju/juju/feature"
 	"github.com/juju/juju/instance"
 	jjj "github.com/juju/juju/juju"
+	"github.com/juju/juju/leadership"
+	"github.com/juju/juju/lease"
 	"github.com/juju/juju/network"
 	"github.com/juju/juju/state"
 	"github.com/juju/juju/state/multiwatcher"
 	statestorage "github.com/juju/juju/state/storage"
+	"github.com/juju/juju/state/watcher"
 	"github.com/juju/juju/storage"
 	"github.com/juju/juju/version"
 )
@@ -41,6 +48,10 @@ var (
 	logger = loggo.GetLogger("juju.apiserver.client")
 
 	newStateStorage = statestorage.NewStorage
+
+	// leaderMgr hands leadership between units for TransferLeadership.
+	// Exposed as a variable so it can be replaced for testing purposes.
+	leaderMgr leadership.LeadershipManager = leadership.NewLeadershipManager(lease.Manager())
 )
 
 type API struct {
@@ -87,6 +98,155 @@ func (c *Client) WatchAll() (params.AllWatcherId, error) {
 	}, nil
 }
 
+// WatchAllEnvs initiates a watcher for changes to all the environments
+// managed by the state server. It may only be used by the owner of the
+// state server's own environment, connected to that environment.
+func (c *Client) WatchAllEnvs() (params.AllWatcherId, error) {
+	env, err := c.api.state.StateServerEnvironment()
+	if err != nil {
+		return params.AllWatcherId{}, errors.Trace(err)
+	}
+	if env.UUID() != c.api.state.EnvironUUID() {
+		return params.AllWatcherId{}, common.ErrPerm
+	}
+	// Until there are real permissions, only the owner of the
+	// state server environment may see every environment, as
+	// only it may manage users.
+	if user, ok := c.api.auth.GetAuthTag().(names.UserTag); !ok || user != env.Owner() {
+		return params.AllWatcherId{}, common.ErrPerm
+	}
+	w := c.api.state.WatchAllEnvs()
+	return params.AllWatcherId{
+		AllWatcherId: c.api.resources.Register(w),
+	}, nil
+}
+
+// AllSnapshot returns the current state of the entire environment,
+// as the deltas first returned by an AllWatcher, without leaving a
+// watcher running.
+func (c *Client) AllSnapshot() (params.AllWatcherNextResults, error) {
+	w := c.api.state.Watch()
+	deltas, err := w.Next()
+	if stopErr := w.Stop(); err == nil {
+		err = stopErr
+	}
+	if err != nil {
+		return params.AllWatcherNextResults{}, errors.Trace(err)
+	}
+	return params.AllWatcherNextResults{Deltas: deltas}, nil
+}
+
+// WatchEnvironConfig returns a NotifyWatcher that observes changes
+// to the environment configuration.
+func (c *Client) WatchEnvironConfig() (params.NotifyWatchResult, error) {
+	environWatcher := common.NewEnvironWatcher(c.api.state, c.api.resources, c.api.auth)
+	return environWatcher.WatchForEnvironConfigChanges()
+}
+
+// WatchService returns a StringsWatcher that notifies of changes to
+// the given service's units, reporting the names of the units that
+// have been added, have changed or have been removed.
+func (c *Client) WatchService(args params.Entity) (params.StringsWatchResult, error) {
+	result := params.StringsWatchResult{}
+	tag, err := names.ParseServiceTag(args.Tag)
+	if err != nil {
+		return result, err
+	}
+	svc, err := c.api.state.Service(tag.Id())
+	if err != nil {
+		return result, err
+	}
+	watch := newServiceUnitsWatcher(c.api.state, svc)
+	// Consume the initial event and forward it to the result.
+	if changes, ok := <-watch.Changes(); ok {
+		result.StringsWatcherId = c.api.resources.Register(watch)
+		result.Changes = changes
+	} else {
+		return result, watcher.EnsureErr(watch)
+	}
+	return result, nil
+}
+
+// WatchMachineAddresses returns a StringsWatcher that notifies of
+// changes to the addresses of machines in the environment, reporting
+// the ids of the machines whose addresses changed.
+func (c *Client) WatchMachineAddresses() (params.StringsWatchResult, error) {
+	result := params.StringsWatchResult{}
+	watch := c.api.state.WatchAllMachineAddresses()
+	// Consume the initial event and forward it to the result.
+	if changes, ok := <-watch.Changes(); ok {
+		result.StringsWatcherId = c.api.resources.Register(watch)
+		result.Changes = changes
+	} else {
+		return result, watcher.EnsureErr(watch)
+	}
+	return result, nil
+}
+
+// WatchActionResults returns a StringsWatcher that notifies when any
+// of the given actions is completed, cancelled or fails, reporting the
+// ids of those that have. Its first event holds the ids of any that
+// already have.
+func (c *Client) WatchActionResults(args params.Entities) (params.StringsWatchResult, error) {
+	result := params.StringsWatchResult{}
+	tags := make([]names.ActionTag, len(args.Entities))
+	for i, entity := range args.Entities {
+		tag, err := names.ParseActionTag(entity.Tag)
+		if err != nil {
+			return result, err
+		}
+		tags[i] = tag
+	}
+	watch := c.api.state.WatchActionResultsFor(tags...)
+	// Consume the initial event and forward it to the result.
+	if changes, ok := <-watch.Changes(); ok {
+		result.StringsWatcherId = c.api.resources.Register(watch)
+		result.Changes = changes
+	} else {
+		return result, watcher.EnsureErr(watch)
+	}
+	return result, nil
+}
+
+// WatchUnit returns a NotifyWatcher that notifies of
+// changes to the given unit.
+func (c *Client) WatchUnit(args params.Entity) (params.NotifyWatchResult, error) {
+	result := params.NotifyWatchResult{}
+	tag, err := names.ParseUnitTag(args.Tag)
+	if err != nil {
+		return result, err
+	}
+	unit, err := c.api.state.Unit(tag.Id())
+	if err != nil {
+		return result, err
+	}
+	watch := unit.Watch()
+	// Consume the initial event. NotifyWatchers
+	// have no state to transmit.
+	if _, ok := <-watch.Changes(); ok {
+		result.NotifyWatcherId = c.api.resources.Register(watch)
+	} else {
+		return result, watcher.EnsureErr(watch)
+	}
+	return result, nil
+}
+
+// WatchForUpgradeAvailable returns a NotifyWatcher that fires when
+// tools newer than the environment's agent version, with the same
+// major version, become available.
+func (c *Client) WatchForUpgradeAvailable() (params.NotifyWatchResult, error) {
+	result := params.NotifyWatchResult{}
+	watch := newUpgradeAvailableWatcher(c.api.state, c.api.toolsFinder)
+	// Consume the initial event. NotifyWatchers
+	// have no state to transmit.
+	if _, ok := <-watch.Changes(); ok {
+		result.NotifyWatcherId = c.api.resources.Register(watch)
+	} else {
+		return result, watcher.EnsureErr(watch)
+	}
+	return result, nil
+}
+
 // ServiceSet implements the server side of Client.ServiceSet. Values set to an
 // empty string will be unset.
 //
@@ -129,10 +289,25 @@ func (c *Client) ServiceUnset(p params.ServiceUnset) error {
 	if err != nil {
 		return err
 	}
+	ch, _, err := svc.Charm()
+	if err != nil {
+		return err
+	}
+	// Report every unknown option, rather than just the first, and
+	// unset nothing if there are any.
+	options := ch.Config().Options
+	var unknown []string
 	settings := make(charm.Settings)
 	for _, option := range p.Options {
+		if _, ok := options[option]; !ok {
+			unknown = append(unknown, fmt.Sprintf("unknown option %q", option))
+			continue
+		}
 		settings[option] = nil
 	}
+	if len(unknown) > 0 {
+		return errors.New(strings.Join(unknown, "; "))
+	}
 	return svc.UpdateConfigSettings(settings)
 }
 
@@ -180,6 +355,63 @@ func (c *Client) Resolved(p params.Resolved) error {
 	return unit.Resolve(p.Retry)
 }
 
+// ResolveUnits marks each of the given units as resolved, so that their
+// hook errors are cleared. If retry is true, the failed hooks are run
+// again.
+func (c *Client) ResolveUnits(p params.ResolveUnits) (params.ErrorResults, error) {
+	if err := c.check.ChangeAllowed(); err != nil {
+		return params.ErrorResults{}, errors.Trace(err)
+	}
+	results := params.ErrorResults{
+		Results: make([]params.ErrorResult, len(p.UnitNames)),
+	}
+	for i, name := range p.UnitNames {
+		unit, err := c.api.state.Unit(name)
+		if err == nil {
+			err = unit.Resolve(p.Retry)
+		}
+		results.Results[i].Error = common.ServerError(err)
+	}
+	return results, nil
+}
+
+// UnitsInError returns the units in an error state, ordered by name,
+// with the messages describing their errors.
+func (c *Client) UnitsInError() (params.UnitsInErrorResults, error) {
+	services, err := c.api.state.AllServices()
+	if err != nil {
+		return params.UnitsInErrorResults{}, errors.Trace(err)
+	}
+	var results params.UnitsInErrorResults
+	for _, service := range services {
+		units, err := service.AllUnits()
+		if err != nil {
+			return params.UnitsInErrorResults{}, errors.Trace(err)
+		}
+		for _, unit := range units {
+			status, info, _, err := unit.Status()
+			if err != nil {
+				return params.UnitsInErrorResults{}, errors.Trace(err)
+			}
+			if status != state.StatusError {
+				continue
+			}
+			results.Units = append(results.Units, params.UnitError{
+				UnitName: unit.Name(),
+				Message:  info,
+			})
+		}
+	}
+	sort.Sort(unitErrorsByName(results.Units))
+	return results, nil
+}
+
+type unitErrorsByName []params.UnitError
+
+func (u unitErrorsByName) Len() int           { return len(u) }
+func (u unitErrorsByName) Swap(i, j int)      { u[i], u[j] = u[j], u[i] }
+func (u unitErrorsByName) Less(i, j int) bool { return u[i].UnitName < u[j].UnitName }
+
 // PublicAddress implements the server side of Client.PublicAddress.
 func (c *Client) PublicAddress(p params.PublicAddress) (results params.PublicAddressResults, err error) {
 	switch {
@@ -236,6 +468,103 @@ func (c *Client) PrivateAddress(p params.PrivateAddress) (results params.Private
 	return results, fmt.Errorf("unknown unit or machine %q", p.Target)
 }
 
+// UnitAddresses returns the public and private addresses of the
+// given unit, and whether each is set. An error satisfying
+// errors.IsNotAssigned is returned if the unit has not yet been
+// assigned to a machine.
+func (c *Client) UnitAddresses(args params.Entity) (params.UnitAddressesResult, error) {
+	result := params.UnitAddressesResult{}
+	tag, err := names.ParseUnitTag(args.Tag)
+	if err != nil {
+		return result, err
+	}
+	unit, err := c.api.state.Unit(tag.Id())
+	if err != nil {
+		return result, err
+	}
+	if _, err := unit.AssignedMachineId(); err != nil {
+		return result, err
+	}
+	result.PublicAddress, result.PublicAddressSet = unit.PublicAddress()
+	result.PrivateAddress, result.PrivateAddressSet = unit.PrivateAddress()
+	return result, nil
+}
+
+// GetMeterStatus returns the meter status of the given unit. An error
+// satisfying errors.IsNotFound is returned if the unit has no meter
+// status set.
+func (c *Client) GetMeterStatus(args params.Entity) (params.MeterStatusResult, error) {
+	unit, err := c.meterStatusUnit(args.Tag)
+	if err != nil {
+		return params.MeterStatusResult{}, err
+	}
+	code, info, err := unit.GetMeterStatus()
+	if err != nil {
+		return params.MeterStatusResult{}, err
+	}
+	if code == string(state.MeterNotSet) {
+		return params.MeterStatusResult{}, errors.NotFoundf("meter status for unit %q", unit.Name())
+	}
+	return params.MeterStatusResult{Code: code, Info: info}, nil
+}
+
+// SetMeterStatus sets the meter status of the given unit.
+func (c *Client) SetMeterStatus(args params.SetMeterStatus) error {
+	if err := c.check.ChangeAllowed(); err != nil {
+		return errors.Trace(err)
+	}
+	unit, err := c.meterStatusUnit(args.Tag)
+	if err != nil {
+		return err
+	}
+	return unit.SetMeterStatus(args.Code, args.Info)
+}
+
+func (c *Client) meterStatusUnit(tag string) (*state.Unit, error) {
+	unitTag, err := names.ParseUnitTag(tag)
+	if err != nil {
+		return nil, err
+	}
+	return c.api.state.Unit(unitTag.Id())
+}
+
+// TransferLeadership hands leadership of a service directly from the
+// unit holding it to another alive unit of the same service, so that
+// no other unit can claim leadership in between.
+func (c *Client) TransferLeadership(args params.TransferLeadershipParams) error {
+	if err := c.check.ChangeAllowed(); err != nil {
+		return errors.Trace(err)
+	}
+	serviceTag, err := names.ParseServiceTag(args.ServiceTag)
+	if err != nil {
+		return err
+	}
+	fromTag, err := names.ParseUnitTag(args.FromUnitTag)
+	if err != nil {
+		return err
+	}
+	toTag, err := names.ParseUnitTag(args.ToUnitTag)
+	if err != nil {
+		return err
+	}
+	serviceName := serviceTag.Id()
+	for _, unitName := range []string{fromTag.Id(), toTag.Id()} {
+		if unitServiceName, err := names.UnitService(unitName); err != nil {
+			return err
+		} else if unitServiceName != serviceName {
+			return errors.Errorf("unit %q is not a unit of service %q", unitName, serviceName)
+		}
+	}
+	unit, err := c.api.state.Unit(toTag.Id())
+	if err != nil {
+		return err
+	}
+	if unit.Life() != state.Alive {
+		return errors.Errorf("unit %q is not alive", unit.Name())
+	}
+	return leaderMgr.TransferLeadership(serviceName, fromTag.Id(), toTag.Id())
+}
+
 // ServiceExpose changes the juju-managed firewall to expose any ports that
 // were also explicitly marked by units as open.
 // TODO(mattyw, all): This api call should be move to the new service facade. The client api version will then need bumping.
@@ -618,6 +947,98 @@ func (c *Client) DestroyServiceUnits(args params.DestroyServiceUnits) error {
 	return destroyErr("units", args.UnitNames, errs)
 }
 
+// ScaleService adds or destroys units of a service so that it has the
+// given number of alive units. Units that are already dying do not count
+// towards the number; when there are too many, the most recently added
+// units are destroyed first. The names of the alive units are returned,
+// in the order they were added.
+func (c *Client) ScaleService(args params.ScaleService) (params.ScaleServiceResults, error) {
+	if err := c.check.ChangeAllowed(); err != nil {
+		return params.ScaleServiceResults{}, errors.Trace(err)
+	}
+	if args.NumUnits < 0 {
+		return params.ScaleServiceResults{}, errors.Errorf("cannot scale service to %d units", args.NumUnits)
+	}
+	service, err := c.api.state.Service(args.ServiceName)
+	if err != nil {
+		return params.ScaleServiceResults{}, errors.Trace(err)
+	}
+	if !service.IsPrincipal() {
+		return params.ScaleServiceResults{}, errors.Errorf("cannot scale subordinate service %q", args.ServiceName)
+	}
+	allUnits, err := service.AllUnits()
+	if err != nil {
+		return params.ScaleServiceResults{}, errors.Trace(err)
+	}
+	var units []*state.Unit
+	for _, unit := range allUnits {
+		if unit.Life() == state.Alive {
+			units = append(units, unit)
+		}
+	}
+	sort.Sort(unitsByNumber(units))
+
+	switch {
+	case len(units) < args.NumUnits:
+		added, err := jjj.AddUnits(c.api.state, service, args.NumUnits-len(units), "")
+		if err != nil {
+			return params.ScaleServiceResults{}, errors.Annotatef(err, "cannot add units to service %q", args.ServiceName)
+		}
+		units = append(units, added...)
+	case len(units) > args.NumUnits:
+		if err := c.check.RemoveAllowed(); err != nil {
+			return params.ScaleServiceResults{}, errors.Trace(err)
+		}
+		var destroyed []string
+		for i := len(units) - 1; i >= args.NumUnits; i-- {
+			if err := destroyUnit(units[i]); err != nil {
+				if len(destroyed) > 0 {
+					err = errors.Annotatef(err, "cannot destroy unit %q after destroying %s", units[i].Name(), strings.Join(destroyed, ", "))
+				} else {
+					err = errors.Annotatef(err, "cannot destroy unit %q", units[i].Name())
+				}
+				// Report the units still alive, so the caller
+				// knows how far the service was scaled down.
+				return params.ScaleServiceResults{
+					Units: aliveUnitNames(units[:i+1]),
+					Error: common.ServerError(err),
+				}, nil
+			}
+			destroyed = append(destroyed, units[i].Name())
+		}
+		units = units[:args.NumUnits]
+	}
+	return params.ScaleServiceResults{Units: aliveUnitNames(units)}, nil
+}
+
+// destroyUnit destroys the given unit; it is a variable so
+// that tests can make destroying a unit fail.
+var destroyUnit = (*state.Unit).Destroy
+
+// aliveUnitNames returns the names of the given units.
+func aliveUnitNames(units []*state.Unit) []string {
+	unitNames := make([]string, len(units))
+	for i, unit := range units {
+		unitNames[i] = unit.Name()
+	}
+	return unitNames
+}
+
+// unitsByNumber sorts units of a service in the order they were added.
+type unitsByNumber []*state.Unit
+
+func (u unitsByNumber) Len() int      { return len(u) }
+func (u unitsByNumber) Swap(i, j int) { u[i], u[j] = u[j], u[i] }
+func (u unitsByNumber) Less(i, j int) bool {
+	return unitNumber(u[i].Name()) < unitNumber(u[j].Name())
+}
+
+// unitNumber returns the number of the unit with the given name.
+func unitNumber(unitName string) int {
+	n, _ := strconv.Atoi(unitName[strings.LastIndex(unitName, "/")+1:])
+	return n
+}
+
 // ServiceDestroy destroys a given service.
 // TODO(mattyw, all): This api call should be move to the new service facade. The client api version will then need bumping.
 func (c *Client) ServiceDestroy(args params.ServiceDestroy) error {
@@ -712,6 +1133,103 @@ func (c *Client) DestroyRelation(args params.DestroyRelation) error {
 	return rel.Destroy()
 }
 
+// GetRelationSettings returns the settings of a unit within a relation.
+func (c *Client) GetRelationSettings(args params.GetRelationSettings) (params.SettingsResult, error) {
+	settings, err := c.relationSettings(args.RelationId, args.UnitName)
+	if err != nil {
+		return params.SettingsResult{}, errors.Trace(err)
+	}
+	result, err := convertRelationSettings(settings.Map())
+	if err != nil {
+		return params.SettingsResult{}, errors.Trace(err)
+	}
+	return params.SettingsResult{Settings: result}, nil
+}
+
+// SetRelationSettings changes the settings of a unit within a relation.
+// As with leader settings, a setting with an empty value is removed.
+func (c *Client) SetRelationSettings(args params.SetRelationSettings) error {
+	if err := c.check.ChangeAllowed(); err != nil {
+		return errors.Trace(err)
+	}
+	settings, err := c.relationSettings(args.RelationId, args.UnitName)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for k, v := range args.Settings {
+		if v == "" {
+			settings.Delete(k)
+		} else {
+			settings.Set(k, v)
+		}
+	}
+	_, err = settings.Write()
+	return errors.Trace(err)
+}
+
+// WatchRelationUnits returns a RelationUnitsWatcher that notifies of
+// units of the relation with the given id, as seen by the named unit,
+// entering and leaving scope, and of changes to their settings. Its
+// first event reports the units already in scope.
+func (c *Client) WatchRelationUnits(args params.WatchRelationUnits) (params.RelationUnitsWatchResult, error) {
+	result := params.RelationUnitsWatchResult{}
+	relUnit, err := c.relationUnit(args.RelationId, args.UnitName)
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+	watch := relUnit.Watch()
+	// Consume the initial event and forward it to the result.
+	if changes, ok := <-watch.Changes(); ok {
+		result.RelationUnitsWatcherId = c.api.resources.Register(watch)
+		result.Changes = changes
+	} else {
+		return result, watcher.EnsureErr(watch)
+	}
+	return result, nil
+}
+
+// relationSettings returns the settings of the named unit within
+// the relation with the given id.
+func (c *Client) relationSettings(relationId int, unitName string) (*state.Settings, error) {
+	relUnit, err := c.relationUnit(relationId, unitName)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return relUnit.Settings()
+}
+
+// relationUnit returns the named unit within
+// the relation with the given id.
+func (c *Client) relationUnit(relationId int, unitName string) (*state.RelationUnit, error) {
+	rel, err := c.api.state.Relation(relationId)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	unit, err := c.api.state.Unit(unitName)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	relUnit, err := rel.Unit(unit)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return relUnit, nil
+}
+
+// convertRelationSettings converts relation settings, which should all
+// be strings, to params.Settings.
+func convertRelationSettings(settings map[string]interface{}) (params.Settings, error) {
+	result := make(params.Settings)
+	for k, v := range settings {
+		sval, ok := v.(string)
+		if !ok {
+			return nil, errors.Errorf("unexpected relation setting %q: expected string, got %T", k, v)
+		}
+		result[k] = sval
+	}
+	return result, nil
+}
+
 // AddMachines adds new machines with the supplied parameters.
 func (c *Client) AddMachines(args params.AddMachines) (params.AddMachinesResults, error) {
 	return c.AddMachinesV2(args)
@@ -965,6 +1483,43 @@ func (c *Client) EnvironmentInfo() (api.EnvironmentInfo, error) {
 	return info, nil
 }
 
+// EnvironCredential reports the cloud and region hosting the environment,
+// and the names of the secret attributes it authenticates with. The
+// values of the attributes are never reported.
+func (c *Client) EnvironCredential() (params.EnvironCredential, error) {
+	cfg, err := c.api.state.EnvironConfig()
+	if err != nil {
+		return params.EnvironCredential{}, errors.Trace(err)
+	}
+	result := params.EnvironCredential{ProviderType: cfg.Type()}
+	env, err := environs.New(cfg)
+	if err != nil {
+		return params.EnvironCredential{}, errors.Trace(err)
+	}
+	if hasRegion, ok := env.(simplestreams.HasRegion); ok {
+		spec, err := hasRegion.Region()
+		if err != nil {
+			return params.EnvironCredential{}, errors.Trace(err)
+		}
+		result.Region = spec.Region
+	}
+	provider, err := environs.Provider(cfg.Type())
+	if err != nil {
+		return params.EnvironCredential{}, errors.Trace(err)
+	}
+	secrets, err := provider.SecretAttrs(cfg)
+	if err != nil {
+		return params.EnvironCredential{}, errors.Trace(err)
+	}
+	for name, value := range secrets {
+		if value != "" {
+			result.CredentialAttributes = append(result.CredentialAttributes, name)
+		}
+	}
+	sort.Strings(result.CredentialAttributes)
+	return result, nil
+}
+
 // ShareEnvironment allows the given user(s) access to the environment.
 func (c *Client) ShareEnvironment(args params.ModifyEnvironUsers) (result params.ErrorResults, err error) {
 	var createdBy names.UserTag
@@ -1116,6 +1671,101 @@ func (c *Client) EnvironmentGet() (params.EnvironmentConfigResults, error) {
 	return result, nil
 }
 
+// stateServerConfigKeys holds the names of the settings of the state
+// server environment that configure the state servers themselves,
+// rather than any environment they serve.
+var stateServerConfigKeys = []string{
+	"uuid",
+	"api-port",
+	"state-port",
+	"syslog-port",
+	"ca-cert",
+	config.SetNumaControlPolicyKey,
+}
+
+// StateServerConfig returns the settings that configure the state
+// servers: the UUID of the environment they were bootstrapped with,
+// their ports and their CA certificate. Secrets, such as the CA's
+// private key, are never returned.
+func (c *Client) StateServerConfig() (params.EnvironmentConfigResults, error) {
+	result := params.EnvironmentConfigResults{}
+	st, release, err := c.stateServerState()
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+	defer release()
+	cfg, err := st.EnvironConfig()
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+	attrs := cfg.AllAttrs()
+	result.Config = make(map[string]interface{})
+	for _, key := range stateServerConfigKeys {
+		if value, ok := attrs[key]; ok {
+			result.Config[key] = value
+		}
+	}
+	return result, nil
+}
+
+// StateServersStatus reports the voting status of each state server
+// machine, and whether the replica set has quorum: whether the agents
+// of a majority of the voting state servers are alive.
+func (c *Client) StateServersStatus() (params.StateServersStatus, error) {
+	result := params.StateServersStatus{}
+	st, release, err := c.stateServerState()
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+	defer release()
+	info, err := st.StateServerInfo()
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+	voters, aliveVoters := 0, 0
+	for _, id := range info.MachineIds {
+		machine, err := st.Machine(id)
+		if err != nil {
+			return result, errors.Trace(err)
+		}
+		alive, err := machine.AgentPresence()
+		if err != nil {
+			return result, errors.Trace(err)
+		}
+		if machine.HasVote() {
+			voters++
+			if alive {
+				aliveVoters++
+			}
+		}
+		result.StateServers = append(result.StateServers, params.StateServerStatus{
+			MachineId:  id,
+			WantsVote:  machine.WantsVote(),
+			HasVote:    machine.HasVote(),
+			AgentAlive: alive,
+		})
+	}
+	result.HasQuorum = aliveVoters*2 > voters
+	return result, nil
+}
+
+// stateServerState returns the State of the state server environment,
+// and a function to call when it is no longer needed.
+func (c *Client) stateServerState() (*state.State, func(), error) {
+	env, err := c.api.state.StateServerEnvironment()
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	if env.UUID() == c.api.state.EnvironUUID() {
+		return c.api.state, func() {}, nil
+	}
+	st, err := c.api.state.ForEnviron(env.EnvironTag())
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	return st, func() { st.Close() }, nil
+}
+
 // EnvironmentSet implements the server-side part of the
 // set-environment CLI command.
 func (c *Client) EnvironmentSet(args params.EnvironmentSet) error {
@@ -1159,6 +1809,20 @@ func (c *Client) EnvironmentUnset(args params.EnvironmentUnset) error {
 	return c.api.state.UpdateEnvironConfig(nil, args.Keys, nil)
 }
 
+// EnvironAgentVersion returns the agent version configured
+// for the environment.
+func (c *Client) EnvironAgentVersion() (params.AgentVersionResult, error) {
+	config, err := c.api.state.EnvironConfig()
+	if err != nil {
+		return params.AgentVersionResult{}, errors.Trace(err)
+	}
+	vers, ok := config.AgentVersion()
+	if !ok {
+		return params.AgentVersionResult{}, errors.NotFoundf("environment agent version")
+	}
+	return params.AgentVersionResult{Version: vers}, nil
+}
+
 // SetEnvironAgentVersion sets the environment agent version.
 func (c *Client) SetEnvironAgentVersion(args params.SetEnvironAgentVersion) error {
 	if err := c.check.ChangeAllowed(); err != nil {
@@ -1167,6 +1831,30 @@ func (c *Client) SetEnvironAgentVersion(args params.SetEnvironAgentVersion) erro
 	return c.api.state.SetEnvironAgentVersion(args.Version)
 }
 
+// StageEnvironAgentVersion sets the environment agent version, as
+// SetEnvironAgentVersion does, but only if tools matching the version
+// can be found. It returns a not-found error if they cannot.
+func (c *Client) StageEnvironAgentVersion(args params.SetEnvironAgentVersion) error {
+	if err := c.check.ChangeAllowed(); err != nil {
+		return errors.Trace(err)
+	}
+	result, err := c.api.toolsFinder.FindTools(params.FindToolsParams{
+		Number:       args.Version,
+		MajorVersion: args.Version.Major,
+		MinorVersion: args.Version.Minor,
+	})
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if result.Error != nil {
+		if params.IsCodeNotFound(result.Error) {
+			return errors.NotFoundf("tools for version %s", args.Version)
+		}
+		return result.Error
+	}
+	return c.api.state.SetEnvironAgentVersion(args.Version)
+}
+
 // AbortCurrentUpgrade aborts and archives the current upgrade
 // synchronisation record, if any.
 func (c *Client) AbortCurrentUpgrade() error {
@@ -1176,6 +1864,16 @@ func (c *Client) AbortCurrentUpgrade() error {
 	return c.api.state.AbortCurrentUpgrade()
 }
 
+// RollbackCurrentUpgrade aborts the current upgrade and sets the
+// environment's agent version back to the version being upgraded
+// from. It returns an error if no upgrade is in progress.
+func (c *Client) RollbackCurrentUpgrade() error {
+	if err := c.check.ChangeAllowed(); err != nil {
+		return errors.Trace(err)
+	}
+	return c.api.state.RollbackCurrentUpgrade()
+}
+
 // FindTools returns a List containing all tools matching the given parameters.
 func (c *Client) FindTools(args params.FindToolsParams) (params.FindToolsResult, error) {
 	return c.api.toolsFinder.FindTools(args)
@@ -1257,6 +1955,135 @@ func (c *Client) AddCharm(args params.CharmURL) error {
 	)
 }
 
+// AddStoreCharm adds the charm store charm referenced by the given URL
+// to the environment, as AddCharm does, and returns the URL it was
+// added with. If the URL has no series, the best series for the charm
+// is chosen; if it has no revision, the latest revision is used.
+func (c *Client) AddStoreCharm(args params.CharmURL) (params.CharmURL, error) {
+	ref, err := charm.ParseReference(args.URL)
+	if err != nil {
+		return params.CharmURL{}, err
+	}
+	if ref.Schema != "cs" {
+		return params.CharmURL{}, fmt.Errorf("only charm store charm URLs are supported, with cs: schema")
+	}
+	envConfig, err := c.api.state.EnvironConfig()
+	if err != nil {
+		return params.CharmURL{}, err
+	}
+	config.SpecializeCharmRepo(CharmStore, envConfig)
+	var curl *charm.URL
+	if ref.Series == "" {
+		curl, err = c.resolveCharm(ref, CharmStore)
+	} else {
+		curl, err = ref.URL("")
+	}
+	if err != nil {
+		return params.CharmURL{}, errors.Annotatef(err, "cannot resolve charm %q", args.URL)
+	}
+	if curl.Revision < 0 {
+		latest, err := charm.Latest(CharmStore, curl)
+		if err != nil {
+			return params.CharmURL{}, errors.Annotatef(err, "cannot resolve charm %q", args.URL)
+		}
+		curl = curl.WithRevision(latest)
+	}
+	if err := c.AddCharm(params.CharmURL{URL: curl.String()}); err != nil {
+		return params.CharmURL{}, err
+	}
+	return params.CharmURL{URL: curl.String()}, nil
+}
+
+// CollectMetrics triggers the collection of metrics by the unit, or by
+// every unit of the service, with the given tag. An action running the
+// collect-metrics hook is enqueued for each unit whose charm declares
+// metrics, and the enqueued actions are returned; units whose charm
+// declares none are reported with an error. It is an error if no unit
+// collects metrics at all.
+func (c *Client) CollectMetrics(args params.Entity) (params.ActionResults, error) {
+	if err := c.check.ChangeAllowed(); err != nil {
+		return params.ActionResults{}, errors.Trace(err)
+	}
+	tag, err := names.ParseTag(args.Tag)
+	if err != nil {
+		return params.ActionResults{}, err
+	}
+	var units []*state.Unit
+	switch tag := tag.(type) {
+	case names.ServiceTag:
+		service, err := c.api.state.Service(tag.Id())
+		if err != nil {
+			return params.ActionResults{}, err
+		}
+		if units, err = service.AllUnits(); err != nil {
+			return params.ActionResults{}, err
+		}
+		if len(units) == 0 {
+			return params.ActionResults{}, errors.Errorf("service %q has no units", tag.Id())
+		}
+	case names.UnitTag:
+		unit, err := c.api.state.Unit(tag.Id())
+		if err != nil {
+			return params.ActionResults{}, err
+		}
+		units = []*state.Unit{unit}
+	default:
+		return params.ActionResults{}, errors.Errorf("%q is not a service or unit tag", args.Tag)
+	}
+
+	results := params.ActionResults{Results: make([]params.ActionResult, len(units))}
+	collecting := 0
+	var unitErr error
+	for i, unit := range units {
+		result := &results.Results[i]
+		ch, err := c.unitCharm(unit)
+		if err != nil {
+			unitErr = err
+			result.Error = common.ServerError(err)
+			continue
+		}
+		if ch.Metrics() == nil {
+			unitErr = errors.Errorf("unit %q does not collect metrics: charm %q declares none", unit.Name(), ch.URL())
+			result.Error = common.ServerError(unitErr)
+			continue
+		}
+		collecting++
+		action, err := c.api.state.EnqueueAction(unit.Tag(), params.CollectMetricsAction, nil)
+		if err != nil {
+			result.Error = common.ServerError(err)
+			continue
+		}
+		result.Action = &params.Action{
+			Receiver: unit.Tag().String(),
+			Tag:      action.ActionTag().String(),
+			Name:     action.Name(),
+		}
+		result.Status = string(action.Status())
+		result.Enqueued = action.Enqueued()
+	}
+	if collecting == 0 {
+		if _, ok := tag.(names.UnitTag); ok {
+			return params.ActionResults{}, unitErr
+		}
+		return params.ActionResults{}, errors.Annotatef(unitErr, "no units of service %q collect metrics", tag.Id())
+	}
+	return results, nil
+}
+
+// unitCharm returns the charm the unit is running, or the charm of its
+// service if the unit has not yet started running one.
+func (c *Client) unitCharm(unit *state.Unit) (*state.Charm, error) {
+	if curl, ok := unit.CharmURL(); ok {
+		return c.api.state.Charm(curl)
+	}
+	service, err := unit.Service()
+	if err != nil {
+		return nil, err
+	}
+	ch, _, err := service.Charm()
+	return ch, err
+}
+
 // StoreCharmArchive stores a charm archive in environment storage.
 func StoreCharmArchive(st *state.State, curl *charm.URL, ch charm.Charm, r io.Reader, size int64, sha256 string) error {
 	storage := newStateStorage(st.EnvironUUID(), st.MongoSession())
@@ -10,6 +10,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/juju/errors"
 	"github.com/juju/names"
@@ -33,12 +34,14 @@ import (
 	toolstesting "github.com/juju/juju/environs/tools/testing"
 	"github.com/juju/juju/instance"
 	"github.com/juju/juju/juju/osenv"
+	"github.com/juju/juju/leadership"
 	"github.com/juju/juju/network"
 	"github.com/juju/juju/provider/dummy"
 	"github.com/juju/juju/state"
 	"github.com/juju/juju/state/multiwatcher"
 	"github.com/juju/juju/state/presence"
 	statestorage "github.com/juju/juju/state/storage"
+	statetesting "github.com/juju/juju/state/testing"
 	"github.com/juju/juju/storage"
 	"github.com/juju/juju/storage/poolmanager"
 	"github.com/juju/juju/storage/provider"
@@ -46,6 +49,7 @@ import (
 	"github.com/juju/juju/testcharms"
 	coretesting "github.com/juju/juju/testing"
 	"github.com/juju/juju/testing/factory"
+	coretools "github.com/juju/juju/tools"
 	"github.com/juju/juju/version"
 )
 
@@ -119,6 +123,38 @@ func (s *serverSuite) TestEnsureAvailabilityDeprecated(c *gc.C) {
 	c.Assert(machines[2].Series(), gc.Equals, "quantal")
 }
 
+func (s *serverSuite) TestEnableHA(c *gc.C) {
+	_, err := s.State.AddMachine("quantal", state.JobManageEnviron)
+	c.Assert(err, jc.ErrorIsNil)
+	pingerA := s.setAgentPresence(c, "0")
+	defer assertKill(c, pingerA)
+
+	changes, err := s.APIState.Client().EnableHA(3, constraints.MustParse("mem=4G"), nil)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(changes.Maintained, gc.DeepEquals, []string{"machine-0"})
+	c.Assert(changes.Added, gc.DeepEquals, []string{"machine-1", "machine-2"})
+	c.Assert(changes.Removed, gc.HasLen, 0)
+
+	machines, err := s.State.AllMachines()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(machines, gc.HasLen, 3)
+	cons, err := machines[1].Constraints()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(cons, gc.DeepEquals, constraints.MustParse("mem=4G"))
+}
+
+func (s *serverSuite) TestEnableHAEvenCount(c *gc.C) {
+	for _, n := range []int{-1, 2, 4} {
+		_, err := s.APIState.Client().EnableHA(n, constraints.Value{}, nil)
+		c.Check(err, gc.ErrorMatches, fmt.Sprintf(
+			"cannot enable HA with %d state servers: the number must be odd and non-negative", n,
+		))
+	}
+	machines, err := s.State.AllMachines()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(machines, gc.HasLen, 0)
+}
+
 func (s *serverSuite) TestBlockEnsureAvailabilityDeprecated(c *gc.C) {
 	_, err := s.State.AddMachine("quantal", state.JobManageEnviron)
 	c.Assert(err, jc.ErrorIsNil)
@@ -135,6 +171,53 @@ func (s *serverSuite) TestBlockEnsureAvailabilityDeprecated(c *gc.C) {
 	c.Assert(machines, gc.HasLen, 1)
 }
 
+func (s *serverSuite) TestStateServersStatus(c *gc.C) {
+	_, err := s.State.AddMachine("quantal", state.JobManageEnviron)
+	c.Assert(err, jc.ErrorIsNil)
+	pinger0 := s.setAgentPresence(c, "0")
+	defer assertKill(c, pinger0)
+	_, err = s.APIState.Client().EnableHA(3, constraints.Value{}, nil)
+	c.Assert(err, jc.ErrorIsNil)
+	s.setHasVote(c, "0", true)
+	s.setHasVote(c, "1", true)
+	s.setHasVote(c, "2", true)
+
+	// Only one of three voting state servers is alive.
+	status, err := s.client.StateServersStatus()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(status, jc.DeepEquals, params.StateServersStatus{
+		StateServers: []params.StateServerStatus{
+			{MachineId: "0", WantsVote: true, HasVote: true, AgentAlive: true},
+			{MachineId: "1", WantsVote: true, HasVote: true},
+			{MachineId: "2", WantsVote: true, HasVote: true},
+		},
+		HasQuorum: false,
+	})
+
+	pinger1 := s.setAgentPresence(c, "1")
+	defer assertKill(c, pinger1)
+	status, err = s.client.StateServersStatus()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(status.HasQuorum, jc.IsTrue)
+
+	// A machine that is yet to gain its vote does not count
+	// towards quorum.
+	s.setHasVote(c, "2", false)
+	status, err = s.client.StateServersStatus()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(status.StateServers[2], jc.DeepEquals, params.StateServerStatus{
+		MachineId: "2", WantsVote: true, HasVote: false,
+	})
+	c.Assert(status.HasQuorum, jc.IsTrue)
+}
+
+func (s *serverSuite) setHasVote(c *gc.C, machineId string, hasVote bool) {
+	m, err := s.State.Machine(machineId)
+	c.Assert(err, jc.ErrorIsNil)
+	err = m.SetHasVote(hasVote)
+	c.Assert(err, jc.ErrorIsNil)
+}
+
 func (s *serverSuite) TestShareEnvironmentAddMissingLocalFails(c *gc.C) {
 	args := params.ModifyEnvironUsers{
 		Changes: []params.ModifyEnvironUser{{
@@ -385,6 +468,46 @@ func (s *serverSuite) TestAbortCurrentUpgrade(c *gc.C) {
 	c.Assert(isUpgrading, jc.IsFalse)
 }
 
+func (s *serverSuite) TestRollbackCurrentUpgrade(c *gc.C) {
+	err := s.client.RollbackCurrentUpgrade()
+	c.Assert(err, jc.Satisfies, params.IsCodeNotFound)
+
+	// Create a provisioned state server running the current version.
+	machine, err := s.State.AddMachine("quantal", state.JobManageEnviron)
+	c.Assert(err, jc.ErrorIsNil)
+	err = machine.SetProvisioned(instance.Id("i-blah"), "fake-nonce", nil)
+	c.Assert(err, jc.ErrorIsNil)
+	err = machine.SetAgentVersion(version.Current)
+	c.Assert(err, jc.ErrorIsNil)
+
+	// Start an upgrade to the next minor version.
+	current := version.Current.Number
+	target := current
+	target.Minor++
+	err = s.State.SetEnvironAgentVersion(target)
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = s.State.EnsureUpgradeInfo(machine.Id(), current, target)
+	c.Assert(err, jc.ErrorIsNil)
+
+	err = s.client.RollbackCurrentUpgrade()
+	c.Assert(err, jc.ErrorIsNil)
+
+	isUpgrading, err := s.State.IsUpgrading()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(isUpgrading, jc.IsFalse)
+	cfg, err := s.State.EnvironConfig()
+	c.Assert(err, jc.ErrorIsNil)
+	agentVersion, ok := cfg.AgentVersion()
+	c.Assert(ok, jc.IsTrue)
+	c.Assert(agentVersion, gc.Equals, current)
+}
+
+func (s *serverSuite) TestBlockChangesRollbackCurrentUpgrade(c *gc.C) {
+	s.blockAllChanges(c)
+	err := s.client.RollbackCurrentUpgrade()
+	c.Assert(errors.Cause(err), gc.Equals, common.ErrOperationBlocked)
+}
+
 func (s *serverSuite) assertAbortCurrentUpgradeBlocked(c *gc.C, blocked bool) {
 	err := s.client.AbortCurrentUpgrade()
 
@@ -583,6 +706,24 @@ func (s *clientSuite) TestClientServerUnset(c *gc.C) {
 	})
 }
 
+func (s *clientSuite) TestClientServiceUnsetUnknownOptions(c *gc.C) {
+	dummy := s.AddTestingService(c, "dummy", s.AddTestingCharm(c, "dummy"))
+	err := s.APIState.Client().ServiceSet("dummy", map[string]string{
+		"title":    "foobar",
+		"username": "user name",
+	})
+	c.Assert(err, jc.ErrorIsNil)
+
+	err = s.APIState.Client().ServiceUnset("dummy", []string{"foo", "username", "bar"})
+	c.Assert(err, gc.ErrorMatches, `unknown option "foo"; unknown option "bar"`)
+	settings, err := dummy.ConfigSettings()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(settings, gc.DeepEquals, charm.Settings{
+		"title":    "foobar",
+		"username": "user name",
+	})
+}
+
 func (s *serverSuite) setupServerUnsetBlocked(c *gc.C) *state.Service {
 	dummy := s.AddTestingService(c, "dummy", s.AddTestingCharm(c, "dummy"))
 
@@ -750,6 +891,97 @@ func (s *clientSuite) TestClientAddServiceUnits(c *gc.C) {
 	c.Assert(assignedMachine, gc.Equals, "0")
 }
 
+func (s *clientSuite) TestClientScaleService(c *gc.C) {
+	s.AddTestingService(c, "dummy", s.AddTestingCharm(c, "dummy"))
+	client := s.APIState.Client()
+	units, err := client.ScaleService("dummy", 3)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(units, gc.DeepEquals, []string{"dummy/0", "dummy/1", "dummy/2"})
+
+	// A dying unit does not count towards the target.
+	dying, err := s.State.Unit("dummy/1")
+	c.Assert(err, jc.ErrorIsNil)
+	err = dying.SetAgentStatus(state.StatusActive, "", nil)
+	c.Assert(err, jc.ErrorIsNil)
+	err = dying.Destroy()
+	c.Assert(err, jc.ErrorIsNil)
+	assertLife(c, dying, state.Dying)
+	units, err = client.ScaleService("dummy", 3)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(units, gc.DeepEquals, []string{"dummy/0", "dummy/2", "dummy/3"})
+
+	// The most recently added units are destroyed first.
+	units, err = client.ScaleService("dummy", 1)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(units, gc.DeepEquals, []string{"dummy/0"})
+	for _, name := range []string{"dummy/2", "dummy/3"} {
+		_, err := s.State.Unit(name)
+		c.Assert(err, jc.Satisfies, errors.IsNotFound)
+	}
+
+	// Scaling to the current number changes nothing.
+	units, err = client.ScaleService("dummy", 1)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(units, gc.DeepEquals, []string{"dummy/0"})
+	units, err = client.ScaleService("dummy", 0)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(units, gc.HasLen, 0)
+}
+
+func (s *clientSuite) TestClientScaleServiceErrors(c *gc.C) {
+	s.AddTestingService(c, "dummy", s.AddTestingCharm(c, "dummy"))
+	s.AddTestingService(c, "logging", s.AddTestingCharm(c, "logging"))
+	client := s.APIState.Client()
+	_, err := client.ScaleService("dummy", -1)
+	c.Assert(err, gc.ErrorMatches, "cannot scale service to -1 units")
+	_, err = client.ScaleService("logging", 1)
+	c.Assert(err, gc.ErrorMatches, `cannot scale subordinate service "logging"`)
+	_, err = client.ScaleService("unknown", 1)
+	c.Assert(err, gc.ErrorMatches, `service "unknown" not found`)
+	c.Assert(err, jc.Satisfies, params.IsCodeNotFound)
+}
+
+func (s *clientSuite) TestClientScaleServiceDestroyFails(c *gc.C) {
+	s.AddTestingService(c, "dummy", s.AddTestingCharm(c, "dummy"))
+	s.PatchValue(client.DestroyUnit, func(u *state.Unit) error {
+		if u.Name() == "dummy/1" {
+			return errors.New("boom")
+		}
+		return u.Destroy()
+	})
+	apiClient := s.APIState.Client()
+	_, err := apiClient.ScaleService("dummy", 4)
+	c.Assert(err, jc.ErrorIsNil)
+
+	units, err := apiClient.ScaleService("dummy", 0)
+	c.Assert(err, gc.ErrorMatches, `cannot destroy unit "dummy/1" after destroying dummy/3, dummy/2: boom`)
+	c.Assert(units, gc.DeepEquals, []string{"dummy/0", "dummy/1"})
+	for _, name := range []string{"dummy/2", "dummy/3"} {
+		_, err := s.State.Unit(name)
+		c.Assert(err, jc.Satisfies, errors.IsNotFound)
+	}
+}
+
+func (s *clientSuite) TestBlockRemoveScaleService(c *gc.C) {
+	s.AddTestingService(c, "dummy", s.AddTestingCharm(c, "dummy"))
+	units, err := s.APIState.Client().ScaleService("dummy", 2)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(units, gc.HasLen, 2)
+	s.blockRemoveObject(c)
+	units, err = s.APIState.Client().ScaleService("dummy", 3)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(units, gc.HasLen, 3)
+	_, err = s.APIState.Client().ScaleService("dummy", 1)
+	c.Assert(errors.Cause(err), gc.DeepEquals, common.ErrOperationBlocked)
+}
+
+func (s *clientSuite) TestBlockChangeScaleService(c *gc.C) {
+	s.AddTestingService(c, "dummy", s.AddTestingCharm(c, "dummy"))
+	s.blockAllChanges(c)
+	_, err := s.APIState.Client().ScaleService("dummy", 1)
+	c.Assert(errors.Cause(err), gc.DeepEquals, common.ErrOperationBlocked)
+}
+
 func (s *clientSuite) assertAddServiceUnitsBlocked(c *gc.C, blocked bool) {
 	units, err := s.APIState.Client().AddServiceUnits("dummy", 3, "")
 	if blocked {
@@ -791,6 +1023,41 @@ func (s *clientSuite) TestClientAddUnitToMachineNotFound(c *gc.C) {
 	c.Assert(err, gc.ErrorMatches, `cannot add units for service "dummy" to machine 42: machine 42 not found`)
 }
 
+func (s *clientSuite) TestClientListCharms(c *gc.C) {
+	curls, err := s.APIState.Client().ListCharms()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(curls, gc.HasLen, 0)
+
+	dummy := s.AddTestingCharm(c, "dummy")
+	wordpress := s.AddTestingCharm(c, "wordpress")
+	curls, err = s.APIState.Client().ListCharms()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(curls, jc.SameContents, []*charm.URL{dummy.URL(), wordpress.URL()})
+
+	curls, err = s.APIState.Client().ListCharms("wordpress")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(curls, jc.DeepEquals, []*charm.URL{wordpress.URL()})
+}
+
+func (s *clientSuite) TestClientGetCharmInfo(c *gc.C) {
+	wordpress := s.AddTestingCharm(c, "wordpress")
+	info, err := s.APIState.Client().GetCharmInfo(wordpress.URL())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(info, jc.DeepEquals, &api.CharmInfo{
+		Revision: wordpress.Revision(),
+		URL:      wordpress.URL().String(),
+		Config:   wordpress.Config(),
+		Meta:     wordpress.Meta(),
+		Actions:  wordpress.Actions(),
+	})
+	c.Assert(info.Meta.Provides, gc.Not(gc.HasLen), 0)
+	c.Assert(info.Meta.Requires, gc.Not(gc.HasLen), 0)
+
+	_, err = s.APIState.Client().GetCharmInfo(charm.MustParseURL("cs:quantal/missing-1"))
+	c.Assert(err, gc.ErrorMatches, `charm "cs:quantal/missing-1" not found`)
+	c.Assert(err, jc.Satisfies, params.IsCodeNotFound)
+}
+
 func (s *clientSuite) TestClientCharmInfo(c *gc.C) {
 	var clientCharmInfoTests = []struct {
 		about           string
@@ -1047,6 +1314,15 @@ func (s *clientSuite) TestClientServiceExpose(c *gc.C) {
 	}
 }
 
+func (s *clientSuite) TestClientServiceExposeUnexposeNotFound(c *gc.C) {
+	err := s.APIState.Client().ServiceExpose("unknown-service")
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+	c.Assert(err, gc.ErrorMatches, `service "unknown-service" not found`)
+	err = s.APIState.Client().ServiceUnexpose("unknown-service")
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+	c.Assert(err, gc.ErrorMatches, `service "unknown-service" not found`)
+}
+
 func (s *clientSuite) setupServiceExpose(c *gc.C) {
 	charm := s.AddTestingCharm(c, "dummy")
 	serviceNames := []string{"dummy-service", "exposed-service"}
@@ -1232,6 +1508,11 @@ func (s *clientSuite) TestClientServiceDestroy(c *gc.C) {
 	c.Assert(service.Life(), gc.Not(gc.Equals), state.Alive)
 }
 
+func (s *clientSuite) TestClientServiceDestroyNotFound(c *gc.C) {
+	err := s.APIState.Client().ServiceDestroy("unknown-service")
+	c.Assert(err, jc.Satisfies, params.IsCodeNotFound)
+}
+
 func assertLife(c *gc.C, entity state.Living, life state.Life) {
 	err := entity.Refresh()
 	c.Assert(err, jc.ErrorIsNil)
@@ -1338,6 +1619,52 @@ func (s *clientSuite) TestClientUnitResolvedRetry(c *gc.C) {
 	s.testClientUnitResolved(c, true, state.ResolvedRetryHooks)
 }
 
+func (s *clientSuite) TestClientUnitsInError(c *gc.C) {
+	s.setUpScenario(c)
+	for _, name := range []string{"wordpress/1", "wordpress/0"} {
+		u, err := s.State.Unit(name)
+		c.Assert(err, jc.ErrorIsNil)
+		err = u.SetStatus(state.StatusError, "gaaah "+name, nil)
+		c.Assert(err, jc.ErrorIsNil)
+	}
+	units, err := s.APIState.Client().UnitsInError()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(units, jc.DeepEquals, []params.UnitError{
+		{UnitName: "wordpress/0", Message: "gaaah wordpress/0"},
+		{UnitName: "wordpress/1", Message: "gaaah wordpress/1"},
+	})
+}
+
+func (s *clientSuite) TestClientResolveUnits(c *gc.C) {
+	s.setUpScenario(c)
+	u, err := s.State.Unit("wordpress/0")
+	c.Assert(err, jc.ErrorIsNil)
+	err = u.SetStatus(state.StatusError, "gaaah", nil)
+	c.Assert(err, jc.ErrorIsNil)
+
+	results, err := s.APIState.Client().ResolveUnits([]string{"wordpress/0", "wordpress/1", "foo/42"}, true)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(results, gc.HasLen, 3)
+	c.Assert(results[0].Error, gc.IsNil)
+	c.Assert(results[1].Error, gc.ErrorMatches, `unit "wordpress/1" is not in an error state`)
+	c.Assert(results[2].Error, gc.ErrorMatches, `unit "foo/42" not found`)
+	c.Assert(results[2].Error, jc.Satisfies, params.IsCodeNotFound)
+
+	err = u.Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(u.Resolved(), gc.Equals, state.ResolvedRetryHooks)
+}
+
+func (s *clientSuite) TestBlockChangesResolveUnits(c *gc.C) {
+	u := s.setupResolved(c)
+	s.blockAllChanges(c)
+	_, err := s.APIState.Client().ResolveUnits([]string{"wordpress/0"}, false)
+	c.Assert(errors.Cause(err), gc.DeepEquals, common.ErrOperationBlocked)
+	err = u.Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(u.Resolved(), gc.Equals, state.ResolvedNone)
+}
+
 func (s *clientSuite) setupResolved(c *gc.C) *state.Unit {
 	s.setUpScenario(c)
 	u, err := s.State.Unit("wordpress/0")
@@ -2334,101 +2661,631 @@ func (s *clientSuite) TestAttemptDestroyingAlreadyDestroyedRelation(c *gc.C) {
 	c.Assert(err, gc.ErrorMatches, `relation "wordpress:db mysql:server" not found`)
 }
 
-func (s *clientSuite) TestClientWatchAll(c *gc.C) {
-	// A very simple end-to-end test, because
-	// all the logic is tested elsewhere.
-	m, err := s.State.AddMachine("quantal", state.JobManageEnviron)
-	c.Assert(err, jc.ErrorIsNil)
-	err = m.SetProvisioned("i-0", agent.BootstrapNonce, nil)
+func (s *clientSuite) setupRelationSettingsScenario(c *gc.C) (*state.Relation, *state.RelationUnit) {
+	rel := s.setupRelationScenario(c, []string{"wordpress", "mysql"})
+	unit, err := s.State.Unit("wordpress/0")
 	c.Assert(err, jc.ErrorIsNil)
-	watcher, err := s.APIState.Client().WatchAll()
+	ru, err := rel.Unit(unit)
 	c.Assert(err, jc.ErrorIsNil)
-	defer func() {
-		err := watcher.Stop()
-		c.Assert(err, jc.ErrorIsNil)
-	}()
-	deltas, err := watcher.Next()
+	err = ru.EnterScope(map[string]interface{}{
+		"some":  "settings",
+		"other": "stuff",
+	})
 	c.Assert(err, jc.ErrorIsNil)
-	if !c.Check(deltas, gc.DeepEquals, []multiwatcher.Delta{{
-		Entity: &multiwatcher.MachineInfo{
-			Id:                      m.Id(),
-			InstanceId:              "i-0",
-			Status:                  multiwatcher.Status("pending"),
-			Life:                    multiwatcher.Life("alive"),
-			Series:                  "quantal",
-			Jobs:                    []multiwatcher.MachineJob{state.JobManageEnviron.ToParams()},
-			Addresses:               []network.Address{},
-			HardwareCharacteristics: &instance.HardwareCharacteristics{},
-			HasVote:                 false,
-			WantsVote:               true,
-		},
-	}}) {
-		c.Logf("got:")
-		for _, d := range deltas {
-			c.Logf("%#v\n", d.Entity)
-		}
-	}
+	return rel, ru
 }
 
-func (s *clientSuite) TestClientSetServiceConstraints(c *gc.C) {
-	service := s.AddTestingService(c, "dummy", s.AddTestingCharm(c, "dummy"))
+func (s *clientSuite) TestClientGetRelationSettings(c *gc.C) {
+	rel, _ := s.setupRelationSettingsScenario(c)
+	settings, err := s.APIState.Client().GetRelationSettings(rel.Id(), "wordpress/0")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(settings, jc.DeepEquals, map[string]string{
+		"some":  "settings",
+		"other": "stuff",
+	})
+}
 
-	// Update constraints for the service.
-	cons, err := constraints.Parse("mem=4096", "cpu-cores=2")
+func (s *clientSuite) TestClientSetRelationSettings(c *gc.C) {
+	rel, ru := s.setupRelationSettingsScenario(c)
+	err := s.APIState.Client().SetRelationSettings(rel.Id(), "wordpress/0", map[string]string{
+		"some":  "different",
+		"other": "",
+		"new":   "value",
+	})
 	c.Assert(err, jc.ErrorIsNil)
-	err = s.APIState.Client().SetServiceConstraints("dummy", cons)
+	settings, err := ru.ReadSettings("wordpress/0")
 	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(settings, jc.DeepEquals, map[string]interface{}{
+		"some": "different",
+		"new":  "value",
+	})
+}
 
-	// Ensure the constraints have been correctly updated.
-	obtained, err := service.Constraints()
-	c.Assert(err, jc.ErrorIsNil)
-	c.Assert(obtained, gc.DeepEquals, cons)
+func (s *clientSuite) TestClientRelationSettingsNotFound(c *gc.C) {
+	rel, _ := s.setupRelationSettingsScenario(c)
+	_, err := s.APIState.Client().GetRelationSettings(rel.Id()+1, "wordpress/0")
+	c.Assert(err, gc.ErrorMatches, `relation \d+ not found`)
+	c.Assert(err, jc.Satisfies, params.IsCodeNotFound)
+	err = s.APIState.Client().SetRelationSettings(rel.Id()+1, "wordpress/0", map[string]string{"some": "value"})
+	c.Assert(err, jc.Satisfies, params.IsCodeNotFound)
+	_, err = s.APIState.Client().GetRelationSettings(rel.Id(), "wordpress/42")
+	c.Assert(err, jc.Satisfies, params.IsCodeNotFound)
 }
 
-func (s *clientSuite) setupSetServiceConstraints(c *gc.C) (*state.Service, constraints.Value) {
-	service := s.AddTestingService(c, "dummy", s.AddTestingCharm(c, "dummy"))
-	// Update constraints for the service.
-	cons, err := constraints.Parse("mem=4096", "cpu-cores=2")
+func (s *clientSuite) TestClientWatchRelationUnits(c *gc.C) {
+	rel, ru := s.setupRelationSettingsScenario(c)
+	mysql, err := s.State.Service("mysql")
+	c.Assert(err, jc.ErrorIsNil)
+	unit, err := mysql.AddUnit()
 	c.Assert(err, jc.ErrorIsNil)
-	return service, cons
-}
 
-func (s *clientSuite) assertSetServiceConstraints(c *gc.C, blocked bool, service *state.Service, cons constraints.Value) {
-	err := s.APIState.Client().SetServiceConstraints("dummy", cons)
-	if blocked {
-		c.Assert(errors.Cause(err), gc.DeepEquals, common.ErrOperationBlocked)
-	} else {
-		c.Assert(err, jc.ErrorIsNil)
-		// Ensure the constraints have been correctly updated.
-		obtained, err := service.Constraints()
-		c.Assert(err, jc.ErrorIsNil)
-		c.Assert(obtained, gc.DeepEquals, cons)
+	w, err := s.APIState.Client().WatchRelationUnits(rel.Id(), unit.Name())
+	c.Assert(err, jc.ErrorIsNil)
+	defer func() {
+		c.Assert(w.Stop(), jc.ErrorIsNil)
+	}()
+	nextChange := func() multiwatcher.RelationUnitsChange {
+		s.BackingState.StartSync()
+		select {
+		case change, ok := <-w.Changes():
+			c.Assert(ok, jc.IsTrue)
+			return change
+		case <-time.After(coretesting.LongWait):
+			c.Fatalf("timed out waiting for relation units change")
+		}
+		panic("unreachable")
 	}
-}
 
-func (s *clientSuite) TestBlockDestroySetServiceConstraints(c *gc.C) {
-	svc, cons := s.setupSetServiceConstraints(c)
-	s.blockDestroyEnvironment(c)
-	s.assertSetServiceConstraints(c, false, svc, cons)
+	// The unit already in scope is reported at once.
+	change := nextChange()
+	c.Assert(change.Departed, gc.HasLen, 0)
+	c.Assert(change.Changed, gc.HasLen, 1)
+	version := change.Changed["wordpress/0"].Version
+
+	settings, err := ru.Settings()
+	c.Assert(err, jc.ErrorIsNil)
+	settings.Set("some", "different")
+	_, err = settings.Write()
+	c.Assert(err, jc.ErrorIsNil)
+	change = nextChange()
+	c.Assert(change.Changed, gc.HasLen, 1)
+	c.Assert(change.Changed["wordpress/0"].Version > version, jc.IsTrue)
+
+	err = ru.LeaveScope()
+	c.Assert(err, jc.ErrorIsNil)
+	change = nextChange()
+	c.Assert(change.Changed, gc.HasLen, 0)
+	c.Assert(change.Departed, jc.DeepEquals, []string{"wordpress/0"})
 }
 
-func (s *clientSuite) TestBlockRemoveSetServiceConstraints(c *gc.C) {
-	svc, cons := s.setupSetServiceConstraints(c)
-	s.blockRemoveObject(c)
-	s.assertSetServiceConstraints(c, false, svc, cons)
+func (s *clientSuite) TestClientWatchRelationUnitsNotFound(c *gc.C) {
+	rel, _ := s.setupRelationSettingsScenario(c)
+	_, err := s.APIState.Client().WatchRelationUnits(rel.Id()+1, "wordpress/0")
+	c.Assert(err, gc.ErrorMatches, `relation \d+ not found`)
+	c.Assert(err, jc.Satisfies, params.IsCodeNotFound)
+	_, err = s.APIState.Client().WatchRelationUnits(rel.Id(), "wordpress/42")
+	c.Assert(err, jc.Satisfies, params.IsCodeNotFound)
 }
 
-func (s *clientSuite) TestBlockChangesSetServiceConstraints(c *gc.C) {
-	svc, cons := s.setupSetServiceConstraints(c)
+func (s *clientSuite) TestBlockChangesSetRelationSettings(c *gc.C) {
+	rel, ru := s.setupRelationSettingsScenario(c)
 	s.blockAllChanges(c)
-	s.assertSetServiceConstraints(c, true, svc, cons)
+	err := s.APIState.Client().SetRelationSettings(rel.Id(), "wordpress/0", map[string]string{"some": "different"})
+	c.Assert(errors.Cause(err), gc.ErrorMatches, common.ErrOperationBlocked.Error())
+	settings, err := ru.ReadSettings("wordpress/0")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(settings["some"], gc.Equals, "settings")
 }
 
-func (s *clientSuite) TestClientGetServiceConstraints(c *gc.C) {
-	service := s.AddTestingService(c, "dummy", s.AddTestingCharm(c, "dummy"))
+func (s *clientSuite) TestClientEnvironCredential(c *gc.C) {
+	result, err := s.APIState.Client().EnvironCredential()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result, jc.DeepEquals, params.EnvironCredential{
+		ProviderType:         "dummy",
+		CredentialAttributes: []string{"secret"},
+	})
+}
 
-	// Set constraints for the service.
-	cons, err := constraints.Parse("mem=4096", "cpu-cores=2")
+func (s *clientSuite) TestClientWatchEnvironConfig(c *gc.C) {
+	w, err := s.APIState.Client().WatchEnvironConfig()
+	c.Assert(err, jc.ErrorIsNil)
+	defer statetesting.AssertStop(c, w)
+
+	wc := statetesting.NewNotifyWatcherC(c, s.BackingState, w)
+	// Initial event.
+	wc.AssertOneChange()
+
+	err = s.State.UpdateEnvironConfig(map[string]interface{}{"logging-config": "<root>=DEBUG"}, nil, nil)
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertOneChange()
+
+	statetesting.AssertStop(c, w)
+	wc.AssertClosed()
+}
+
+func (s *clientSuite) TestClientWatchForUpgradeAvailable(c *gc.C) {
+	w, err := s.APIState.Client().WatchForUpgradeAvailable()
+	c.Assert(err, jc.ErrorIsNil)
+	defer statetesting.AssertStop(c, w)
+
+	wc := statetesting.NewNotifyWatcherC(c, s.BackingState, w)
+	// Initial event.
+	wc.AssertOneChange()
+	// No newer tools are found when the watcher starts.
+	wc.AssertNoChange()
+
+	// Older tools, and tools with a different major
+	// version, do not trigger the watcher.
+	older := version.Current
+	older.Minor--
+	newerMajor := version.Current
+	newerMajor.Major++
+	toolstesting.UploadToStorage(c, s.DefaultToolsStorage, "released", older, newerMajor)
+	err = s.State.UpdateEnvironConfig(map[string]interface{}{
+		"agent-metadata-url": "file://" + c.MkDir(),
+	}, nil, nil)
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertNoChange()
+
+	// A configuration change that does not affect the tools
+	// found does not search for them, so newer tools go
+	// unnoticed.
+	newer := version.Current
+	newer.Minor++
+	toolstesting.UploadToStorage(c, s.DefaultToolsStorage, "released", older, newerMajor, newer)
+	err = s.State.UpdateEnvironConfig(map[string]interface{}{"logging-config": "<root>=DEBUG"}, nil, nil)
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertNoChange()
+
+	// A change of the tools URL finds them.
+	err = s.State.UpdateEnvironConfig(map[string]interface{}{
+		"agent-metadata-url": "file://" + c.MkDir(),
+	}, nil, nil)
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertOneChange()
+
+	statetesting.AssertStop(c, w)
+	wc.AssertClosed()
+}
+
+func (s *clientSuite) TestClientWatchForUpgradeAvailablePolls(c *gc.C) {
+	s.PatchValue(client.UpgradeAvailablePollDelay, coretesting.ShortWait)
+	w, err := s.APIState.Client().WatchForUpgradeAvailable()
+	c.Assert(err, jc.ErrorIsNil)
+	defer statetesting.AssertStop(c, w)
+
+	wc := statetesting.NewNotifyWatcherC(c, s.BackingState, w)
+	// Initial event.
+	wc.AssertOneChange()
+
+	newer := version.Current
+	newer.Patch++
+	toolstesting.UploadToStorage(c, s.DefaultToolsStorage, "released", newer)
+	wc.AssertOneChange()
+
+	statetesting.AssertStop(c, w)
+	wc.AssertClosed()
+}
+
+func (s *clientSuite) TestClientWatchService(c *gc.C) {
+	wordpress := s.AddTestingService(c, "wordpress", s.AddTestingCharm(c, "wordpress"))
+	unit0, err := wordpress.AddUnit()
+	c.Assert(err, jc.ErrorIsNil)
+
+	w, err := s.APIState.Client().WatchService("wordpress")
+	c.Assert(err, jc.ErrorIsNil)
+	defer statetesting.AssertStop(c, w)
+
+	wc := statetesting.NewStringsWatcherC(c, s.BackingState, w)
+	// Initial event.
+	wc.AssertChange(unit0.Name())
+	wc.AssertNoChange()
+
+	unit1, err := wordpress.AddUnit()
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertChange(unit1.Name())
+	wc.AssertNoChange()
+
+	err = unit1.SetPassword("arble-farble-dying-yarble")
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertChange(unit1.Name())
+	wc.AssertNoChange()
+
+	// Both the unit's life and its document change, so
+	// the unit may be reported more than once.
+	err = unit0.Destroy()
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertChange(unit0.Name())
+
+	statetesting.AssertStop(c, w)
+	wc.AssertClosed()
+}
+
+func (s *clientSuite) TestClientWatchServiceNotFound(c *gc.C) {
+	_, err := s.APIState.Client().WatchService("unknown-service")
+	c.Assert(err, gc.ErrorMatches, `service "unknown-service" not found`)
+	c.Assert(err, jc.Satisfies, params.IsCodeNotFound)
+}
+
+func (s *clientSuite) TestClientWatchServiceInvalidName(c *gc.C) {
+	_, err := s.APIState.Client().WatchService("wordpress/0")
+	c.Assert(err, gc.ErrorMatches, `service name "wordpress/0" not valid`)
+	c.Assert(err, jc.Satisfies, errors.IsNotValid)
+}
+
+func (s *clientSuite) TestClientWatchUnit(c *gc.C) {
+	wordpress := s.AddTestingService(c, "wordpress", s.AddTestingCharm(c, "wordpress"))
+	unit, err := wordpress.AddUnit()
+	c.Assert(err, jc.ErrorIsNil)
+
+	w, err := s.APIState.Client().WatchUnit(unit.Name())
+	c.Assert(err, jc.ErrorIsNil)
+	defer statetesting.AssertStop(c, w)
+
+	wc := statetesting.NewNotifyWatcherC(c, s.BackingState, w)
+	// Initial event.
+	wc.AssertOneChange()
+
+	err = unit.SetPassword("arble-farble-dying-yarble")
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertOneChange()
+
+	statetesting.AssertStop(c, w)
+	wc.AssertClosed()
+}
+
+func (s *clientSuite) TestClientWatchUnitNotFound(c *gc.C) {
+	_, err := s.APIState.Client().WatchUnit("wordpress/0")
+	c.Assert(err, gc.ErrorMatches, `unit "wordpress/0" not found`)
+	c.Assert(err, jc.Satisfies, params.IsCodeNotFound)
+}
+
+func (s *clientSuite) TestClientWatchUnitInvalidName(c *gc.C) {
+	_, err := s.APIState.Client().WatchUnit("wordpress")
+	c.Assert(err, gc.ErrorMatches, `unit name "wordpress" not valid`)
+	c.Assert(err, jc.Satisfies, errors.IsNotValid)
+}
+
+func (s *clientSuite) TestClientWatchMachineAddresses(c *gc.C) {
+	machine, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+
+	w, err := s.APIState.Client().WatchMachineAddresses()
+	c.Assert(err, jc.ErrorIsNil)
+	defer func() {
+		c.Assert(w.Stop(), jc.ErrorIsNil)
+	}()
+	nextChange := func() []api.MachineAddresses {
+		s.BackingState.StartSync()
+		select {
+		case changes, ok := <-w.Changes():
+			c.Assert(ok, jc.IsTrue)
+			return changes
+		case <-time.After(coretesting.LongWait):
+			c.Fatalf("timed out waiting for machine addresses")
+		}
+		panic("unreachable")
+	}
+
+	// Initial event.
+	c.Assert(nextChange(), jc.DeepEquals, []api.MachineAddresses{{
+		MachineId: machine.Id(),
+		Addresses: []network.Address{},
+	}})
+
+	addrs := network.NewAddresses("10.0.0.1", "example.com")
+	err = machine.SetAddresses(addrs...)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(nextChange(), jc.DeepEquals, []api.MachineAddresses{{
+		MachineId: machine.Id(),
+		Addresses: addrs,
+	}})
+
+	// A removed machine is reported without addresses.
+	err = machine.EnsureDead()
+	c.Assert(err, jc.ErrorIsNil)
+	err = machine.Remove()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(nextChange(), jc.DeepEquals, []api.MachineAddresses{{
+		MachineId: machine.Id(),
+	}})
+}
+
+func (s *clientSuite) TestClientWatchActionResults(c *gc.C) {
+	service := s.AddTestingService(c, "dummy", s.AddTestingCharm(c, "dummy"))
+	unit, err := service.AddUnit()
+	c.Assert(err, jc.ErrorIsNil)
+	done, err := unit.AddAction("snapshot", nil)
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = done.Finish(state.ActionResults{
+		Status:  state.ActionCompleted,
+		Results: map[string]interface{}{"outfile": "/some/file.bz2"},
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	pending, err := unit.AddAction("snapshot", nil)
+	c.Assert(err, jc.ErrorIsNil)
+
+	w, err := s.APIState.Client().WatchActionResults([]string{
+		done.ActionTag().String(), pending.ActionTag().String(),
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	defer func() {
+		c.Assert(w.Stop(), jc.ErrorIsNil)
+	}()
+	nextChange := func() []params.ActionResult {
+		s.BackingState.StartSync()
+		select {
+		case changes, ok := <-w.Changes():
+			c.Assert(ok, jc.IsTrue)
+			return changes
+		case <-time.After(coretesting.LongWait):
+			c.Fatalf("timed out waiting for action results")
+		}
+		panic("unreachable")
+	}
+
+	// The action that has already completed is reported at once.
+	results := nextChange()
+	c.Assert(results, gc.HasLen, 1)
+	c.Assert(results[0].Action.Tag, gc.Equals, done.ActionTag().String())
+	c.Assert(results[0].Status, gc.Equals, params.ActionCompleted)
+	c.Assert(results[0].Output, jc.DeepEquals, map[string]interface{}{"outfile": "/some/file.bz2"})
+
+	_, err = pending.Finish(state.ActionResults{Status: state.ActionFailed, Message: "oops"})
+	c.Assert(err, jc.ErrorIsNil)
+	results = nextChange()
+	c.Assert(results, gc.HasLen, 1)
+	c.Assert(results[0].Action.Tag, gc.Equals, pending.ActionTag().String())
+	c.Assert(results[0].Status, gc.Equals, params.ActionFailed)
+	c.Assert(results[0].Message, gc.Equals, "oops")
+}
+
+func (s *clientSuite) TestClientWatchActionResultsInvalidTag(c *gc.C) {
+	_, err := s.APIState.Client().WatchActionResults([]string{"unit-dummy-0"})
+	c.Assert(err, gc.ErrorMatches, `"unit-dummy-0" is not a valid action tag`)
+}
+
+func (s *clientSuite) TestClientWatchAll(c *gc.C) {
+	// A very simple end-to-end test, because
+	// all the logic is tested elsewhere.
+	m, err := s.State.AddMachine("quantal", state.JobManageEnviron)
+	c.Assert(err, jc.ErrorIsNil)
+	err = m.SetProvisioned("i-0", agent.BootstrapNonce, nil)
+	c.Assert(err, jc.ErrorIsNil)
+	watcher, err := s.APIState.Client().WatchAll()
+	c.Assert(err, jc.ErrorIsNil)
+	defer func() {
+		err := watcher.Stop()
+		c.Assert(err, jc.ErrorIsNil)
+	}()
+	deltas, err := watcher.Next()
+	c.Assert(err, jc.ErrorIsNil)
+	if !c.Check(deltas, gc.DeepEquals, []multiwatcher.Delta{{
+		Entity: &multiwatcher.MachineInfo{
+			Id:                      m.Id(),
+			InstanceId:              "i-0",
+			Status:                  multiwatcher.Status("pending"),
+			Life:                    multiwatcher.Life("alive"),
+			Series:                  "quantal",
+			Jobs:                    []multiwatcher.MachineJob{state.JobManageEnviron.ToParams()},
+			Addresses:               []network.Address{},
+			HardwareCharacteristics: &instance.HardwareCharacteristics{},
+			HasVote:                 false,
+			WantsVote:               true,
+		},
+	}}) {
+		c.Logf("got:")
+		for _, d := range deltas {
+			c.Logf("%#v\n", d.Entity)
+		}
+	}
+}
+
+func (s *clientSuite) TestClientWatchAllEnvs(c *gc.C) {
+	otherState := s.Factory.MakeEnvironment(c, nil)
+	defer otherState.Close()
+	_, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = otherState.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+
+	watcher, err := s.APIState.Client().WatchAllEnvs()
+	c.Assert(err, jc.ErrorIsNil)
+	defer func() {
+		err := watcher.Stop()
+		c.Assert(err, jc.ErrorIsNil)
+	}()
+	machines := make(map[string][]string)
+	for len(machines) < 2 {
+		deltas, err := watcher.Next()
+		c.Assert(err, jc.ErrorIsNil)
+		for _, d := range deltas {
+			if m, ok := d.Delta.Entity.(*multiwatcher.MachineInfo); ok {
+				machines[d.EnvUUID] = append(machines[d.EnvUUID], m.Id)
+			}
+		}
+	}
+	c.Assert(machines, jc.DeepEquals, map[string][]string{
+		s.State.EnvironUUID():    {"0"},
+		otherState.EnvironUUID(): {"0"},
+	})
+}
+
+func (s *clientSuite) TestClientWatchAllEnvsFromHostedEnvironment(c *gc.C) {
+	otherState := s.Factory.MakeEnvironment(c, nil)
+	defer otherState.Close()
+	info := s.APIInfo(c)
+	info.EnvironTag = otherState.EnvironTag()
+	st, err := api.Open(info, api.DialOpts{})
+	c.Assert(err, jc.ErrorIsNil)
+	defer st.Close()
+
+	_, err = st.Client().WatchAllEnvs()
+	c.Assert(err, gc.ErrorMatches, "permission denied")
+}
+
+func (s *clientSuite) TestClientWatchAllEnvsNotOwner(c *gc.C) {
+	user := s.Factory.MakeUser(c, &factory.UserParams{Password: "password"})
+	st := s.OpenAPIAs(c, user.Tag(), "password")
+	defer st.Close()
+
+	_, err := st.Client().WatchAllEnvs()
+	c.Assert(err, gc.ErrorMatches, "permission denied")
+}
+
+func (s *clientSuite) TestClientStateServerConfig(c *gc.C) {
+	config, err := s.APIState.Client().StateServerConfig()
+	c.Assert(err, jc.ErrorIsNil)
+	envConfig, err := s.State.EnvironConfig()
+	c.Assert(err, jc.ErrorIsNil)
+	caCert, _ := envConfig.CACert()
+	// Numbers are decoded from JSON as float64s.
+	c.Assert(config["uuid"], gc.Equals, s.State.EnvironUUID())
+	c.Assert(config["api-port"], gc.Equals, float64(envConfig.APIPort()))
+	c.Assert(config["state-port"], gc.Equals, float64(envConfig.StatePort()))
+	c.Assert(config["syslog-port"], gc.Equals, float64(envConfig.SyslogPort()))
+	c.Assert(config["ca-cert"], gc.Equals, caCert)
+	for key := range config {
+		c.Check(key, gc.Not(gc.Equals), "ca-private-key")
+		c.Check(key, gc.Not(gc.Equals), "admin-secret")
+	}
+}
+
+func (s *clientSuite) TestClientStateServerConfigFromHostedEnvironment(c *gc.C) {
+	otherState := s.Factory.MakeEnvironment(c, nil)
+	defer otherState.Close()
+	info := s.APIInfo(c)
+	info.EnvironTag = otherState.EnvironTag()
+	st, err := api.Open(info, api.DialOpts{})
+	c.Assert(err, jc.ErrorIsNil)
+	defer st.Close()
+
+	// The settings of the state server environment are reported.
+	config, err := st.Client().StateServerConfig()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(config["uuid"], gc.Equals, s.State.EnvironUUID())
+	_, ok := config["ca-private-key"]
+	c.Assert(ok, jc.IsFalse)
+}
+
+func (s *clientSuite) TestClientAllSnapshot(c *gc.C) {
+	m, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	wordpress := s.AddTestingService(c, "wordpress", s.AddTestingCharm(c, "wordpress"))
+	unit, err := wordpress.AddUnit()
+	c.Assert(err, jc.ErrorIsNil)
+
+	deltas, err := s.APIState.Client().AllSnapshot()
+	c.Assert(err, jc.ErrorIsNil)
+	ids := make(map[multiwatcher.EntityId]bool)
+	for _, delta := range deltas {
+		c.Check(delta.Removed, jc.IsFalse)
+		ids[delta.Entity.EntityId()] = true
+	}
+	c.Assert(ids[multiwatcher.EntityId{"machine", m.Id()}], jc.IsTrue)
+	c.Assert(ids[multiwatcher.EntityId{"service", wordpress.Name()}], jc.IsTrue)
+	c.Assert(ids[multiwatcher.EntityId{"unit", unit.Name()}], jc.IsTrue)
+
+	// Taking another snapshot reports the complete state again.
+	again, err := s.APIState.Client().AllSnapshot()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(again, gc.HasLen, len(deltas))
+}
+
+func (s *clientSuite) TestClientSetServiceConstraints(c *gc.C) {
+	service := s.AddTestingService(c, "dummy", s.AddTestingCharm(c, "dummy"))
+
+	// Update constraints for the service.
+	cons, err := constraints.Parse("mem=4096", "cpu-cores=2")
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.APIState.Client().SetServiceConstraints("dummy", cons)
+	c.Assert(err, jc.ErrorIsNil)
+
+	// Ensure the constraints have been correctly updated.
+	obtained, err := service.Constraints()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(obtained, gc.DeepEquals, cons)
+}
+
+func (s *clientSuite) TestClientSetServiceConstraintsInvalidName(c *gc.C) {
+	err := s.APIState.Client().SetServiceConstraints("bad/name", constraints.Value{})
+	c.Assert(err, jc.Satisfies, errors.IsNotValid)
+	c.Assert(err, gc.ErrorMatches, `service name "bad/name" not valid`)
+}
+
+func (s *clientSuite) TestClientSetServiceConstraintsNotFound(c *gc.C) {
+	err := s.APIState.Client().SetServiceConstraints("missing", constraints.MustParse("mem=4096"))
+	c.Assert(err, jc.Satisfies, params.IsCodeNotFound)
+	c.Assert(err, gc.ErrorMatches, `service "missing" not found`)
+}
+
+func (s *clientSuite) TestClientSetServiceConstraintsInvalid(c *gc.C) {
+	service := s.AddTestingService(c, "dummy", s.AddTestingCharm(c, "dummy"))
+	bogus := "bogus"
+	err := s.APIState.Client().SetServiceConstraints("dummy", constraints.Value{Arch: &bogus})
+	c.Assert(err, gc.ErrorMatches, `invalid constraints for service "dummy": bad "arch" constraint: "bogus" not recognized`)
+	obtained, err := service.Constraints()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(obtained, gc.DeepEquals, constraints.Value{})
+}
+
+func (s *clientSuite) TestClientSetServiceConstraintsRejected(c *gc.C) {
+	s.AddTestingService(c, "dummy", s.AddTestingCharm(c, "dummy"))
+	cons := constraints.MustParse("mem=4096", "instance-type=foo")
+	err := s.APIState.Client().SetServiceConstraints("dummy", cons)
+	c.Assert(err, gc.ErrorMatches, `ambiguous constraints: "instance-type" overlaps with "mem"`)
+}
+
+func (s *clientSuite) TestClientGetServiceConstraintsNotFound(c *gc.C) {
+	_, err := s.APIState.Client().GetServiceConstraints("missing")
+	c.Assert(err, jc.Satisfies, params.IsCodeNotFound)
+	c.Assert(err, gc.ErrorMatches, `service "missing" not found`)
+}
+
+func (s *clientSuite) setupSetServiceConstraints(c *gc.C) (*state.Service, constraints.Value) {
+	service := s.AddTestingService(c, "dummy", s.AddTestingCharm(c, "dummy"))
+	// Update constraints for the service.
+	cons, err := constraints.Parse("mem=4096", "cpu-cores=2")
+	c.Assert(err, jc.ErrorIsNil)
+	return service, cons
+}
+
+func (s *clientSuite) assertSetServiceConstraints(c *gc.C, blocked bool, service *state.Service, cons constraints.Value) {
+	err := s.APIState.Client().SetServiceConstraints("dummy", cons)
+	if blocked {
+		c.Assert(errors.Cause(err), gc.DeepEquals, common.ErrOperationBlocked)
+	} else {
+		c.Assert(err, jc.ErrorIsNil)
+		// Ensure the constraints have been correctly updated.
+		obtained, err := service.Constraints()
+		c.Assert(err, jc.ErrorIsNil)
+		c.Assert(obtained, gc.DeepEquals, cons)
+	}
+}
+
+func (s *clientSuite) TestBlockDestroySetServiceConstraints(c *gc.C) {
+	svc, cons := s.setupSetServiceConstraints(c)
+	s.blockDestroyEnvironment(c)
+	s.assertSetServiceConstraints(c, false, svc, cons)
+}
+
+func (s *clientSuite) TestBlockRemoveSetServiceConstraints(c *gc.C) {
+	svc, cons := s.setupSetServiceConstraints(c)
+	s.blockRemoveObject(c)
+	s.assertSetServiceConstraints(c, false, svc, cons)
+}
+
+func (s *clientSuite) TestBlockChangesSetServiceConstraints(c *gc.C) {
+	svc, cons := s.setupSetServiceConstraints(c)
+	s.blockAllChanges(c)
+	s.assertSetServiceConstraints(c, true, svc, cons)
+}
+
+func (s *clientSuite) TestClientGetServiceConstraints(c *gc.C) {
+	service := s.AddTestingService(c, "dummy", s.AddTestingCharm(c, "dummy"))
+
+	// Set constraints for the service.
+	cons, err := constraints.Parse("mem=4096", "cpu-cores=2")
 	c.Assert(err, jc.ErrorIsNil)
 	err = service.SetConstraints(cons)
 	c.Assert(err, jc.ErrorIsNil)
@@ -2550,6 +3407,175 @@ func (s *clientSuite) TestClientPublicAddressUnit(c *gc.C) {
 	c.Assert(addr, gc.Equals, "public")
 }
 
+func (s *clientSuite) TestClientGetUnitAddresses(c *gc.C) {
+	wordpress := s.AddTestingService(c, "wordpress", s.AddTestingCharm(c, "wordpress"))
+	unit, err := wordpress.AddUnit()
+	c.Assert(err, jc.ErrorIsNil)
+
+	// The unit is not yet assigned to a machine.
+	_, err = s.APIState.Client().GetUnitAddresses(unit.Name())
+	c.Assert(err, gc.ErrorMatches, `unit "wordpress/0" is not assigned to a machine`)
+	c.Assert(err, jc.Satisfies, params.IsCodeNotAssigned)
+
+	// The unit's machine has no addresses.
+	m, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	err = unit.AssignToMachine(m)
+	c.Assert(err, jc.ErrorIsNil)
+	addrs, err := s.APIState.Client().GetUnitAddresses(unit.Name())
+	c.Assert(err, jc.ErrorIsNil)
+	_, ok := addrs.PublicAddress()
+	c.Assert(ok, jc.IsFalse)
+	_, ok = addrs.PrivateAddress()
+	c.Assert(ok, jc.IsFalse)
+
+	err = m.SetAddresses(
+		network.NewAddress("cloudlocal", network.ScopeCloudLocal),
+		network.NewAddress("public", network.ScopePublic),
+	)
+	c.Assert(err, jc.ErrorIsNil)
+	addrs, err = s.APIState.Client().GetUnitAddresses(unit.Name())
+	c.Assert(err, jc.ErrorIsNil)
+	addr, ok := addrs.PublicAddress()
+	c.Assert(ok, jc.IsTrue)
+	c.Assert(addr, gc.Equals, "public")
+	addr, ok = addrs.PrivateAddress()
+	c.Assert(ok, jc.IsTrue)
+	c.Assert(addr, gc.Equals, "cloudlocal")
+}
+
+func (s *clientSuite) TestClientGetUnitAddressesNotFound(c *gc.C) {
+	_, err := s.APIState.Client().GetUnitAddresses("wordpress/0")
+	c.Assert(err, gc.ErrorMatches, `unit "wordpress/0" not found`)
+	c.Assert(err, jc.Satisfies, params.IsCodeNotFound)
+}
+
+func (s *clientSuite) TestClientGetUnitAddressesInvalidUnit(c *gc.C) {
+	_, err := s.APIState.Client().GetUnitAddresses("wordpress")
+	c.Assert(err, gc.ErrorMatches, `unit name "wordpress" not valid`)
+	c.Assert(err, jc.Satisfies, errors.IsNotValid)
+}
+
+type transferLeadershipManager struct {
+	leadership.LeadershipManager
+	calls [][]string
+}
+
+func (m *transferLeadershipManager) TransferLeadership(serviceId, fromUnitId, toUnitId string) error {
+	m.calls = append(m.calls, []string{serviceId, fromUnitId, toUnitId})
+	return nil
+}
+
+func (s *clientSuite) TestClientTransferLeadership(c *gc.C) {
+	manager := &transferLeadershipManager{}
+	s.PatchValue(client.LeaderMgr, leadership.LeadershipManager(manager))
+	wordpress := s.AddTestingService(c, "wordpress", s.AddTestingCharm(c, "wordpress"))
+	for i := 0; i < 2; i++ {
+		_, err := wordpress.AddUnit()
+		c.Assert(err, jc.ErrorIsNil)
+	}
+
+	err := s.APIState.Client().TransferLeadership("wordpress", "wordpress/0", "wordpress/1")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(manager.calls, gc.DeepEquals, [][]string{{"wordpress", "wordpress/0", "wordpress/1"}})
+}
+
+func (s *clientSuite) TestClientTransferLeadershipIneligibleUnit(c *gc.C) {
+	manager := &transferLeadershipManager{}
+	s.PatchValue(client.LeaderMgr, leadership.LeadershipManager(manager))
+	wordpress := s.AddTestingService(c, "wordpress", s.AddTestingCharm(c, "wordpress"))
+	s.AddTestingService(c, "mysql", s.AddTestingCharm(c, "mysql"))
+	_, err := wordpress.AddUnit()
+	c.Assert(err, jc.ErrorIsNil)
+	unit, err := wordpress.AddUnit()
+	c.Assert(err, jc.ErrorIsNil)
+	err = unit.Destroy()
+	c.Assert(err, jc.ErrorIsNil)
+
+	apiClient := s.APIState.Client()
+	err = apiClient.TransferLeadership("wordpress", "wordpress/0", "mysql/0")
+	c.Assert(err, gc.ErrorMatches, `unit "mysql/0" is not a unit of service "wordpress"`)
+	err = apiClient.TransferLeadership("wordpress", "wordpress/0", "wordpress/2")
+	c.Assert(err, gc.ErrorMatches, `unit "wordpress/2" not found`)
+	c.Assert(err, jc.Satisfies, params.IsCodeNotFound)
+	err = apiClient.TransferLeadership("wordpress", "wordpress/0", "wordpress/1")
+	c.Assert(err, gc.ErrorMatches, `unit "wordpress/1" is not alive`)
+	c.Assert(manager.calls, gc.HasLen, 0)
+}
+
+func (s *clientSuite) TestClientTransferLeadershipInvalidName(c *gc.C) {
+	err := s.APIState.Client().TransferLeadership("wordpress", "wordpress/0", "wordpress")
+	c.Assert(err, gc.ErrorMatches, `unit name "wordpress" not valid`)
+	c.Assert(err, jc.Satisfies, errors.IsNotValid)
+}
+
+func (s *clientSuite) TestBlockChangesTransferLeadership(c *gc.C) {
+	s.PatchValue(client.LeaderMgr, leadership.LeadershipManager(&transferLeadershipManager{}))
+	wordpress := s.AddTestingService(c, "wordpress", s.AddTestingCharm(c, "wordpress"))
+	for i := 0; i < 2; i++ {
+		_, err := wordpress.AddUnit()
+		c.Assert(err, jc.ErrorIsNil)
+	}
+	s.blockAllChanges(c)
+	err := s.APIState.Client().TransferLeadership("wordpress", "wordpress/0", "wordpress/1")
+	c.Assert(errors.Cause(err), gc.DeepEquals, common.ErrOperationBlocked)
+}
+
+func (s *clientSuite) TestClientMeterStatus(c *gc.C) {
+	wordpress := s.AddTestingService(c, "wordpress", s.AddTestingCharm(c, "wordpress"))
+	unit, err := wordpress.AddUnit()
+	c.Assert(err, jc.ErrorIsNil)
+
+	// No meter status has been set yet.
+	_, _, err = s.APIState.Client().GetMeterStatus(unit.Name())
+	c.Assert(err, gc.ErrorMatches, `meter status for unit "wordpress/0" not found`)
+	c.Assert(err, jc.Satisfies, params.IsCodeNotFound)
+
+	err = s.APIState.Client().SetMeterStatus(unit.Name(), "AMBER", "running low")
+	c.Assert(err, jc.ErrorIsNil)
+	code, info, err := s.APIState.Client().GetMeterStatus(unit.Name())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(code, gc.Equals, "AMBER")
+	c.Assert(info, gc.Equals, "running low")
+
+	code, info, err = unit.GetMeterStatus()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(code, gc.Equals, "AMBER")
+	c.Assert(info, gc.Equals, "running low")
+}
+
+func (s *clientSuite) TestClientMeterStatusErrors(c *gc.C) {
+	_, _, err := s.APIState.Client().GetMeterStatus("wordpress/0")
+	c.Assert(err, gc.ErrorMatches, `unit "wordpress/0" not found`)
+	c.Assert(err, jc.Satisfies, params.IsCodeNotFound)
+	err = s.APIState.Client().SetMeterStatus("wordpress/0", "GREEN", "")
+	c.Assert(err, gc.ErrorMatches, `unit "wordpress/0" not found`)
+
+	wordpress := s.AddTestingService(c, "wordpress", s.AddTestingCharm(c, "wordpress"))
+	_, err = wordpress.AddUnit()
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.APIState.Client().SetMeterStatus("wordpress/0", "PURPLE", "")
+	c.Assert(err, gc.ErrorMatches, `invalid meter status "PURPLE"`)
+}
+
+func (s *clientSuite) TestClientMeterStatusInvalidUnit(c *gc.C) {
+	_, _, err := s.APIState.Client().GetMeterStatus("wordpress")
+	c.Assert(err, gc.ErrorMatches, `unit name "wordpress" not valid`)
+	c.Assert(err, jc.Satisfies, errors.IsNotValid)
+	err = s.APIState.Client().SetMeterStatus("wordpress", "GREEN", "")
+	c.Assert(err, gc.ErrorMatches, `unit name "wordpress" not valid`)
+	c.Assert(err, jc.Satisfies, errors.IsNotValid)
+}
+
+func (s *clientSuite) TestBlockChangesSetMeterStatus(c *gc.C) {
+	wordpress := s.AddTestingService(c, "wordpress", s.AddTestingCharm(c, "wordpress"))
+	_, err := wordpress.AddUnit()
+	c.Assert(err, jc.ErrorIsNil)
+	s.blockAllChanges(c)
+	err = s.APIState.Client().SetMeterStatus("wordpress/0", "GREEN", "")
+	c.Assert(errors.Cause(err), gc.DeepEquals, common.ErrOperationBlocked)
+}
+
 func (s *clientSuite) TestClientPrivateAddressErrors(c *gc.C) {
 	s.setUpScenario(c)
 	_, err := s.APIState.Client().PrivateAddress("wordpress")
@@ -2755,6 +3781,67 @@ func (s *clientSuite) TestClientFindTools(c *gc.C) {
 	c.Assert(result.List[0].URL, gc.Equals, url)
 }
 
+func (s *clientSuite) TestClientFindToolsList(c *gc.C) {
+	_, err := s.APIState.Client().FindToolsList(2, -1, "", "")
+	c.Assert(err, gc.Equals, coretools.ErrNoMatches)
+	toolstesting.UploadToStorage(c, s.DefaultToolsStorage, "released", version.MustParseBinary("2.12.0-precise-amd64"))
+	list, err := s.APIState.Client().FindToolsList(2, 12, "precise", "amd64")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(list, gc.HasLen, 1)
+	c.Assert(list[0].Version, gc.Equals, version.MustParseBinary("2.12.0-precise-amd64"))
+}
+
+func (s *clientSuite) TestClientStageEnvironAgentVersion(c *gc.C) {
+	client := s.APIState.Client()
+	err := client.StageEnvironAgentVersion(version.MustParse("2.12.0"))
+	c.Assert(err, gc.Equals, coretools.ErrNoMatches)
+	s.assertAgentVersion(c, version.Current.Number)
+
+	toolstesting.UploadToStorage(c, s.DefaultToolsStorage, "released", version.MustParseBinary("2.12.0-precise-amd64"))
+	err = client.StageEnvironAgentVersion(version.MustParse("2.12.0"))
+	c.Assert(err, jc.ErrorIsNil)
+	s.assertAgentVersion(c, version.MustParse("2.12.0"))
+
+	// Tools of another version with the same major and minor
+	// versions do not match.
+	err = client.StageEnvironAgentVersion(version.MustParse("2.12.1"))
+	c.Assert(err, gc.Equals, coretools.ErrNoMatches)
+	s.assertAgentVersion(c, version.MustParse("2.12.0"))
+}
+
+func (s *clientSuite) TestBlockChangesStageEnvironAgentVersion(c *gc.C) {
+	toolstesting.UploadToStorage(c, s.DefaultToolsStorage, "released", version.MustParseBinary("2.12.0-precise-amd64"))
+	s.blockAllChanges(c)
+	err := s.APIState.Client().StageEnvironAgentVersion(version.MustParse("2.12.0"))
+	c.Assert(errors.Cause(err), gc.ErrorMatches, common.ErrOperationBlocked.Error())
+	s.assertAgentVersion(c, version.Current.Number)
+}
+
+func (s *clientSuite) assertAgentVersion(c *gc.C, expected version.Number) {
+	envConfig, err := s.State.EnvironConfig()
+	c.Assert(err, jc.ErrorIsNil)
+	agentVersion, ok := envConfig.AgentVersion()
+	c.Assert(ok, jc.IsTrue)
+	c.Assert(agentVersion, gc.Equals, expected)
+}
+
+func (s *clientSuite) TestClientUploadToolsList(c *gc.C) {
+	vers := version.MustParseBinary("2.12.0-quantal-amd64")
+	list, err := s.APIState.Client().UploadToolsList(strings.NewReader("fake tools"), vers)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(list, gc.HasLen, 2)
+	c.Assert(list[0].Version, gc.Equals, vers)
+	// The tools are also registered for the environment's default series.
+	vers.Series = coretesting.FakeDefaultSeries
+	c.Assert(list[1].Version, gc.Equals, vers)
+
+	storage, err := s.State.ToolsStorage()
+	c.Assert(err, jc.ErrorIsNil)
+	defer storage.Close()
+	_, err = storage.Metadata(vers)
+	c.Assert(err, jc.ErrorIsNil)
+}
+
 func (s *clientSuite) checkMachine(c *gc.C, id, series, cons string) {
 	// Ensure the machine was actually created.
 	machine, err := s.BackingState.Machine(id)
@@ -3326,6 +4413,96 @@ func (s *clientSuite) TestAddCharm(c *gc.C) {
 	s.assertUploaded(c, storage, sch.StoragePath(), sch.BundleSha256())
 }
 
+func (s *clientSuite) TestAddStoreCharm(c *gc.C) {
+	store := s.makeMockCharmStore()
+	store.SetDefaultSeries("precise")
+	curl, _ := addCharm(c, "wordpress")
+
+	client := s.APIState.Client()
+	added, err := client.AddStoreCharm("cs:wordpress")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(added, gc.DeepEquals, curl)
+	_, err = s.State.Charm(curl)
+	c.Assert(err, jc.ErrorIsNil)
+
+	// A fully specified URL is added as given.
+	added, err = client.AddStoreCharm(curl.String())
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(added, gc.DeepEquals, curl)
+}
+
+func (s *clientSuite) TestAddStoreCharmErrors(c *gc.C) {
+	store := s.makeMockCharmStore()
+	store.SetDefaultSeries("precise")
+
+	client := s.APIState.Client()
+	_, err := client.AddStoreCharm("local:precise/wordpress")
+	c.Assert(err, gc.ErrorMatches, "only charm store charm URLs are supported, with cs: schema")
+	_, err = client.AddStoreCharm("cs:wordpress")
+	c.Assert(err, gc.ErrorMatches, `cannot resolve charm "cs:wordpress": .*`)
+}
+
+func (s *clientSuite) TestCollectMetrics(c *gc.C) {
+	service := s.AddTestingService(c, "metered", s.AddTestingCharm(c, "metered"))
+	unit0, err := service.AddUnit()
+	c.Assert(err, jc.ErrorIsNil)
+	unit1, err := service.AddUnit()
+	c.Assert(err, jc.ErrorIsNil)
+
+	client := s.APIState.Client()
+	results, err := client.CollectMetrics("metered")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(results, gc.HasLen, 2)
+	for i, unit := range []*state.Unit{unit0, unit1} {
+		result := results[i]
+		c.Assert(result.Error, gc.IsNil)
+		c.Assert(result.Action.Receiver, gc.Equals, unit.Tag().String())
+		c.Assert(result.Action.Name, gc.Equals, params.CollectMetricsAction)
+		c.Assert(result.Status, gc.Equals, params.ActionPending)
+		pending, err := unit.PendingActions()
+		c.Assert(err, jc.ErrorIsNil)
+		c.Assert(pending, gc.HasLen, 1)
+		c.Assert(pending[0].ActionTag().String(), gc.Equals, result.Action.Tag)
+	}
+
+	results, err = client.CollectMetrics("metered/1")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(results, gc.HasLen, 1)
+	c.Assert(results[0].Error, gc.IsNil)
+	c.Assert(results[0].Action.Receiver, gc.Equals, unit1.Tag().String())
+}
+
+func (s *clientSuite) TestCollectMetricsErrors(c *gc.C) {
+	client := s.APIState.Client()
+	_, err := client.CollectMetrics("no/such/thing")
+	c.Assert(err, gc.ErrorMatches, `service or unit name "no/such/thing" not valid`)
+	_, err = client.CollectMetrics("wordpress")
+	c.Assert(err, gc.ErrorMatches, `service "wordpress" not found`)
+
+	service := s.AddTestingService(c, "dummy", s.AddTestingCharm(c, "dummy"))
+	_, err = client.CollectMetrics("dummy")
+	c.Assert(err, gc.ErrorMatches, `service "dummy" has no units`)
+	_, err = service.AddUnit()
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = client.CollectMetrics("dummy")
+	c.Assert(err, gc.ErrorMatches, `no units of service "dummy" collect metrics: unit "dummy/0" does not collect metrics: charm "local:quantal/dummy-1" declares none`)
+	_, err = client.CollectMetrics("dummy/0")
+	c.Assert(err, gc.ErrorMatches, `unit "dummy/0" does not collect metrics: charm "local:quantal/dummy-1" declares none`)
+}
+
+func (s *clientSuite) TestBlockCollectMetrics(c *gc.C) {
+	service := s.AddTestingService(c, "metered", s.AddTestingCharm(c, "metered"))
+	unit, err := service.AddUnit()
+	c.Assert(err, jc.ErrorIsNil)
+	s.blockAllChanges(c)
+
+	_, err = s.APIState.Client().CollectMetrics("metered")
+	c.Assert(errors.Cause(err), gc.ErrorMatches, common.ErrOperationBlocked.Error())
+	pending, err := unit.PendingActions()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(pending, gc.HasLen, 0)
+}
+
 var resolveCharmCases = []struct {
 	schema, defaultSeries, charmName string
 	parseErr                         string
@@ -3534,6 +4711,34 @@ func (s *clientSuite) TestRetryProvisioning(c *gc.C) {
 	c.Assert(data["transient"], jc.IsTrue)
 }
 
+func (s *clientSuite) TestRetryProvisioningErrors(c *gc.C) {
+	failed := s.setupRetryProvisioning(c)
+	started, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	err = started.SetStatus(state.StatusStarted, "", nil)
+	c.Assert(err, jc.ErrorIsNil)
+
+	results, err := s.APIState.Client().RetryProvisioning(
+		failed.Tag().(names.MachineTag),
+		started.Tag().(names.MachineTag),
+		names.NewMachineTag("42"),
+	)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(results, gc.HasLen, 3)
+	c.Assert(results[0].Error, gc.IsNil)
+	c.Assert(results[1].Error, gc.ErrorMatches, `machine 1 is not in an error state`)
+	c.Assert(results[2].Error, gc.ErrorMatches, `machine 42 not found`)
+	c.Assert(results[2].Error, jc.Satisfies, params.IsCodeNotFound)
+
+	// Only the machine in an error state is retried.
+	_, _, data, err := failed.Status()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(data["transient"], jc.IsTrue)
+	_, _, data, err = started.Status()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(data, gc.HasLen, 0)
+}
+
 func (s *clientSuite) setupRetryProvisioning(c *gc.C) *state.Machine {
 	machine, err := s.State.AddMachine("quantal", state.JobHostUnits)
 	c.Assert(err, jc.ErrorIsNil)
@@ -3611,6 +4816,15 @@ func (s *clientSuite) TestClientAgentVersion(c *gc.C) {
 	c.Assert(result, gc.Equals, current)
 }
 
+func (s *clientSuite) TestClientEnvironAgentVersion(c *gc.C) {
+	vers := version.MustParse("1.2.3")
+	err := s.State.SetEnvironAgentVersion(vers)
+	c.Assert(err, jc.ErrorIsNil)
+	result, err := s.APIState.Client().EnvironAgentVersion()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(result, gc.Equals, vers)
+}
+
 func (s *clientSuite) TestMachineJobFromParams(c *gc.C) {
 	var tests = []struct {
 		name multiwatcher.MachineJob
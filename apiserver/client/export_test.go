@@ -8,6 +8,10 @@ var (
 	RemoteParamsForMachine  = remoteParamsForMachine
 	GetAllUnitNames         = getAllUnitNames
 	NewStateStorage         = &newStateStorage
+	DestroyUnit             = &destroyUnit
+	LeaderMgr               = &leaderMgr
+
+	UpgradeAvailablePollDelay = &upgradeAvailablePollDelay
 )
 
 var MachineJobFromParams = machineJobFromParams
@@ -0,0 +1,170 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package client
+
+import (
+	"github.com/juju/errors"
+	"github.com/juju/utils/set"
+	"launchpad.net/tomb"
+
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/state/watcher"
+)
+
+// serviceUnitsWatcher is a strings watcher that reports the names of
+// a service's units when they are added, change life, are removed, or
+// otherwise change.
+type serviceUnitsWatcher struct {
+	tomb    tomb.Tomb
+	st      *state.State
+	service *state.Service
+	out     chan []string
+	changed chan string
+	units   map[string]state.NotifyWatcher
+}
+
+func newServiceUnitsWatcher(st *state.State, service *state.Service) state.StringsWatcher {
+	w := &serviceUnitsWatcher{
+		st:      st,
+		service: service,
+		out:     make(chan []string),
+		changed: make(chan string),
+		units:   make(map[string]state.NotifyWatcher),
+	}
+	go func() {
+		defer w.tomb.Done()
+		defer close(w.out)
+		defer w.stopUnitWatchers()
+		w.tomb.Kill(w.loop())
+	}()
+	return w
+}
+
+// Stop stops the watcher, and returns any error encountered while running
+// or shutting down.
+func (w *serviceUnitsWatcher) Stop() error {
+	w.Kill()
+	return w.Wait()
+}
+
+// Kill kills the watcher without waiting for it to shut down.
+func (w *serviceUnitsWatcher) Kill() {
+	w.tomb.Kill(nil)
+}
+
+// Wait waits for the watcher to die and returns any
+// error encountered when it was running.
+func (w *serviceUnitsWatcher) Wait() error {
+	return w.tomb.Wait()
+}
+
+// Err returns any error encountered while running or shutting down, or
+// tomb.ErrStillAlive if the watcher is still running.
+func (w *serviceUnitsWatcher) Err() error {
+	return w.tomb.Err()
+}
+
+// Changes returns the event channel for the serviceUnitsWatcher.
+func (w *serviceUnitsWatcher) Changes() <-chan []string {
+	return w.out
+}
+
+// The service's units are watched for life cycle changes, and each
+// unit that is not Dead is watched for any other change. The initial
+// event holds the names of all the service's units; later events hold
+// the names of the units that have changed since the last one.
+func (w *serviceUnitsWatcher) loop() error {
+	unitsWatcher := w.service.WatchUnits()
+	defer watcher.Stop(unitsWatcher, &w.tomb)
+
+	changes := set.NewStrings()
+	var out chan []string
+	initial := true
+	for {
+		select {
+		case <-w.tomb.Dying():
+			return tomb.ErrDying
+		case names, ok := <-unitsWatcher.Changes():
+			if !ok {
+				return watcher.EnsureErr(unitsWatcher)
+			}
+			for _, name := range names {
+				if err := w.updateUnitWatcher(name); err != nil {
+					return errors.Trace(err)
+				}
+				changes.Add(name)
+			}
+			if initial {
+				// The initial event is sent even when
+				// the service has no units.
+				initial = false
+				out = w.out
+				continue
+			}
+		case name := <-w.changed:
+			if _, ok := w.units[name]; !ok {
+				// The unit has been removed since it changed.
+				continue
+			}
+			changes.Add(name)
+		case out <- changes.SortedValues():
+			changes = set.NewStrings()
+			out = nil
+			continue
+		}
+		if !changes.IsEmpty() {
+			out = w.out
+		}
+	}
+}
+
+// updateUnitWatcher starts watching the named unit if it is not yet
+// watched, or stops watching it if it is Dead or has been removed.
+func (w *serviceUnitsWatcher) updateUnitWatcher(name string) error {
+	unit, err := w.st.Unit(name)
+	if err != nil && !errors.IsNotFound(err) {
+		return errors.Trace(err)
+	}
+	unitWatcher, watched := w.units[name]
+	if err != nil || unit.Life() == state.Dead {
+		if watched {
+			delete(w.units, name)
+			return errors.Trace(unitWatcher.Stop())
+		}
+		return nil
+	}
+	if watched {
+		return nil
+	}
+	unitWatcher = unit.Watch()
+	// Consume the initial event; the unit is already
+	// reported by the service's units watcher.
+	if _, ok := <-unitWatcher.Changes(); !ok {
+		return watcher.EnsureErr(unitWatcher)
+	}
+	w.units[name] = unitWatcher
+	go w.forward(name, unitWatcher)
+	return nil
+}
+
+// forward reports the named unit as changed on each event from its
+// watcher, until the watcher or the serviceUnitsWatcher is stopped.
+func (w *serviceUnitsWatcher) forward(name string, unitWatcher state.NotifyWatcher) {
+	for _ = range unitWatcher.Changes() {
+		select {
+		case <-w.tomb.Dying():
+			return
+		case w.changed <- name:
+		}
+	}
+}
+
+// stopUnitWatchers stops the watchers of all the watched units.
+func (w *serviceUnitsWatcher) stopUnitWatchers() {
+	for name, unitWatcher := range w.units {
+		if err := unitWatcher.Stop(); err != nil {
+			logger.Warningf("cannot stop watcher for unit %q: %v", name, err)
+		}
+	}
+}
@@ -8,10 +8,12 @@ import (
 	"strings"
 
 	"github.com/juju/errors"
+	"github.com/juju/names"
 	"github.com/juju/utils/set"
 	"gopkg.in/juju/charm.v4"
 
 	"github.com/juju/juju/api"
+	"github.com/juju/juju/apiserver/common"
 	"github.com/juju/juju/apiserver/params"
 	"github.com/juju/juju/constraints"
 	"github.com/juju/juju/network"
@@ -121,6 +123,77 @@ func (c *Client) FullStatus(args params.StatusParams) (api.Status, error) {
 	}, nil
 }
 
+// MachinesStatus returns the status of each of the given machines,
+// so that many machines can be queried without a round trip each.
+func (c *Client) MachinesStatus(args params.Entities) (params.MachineStatusResults, error) {
+	result := params.MachineStatusResults{
+		Results: make([]params.MachineStatusResult, len(args.Entities)),
+	}
+	for i, entity := range args.Entities {
+		status, err := c.machineStatus(entity.Tag)
+		if err != nil {
+			status.Error = common.ServerError(err)
+		}
+		result.Results[i] = status
+	}
+	return result, nil
+}
+
+// UnitStatusHistory returns up to args.Size of the statuses most
+// recently set for the named unit and its agent, most recent first.
+func (c *Client) UnitStatusHistory(args params.UnitStatusHistory) (params.UnitStatusHistoryResult, error) {
+	var result params.UnitStatusHistoryResult
+	if !names.IsValidUnit(args.Name) {
+		return result, errors.NotValidf("unit name %q", args.Name)
+	}
+	unit, err := c.api.state.Unit(args.Name)
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+	history, err := unit.StatusHistory(args.Size)
+	if err != nil {
+		return result, errors.Trace(err)
+	}
+	result.Statuses = make([]params.StatusHistoryEntry, len(history))
+	for i, entry := range history {
+		result.Statuses[i] = params.StatusHistoryEntry{
+			Agent:  entry.Agent,
+			Status: params.Status(entry.Status),
+			Info:   entry.Info,
+			Data:   entry.Data,
+			Since:  entry.Since,
+		}
+	}
+	return result, nil
+}
+
+func (c *Client) machineStatus(tag string) (params.MachineStatusResult, error) {
+	var result params.MachineStatusResult
+	machineTag, err := names.ParseMachineTag(tag)
+	if err != nil {
+		return result, err
+	}
+	result.Id = machineTag.Id()
+	machine, err := c.api.state.Machine(machineTag.Id())
+	if err != nil {
+		return result, err
+	}
+	result.Life = params.Life(machine.Life().String())
+	status, info, _, err := machine.Status()
+	if err != nil {
+		return result, err
+	}
+	result.Status = params.Status(status)
+	result.Info = info
+	instId, err := machine.InstanceId()
+	if err != nil && !errors.IsNotProvisioned(err) {
+		return result, err
+	}
+	result.InstanceId = instId
+	result.Addresses = machine.Addresses()
+	return result, nil
+}
+
 // Status is a stub version of FullStatus that was introduced in 1.16
 func (c *Client) Status() (api.LegacyStatus, error) {
 	var legacyStatus api.LegacyStatus
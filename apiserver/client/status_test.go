@@ -8,7 +8,9 @@ import (
 	gc "gopkg.in/check.v1"
 
 	"github.com/juju/juju/apiserver/client"
+	"github.com/juju/juju/apiserver/params"
 	"github.com/juju/juju/instance"
+	"github.com/juju/juju/network"
 	"github.com/juju/juju/state"
 	"github.com/juju/juju/testing/factory"
 )
@@ -45,6 +47,41 @@ func (s *statusSuite) TestFullStatus(c *gc.C) {
 	c.Check(resultMachine.Series, gc.Equals, machine.Series())
 }
 
+func (s *statusSuite) TestMachinesStatus(c *gc.C) {
+	provisioned := s.addMachine(c)
+	err := provisioned.SetProvisioned(instance.Id("i-fakeinstance"), "fakenonce", nil)
+	c.Assert(err, jc.ErrorIsNil)
+	addrs := network.NewAddresses("10.0.0.1")
+	err = provisioned.SetAddresses(addrs...)
+	c.Assert(err, jc.ErrorIsNil)
+	err = provisioned.SetStatus(state.StatusStarted, "running", nil)
+	c.Assert(err, jc.ErrorIsNil)
+	pending := s.addMachine(c)
+
+	client := s.APIState.Client()
+	statuses, err := client.MachinesStatus([]string{provisioned.Id(), pending.Id(), "42"})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(statuses, gc.HasLen, 3)
+
+	c.Check(statuses[provisioned.Id()], jc.DeepEquals, params.MachineStatusResult{
+		Id:         provisioned.Id(),
+		Life:       params.Alive,
+		Status:     params.StatusStarted,
+		Info:       "running",
+		InstanceId: "i-fakeinstance",
+		Addresses:  addrs,
+	})
+	c.Check(statuses[pending.Id()].Error, gc.IsNil)
+	c.Check(statuses[pending.Id()].Status, gc.Equals, params.StatusPending)
+	c.Check(statuses[pending.Id()].InstanceId, gc.Equals, instance.Id(""))
+	c.Check(statuses["42"].Error, jc.Satisfies, params.IsCodeNotFound)
+}
+
+func (s *statusSuite) TestMachinesStatusInvalidId(c *gc.C) {
+	_, err := s.APIState.Client().MachinesStatus([]string{"0", "bad/id"})
+	c.Assert(err, gc.ErrorMatches, `machine id "bad/id" not valid`)
+}
+
 func (s *statusSuite) TestLegacyStatus(c *gc.C) {
 	machine := s.addMachine(c)
 	instanceId := "i-fakeinstance"
@@ -108,3 +145,46 @@ func (s *statusUnitTestSuite) TestProcessMachinesWithEmbeddedContainers(c *gc.C)
 	c.Check(hostContainer, gc.HasLen, 2)
 	c.Check(hostContainer[lxcHost.Id()].Containers, gc.HasLen, 1)
 }
+
+func (s *statusUnitTestSuite) TestUnitStatusHistory(c *gc.C) {
+	unit := s.MakeUnit(c, nil)
+	client := s.APIState.Client()
+	history, err := client.UnitStatusHistory(unit.Name(), 10)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(history, gc.HasLen, 0)
+
+	err = unit.SetStatus(state.StatusBusy, "", nil)
+	c.Assert(err, jc.ErrorIsNil)
+	err = unit.SetAgentStatus(state.StatusError, "hook failed", nil)
+	c.Assert(err, jc.ErrorIsNil)
+	err = unit.SetStatus(state.StatusRunning, "", nil)
+	c.Assert(err, jc.ErrorIsNil)
+
+	// Only as many statuses as were asked for are returned.
+	history, err = client.UnitStatusHistory(unit.Name(), 2)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(history, gc.HasLen, 2)
+	c.Check(history[0].Agent, jc.IsFalse)
+	c.Check(history[0].Status, gc.Equals, params.StatusRunning)
+	c.Check(history[1].Agent, jc.IsTrue)
+	c.Check(history[1].Status, gc.Equals, params.StatusError)
+	c.Check(history[1].Info, gc.Equals, "hook failed")
+
+	// A history shorter than asked for is returned in full.
+	history, err = client.UnitStatusHistory(unit.Name(), 10)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(history, gc.HasLen, 3)
+	c.Check(history[2].Status, gc.Equals, params.StatusBusy)
+}
+
+func (s *statusUnitTestSuite) TestUnitStatusHistoryErrors(c *gc.C) {
+	client := s.APIState.Client()
+	_, err := client.UnitStatusHistory("bad", 10)
+	c.Check(err, gc.ErrorMatches, `unit name "bad" not valid`)
+	_, err = client.UnitStatusHistory("missing/0", 10)
+	c.Check(err, jc.Satisfies, params.IsCodeNotFound)
+
+	unit := s.MakeUnit(c, nil)
+	_, err = client.UnitStatusHistory(unit.Name(), 0)
+	c.Check(err, gc.ErrorMatches, "status history size 0 not valid")
+}
@@ -0,0 +1,186 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package client
+
+import (
+	"time"
+
+	"github.com/juju/errors"
+	"launchpad.net/tomb"
+
+	"github.com/juju/juju/apiserver/common"
+	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/state/watcher"
+	"github.com/juju/juju/version"
+)
+
+// upgradeAvailablePollDelay is the interval at which the tools
+// sources are searched for a newer version.
+var upgradeAvailablePollDelay = 10 * time.Minute
+
+// upgradeAvailableWatcher is a notify watcher that fires when tools
+// newer than the environment's agent version, and with the same
+// major version, become available.
+type upgradeAvailableWatcher struct {
+	tomb   tomb.Tomb
+	st     *state.State
+	finder *common.ToolsFinder
+	out    chan struct{}
+}
+
+func newUpgradeAvailableWatcher(st *state.State, finder *common.ToolsFinder) state.NotifyWatcher {
+	w := &upgradeAvailableWatcher{
+		st:     st,
+		finder: finder,
+		out:    make(chan struct{}),
+	}
+	go func() {
+		defer w.tomb.Done()
+		defer close(w.out)
+		w.tomb.Kill(w.loop())
+	}()
+	return w
+}
+
+// Stop stops the watcher, and returns any error encountered while running
+// or shutting down.
+func (w *upgradeAvailableWatcher) Stop() error {
+	w.Kill()
+	return w.Wait()
+}
+
+// Kill kills the watcher without waiting for it to shut down.
+func (w *upgradeAvailableWatcher) Kill() {
+	w.tomb.Kill(nil)
+}
+
+// Wait waits for the watcher to die and returns any
+// error encountered when it was running.
+func (w *upgradeAvailableWatcher) Wait() error {
+	return w.tomb.Wait()
+}
+
+// Err returns any error encountered while running or shutting down, or
+// tomb.ErrStillAlive if the watcher is still running.
+func (w *upgradeAvailableWatcher) Err() error {
+	return w.tomb.Err()
+}
+
+// Changes returns the event channel for the upgradeAvailableWatcher.
+func (w *upgradeAvailableWatcher) Changes() <-chan struct{} {
+	return w.out
+}
+
+// The tools sources are searched when the watcher starts, whenever the
+// environment settings that determine which tools are found change, and
+// every upgradeAvailablePollDelay. An event is sent whenever a version
+// newer than the agent version, and than any previously found, becomes
+// available. A failed search is logged, and the version found before
+// is kept.
+func (w *upgradeAvailableWatcher) loop() error {
+	configWatcher := w.st.WatchForEnvironConfigChanges()
+	defer watcher.Stop(configWatcher, &w.tomb)
+
+	// The initial event is sent before the tools sources are
+	// searched, so that starting the watcher does not wait for
+	// a search.
+	select {
+	case <-w.tomb.Dying():
+		return tomb.ErrDying
+	case w.out <- struct{}{}:
+	}
+	var (
+		search toolsSearch
+		newest version.Number
+		poll   <-chan time.Time
+		out    chan struct{}
+	)
+	for {
+		select {
+		case <-w.tomb.Dying():
+			return tomb.ErrDying
+		case _, ok := <-configWatcher.Changes():
+			if !ok {
+				return watcher.EnsureErr(configWatcher)
+			}
+			changed, err := w.toolsSearch()
+			if err != nil {
+				logger.Warningf("cannot read environment config: %v", err)
+				continue
+			}
+			if changed == search {
+				// The change does not affect the tools found.
+				continue
+			}
+			search = changed
+		case <-poll:
+		case out <- struct{}{}:
+			out = nil
+			continue
+		}
+		poll = time.After(upgradeAvailablePollDelay)
+		available, err := w.newestAvailable(search)
+		if err != nil {
+			logger.Debugf("cannot find tools newer than %s: %v", search.agentVersion, err)
+			continue
+		}
+		if available.Compare(newest) > 0 {
+			if newest != version.Zero || available.Compare(search.agentVersion) > 0 {
+				out = w.out
+			}
+			newest = available
+		}
+	}
+}
+
+// toolsSearch holds the environment settings that
+// determine which tools a search of the tools sources finds.
+type toolsSearch struct {
+	agentVersion version.Number
+	metadataURL  string
+	stream       string
+}
+
+// toolsSearch returns the environment's current tools search settings.
+func (w *upgradeAvailableWatcher) toolsSearch() (toolsSearch, error) {
+	cfg, err := w.st.EnvironConfig()
+	if err != nil {
+		return toolsSearch{}, errors.Trace(err)
+	}
+	current, ok := cfg.AgentVersion()
+	if !ok {
+		return toolsSearch{}, errors.New("agent version not set in environment config")
+	}
+	metadataURL, _ := cfg.AgentMetadataURL()
+	return toolsSearch{
+		agentVersion: current,
+		metadataURL:  metadataURL,
+		stream:       cfg.AgentStream(),
+	}, nil
+}
+
+// newestAvailable returns the newest version of the tools available
+// with the same major version as the search's agent version, or the
+// agent version itself if there is no newer version. The tools sources
+// may be temporarily unavailable, or hold no tools at all; both are
+// reported as errors.
+func (w *upgradeAvailableWatcher) newestAvailable(search toolsSearch) (version.Number, error) {
+	current := search.agentVersion
+	result, err := w.finder.FindTools(params.FindToolsParams{
+		MajorVersion: current.Major,
+		MinorVersion: -1,
+	})
+	if err != nil {
+		return version.Zero, errors.Trace(err)
+	}
+	if result.Error != nil {
+		return version.Zero, result.Error
+	}
+	newest, _ := result.List.Newest()
+	if newest.Compare(current) < 0 {
+		return current, nil
+	}
+	return newest, nil
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/juju/txn"
 
 	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/leadership"
 	"github.com/juju/juju/state"
 )
 
@@ -82,19 +83,20 @@ var (
 )
 
 var singletonErrorCodes = map[error]string{
-	state.ErrCannotEnterScopeYet: params.CodeCannotEnterScopeYet,
-	state.ErrCannotEnterScope:    params.CodeCannotEnterScope,
-	state.ErrUnitHasSubordinates: params.CodeUnitHasSubordinates,
-	state.ErrDead:                params.CodeDead,
-	txn.ErrExcessiveContention:   params.CodeExcessiveContention,
-	ErrBadId:                     params.CodeNotFound,
-	ErrBadCreds:                  params.CodeUnauthorized,
-	ErrPerm:                      params.CodeUnauthorized,
-	ErrNotLoggedIn:               params.CodeUnauthorized,
-	ErrUnknownWatcher:            params.CodeNotFound,
-	ErrStoppedWatcher:            params.CodeStopped,
-	ErrTryAgain:                  params.CodeTryAgain,
-	ErrActionNotAvailable:        params.CodeActionNotAvailable,
+	state.ErrCannotEnterScopeYet:        params.CodeCannotEnterScopeYet,
+	state.ErrCannotEnterScope:           params.CodeCannotEnterScope,
+	state.ErrUnitHasSubordinates:        params.CodeUnitHasSubordinates,
+	state.ErrDead:                       params.CodeDead,
+	txn.ErrExcessiveContention:          params.CodeExcessiveContention,
+	ErrBadId:                            params.CodeNotFound,
+	ErrBadCreds:                         params.CodeUnauthorized,
+	ErrPerm:                             params.CodeUnauthorized,
+	ErrNotLoggedIn:                      params.CodeUnauthorized,
+	ErrUnknownWatcher:                   params.CodeNotFound,
+	ErrStoppedWatcher:                   params.CodeStopped,
+	ErrTryAgain:                         params.CodeTryAgain,
+	ErrActionNotAvailable:               params.CodeActionNotAvailable,
+	leadership.LeadershipClaimDeniedErr: params.CodeLeadershipClaimDenied,
 }
 
 func singletonCode(err error) (string, bool) {
@@ -14,6 +14,7 @@ import (
 
 	"github.com/juju/juju/apiserver/common"
 	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/leadership"
 	"github.com/juju/juju/state"
 	"github.com/juju/juju/testing"
 )
@@ -112,6 +113,10 @@ var errorTransformTests = []struct {
 	err:        common.ErrOperationBlocked,
 	code:       params.CodeOperationBlocked,
 	helperFunc: params.IsCodeOperationBlocked,
+}, {
+	err:        errors.Wrap(stderrors.New("lease claim denied"), leadership.LeadershipClaimDeniedErr),
+	code:       params.CodeLeadershipClaimDenied,
+	helperFunc: params.IsCodeLeadershipClaimDenied,
 }, {
 	err:  stderrors.New("an error"),
 	code: "",
@@ -15,6 +15,9 @@ type LeadershipService interface {
 	// ReleaseLeadership makes a call to release leadership for all the
 	// parameters passed in.
 	ReleaseLeadership(params params.ReleaseLeadershipBulkParams) (params.ReleaseLeadershipBulkResults, error)
+	// TransferLeadership hands leadership directly from one unit to
+	// another for all the parameters passed in.
+	TransferLeadership(params params.TransferLeadershipBulkParams) (params.TransferLeadershipBulkResults, error)
 	// BlockUntilLeadershipReleased blocks the caller until leadership is
 	// released for the given service.
 	BlockUntilLeadershipReleased(serviceTag names.ServiceTag) (params.ErrorResult, error)
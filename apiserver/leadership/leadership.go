@@ -6,6 +6,7 @@ package leadership
 import (
 	"time"
 
+	"github.com/juju/errors"
 	"github.com/juju/loggo"
 	"github.com/juju/names"
 
@@ -30,6 +31,11 @@ var (
 	// implementation for testing purposes.
 	leaseMgr  = lease.Manager()
 	leaderMgr = leadership.NewLeadershipManager(leaseMgr)
+
+	// now is used to measure how much of a granted lease has
+	// elapsed before the claim result is returned. Exposed as a
+	// variable so it can be replaced for testing purposes.
+	now = time.Now
 )
 
 func init() {
@@ -73,26 +79,37 @@ func NewLeadershipService(
 		state:             state,
 		authorizer:        authorizer,
 		LeadershipManager: leadershipMgr,
+		unitLife:          unitLifeFn(state),
 	}, nil
 }
 
+// unitLifeFn returns a function which reports the life of the
+// unit with the given id in st.
+func unitLifeFn(st *state.State) func(string) (state.Life, error) {
+	return func(unitId string) (state.Life, error) {
+		unit, err := st.Unit(unitId)
+		if err != nil {
+			return state.Dead, err
+		}
+		return unit.Life(), nil
+	}
+}
+
 // LeadershipService implements the LeadershipManager interface and
 // is the concrete implementation of the API endpoint.
 type leadershipService struct {
 	state      *state.State
 	authorizer common.Authorizer
 	leadership.LeadershipManager
+
+	// unitLife reports the life of a unit, to check that it can
+	// take over leadership.
+	unitLife func(unitId string) (state.Life, error)
 }
 
 // ClaimLeadership implements the LeadershipService interface.
 func (m *leadershipService) ClaimLeadership(args params.ClaimLeadershipBulkParams) (params.ClaimLeadershipBulkResults, error) {
 
-	var dur time.Duration
-	claim := callWithIds(func(sid, uid string) (err error) {
-		dur, err = m.LeadershipManager.ClaimLeadership(sid, uid)
-		return err
-	})
-
 	results := make([]params.ClaimLeadershipResults, len(args.Params))
 	for pIdx, p := range args.Params {
 
@@ -109,18 +126,47 @@ func (m *leadershipService) ClaimLeadership(args params.ClaimLeadershipBulkParam
 		if !m.authorizer.AuthUnitAgent() || !m.authorizer.AuthOwner(unitTag) {
 			result.Error = common.ServerError(common.ErrPerm)
 			continue
-		} else if err := claim(svcTag, unitTag).Error; err != nil {
-			result.Error = err
+		}
+
+		claim := func() claimOutcome {
+			return m.claim(svcTag, unitTag)
+		}
+		var outcome claimOutcome
+		if p.IdempotencyToken == "" {
+			outcome = claim()
+		} else {
+			outcome = claimTokens.claimOnce(claimKey{
+				token:   p.IdempotencyToken,
+				service: svcTag.Id(),
+				unit:    unitTag.Id(),
+			}, claim)
+		}
+		if outcome.err != nil {
+			result.Error = outcome.err
 			continue
 		}
 
-		result.ClaimDurationInSec = dur.Seconds()
+		result.ClaimDurationInSec = outcome.duration.Seconds()
+		if remaining := outcome.duration - now().Sub(outcome.claimed); remaining > 0 {
+			result.LeaseRemainingInSec = remaining.Seconds()
+		}
 		result.ServiceTag = p.ServiceTag
 	}
 
 	return params.ClaimLeadershipBulkResults{results}, nil
 }
 
+// claim makes a leadership claim for the unit, recording when
+// it was made so the lease remaining can be reported later.
+func (m *leadershipService) claim(svcTag names.ServiceTag, unitTag names.UnitTag) claimOutcome {
+	claimed := now()
+	dur, err := m.LeadershipManager.ClaimLeadership(svcTag.Id(), unitTag.Id())
+	if err != nil {
+		return claimOutcome{claimed: claimed, err: common.ServerError(err)}
+	}
+	return claimOutcome{claimed: claimed, duration: dur}
+}
+
 // ReleaseLeadership implements the LeadershipService interface.
 func (m *leadershipService) ReleaseLeadership(args params.ReleaseLeadershipBulkParams) (params.ReleaseLeadershipBulkResults, error) {
 
@@ -150,6 +196,62 @@ func (m *leadershipService) ReleaseLeadership(args params.ReleaseLeadershipBulkP
 	return params.ReleaseLeadershipBulkResults{results}, nil
 }
 
+// TransferLeadership implements the LeadershipService interface.
+func (m *leadershipService) TransferLeadership(args params.TransferLeadershipBulkParams) (params.TransferLeadershipBulkResults, error) {
+
+	results := make([]params.ErrorResult, len(args.Params))
+	for paramIdx, p := range args.Params {
+
+		result := &results[paramIdx]
+		svcTag, fromTag, err := parseServiceAndUnitTags(p.ServiceTag, p.FromUnitTag)
+		if err != nil {
+			result.Error = err
+			continue
+		}
+		toTag, parseErr := names.ParseUnitTag(p.ToUnitTag)
+		if parseErr != nil {
+			result.Error = common.ServerError(common.ErrPerm)
+			continue
+		}
+
+		// Only the leader may hand leadership on.
+		if !m.authorizer.AuthUnitAgent() || !m.authorizer.AuthOwner(fromTag) {
+			result.Error = common.ServerError(common.ErrPerm)
+			continue
+		}
+
+		if err := m.checkCanLead(svcTag, toTag); err != nil {
+			result.Error = common.ServerError(err)
+			continue
+		}
+		result.Error = common.ServerError(
+			m.LeadershipManager.TransferLeadership(svcTag.Id(), fromTag.Id(), toTag.Id()),
+		)
+	}
+
+	return params.TransferLeadershipBulkResults{results}, nil
+}
+
+// checkCanLead returns an error if the unit is not eligible
+// to take over leadership of the service.
+func (m *leadershipService) checkCanLead(serviceTag names.ServiceTag, unitTag names.UnitTag) error {
+	serviceName, err := names.UnitService(unitTag.Id())
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if serviceName != serviceTag.Id() {
+		return errors.Errorf("unit %q is not a unit of service %q", unitTag.Id(), serviceTag.Id())
+	}
+	life, err := m.unitLife(unitTag.Id())
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if life != state.Alive {
+		return errors.Errorf("unit %q is not alive", unitTag.Id())
+	}
+	return nil
+}
+
 // BlockUntilLeadershipReleased implements the LeadershipService interface.
 func (m *leadershipService) BlockUntilLeadershipReleased(serviceTag names.ServiceTag) (params.ErrorResult, error) {
 	if !m.authorizer.AuthUnitAgent() {
@@ -12,11 +12,15 @@ network parameters.
 import (
 	"time"
 
+	"github.com/juju/errors"
 	"github.com/juju/names"
+	jc "github.com/juju/testing/checkers"
 	gc "gopkg.in/check.v1"
 
 	"github.com/juju/juju/apiserver/common"
 	"github.com/juju/juju/apiserver/params"
+	"github.com/juju/juju/leadership"
+	"github.com/juju/juju/state"
 )
 
 func init() {
@@ -37,6 +41,7 @@ type stubLeadershipManager struct {
 	ClaimLeadershipFn              func(sid, uid string) (time.Duration, error)
 	ReleaseLeadershipFn            func(sid, uid string) error
 	BlockUntilLeadershipReleasedFn func(serviceId string) error
+	TransferLeadershipFn           func(sid, fromUid, toUid string) error
 }
 
 func (m *stubLeadershipManager) ClaimLeadership(sid, uid string) (time.Duration, error) {
@@ -60,6 +65,13 @@ func (m *stubLeadershipManager) BlockUntilLeadershipReleased(serviceId string) e
 	return nil
 }
 
+func (m *stubLeadershipManager) TransferLeadership(sid, fromUid, toUid string) error {
+	if m.TransferLeadershipFn != nil {
+		return m.TransferLeadershipFn(sid, fromUid, toUid)
+	}
+	return nil
+}
+
 type stubAuthorizer struct {
 	AuthOwnerFn     func(names.Tag) bool
 	AuthUnitAgentFn func() bool
@@ -104,6 +116,36 @@ func (s *leadershipSuite) TestClaimLeadershipTranslation(c *gc.C) {
 	c.Assert(results.Results, gc.HasLen, 1)
 }
 
+func (s *leadershipSuite) TestClaimLeadershipReportsRemainingLease(c *gc.C) {
+	orig := now
+	defer func() { now = orig }()
+	t := time.Now()
+	now = func() time.Time {
+		t = t.Add(time.Second)
+		return t
+	}
+
+	var ldrMgr stubLeadershipManager
+	ldrMgr.ClaimLeadershipFn = func(sid, uid string) (time.Duration, error) {
+		return 30 * time.Second, nil
+	}
+
+	ldrSvc := &leadershipService{LeadershipManager: &ldrMgr, authorizer: &stubAuthorizer{}}
+	results, err := ldrSvc.ClaimLeadership(params.ClaimLeadershipBulkParams{
+		Params: []params.ClaimLeadershipParams{
+			{
+				ServiceTag: names.NewServiceTag(StubServiceNm).String(),
+				UnitTag:    names.NewUnitTag(StubUnitNm).String(),
+			},
+		},
+	})
+
+	c.Assert(err, gc.IsNil)
+	c.Assert(results.Results, gc.HasLen, 1)
+	c.Check(results.Results[0].ClaimDurationInSec, gc.Equals, 30.0)
+	c.Check(results.Results[0].LeaseRemainingInSec, gc.Equals, 29.0)
+}
+
 func (s *leadershipSuite) TestReleaseLeadershipTranslation(c *gc.C) {
 
 	var ldrMgr stubLeadershipManager
@@ -142,6 +184,99 @@ func (s *leadershipSuite) TestBlockUntilLeadershipReleasedTranslation(c *gc.C) {
 	c.Assert(result.Error, gc.IsNil)
 }
 
+func aliveUnits(unitId string) (state.Life, error) {
+	return state.Alive, nil
+}
+
+func transferParams(toUnitNm string) params.TransferLeadershipBulkParams {
+	return params.TransferLeadershipBulkParams{
+		Params: []params.TransferLeadershipParams{
+			{
+				ServiceTag:  names.NewServiceTag(StubServiceNm).String(),
+				FromUnitTag: names.NewUnitTag(StubUnitNm).String(),
+				ToUnitTag:   names.NewUnitTag(toUnitNm).String(),
+			},
+		},
+	}
+}
+
+func (s *leadershipSuite) TestTransferLeadershipTranslation(c *gc.C) {
+
+	numStubCalls := 0
+	var ldrMgr stubLeadershipManager
+	ldrMgr.TransferLeadershipFn = func(sid, fromUid, toUid string) error {
+		numStubCalls++
+		c.Check(sid, gc.Equals, StubServiceNm)
+		c.Check(fromUid, gc.Equals, StubUnitNm)
+		c.Check(toUid, gc.Equals, StubServiceNm+"/1")
+		return nil
+	}
+
+	ldrSvc := &leadershipService{LeadershipManager: &ldrMgr, authorizer: &stubAuthorizer{}, unitLife: aliveUnits}
+	results, err := ldrSvc.TransferLeadership(transferParams(StubServiceNm + "/1"))
+
+	c.Assert(err, gc.IsNil)
+	c.Assert(results.Results, gc.HasLen, 1)
+	c.Check(results.Results[0].Error, gc.IsNil)
+	c.Check(numStubCalls, gc.Equals, 1)
+}
+
+func (s *leadershipSuite) TestTransferLeadershipIneligibleUnit(c *gc.C) {
+
+	var ldrMgr stubLeadershipManager
+	ldrMgr.TransferLeadershipFn = func(sid, fromUid, toUid string) error {
+		c.Errorf("leadership transferred to ineligible unit %q", toUid)
+		return nil
+	}
+	dyingUnits := func(unitId string) (state.Life, error) {
+		return state.Dying, nil
+	}
+	missingUnits := func(unitId string) (state.Life, error) {
+		return state.Dead, errors.NotFoundf("unit %q", unitId)
+	}
+
+	for i, test := range []struct {
+		toUnitNm string
+		unitLife func(string) (state.Life, error)
+		err      string
+	}{{
+		toUnitNm: "other-service/1",
+		unitLife: aliveUnits,
+		err:      `unit "other-service/1" is not a unit of service "stub-service"`,
+	}, {
+		toUnitNm: StubServiceNm + "/1",
+		unitLife: dyingUnits,
+		err:      `unit "stub-service/1" is not alive`,
+	}, {
+		toUnitNm: StubServiceNm + "/1",
+		unitLife: missingUnits,
+		err:      `unit "stub-service/1" not found`,
+	}} {
+		c.Logf("test %d: %s", i, test.toUnitNm)
+		ldrSvc := &leadershipService{LeadershipManager: &ldrMgr, authorizer: &stubAuthorizer{}, unitLife: test.unitLife}
+		results, err := ldrSvc.TransferLeadership(transferParams(test.toUnitNm))
+
+		c.Assert(err, gc.IsNil)
+		c.Assert(results.Results, gc.HasLen, 1)
+		c.Check(results.Results[0].Error, gc.ErrorMatches, test.err)
+	}
+}
+
+func (s *leadershipSuite) TestTransferLeadershipFailOnAuthorizerErrors(c *gc.C) {
+	authorizer := &stubAuthorizer{
+		AuthOwnerFn: func(tag names.Tag) bool {
+			return tag.Id() != StubUnitNm
+		},
+	}
+
+	ldrSvc := &leadershipService{LeadershipManager: nil, authorizer: authorizer, unitLife: aliveUnits}
+	results, err := ldrSvc.TransferLeadership(transferParams(StubServiceNm + "/1"))
+
+	c.Assert(err, gc.IsNil)
+	c.Assert(results.Results, gc.HasLen, 1)
+	c.Check(results.Results[0].Error, gc.ErrorMatches, common.ErrPerm.Error())
+}
+
 func (s *leadershipSuite) TestClaimLeadershipFailOnAuthorizerErrors(c *gc.C) {
 	authorizer := &stubAuthorizer{
 		AuthUnitAgentFn: func() bool { return false },
@@ -197,3 +332,132 @@ func (s *leadershipSuite) TestBlockUntilLeadershipReleasedErrors(c *gc.C) {
 	c.Assert(err, gc.IsNil)
 	c.Check(result.Error, gc.ErrorMatches, common.ErrPerm.Error())
 }
+
+func (s *leadershipSuite) TestClaimLeadershipIdempotencyToken(c *gc.C) {
+	origTokens, origNow := claimTokens, now
+	defer func() { claimTokens, now = origTokens, origNow }()
+	claimTokens = newClaimTokenCache()
+	t := time.Now()
+	now = func() time.Time { return t }
+
+	var claims int
+	var ldrMgr stubLeadershipManager
+	ldrMgr.ClaimLeadershipFn = func(sid, uid string) (time.Duration, error) {
+		claims++
+		return 30 * time.Second, nil
+	}
+	ldrSvc := &leadershipService{LeadershipManager: &ldrMgr, authorizer: &stubAuthorizer{}}
+	claim := func(token string) params.ClaimLeadershipResults {
+		results, err := ldrSvc.ClaimLeadership(params.ClaimLeadershipBulkParams{
+			Params: []params.ClaimLeadershipParams{{
+				ServiceTag:       names.NewServiceTag(StubServiceNm).String(),
+				UnitTag:          names.NewUnitTag(StubUnitNm).String(),
+				IdempotencyToken: token,
+			}},
+		})
+		c.Assert(err, gc.IsNil)
+		c.Assert(results.Results, gc.HasLen, 1)
+		return results.Results[0]
+	}
+
+	result := claim("token-1")
+	c.Check(result.Error, gc.IsNil)
+	c.Check(claims, gc.Equals, 1)
+
+	// A retry with the same token returns the original
+	// result, with the lease remaining since then.
+	t = t.Add(10 * time.Second)
+	result = claim("token-1")
+	c.Check(result.Error, gc.IsNil)
+	c.Check(result.ClaimDurationInSec, gc.Equals, 30.0)
+	c.Check(result.LeaseRemainingInSec, gc.Equals, 20.0)
+	c.Check(claims, gc.Equals, 1)
+
+	// Claims with other tokens, or without
+	// tokens, are always made.
+	claim("token-2")
+	c.Check(claims, gc.Equals, 2)
+	claim("")
+	claim("")
+	c.Check(claims, gc.Equals, 4)
+
+	// Once the original lease has expired, the token is forgotten.
+	t = t.Add(30 * time.Second)
+	claim("token-1")
+	c.Check(claims, gc.Equals, 5)
+}
+
+func (s *leadershipSuite) TestClaimLeadershipIdempotencyTokenDenied(c *gc.C) {
+	origTokens := claimTokens
+	defer func() { claimTokens = origTokens }()
+	claimTokens = newClaimTokenCache()
+
+	var claims int
+	var ldrMgr stubLeadershipManager
+	ldrMgr.ClaimLeadershipFn = func(sid, uid string) (time.Duration, error) {
+		claims++
+		if claims == 1 {
+			return 0, leadership.LeadershipClaimDeniedErr
+		}
+		return 30 * time.Second, nil
+	}
+	ldrSvc := &leadershipService{LeadershipManager: &ldrMgr, authorizer: &stubAuthorizer{}}
+	args := params.ClaimLeadershipBulkParams{
+		Params: []params.ClaimLeadershipParams{{
+			ServiceTag:       names.NewServiceTag(StubServiceNm).String(),
+			UnitTag:          names.NewUnitTag(StubUnitNm).String(),
+			IdempotencyToken: "token",
+		}},
+	}
+
+	// A retried claim that was denied is still denied,
+	// even if leadership has since become available.
+	for i := 0; i < 2; i++ {
+		results, err := ldrSvc.ClaimLeadership(args)
+		c.Assert(err, gc.IsNil)
+		c.Assert(results.Results, gc.HasLen, 1)
+		c.Check(results.Results[0].Error, jc.Satisfies, params.IsCodeLeadershipClaimDenied)
+	}
+	c.Check(claims, gc.Equals, 1)
+}
+
+func (s *leadershipSuite) TestClaimOnceServicesConcurrent(c *gc.C) {
+	cache := newClaimTokenCache()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan claimOutcome)
+	go func() {
+		done <- cache.claimOnce(claimKey{"token", "blocked", "blocked/0"}, func() claimOutcome {
+			close(started)
+			<-release
+			return claimOutcome{duration: time.Minute}
+		})
+	}()
+	<-started
+
+	// A claim for another service is not held up by the blocked one.
+	claimed := make(chan claimOutcome)
+	go func() {
+		claimed <- cache.claimOnce(claimKey{"token", "other", "other/0"}, func() claimOutcome {
+			return claimOutcome{duration: time.Second}
+		})
+	}()
+	select {
+	case outcome := <-claimed:
+		c.Check(outcome.duration, gc.Equals, time.Second)
+	case <-time.After(10 * time.Second):
+		c.Fatalf("claim for other service was blocked")
+	}
+
+	// A retry of the blocked claim waits for its outcome.
+	retried := make(chan claimOutcome)
+	go func() {
+		retried <- cache.claimOnce(claimKey{"token", "blocked", "blocked/0"}, func() claimOutcome {
+			c.Errorf("claim made twice")
+			return claimOutcome{}
+		})
+	}()
+	close(release)
+	c.Check((<-done).duration, gc.Equals, time.Minute)
+	c.Check((<-retried).duration, gc.Equals, time.Minute)
+}
@@ -0,0 +1,126 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package leadership
+
+import (
+	"sync"
+	"time"
+
+	"github.com/juju/juju/apiserver/params"
+)
+
+// deniedClaimTokenTTL is how long the outcome of a claim that did not
+// grant a lease is remembered for its idempotency token.
+const deniedClaimTokenTTL = time.Minute
+
+// claimTokens remembers the outcomes of claims made with idempotency
+// tokens. It is shared by all connections, so that a claim retried
+// over a new connection is still recognised.
+var claimTokens = newClaimTokenCache()
+
+// claimKey identifies a claim made with an idempotency token. The
+// service and unit are included so that tokens chosen by different
+// units cannot collide.
+type claimKey struct {
+	token   string
+	service string
+	unit    string
+}
+
+// claimOutcome records the outcome of a leadership claim.
+type claimOutcome struct {
+	claimed  time.Time
+	duration time.Duration
+	err      *params.Error
+}
+
+// expires returns the time after which a retry
+// of the claim should be treated as a new claim.
+func (o claimOutcome) expires() time.Time {
+	if o.err != nil || o.duration <= 0 {
+		return o.claimed.Add(deniedClaimTokenTTL)
+	}
+	return o.claimed.Add(o.duration)
+}
+
+// claimTokenCache holds the outcomes of claims
+// made with idempotency tokens until they expire.
+type claimTokenCache struct {
+	mu       sync.Mutex
+	outcomes map[claimKey]claimOutcome
+	services map[string]*serviceLock
+}
+
+// serviceLock serialises the claims for a single service. It is
+// removed from its claimTokenCache once it has no more users.
+type serviceLock struct {
+	sync.Mutex
+	users int
+}
+
+func newClaimTokenCache() *claimTokenCache {
+	return &claimTokenCache{
+		outcomes: make(map[claimKey]claimOutcome),
+		services: make(map[string]*serviceLock),
+	}
+}
+
+// claimOnce returns the outcome of the unexpired claim previously made
+// with the given key, if any; otherwise it calls claim and remembers
+// the outcome. Claims for the same service are made one at a time, so
+// that a retry waits for the outcome of the original claim, but claims
+// for different services are made concurrently.
+func (c *claimTokenCache) claimOnce(key claimKey, claim func() claimOutcome) claimOutcome {
+	unlock := c.lockService(key.service)
+	defer unlock()
+	if outcome, ok := c.outcome(key); ok {
+		logger.Debugf("returning original outcome of repeated claim by %q for %q", key.unit, key.service)
+		return outcome
+	}
+	outcome := claim()
+	c.mu.Lock()
+	c.outcomes[key] = outcome
+	c.mu.Unlock()
+	return outcome
+}
+
+// outcome forgets the outcomes of expired claims, and then returns
+// the outcome of the claim made with the given key, if any.
+func (c *claimTokenCache) outcome(key claimKey) (claimOutcome, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	current := now()
+	for k, outcome := range c.outcomes {
+		if !current.Before(outcome.expires()) {
+			delete(c.outcomes, k)
+		}
+	}
+	outcome, ok := c.outcomes[key]
+	return outcome, ok
+}
+
+// lockService blocks until no other claim for the service is being
+// made, and then acquires the service's lock. The returned function
+// must be called to release the lock.
+func (c *claimTokenCache) lockService(service string) (unlock func()) {
+	c.mu.Lock()
+	l, ok := c.services[service]
+	if !ok {
+		l = &serviceLock{}
+		c.services[service] = l
+	}
+	l.users++
+	c.mu.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+		c.mu.Lock()
+		l.users--
+		if l.users == 0 {
+			delete(c.services, service)
+		}
+		c.mu.Unlock()
+	}
+}
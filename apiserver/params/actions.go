@@ -32,6 +32,12 @@ const (
 	ActionRunning string = "running"
 )
 
+// CollectMetricsAction is the name of the action, predefined for the
+// units of every charm that declares metrics, that runs the unit's
+// collect-metrics hook immediately rather than waiting for it to be
+// run periodically.
+const CollectMetricsAction = "juju-collect-metrics"
+
 // Actions is a slice of Action for bulk requests.
 type Actions struct {
 	Actions []Action `json:"actions,omitempty"`
@@ -633,6 +633,7 @@ type VersionResults struct {
 // Tools() API call.
 type ToolsResult struct {
 	Tools                          *tools.Tools
+	ToolsList                      tools.List
 	DisableSSLHostnameVerification bool
 	Error                          *Error
 }
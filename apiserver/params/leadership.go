@@ -21,6 +21,14 @@ type ClaimLeadershipParams struct {
 
 	// UnitTag is the unit which is making the leadership claim.
 	UnitTag string
+
+	// IdempotencyToken optionally identifies the claim, so that a
+	// request retried after a transport failure is not applied
+	// twice: while the original claim's lease lasts, the server
+	// returns the original result for a repeated token instead of
+	// claiming again. If it is empty, every request is a new claim,
+	// as it was before tokens were introduced.
+	IdempotencyToken string `json:",omitempty"`
 }
 
 // ClaimLeadershipBulkResults is the collection of results from a bulk
@@ -43,6 +51,12 @@ type ClaimLeadershipResults struct {
 	// held.
 	ClaimDurationInSec float64
 
+	// LeaseRemainingInSec is the number of seconds left on the lease
+	// when the result was produced, as measured by the server. Being
+	// relative rather than absolute, it is unaffected by any skew
+	// between the client's and server's clocks.
+	LeaseRemainingInSec float64
+
 	// Error is filled in if there was an error fulfilling the claim.
 	Error *Error
 }
@@ -69,6 +83,30 @@ type ReleaseLeadershipParams struct {
 // a bulk leadership call.
 type ReleaseLeadershipBulkResults ErrorResults
 
+// TransferLeadershipBulkParams is a collection of parameters needed
+// to make a bulk transfer leadership call.
+type TransferLeadershipBulkParams struct {
+	Params []TransferLeadershipParams
+}
+
+// TransferLeadershipParams are the parameters needed to hand
+// leadership of a service directly from one unit to another.
+type TransferLeadershipParams struct {
+
+	// ServiceTag is the service whose leadership is transferred.
+	ServiceTag string
+
+	// FromUnitTag is the unit which currently holds leadership.
+	FromUnitTag string
+
+	// ToUnitTag is the unit which will hold leadership.
+	ToUnitTag string
+}
+
+// TransferLeadershipBulkResults is a type which contains results from
+// a bulk transfer leadership call.
+type TransferLeadershipBulkResults ErrorResults
+
 // GetLeadershipSettingsBulkResults is the collection of results from
 // a bulk request for leadership settings.
 type GetLeadershipSettingsBulkResults struct {
@@ -115,6 +115,29 @@ type DestroyRelation struct {
 	Endpoints []string
 }
 
+// GetRelationSettings holds the parameters for making the
+// GetRelationSettings call.
+type GetRelationSettings struct {
+	RelationId int
+	UnitName   string
+}
+
+// WatchRelationUnits holds the parameters for making the
+// WatchRelationUnits call.
+type WatchRelationUnits struct {
+	RelationId int
+	UnitName   string
+}
+
+// SetRelationSettings holds the parameters for making the
+// SetRelationSettings call. Settings with empty values are
+// removed.
+type SetRelationSettings struct {
+	RelationId int
+	UnitName   string
+	Settings   Settings
+}
+
 // AddMachineParams encapsulates the parameters used to create a new machine.
 type AddMachineParams struct {
 	// The following fields hold attributes that will be given to the
@@ -306,12 +329,47 @@ type PrivateAddressResults struct {
 	PrivateAddress string
 }
 
+// SetMeterStatus holds parameters for the SetMeterStatus call.
+type SetMeterStatus struct {
+	Tag  string
+	Code string
+	Info string
+}
+
+// UnitAddressesResult holds results of the UnitAddresses call.
+// Each address is only valid if the corresponding Set field
+// is true.
+type UnitAddressesResult struct {
+	PublicAddress     string
+	PublicAddressSet  bool
+	PrivateAddress    string
+	PrivateAddressSet bool
+}
+
 // Resolved holds parameters for the Resolved call.
 type Resolved struct {
 	UnitName string
 	Retry    bool
 }
 
+// ResolveUnits holds parameters for the ResolveUnits call.
+type ResolveUnits struct {
+	UnitNames []string
+	Retry     bool
+}
+
+// UnitError holds the name of a unit in an error
+// state, and the message describing the error.
+type UnitError struct {
+	UnitName string
+	Message  string
+}
+
+// UnitsInErrorResults holds the results of the UnitsInError call.
+type UnitsInErrorResults struct {
+	Units []UnitError
+}
+
 // ResolvedResults holds results of the Resolved call.
 type ResolvedResults struct {
 	Service  string
@@ -337,6 +395,20 @@ type DestroyServiceUnits struct {
 	UnitNames []string
 }
 
+// ScaleService holds parameters for the ScaleService call.
+type ScaleService struct {
+	ServiceName string
+	NumUnits    int
+}
+
+// ScaleServiceResults holds the names of the service's alive
+// units after the ScaleService call. If destroying a unit failed,
+// Error is set and Units holds the units that are still alive.
+type ScaleServiceResults struct {
+	Units []string
+	Error *Error
+}
+
 // ServiceDestroy holds the parameters for making the ServiceDestroy call.
 type ServiceDestroy struct {
 	ServiceName string
@@ -422,6 +494,12 @@ type AllWatcherNextResults struct {
 	Deltas []multiwatcher.Delta
 }
 
+// AllEnvWatcherNextResults holds deltas returned from calling
+// AllEnvWatcher.Next().
+type AllEnvWatcherNextResults struct {
+	Deltas []multiwatcher.EnvironDelta
+}
+
 // ListSSHKeys stores parameters used for a KeyManager.ListKeys call.
 type ListSSHKeys struct {
 	Entities
@@ -568,6 +646,51 @@ type StatusParams struct {
 	Patterns []string
 }
 
+// MachineStatusResult holds the status of a single machine,
+// as returned by the MachinesStatus call, or an error.
+type MachineStatusResult struct {
+	Error      *Error
+	Id         string
+	Life       Life
+	Status     Status
+	Info       string
+	InstanceId instance.Id
+	Addresses  []network.Address
+}
+
+// MachineStatusResults holds the results of a MachinesStatus call.
+type MachineStatusResults struct {
+	Results []MachineStatusResult
+}
+
+// UnitStatusHistory holds the parameters for a UnitStatusHistory call.
+type UnitStatusHistory struct {
+	// Name holds the name of the unit.
+	Name string
+
+	// Size limits the number of statuses returned.
+	Size int
+}
+
+// StatusHistoryEntry holds a status that a unit or its agent
+// had since some time.
+type StatusHistoryEntry struct {
+	// Agent reports whether the status is that of the
+	// unit's agent, rather than the unit itself.
+	Agent bool
+
+	Status Status
+	Info   string
+	Data   map[string]interface{}
+	Since  time.Time
+}
+
+// UnitStatusHistoryResult holds the result of a UnitStatusHistory
+// call, with the most recent status first.
+type UnitStatusHistoryResult struct {
+	Statuses []StatusHistoryEntry
+}
+
 // SetRsyslogCertParams holds parameters for the SetRsyslogCert call.
 type SetRsyslogCertParams struct {
 	CACert []byte
@@ -729,6 +852,39 @@ type StateServersChanges struct {
 	Demoted    []string `json:"demoted,omitempty"`
 }
 
+// StateServerStatus holds the voting status of a state server
+// machine. A machine that has a vote but no longer wants one is
+// losing its vote; one that wants a vote but has none is gaining one.
+type StateServerStatus struct {
+	MachineId  string `json:"machine-id"`
+	WantsVote  bool   `json:"wants-vote"`
+	HasVote    bool   `json:"has-vote"`
+	AgentAlive bool   `json:"agent-alive"`
+}
+
+// StateServersStatus holds the result of a StateServersStatus call.
+// HasQuorum reports whether the agents of a majority of the voting
+// state servers are alive.
+type StateServersStatus struct {
+	StateServers []StateServerStatus `json:"state-servers"`
+	HasQuorum    bool                `json:"has-quorum"`
+}
+
+// EnvironCredential holds the result of an EnvironCredential call.
+type EnvironCredential struct {
+	// ProviderType is the type of the cloud hosting the environment.
+	ProviderType string `json:"provider-type"`
+
+	// Region is the cloud region hosting the environment. It is
+	// empty if the cloud has no regions.
+	Region string `json:"region,omitempty"`
+
+	// CredentialAttributes holds the names, but not the values, of
+	// the secret attributes the environment uses to authenticate with
+	// the cloud. It is empty if the environment uses no credentials.
+	CredentialAttributes []string `json:"credential-attributes,omitempty"`
+}
+
 // FindToolsParams defines parameters for the FindTools method.
 type FindToolsParams struct {
 	// Number will be used to match tools versions exactly if non-zero.
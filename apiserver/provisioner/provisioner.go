@@ -15,6 +15,7 @@ import (
 	"github.com/juju/juju/constraints"
 	"github.com/juju/juju/container"
 	"github.com/juju/juju/instance"
+	"github.com/juju/juju/network"
 	"github.com/juju/juju/state"
 	"github.com/juju/juju/state/multiwatcher"
 	"github.com/juju/juju/state/watcher"
@@ -204,6 +205,45 @@ func (p *ProvisionerAPI) SetSupportedContainers(args params.MachineContainersPar
 	return result, nil
 }
 
+// SetProviderAddresses records the addresses reported for each
+// given machine by the provider. Machine-observed addresses are
+// left unchanged.
+func (p *ProvisionerAPI) SetProviderAddresses(args params.SetMachinesAddresses) (params.ErrorResults, error) {
+	return p.setAddresses(args, (*state.Machine).SetAddresses)
+}
+
+// SetMachineAddresses records the addresses observed on each given
+// machine. Provider addresses are left unchanged.
+func (p *ProvisionerAPI) SetMachineAddresses(args params.SetMachinesAddresses) (params.ErrorResults, error) {
+	return p.setAddresses(args, (*state.Machine).SetMachineAddresses)
+}
+
+func (p *ProvisionerAPI) setAddresses(
+	args params.SetMachinesAddresses,
+	setAddresses func(*state.Machine, ...network.Address) error,
+) (params.ErrorResults, error) {
+	result := params.ErrorResults{
+		Results: make([]params.ErrorResult, len(args.MachineAddresses)),
+	}
+	canAccess, err := p.getAuthFunc()
+	if err != nil {
+		return result, err
+	}
+	for i, arg := range args.MachineAddresses {
+		tag, err := names.ParseMachineTag(arg.Tag)
+		if err != nil {
+			result.Results[i].Error = common.ServerError(common.ErrPerm)
+			continue
+		}
+		machine, err := p.getMachine(canAccess, tag)
+		if err == nil {
+			err = setAddresses(machine, arg.Addresses...)
+		}
+		result.Results[i].Error = common.ServerError(err)
+	}
+	return result, nil
+}
+
 // ContainerManagerConfig returns information from the environment config that is
 // needed for configuring the container manager.
 func (p *ProvisionerAPI) ContainerManagerConfig(args params.ContainerManagerConfigParams) (params.ContainerManagerConfig, error) {
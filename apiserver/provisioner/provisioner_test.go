@@ -1314,6 +1314,47 @@ func (s *withoutStateServerSuite) TestSetSupportedContainers(c *gc.C) {
 	c.Assert(containers, gc.DeepEquals, []instance.ContainerType{instance.LXC, instance.KVM})
 }
 
+func (s *withoutStateServerSuite) TestSetProviderAddresses(c *gc.C) {
+	addresses := network.NewAddresses("10.0.0.1")
+	args := params.SetMachinesAddresses{
+		MachineAddresses: []params.MachineAddresses{
+			{Tag: s.machines[0].Tag().String(), Addresses: addresses},
+			{Tag: "machine-42"},
+			{Tag: "unit-foo-0"},
+		},
+	}
+	results, err := s.provisioner.SetProviderAddresses(args)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(results, gc.DeepEquals, params.ErrorResults{
+		Results: []params.ErrorResult{
+			{nil},
+			{apiservertesting.NotFoundError("machine 42")},
+			{apiservertesting.ErrUnauthorized},
+		},
+	})
+
+	err = s.machines[0].Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.machines[0].Addresses(), jc.DeepEquals, addresses)
+	c.Assert(s.machines[0].MachineAddresses(), gc.HasLen, 0)
+}
+
+func (s *withoutStateServerSuite) TestSetMachineAddresses(c *gc.C) {
+	addresses := network.NewAddresses("192.168.0.1")
+	args := params.SetMachinesAddresses{
+		MachineAddresses: []params.MachineAddresses{
+			{Tag: s.machines[0].Tag().String(), Addresses: addresses},
+		},
+	}
+	results, err := s.provisioner.SetMachineAddresses(args)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(results.OneError(), jc.ErrorIsNil)
+
+	err = s.machines[0].Refresh()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.machines[0].MachineAddresses(), jc.DeepEquals, addresses)
+}
+
 func (s *withoutStateServerSuite) TestSetSupportedContainersPermissions(c *gc.C) {
 	// Login as a machine agent for machine 0.
 	anAuthorizer := s.authorizer
@@ -89,7 +89,10 @@ func (h *toolsUploadHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			h.sendError(w, http.StatusBadRequest, err.Error())
 			return
 		}
-		h.sendJSON(w, http.StatusOK, &params.ToolsResult{Tools: agentTools})
+		h.sendJSON(w, http.StatusOK, &params.ToolsResult{
+			Tools:     agentTools[0],
+			ToolsList: agentTools,
+		})
 	default:
 		h.sendError(w, http.StatusMethodNotAllowed, fmt.Sprintf("unsupported method: %q", r.Method))
 	}
@@ -215,7 +218,7 @@ func (h *toolsDownloadHandler) sendTools(w http.ResponseWriter, statusCode int,
 }
 
 // processPost handles a tools upload POST request after authentication.
-func (h *toolsUploadHandler) processPost(r *http.Request, st *state.State) (*tools.Tools, error) {
+func (h *toolsUploadHandler) processPost(r *http.Request, st *state.State) (tools.List, error) {
 	query := r.URL.Query()
 
 	binaryVersionParam := query.Get("binaryVersion")
@@ -246,7 +249,7 @@ func (h *toolsUploadHandler) processPost(r *http.Request, st *state.State) (*too
 
 	toolsVersions := []version.Binary{toolsVersion}
 	for _, series := range cloneSeries {
-		if series != toolsVersion.Series {
+		if series != "" && series != toolsVersion.Series {
 			v := toolsVersion
 			v.Series = series
 			toolsVersions = append(toolsVersions, v)
@@ -267,8 +270,9 @@ func (h *toolsUploadHandler) getServerRoot(r *http.Request, query url.Values, st
 	return fmt.Sprintf("https://%s/environment/%s", r.Host, uuid), nil
 }
 
-// handleUpload uploads the tools data from the reader to env storage as the specified version.
-func (h *toolsUploadHandler) handleUpload(r io.Reader, toolsVersions []version.Binary, serverRoot string, st *state.State) (*tools.Tools, error) {
+// handleUpload uploads the tools data from the reader to env storage as the
+// specified versions, returning the tools stored for each version.
+func (h *toolsUploadHandler) handleUpload(r io.Reader, toolsVersions []version.Binary, serverRoot string, st *state.State) (tools.List, error) {
 	// Check if changes are allowed and the command may proceed.
 	blockChecker := common.NewBlockChecker(st)
 	if err := blockChecker.ChangeAllowed(); err != nil {
@@ -292,7 +296,8 @@ func (h *toolsUploadHandler) handleUpload(r io.Reader, toolsVersions []version.B
 	// TODO(wallyworld): check integrity of tools tarball.
 
 	// Store tools and metadata in toolstorage.
-	for _, v := range toolsVersions {
+	uploaded := make(tools.List, len(toolsVersions))
+	for i, v := range toolsVersions {
 		metadata := toolstorage.Metadata{
 			Version: v,
 			Size:    int64(len(data)),
@@ -302,15 +307,14 @@ func (h *toolsUploadHandler) handleUpload(r io.Reader, toolsVersions []version.B
 		if err := storage.AddTools(bytes.NewReader(data), metadata); err != nil {
 			return nil, err
 		}
+		uploaded[i] = &tools.Tools{
+			Version: v,
+			Size:    int64(len(data)),
+			SHA256:  sha256,
+			URL:     common.ToolsURL(serverRoot, v),
+		}
 	}
-
-	tools := &tools.Tools{
-		Version: toolsVersions[0],
-		Size:    int64(len(data)),
-		SHA256:  sha256,
-		URL:     common.ToolsURL(serverRoot, toolsVersions[0]),
-	}
-	return tools, nil
+	return uploaded, nil
 }
 
 func readAndHash(r io.Reader) (data []byte, sha256hex string, err error) {
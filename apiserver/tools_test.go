@@ -241,6 +241,23 @@ func (s *toolsSuite) TestUploadSeriesExpanded(c *gc.C) {
 	c.Assert(err, jc.Satisfies, errors.IsNotFound)
 }
 
+func (s *toolsSuite) TestUploadReportsAllSeries(c *gc.C) {
+	_, vers, toolPath := s.setupToolsForUpload(c)
+	params := "?binaryVersion=" + vers.String() + "&series=precise"
+	resp, err := s.uploadRequest(c, s.toolsURI(c, params), true, toolPath)
+	c.Assert(err, jc.ErrorIsNil)
+	body := assertResponse(c, resp, http.StatusOK, apihttp.CTypeJSON)
+	toolsResult := jsonToolsResponse(c, body)
+	c.Assert(toolsResult.Error, gc.IsNil)
+	c.Assert(toolsResult.ToolsList, gc.HasLen, 2)
+	c.Assert(toolsResult.Tools, gc.DeepEquals, toolsResult.ToolsList[0])
+	c.Assert(toolsResult.ToolsList[0].Version, gc.Equals, vers)
+	precise := vers
+	precise.Series = "precise"
+	c.Assert(toolsResult.ToolsList[1].Version, gc.Equals, precise)
+	c.Assert(toolsResult.ToolsList[1].SHA256, gc.Equals, toolsResult.ToolsList[0].SHA256)
+}
+
 func (s *toolsSuite) TestDownloadEnvUUIDPath(c *gc.C) {
 	tools := s.storeFakeTools(c, s.State, "abc", toolstorage.Metadata{
 		Version: version.Current,
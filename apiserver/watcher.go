@@ -16,6 +16,10 @@ func init() {
 		"AllWatcher", 0, newClientAllWatcher,
 		reflect.TypeOf((*srvClientAllWatcher)(nil)),
 	)
+	common.RegisterFacade(
+		"AllEnvWatcher", 0, newClientAllEnvWatcher,
+		reflect.TypeOf((*srvClientAllEnvWatcher)(nil)),
+	)
 	common.RegisterFacade(
 		"NotifyWatcher", 0, newNotifyWatcher,
 		reflect.TypeOf((*srvNotifyWatcher)(nil)),
@@ -65,6 +69,41 @@ func (w *srvClientAllWatcher) Stop() error {
 	return w.resources.Stop(w.id)
 }
 
+func newClientAllEnvWatcher(st *state.State, resources *common.Resources, auth common.Authorizer, id string) (interface{}, error) {
+	if !auth.AuthClient() {
+		return nil, common.ErrPerm
+	}
+	watcher, ok := resources.Get(id).(*state.AllEnvWatcher)
+	if !ok {
+		return nil, common.ErrUnknownWatcher
+	}
+	return &srvClientAllEnvWatcher{
+		watcher:   watcher,
+		id:        id,
+		resources: resources,
+	}, nil
+}
+
+// srvClientAllEnvWatcher defines the API methods on a
+// state.AllEnvWatcher, which watches any changes to all the
+// environments managed by the state server.
+type srvClientAllEnvWatcher struct {
+	watcher   *state.AllEnvWatcher
+	id        string
+	resources *common.Resources
+}
+
+func (aw *srvClientAllEnvWatcher) Next() (params.AllEnvWatcherNextResults, error) {
+	deltas, err := aw.watcher.Next()
+	return params.AllEnvWatcherNextResults{
+		Deltas: deltas,
+	}, err
+}
+
+func (w *srvClientAllEnvWatcher) Stop() error {
+	return w.resources.Stop(w.id)
+}
+
 // srvNotifyWatcher defines the API access to methods on a state.NotifyWatcher.
 // Each client has its own current set of watchers, stored in resources.
 type srvNotifyWatcher struct {
@@ -77,8 +116,16 @@ func isAgent(auth common.Authorizer) bool {
 	return auth.AuthMachineAgent() || auth.AuthUnitAgent()
 }
 
+// isAgentOrClient reports whether the watcher may be used by the
+// authenticated entity. Clients may watch too, through the watchers
+// returned by the Client facade; each connection can only reach the
+// watchers registered in its own resources.
+func isAgentOrClient(auth common.Authorizer) bool {
+	return isAgent(auth) || auth.AuthClient()
+}
+
 func newNotifyWatcher(st *state.State, resources *common.Resources, auth common.Authorizer, id string) (interface{}, error) {
-	if !isAgent(auth) {
+	if !isAgentOrClient(auth) {
 		return nil, common.ErrPerm
 	}
 	watcher, ok := resources.Get(id).(state.NotifyWatcher)
@@ -122,7 +169,7 @@ type srvStringsWatcher struct {
 }
 
 func newStringsWatcher(st *state.State, resources *common.Resources, auth common.Authorizer, id string) (interface{}, error) {
-	if !isAgent(auth) {
+	if !isAgentOrClient(auth) {
 		return nil, common.ErrPerm
 	}
 	watcher, ok := resources.Get(id).(state.StringsWatcher)
@@ -167,7 +214,7 @@ type srvRelationUnitsWatcher struct {
 }
 
 func newRelationUnitsWatcher(st *state.State, resources *common.Resources, auth common.Authorizer, id string) (interface{}, error) {
-	if !isAgent(auth) {
+	if !isAgentOrClient(auth) {
 		return nil, common.ErrPerm
 	}
 	watcher, ok := resources.Get(id).(state.RelationUnitsWatcher)
@@ -11,6 +11,7 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/juju/errors"
 	"github.com/juju/utils"
@@ -74,6 +75,42 @@ func (f *fileStorageReader) Get(name string) (io.ReadCloser, error) {
 	return file, nil
 }
 
+// Size returns the size of the named file, in bytes.
+func (f *fileStorageReader) Size(name string) (int64, error) {
+	fi, err := f.stat(name)
+	if err != nil {
+		return 0, err
+	}
+	return fi.Size(), nil
+}
+
+// ModTime returns the time the named file was last modified.
+func (f *fileStorageReader) ModTime(name string) (time.Time, error) {
+	fi, err := f.stat(name)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return fi.ModTime(), nil
+}
+
+// stat returns information about the named file, or
+// a not found error if there is no such file.
+func (f *fileStorageReader) stat(name string) (os.FileInfo, error) {
+	if isInternalPath(name) {
+		return nil, errors.NotFoundf("no such file with name %q", name)
+	}
+	fi, err := os.Stat(f.fullPath(name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = errors.NewNotFound(err, "")
+		}
+		return nil, err
+	} else if fi.IsDir() {
+		return nil, errors.NotFoundf("no such file with name %q", name)
+	}
+	return fi, nil
+}
+
 // isInternalPath returns true if a path should be hidden from user visibility
 // filestorage uses ".tmp/" as a staging directory for uploads, so we don't
 // want it to be visible
@@ -150,14 +187,23 @@ func (f *fileStorageWriter) Put(name string, r io.Reader, length int64) error {
 		return err
 	}
 	tmpdir := filepath.Join(f.path, ".tmp")
-	if err := os.MkdirAll(tmpdir, 0755); err != nil {
-		return err
-	}
 	defer os.Remove(tmpdir)
 	// Write to a temporary file first, and then move (atomically).
-	file, err := ioutil.TempFile(tmpdir, "juju-filestorage-")
-	if err != nil {
-		return err
+	var file *os.File
+	var err error
+	for {
+		if err := os.MkdirAll(tmpdir, 0755); err != nil {
+			return err
+		}
+		file, err = ioutil.TempFile(tmpdir, "juju-filestorage-")
+		if err == nil {
+			break
+		}
+		// A concurrent Put may have removed the staging directory
+		// between its creation and ours; if so, create it again.
+		if !os.IsNotExist(err) {
+			return err
+		}
 	}
 	_, err = io.CopyN(file, r, length)
 	file.Close()
@@ -168,6 +214,51 @@ func (f *fileStorageWriter) Put(name string, r io.Reader, length int64) error {
 	return utils.ReplaceFile(file.Name(), fullpath)
 }
 
+// Append adds length bytes read from r to the end of the named
+// file, creating it if necessary. Unlike Put, it is not atomic:
+// if reading fails, the bytes read so far remain appended.
+func (f *fileStorageWriter) Append(name string, r io.Reader, length int64) error {
+	if isInternalPath(name) {
+		return &os.PathError{
+			Op:   "Append",
+			Path: name,
+			Err:  os.ErrPermission,
+		}
+	}
+	fullpath := f.fullPath(name)
+	if err := os.MkdirAll(filepath.Dir(fullpath), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(fullpath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = io.CopyN(file, r, length)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Move moves the named file to the name to, atomically replacing
+// any file already stored under that name.
+func (f *fileStorageWriter) Move(from, to string) error {
+	for _, name := range []string{from, to} {
+		if isInternalPath(name) {
+			return &os.PathError{
+				Op:   "Move",
+				Path: name,
+				Err:  os.ErrPermission,
+			}
+		}
+	}
+	fullpath := f.fullPath(to)
+	if err := os.MkdirAll(filepath.Dir(fullpath), 0755); err != nil {
+		return err
+	}
+	return utils.ReplaceFile(f.fullPath(from), fullpath)
+}
+
 func (f *fileStorageWriter) Remove(name string) error {
 	fullpath := f.fullPath(name)
 	err := os.Remove(fullpath)
@@ -180,3 +271,7 @@ func (f *fileStorageWriter) Remove(name string) error {
 func (f *fileStorageWriter) RemoveAll() error {
 	return storage.RemoveAll(f)
 }
+
+func (f *fileStorageWriter) RemovePrefix(prefix string) (int, error) {
+	return storage.RemovePrefix(f, prefix)
+}
@@ -9,11 +9,13 @@ package filestorage_test
 
 import (
 	"bytes"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/juju/errors"
 	jc "github.com/juju/testing/checkers"
@@ -145,6 +147,46 @@ func (s *filestorageSuite) TestGet(c *gc.C) {
 	c.Assert(err, jc.Satisfies, errors.IsNotFound)
 }
 
+func (s *filestorageSuite) TestSize(c *gc.C) {
+	_, data := s.createFile(c, "test-file")
+	sizer, ok := s.reader.(interface {
+		Size(string) (int64, error)
+	})
+	c.Assert(ok, jc.IsTrue)
+	size, err := sizer.Size("test-file")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(size, gc.Equals, int64(len(data)))
+
+	_, err = sizer.Size("nowhere")
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+	s.createFile(c, "dir/file")
+	_, err = sizer.Size("dir")
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+	s.createFile(c, ".tmp/test-file")
+	_, err = sizer.Size(".tmp/test-file")
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *filestorageSuite) TestModTime(c *gc.C) {
+	path, _ := s.createFile(c, "test-file")
+	modTime := time.Date(2015, 4, 1, 12, 0, 0, 0, time.UTC)
+	err := os.Chtimes(path, modTime, modTime)
+	c.Assert(err, jc.ErrorIsNil)
+	stat, ok := s.reader.(interface {
+		ModTime(string) (time.Time, error)
+	})
+	c.Assert(ok, jc.IsTrue)
+	t, err := stat.ModTime("test-file")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(t.Equal(modTime), jc.IsTrue)
+
+	_, err = stat.ModTime("nowhere")
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+	s.createFile(c, "dir/file")
+	_, err = stat.ModTime("dir")
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
 func (s *filestorageSuite) TestGetRefusesTemp(c *gc.C) {
 	s.createFile(c, ".tmp/test-file")
 	_, err := storage.Get(s.reader, ".tmp/test-file")
@@ -165,6 +207,43 @@ func (s *filestorageSuite) TestPut(c *gc.C) {
 	c.Assert(b, gc.DeepEquals, data)
 }
 
+func (s *filestorageSuite) TestAppend(c *gc.C) {
+	appender, ok := s.writer.(interface {
+		Append(string, io.Reader, int64) error
+	})
+	c.Assert(ok, jc.IsTrue)
+	err := appender.Append("dir/test-append", bytes.NewReader([]byte{1, 2, 3}), 3)
+	c.Assert(err, jc.ErrorIsNil)
+	err = appender.Append("dir/test-append", bytes.NewReader([]byte{4, 5, 6}), 2)
+	c.Assert(err, jc.ErrorIsNil)
+	b, err := ioutil.ReadFile(filepath.Join(s.dir, "dir", "test-append"))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(b, gc.DeepEquals, []byte{1, 2, 3, 4, 5})
+
+	err = appender.Append(".tmp/test-append", bytes.NewReader(nil), 0)
+	c.Check(err, jc.Satisfies, os.IsPermission)
+}
+
+func (s *filestorageSuite) TestMove(c *gc.C) {
+	mover, ok := s.writer.(interface {
+		Move(string, string) error
+	})
+	c.Assert(ok, jc.IsTrue)
+	_, data := s.createFile(c, "test-move")
+	err := mover.Move("test-move", "dir/test-moved")
+	c.Assert(err, jc.ErrorIsNil)
+	b, err := ioutil.ReadFile(filepath.Join(s.dir, "dir", "test-moved"))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(b, gc.DeepEquals, data)
+	_, err = os.Stat(filepath.Join(s.dir, "test-move"))
+	c.Assert(err, jc.Satisfies, os.IsNotExist)
+
+	err = mover.Move("missing", "test-moved")
+	c.Check(err, jc.Satisfies, os.IsNotExist)
+	err = mover.Move("dir/test-moved", ".tmp/test-moved")
+	c.Check(err, jc.Satisfies, os.IsPermission)
+}
+
 func (s *filestorageSuite) TestPutRefusesTmp(c *gc.C) {
 	data := []byte{1, 2, 3, 4, 5}
 	err := s.writer.Put(".tmp/test-write", bytes.NewReader(data), int64(len(data)))
@@ -4,24 +4,149 @@
 package httpstorage
 
 import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
-	"errors"
+	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"math"
+	"mime"
 	"net"
 	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/juju/errors"
+
 	"github.com/juju/juju/cert"
 	"github.com/juju/juju/environs/storage"
 )
 
-// storageBackend provides HTTP access to a storage object.
+// StatStorage is implemented by storage backends that
+// can cheaply report the size of a stored object. The
+// size is sent as the Content-Length of GET responses
+// streamed from the storage.
+type StatStorage interface {
+	// Size returns the size of the named object, in bytes.
+	Size(name string) (int64, error)
+}
+
+// AppendStorage is implemented by storage backends that
+// can add to the end of a stored object.
+type AppendStorage interface {
+	// Append reads length bytes from r and adds them to the end
+	// of the named object, creating it if it does not exist.
+	Append(name string, r io.Reader, length int64) error
+}
+
+// ModTimeStorage is implemented by storage backends that
+// can report when a stored object was last modified.
+type ModTimeStorage interface {
+	// ModTime returns the time the named object was last modified.
+	ModTime(name string) (time.Time, error)
+}
+
+// StreamStorage is implemented by storage backends that
+// can store an object without knowing its length in advance.
+type StreamStorage interface {
+	// PutStream reads from r until EOF and stores the data read
+	// as the named object. If reading from r fails, the object
+	// must be left as it was.
+	PutStream(name string, r io.Reader) error
+}
+
+// HashStorage is implemented by storage backends that can cheaply
+// report the hash of a stored object's contents, without it being read
+// by the server. The hash is used as the object's entity tag, sent in
+// the ETag header of GET and HEAD responses. For storages that do not
+// implement it, a weak tag is derived from the object's size and
+// modification time if they implement StatStorage and ModTimeStorage;
+// otherwise objects streamed from them are sent without one. A weak
+// tag never satisfies an If-Match condition.
+type HashStorage interface {
+	// Hash returns the hex-encoded SHA-256 hash
+	// of the contents of the named object.
+	Hash(name string) (string, error)
+}
+
+// MoveStorage is implemented by storage backends that
+// can move a stored object to another name.
+type MoveStorage interface {
+	// Move moves the object named from to the name to, atomically
+	// replacing any object already stored under that name.
+	Move(from, to string) error
+}
+
+// AppendHeader may be set to "true" on a PUT request to append
+// the body to the object, rather than replacing the object. If
+// the storage does not implement AppendStorage, the request is
+// rejected with 501 Not Implemented.
+const AppendHeader = "X-Juju-Append"
+
+// The following headers control a MOVE request, which moves
+// the addressed object to another name. If the storage does not
+// implement MoveStorage, the request is rejected with 501 Not
+// Implemented.
+const (
+	// DestinationHeader holds the name to which the object is moved.
+	DestinationHeader = "X-Juju-Destination"
+
+	// OverwriteHeader may be set to "true" to replace any object
+	// already stored under the destination name. Otherwise, the
+	// request fails with 412 Precondition Failed if there is one.
+	OverwriteHeader = "X-Juju-Overwrite"
+)
+
+// FallbackHeader is set on the response to a GET request for an object
+// that could not be read, and for which a default object was served
+// instead. It holds the name of the default object.
+const FallbackHeader = "X-Juju-Fallback"
+
+// DownloadParam may be set to "1" or "true" in the query of a GET
+// request to have the object served as an attachment, named after
+// the last element of its name, rather than displayed inline.
+const DownloadParam = "download"
+
+// The following headers report the objects matching
+// a prefix, in response to a HEAD request for the
+// prefix followed by '*'.
+const (
+	// ObjectCountHeader holds the number of matching objects.
+	ObjectCountHeader = "X-Juju-Object-Count"
+
+	// ObjectBytesHeader holds the total size of the matching
+	// objects, in bytes. It is only set if the storage
+	// implements StatStorage.
+	ObjectBytesHeader = "X-Juju-Object-Bytes"
+)
+
+// storageBackend provides HTTP access to a storage object. Requests
+// are served concurrently, so any state shared between requests, such
+// as the cache and the object locks, must be safe for concurrent use.
 type storageBackend struct {
 	backend storage.Storage
 
+	// mirrors holds storages from which objects are read,
+	// in order, when they cannot be read from backend.
+	mirrors []storage.Storage
+
+	// defaults maps object name prefixes to the names of the
+	// objects served when objects with those prefixes cannot
+	// be read.
+	defaults map[string]string
+
+	// prefix is the path prefix, without the leading '/',
+	// under which the storage is served.
+	prefix string
+
 	// httpsPort is the port to send to clients
 	// if they perform a HEAD request.
 	httpsPort int
@@ -29,37 +154,123 @@ type storageBackend struct {
 	// authkey is non-empty if modifying requests
 	// require an auth key.
 	authkey string
+
+	// opts holds the server's optional configuration.
+	opts ServeOpts
+
+	// locks serialises modifications to each object. It is
+	// shared by all backends serving the same storage.
+	locks *keyLocker
+
+	// cache holds recently read objects, if caching is enabled.
+	// It is shared by all backends serving the same storage.
+	cache *objectCache
+
+	// limiter limits the rate of requests from each client, if
+	// rate limiting is enabled. It is shared by all the backends
+	// of a server.
+	limiter *rateLimiter
 }
 
-// ServeHTTP handles the HTTP requests to the container.
+// ServeHTTP handles the HTTP requests to the container,
+// recording them if a metrics collector is configured.
 func (s *storageBackend) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if s.opts.Metrics == nil {
+		s.handle(w, req)
+		return
+	}
+	start := time.Now()
+	rec := &statusRecorder{ResponseWriter: w}
+	s.handle(rec, req)
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	s.opts.Metrics.ObserveRequest(requestKind(req), rec.status, time.Since(start))
+}
+
+// handle handles a request to the container.
+func (s *storageBackend) handle(w http.ResponseWriter, req *http.Request) {
+	if ok, wait := s.limiter.allow(clientIP(req)); !ok {
+		seconds := int64(math.Ceil(wait.Seconds()))
+		if seconds < 1 {
+			seconds = 1
+		}
+		w.Header().Set("Retry-After", fmt.Sprint(seconds))
+		http.Error(w, "too many requests", tooManyRequests)
+		return
+	}
 	switch req.Method {
-	case "PUT", "DELETE":
+	case "PUT", "DELETE", "MOVE":
 		// Don't allow modifying operations if there's an HTTPS backend
 		// to handle that, and ensure the user is authorized/authenticated.
 		if s.httpsPort != 0 || !s.authorized(req) {
 			http.Error(w, "unauthorized access", http.StatusUnauthorized)
 			return
 		}
+		if isPrefixRequest(req) {
+			// The objects matching the prefix are
+			// locked as they are removed.
+			break
+		}
+		// Order modifications of the same object, so that
+		// concurrent PUTs and DELETEs cannot interleave. A
+		// MOVE modifies its destination too; the locks are
+		// taken in name order, so that concurrent MOVEs
+		// cannot deadlock.
+		names := []string{s.objectName(req)}
+		if req.Method == "MOVE" {
+			if dest := req.Header.Get(DestinationHeader); dest != "" && dest != names[0] {
+				names = append(names, dest)
+				sort.Strings(names)
+			}
+		}
+		for _, name := range names {
+			unlock := s.locks.lock(name)
+			defer unlock()
+			defer s.cache.invalidate(name)
+		}
 	}
 	switch req.Method {
 	case "GET":
-		if strings.HasSuffix(req.URL.Path, "*") {
+		if isPrefixRequest(req) {
 			s.handleList(w, req)
+		} else if s.opts.GzipResponses && acceptsGzip(req) {
+			zw := newGzipResponseWriter(w)
+			s.handleGet(zw, req)
+			if err := zw.Close(); err != nil {
+				logger.Warningf("cannot finish gzip-encoded response: %v", err)
+			}
 		} else {
 			s.handleGet(w, req)
 		}
 	case "HEAD":
-		s.handleHead(w, req)
+		if isPrefixRequest(req) {
+			s.handleHeadList(w, req)
+		} else {
+			s.handleHead(w, req)
+		}
 	case "PUT":
 		s.handlePut(w, req)
 	case "DELETE":
-		s.handleDelete(w, req)
+		if isPrefixRequest(req) {
+			s.handleDeletePrefix(w, req)
+		} else {
+			s.handleDelete(w, req)
+		}
+	case "MOVE":
+		s.handleMove(w, req)
 	default:
 		http.Error(w, "method "+req.Method+" is not supported", http.StatusMethodNotAllowed)
 	}
 }
 
+// isPrefixRequest reports whether the request addresses the objects
+// whose names begin with a prefix, rather than a single object, by
+// ending its path with '*'.
+func isPrefixRequest(req *http.Request) bool {
+	return strings.HasSuffix(req.URL.Path, "*")
+}
+
 // authorized checks that either the storage does not require
 // authorization, or the user has specified the correct auth key.
 func (s *storageBackend) authorized(req *http.Request) bool {
@@ -69,6 +280,12 @@ func (s *storageBackend) authorized(req *http.Request) bool {
 	return req.URL.Query().Get("authkey") == s.authkey
 }
 
+// objectName returns the name of the storage object
+// addressed by the request's path.
+func (s *storageBackend) objectName(req *http.Request) string {
+	return strings.TrimPrefix(req.URL.Path[1:], s.prefix)
+}
+
 // hostOnly splits a host of the form host, or host:port,
 // into its host and port parts, and returns the host part.
 func hostOnly(host string) (string, error) {
@@ -88,7 +305,8 @@ func hostOnly(host string) (string, error) {
 }
 
 // handleHead returns the HTTPS URL for the specified
-// path in the Location header.
+// path in the Location header, and the entity tag of
+// the addressed object, if any, in the ETag header.
 func (s *storageBackend) handleHead(w http.ResponseWriter, req *http.Request) {
 	if s.httpsPort != 0 {
 		host, err := hostOnly(req.Host)
@@ -96,6 +314,11 @@ func (s *storageBackend) handleHead(w http.ResponseWriter, req *http.Request) {
 			http.Error(w, fmt.Sprintf("failed to split host: %v", err), http.StatusBadRequest)
 			return
 		}
+		if name := s.objectName(req); name != "" {
+			if etag, ok := s.objectETag(name); ok && notModified(w, req, etag) {
+				return
+			}
+		}
 		url := fmt.Sprintf("https://%s:%d%s", host, s.httpsPort, req.URL.Path)
 		w.Header().Set("Location", url)
 	} else {
@@ -105,44 +328,544 @@ func (s *storageBackend) handleHead(w http.ResponseWriter, req *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
-// handleGet returns a storage file to the client.
+// handleHeadList reports the number of objects matching the
+// requested prefix and, if the storage can report it, their
+// total size, without transferring their names.
+func (s *storageBackend) handleHeadList(w http.ResponseWriter, req *http.Request) {
+	names, err := s.list(req)
+	if err != nil {
+		http.Error(w, fmt.Sprint(err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set(ObjectCountHeader, fmt.Sprint(len(names)))
+	if stat, ok := s.backend.(StatStorage); ok {
+		var total int64
+		for _, name := range names {
+			size, err := stat.Size(name)
+			if err != nil {
+				http.Error(w, fmt.Sprint(err), http.StatusInternalServerError)
+				return
+			}
+			total += size
+		}
+		w.Header().Set(ObjectBytesHeader, fmt.Sprint(total))
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleGet returns a storage file to the client. If the file
+// cannot be read from the storage, each mirror is tried in turn.
+// Files read from the storage are streamed to the client unless
+// they may be cached; see serveStream.
 func (s *storageBackend) handleGet(w http.ResponseWriter, req *http.Request) {
-	readcloser, err := s.backend.Get(req.URL.Path[1:])
+	name := s.objectName(req)
+	download, err := downloadRequested(req)
 	if err != nil {
-		http.Error(w, fmt.Sprint(err), http.StatusNotFound)
+		http.Error(w, fmt.Sprint(err), http.StatusBadRequest)
 		return
 	}
-	defer readcloser.Close()
-	data, err := ioutil.ReadAll(readcloser)
+	if s.serveStream(w, req, name, download) {
+		return
+	}
+	var etag string
+	data, err := s.getObject(name)
 	if err != nil {
-		http.Error(w, fmt.Sprint(err), http.StatusInternalServerError)
+		defaultName, ok := s.defaultObject(name)
+		if !ok {
+			http.Error(w, fmt.Sprint(err), statusOf(err))
+			return
+		}
+		logger.Debugf("cannot read %q, serving default %q: %v", name, defaultName, err)
+		defaultData, defaultErr := s.getObject(defaultName)
+		if defaultErr != nil {
+			// Report the requested object's error.
+			logger.Warningf("cannot read default object %q: %v", defaultName, defaultErr)
+			http.Error(w, fmt.Sprint(err), statusOf(err))
+			return
+		}
+		data = defaultData
+		w.Header().Set(FallbackHeader, defaultName)
+	} else {
+		etag, _ = s.objectETag(name)
+	}
+	if etag == "" {
+		// The object was read from a mirror, or is a default,
+		// or the storage cannot tag it; tag its contents.
+		etag = dataETag(data)
+	}
+	if notModified(w, req, etag) {
 		return
 	}
-	w.Header().Set("Content-Type", "application/octet-stream")
-	w.Write(data)
+	if download {
+		setDisposition(w, name)
+	}
+	w.Header().Set("Content-Type", s.contentType(name))
+	serveObject(w, req, data)
+}
+
+// serveStream serves the named object straight from the storage,
+// copying it to the client as it is read rather than holding it in
+// memory. If the storage implements StatStorage, the length of the
+// object is sent before its contents. If the request has a Range
+// header specifying a single byte range, and the reader returned by
+// the storage can seek, only the bytes in the range are read.
+//
+// If the size of the object is not known and objects are cached, as
+// much of the object as may be cached is read first. If that is all of
+// it, the object is cached and served from memory like any other.
+//
+// It returns false, having written nothing, if the object is to be
+// served from memory instead: if it is cached or may be cached, if it
+// cannot be read from the storage, so that mirrors and defaults are
+// tried, or if a range is requested and the reader cannot seek.
+func (s *storageBackend) serveStream(w http.ResponseWriter, req *http.Request, name string, download bool) bool {
+	if _, ok := s.cache.get(name); ok {
+		return false
+	}
+	size := int64(-1)
+	if stat, ok := s.backend.(StatStorage); ok {
+		if n, err := stat.Size(name); err == nil {
+			size = n
+		}
+	}
+	if s.cache.accepts(size) {
+		return false
+	}
+	gen := s.cache.generation()
+	r, err := s.backend.Get(name)
+	if err != nil {
+		return false
+	}
+	defer r.Close()
+	var body io.Reader = r
+	if size < 0 && s.cache != nil {
+		// The object may yet be small enough to cache, so read
+		// no more of it than may be cached before deciding.
+		head, err := ioutil.ReadAll(io.LimitReader(r, s.cache.maxObjectBytes+1))
+		if err != nil {
+			return false
+		}
+		if s.cache.accepts(int64(len(head))) {
+			s.cache.add(name, head, gen)
+			return false
+		}
+		body = io.MultiReader(bytes.NewReader(head), r)
+	}
+	if etag, ok := s.objectETag(name); ok && notModified(w, req, etag) {
+		return true
+	}
+
+	status := http.StatusOK
+	if rangeHeader := req.Header.Get("Range"); rangeHeader != "" {
+		seeker, ok := r.(io.ReadSeeker)
+		if !ok {
+			return false
+		}
+		if size, err = seeker.Seek(0, os.SEEK_END); err != nil {
+			return false
+		}
+		start, end, ok, err := parseRange(rangeHeader, size)
+		if err != nil {
+			w.Header().Set("Accept-Ranges", "bytes")
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+			http.Error(w, err.Error(), http.StatusRequestedRangeNotSatisfiable)
+			return true
+		}
+		if !ok {
+			start, end = 0, size
+		} else {
+			status = http.StatusPartialContent
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end-1, size))
+		}
+		if _, err := seeker.Seek(start, os.SEEK_SET); err != nil {
+			return false
+		}
+		body = io.LimitReader(seeker, end-start)
+		size = end - start
+	}
+	if download {
+		setDisposition(w, name)
+	}
+	w.Header().Set("Content-Type", s.contentType(name))
+	w.Header().Set("Accept-Ranges", "bytes")
+	if size >= 0 {
+		w.Header().Set("Content-Length", fmt.Sprint(size))
+	}
+	w.WriteHeader(status)
+	if _, err := io.Copy(w, body); err != nil {
+		// The response has been started, so the error can
+		// only be reported by cutting it short.
+		logger.Warningf("cannot serve %q: %v", name, err)
+	}
+	return true
+}
+
+// setDisposition has the named object served
+// as an attachment named after its last element.
+func setDisposition(w http.ResponseWriter, name string) {
+	disposition := mime.FormatMediaType("attachment", map[string]string{
+		"filename": path.Base(name),
+	})
+	w.Header().Set("Content-Disposition", disposition)
+}
+
+// contentTypes maps the suffixes of object names to the
+// content types with which the objects are served. Longer
+// suffixes come first, so that they take precedence.
+var contentTypes = []struct {
+	suffix      string
+	contentType string
+}{
+	{".tar.gz", "application/x-compressed-tar"},
+	{".tgz", "application/x-compressed-tar"},
+	{".json", "application/json"},
+	{".yaml", "text/yaml; charset=utf-8"},
+	{".yml", "text/yaml; charset=utf-8"},
+	{".txt", "text/plain; charset=utf-8"},
+	{".gz", "application/gzip"},
+}
+
+// contentType returns the content type with which the named object is
+// served: the one for the suffix of its name in contentTypes, ignoring
+// case, or application/octet-stream if there is none or content types
+// are disabled.
+func (s *storageBackend) contentType(name string) string {
+	if !s.opts.DisableContentTypes {
+		name = strings.ToLower(name)
+		for _, t := range contentTypes {
+			if strings.HasSuffix(name, t.suffix) {
+				return t.contentType
+			}
+		}
+	}
+	return "application/octet-stream"
+}
+
+// getObject returns the contents of the named object, read from the
+// cache, the storage or else the first mirror that holds it.
+func (s *storageBackend) getObject(name string) ([]byte, error) {
+	if data, ok := s.cache.get(name); ok {
+		return data, nil
+	}
+	gen := s.cache.generation()
+	data, err := readObject(s.backend, name)
+	if err != nil {
+		for i, mirror := range s.mirrors {
+			logger.Debugf("cannot read %q, trying mirror %d: %v", name, i, err)
+			if mirrorData, mirrorErr := readObject(mirror, name); mirrorErr == nil {
+				data, err = mirrorData, nil
+				break
+			}
+		}
+	}
+	if err != nil {
+		// Report the primary storage's error.
+		return nil, err
+	}
+	s.cache.add(name, data, gen)
+	return data, nil
+}
+
+// defaultObject returns the name of the default object for the
+// longest prefix of the given object name that has one. It returns
+// false if there is none, or if the object is itself the default.
+func (s *storageBackend) defaultObject(name string) (string, bool) {
+	var defaultName string
+	longest := -1
+	for prefix, candidate := range s.defaults {
+		if strings.HasPrefix(name, prefix) && len(prefix) > longest {
+			defaultName, longest = candidate, len(prefix)
+		}
+	}
+	if longest < 0 || defaultName == name {
+		return "", false
+	}
+	return defaultName, true
+}
+
+// downloadRequested reports whether the request's DownloadParam
+// asks for the object to be served as an attachment.
+func downloadRequested(req *http.Request) (bool, error) {
+	value := req.URL.Query().Get(DownloadParam)
+	if value == "" {
+		return false, nil
+	}
+	download, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s parameter %q", DownloadParam, value)
+	}
+	return download, nil
+}
+
+// serveObject writes the contents of an object to the client. If the
+// request has a Range header specifying a single byte range, only that
+// range is written, with status 206 Partial Content; requests for
+// multiple ranges are answered with the whole object. If the range
+// cannot be satisfied, status 416 Requested Range Not Satisfiable is
+// returned instead.
+func serveObject(w http.ResponseWriter, req *http.Request, data []byte) {
+	w.Header().Set("Accept-Ranges", "bytes")
+	size := int64(len(data))
+	start, end, ok, err := parseRange(req.Header.Get("Range"), size)
+	switch {
+	case err != nil:
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+		http.Error(w, err.Error(), http.StatusRequestedRangeNotSatisfiable)
+	case ok:
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end-1, size))
+		w.Header().Set("Content-Length", fmt.Sprint(end-start))
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write(data[start:end])
+	default:
+		w.Write(data)
+	}
+}
+
+// parseRange returns the start and end offsets of the single byte
+// range of an object of the given size requested by a Range header.
+// It returns false if the whole object should be served: if there
+// is no header, the header is malformed or the header specifies
+// multiple ranges. It returns an error if the range requested cannot
+// be satisfied.
+func parseRange(header string, size int64) (start, end int64, ok bool, err error) {
+	spec := strings.TrimSpace(header)
+	if !strings.HasPrefix(spec, "bytes=") {
+		return 0, 0, false, nil
+	}
+	spec = strings.TrimSpace(spec[len("bytes="):])
+	i := strings.Index(spec, "-")
+	if i < 0 || strings.Contains(spec, ",") {
+		return 0, 0, false, nil
+	}
+	first, last := strings.TrimSpace(spec[:i]), strings.TrimSpace(spec[i+1:])
+	if first == "" {
+		// A suffix range, holding the last n bytes.
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n < 0 {
+			return 0, 0, false, nil
+		}
+		if n == 0 || size == 0 {
+			return 0, 0, false, errors.Errorf("range %q not satisfiable", header)
+		}
+		if n > size {
+			n = size
+		}
+		return size - n, size, true, nil
+	}
+	start, err = strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 {
+		return 0, 0, false, nil
+	}
+	end = size
+	if last != "" {
+		lastByte, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || lastByte < start {
+			return 0, 0, false, nil
+		}
+		if lastByte < size {
+			end = lastByte + 1
+		}
+	}
+	if start >= size {
+		return 0, 0, false, errors.Errorf("range %q not satisfiable", header)
+	}
+	return start, end, true, nil
+}
+
+// readObject returns the contents of the named object in stor.
+func readObject(stor storage.Storage, name string) ([]byte, error) {
+	readcloser, err := stor.Get(name)
+	if err != nil {
+		return nil, &statusError{http.StatusNotFound, err}
+	}
+	defer readcloser.Close()
+	return ioutil.ReadAll(readcloser)
 }
 
 // handleList returns the file names in the storage to the client.
+// The names are always returned in lexical order, whatever order
+// the backend lists them in. A prefix matching no objects yields an
+// empty list; only a failure of the storage is reported as an error.
+//
+// If the request's Accept header allows application/json, the objects
+// are returned as a JSON array of StorageObjects instead of newline
+// separated names.
 func (s *storageBackend) handleList(w http.ResponseWriter, req *http.Request) {
-	prefix := req.URL.Path
-	prefix = prefix[1 : len(prefix)-1] // drop the leading '/' and trailing '*'
-	names, err := s.backend.List(prefix)
+	names, err := s.list(req)
 	if err != nil {
 		http.Error(w, fmt.Sprint(err), http.StatusInternalServerError)
 		return
 	}
+	sort.Strings(names)
+	w.Header().Add("Vary", "Accept")
+	if acceptsJSON(req) {
+		s.serveListDetails(w, names)
+		return
+	}
 	data := []byte(strings.Join(names, "\n"))
 	w.Header().Set("Content-Type", "application/octet-stream")
 	w.Write(data)
 }
 
+// StorageObject describes an object held in a storage, in
+// the response to a request to list the objects matching
+// a prefix that accepts application/json.
+type StorageObject struct {
+	Name string `json:"name"`
+
+	// Size holds the size of the object, in bytes, or
+	// -1 if the storage does not implement StatStorage.
+	Size int64 `json:"size"`
+
+	// LastModified holds the time the object was last modified,
+	// or the zero time if the storage does not implement
+	// ModTimeStorage.
+	LastModified time.Time `json:"lastModified"`
+}
+
+// serveListDetails writes a JSON array describing the named objects.
+func (s *storageBackend) serveListDetails(w http.ResponseWriter, names []string) {
+	stat, hasSize := s.backend.(StatStorage)
+	modTimer, hasModTime := s.backend.(ModTimeStorage)
+	objects := make([]StorageObject, len(names))
+	for i, name := range names {
+		object := StorageObject{Name: name, Size: -1}
+		var err error
+		if hasSize {
+			object.Size, err = stat.Size(name)
+		}
+		if err == nil && hasModTime {
+			object.LastModified, err = modTimer.ModTime(name)
+		}
+		if err != nil {
+			http.Error(w, fmt.Sprint(err), http.StatusInternalServerError)
+			return
+		}
+		objects[i] = object
+	}
+	data, err := json.Marshal(objects)
+	if err != nil {
+		http.Error(w, fmt.Sprint(err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// acceptsJSON reports whether the request's Accept
+// header explicitly allows application/json.
+func acceptsJSON(req *http.Request) bool {
+	for _, accepted := range strings.Split(req.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(accepted)
+		if err != nil || mediaType != "application/json" {
+			continue
+		}
+		if q, ok := params["q"]; ok {
+			if v, err := strconv.ParseFloat(q, 64); err != nil || v <= 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// listPrefix returns the prefix addressed by the request,
+// without the '*' that ends it.
+func (s *storageBackend) listPrefix(req *http.Request) string {
+	prefix := s.objectName(req)
+	return prefix[:len(prefix)-1]
+}
+
+// list returns the names of the objects matching the prefix addressed
+// by the request, which ends in '*'. Some storages report a prefix
+// with no objects as not found, so that is treated as an empty list.
+func (s *storageBackend) list(req *http.Request) ([]string, error) {
+	names, err := s.backend.List(s.listPrefix(req))
+	if errors.IsNotFound(err) || os.IsNotExist(errors.Cause(err)) {
+		return nil, nil
+	}
+	return names, err
+}
+
 // handlePut stores data from the client in the storage.
+// Objects with names beginning with ReservedPrefix are rejected.
+// If the request has preconditions, the data is only stored
+// if they hold.
+// A gzip-encoded body is decompressed into a temporary file
+// before it is stored. A body of unknown length, such as a
+// chunked one, is streamed to the storage if it implements
+// StreamStorage; otherwise it too is read into a temporary
+// file first, so that the storage is told the length and is
+// never given more data than allowed.
 func (s *storageBackend) handlePut(w http.ResponseWriter, req *http.Request) {
-	if req.ContentLength < 0 {
-		http.Error(w, "missing or invalid Content-Length header", http.StatusInternalServerError)
+	name := s.objectName(req)
+	if isReserved(name) {
+		http.Error(w, fmt.Sprintf("object name %q is reserved", name), http.StatusBadRequest)
+		return
+	}
+	if err := s.checkPreconditions(req, name); err != nil {
+		http.Error(w, fmt.Sprint(err), statusOf(err))
+		return
+	}
+	max := s.opts.MaxUploadBytes
+	if max > 0 && req.ContentLength > max {
+		msg := fmt.Sprintf("body exceeds %d bytes", max)
+		http.Error(w, msg, http.StatusRequestEntityTooLarge)
+		return
+	}
+	put := s.backend.Put
+	appending := false
+	switch appendMode := req.Header.Get(AppendHeader); appendMode {
+	case "", "false":
+	case "true":
+		appender, ok := s.backend.(AppendStorage)
+		if !ok {
+			http.Error(w, "append is not supported by this storage", http.StatusNotImplemented)
+			return
+		}
+		put = appender.Append
+		appending = true
+	default:
+		msg := fmt.Sprintf("invalid %s header %q", AppendHeader, appendMode)
+		http.Error(w, msg, http.StatusBadRequest)
 		return
 	}
-	err := s.backend.Put(req.URL.Path[1:], req.Body, req.ContentLength)
+	var body io.Reader = req.Body
+	if max > 0 {
+		body = &maxBytesReader{r: req.Body, remaining: max, max: max}
+	}
+	length := req.ContentLength
+	switch encoding := req.Header.Get("Content-Encoding"); encoding {
+	case "", "identity":
+		if length >= 0 {
+			break
+		}
+		if streamer, ok := s.backend.(StreamStorage); ok && !appending {
+			s.handlePutStream(w, req, streamer, body)
+			return
+		}
+		f, n, err := spool(body, s.opts.TempDir)
+		if err != nil {
+			http.Error(w, fmt.Sprint(err), statusOf(err))
+			return
+		}
+		defer removeTempFile(f)
+		body, length = f, n
+	case "gzip":
+		f, n, err := decompress(body, s.opts.maxDecompressedBytes(), s.opts.TempDir)
+		if err != nil {
+			http.Error(w, fmt.Sprint(err), statusOf(err))
+			return
+		}
+		defer removeTempFile(f)
+		body, length = f, n
+	default:
+		msg := fmt.Sprintf("unsupported Content-Encoding %q", encoding)
+		http.Error(w, msg, http.StatusUnsupportedMediaType)
+		return
+	}
+	err := put(name, body, length)
 	if err != nil {
 		http.Error(w, fmt.Sprint(err), http.StatusInternalServerError)
 		return
@@ -150,13 +873,30 @@ func (s *storageBackend) handlePut(w http.ResponseWriter, req *http.Request) {
 	w.WriteHeader(http.StatusCreated)
 }
 
-// handleDelete removes a file from the storage.
+// handlePutStream stores a body of unknown length in storage
+// that can read it until EOF, without buffering it first.
+func (s *storageBackend) handlePutStream(w http.ResponseWriter, req *http.Request, streamer StreamStorage, body io.Reader) {
+	err := streamer.PutStream(s.objectName(req), body)
+	if err != nil {
+		http.Error(w, fmt.Sprint(err), statusOf(err))
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+// handleDelete removes a file from the storage. If the request
+// has preconditions, the file is only removed if they hold.
 func (s *storageBackend) handleDelete(w http.ResponseWriter, req *http.Request) {
 	if !s.authorized(req) {
 		http.Error(w, "unauthorized access", http.StatusUnauthorized)
 		return
 	}
-	err := s.backend.Remove(req.URL.Path[1:])
+	name := s.objectName(req)
+	if err := s.checkPreconditions(req, name); err != nil {
+		http.Error(w, fmt.Sprint(err), statusOf(err))
+		return
+	}
+	err := s.backend.Remove(name)
 	if err != nil {
 		http.Error(w, fmt.Sprint(err), http.StatusInternalServerError)
 		return
@@ -164,23 +904,631 @@ func (s *storageBackend) handleDelete(w http.ResponseWriter, req *http.Request)
 	w.WriteHeader(http.StatusOK)
 }
 
+// RemovePrefixResult is the body of the response to a DELETE request
+// for a prefix followed by '*', which removes all the objects whose
+// names begin with the prefix. The response has status 200 OK if all
+// of them were removed, and 500 Internal Server Error otherwise.
+type RemovePrefixResult struct {
+	// Removed holds the names of the objects removed, in order.
+	Removed []string `json:"removed"`
+
+	// Failed holds the objects that could not be removed, in order.
+	Failed []RemoveFailure `json:"failed,omitempty"`
+}
+
+// RemoveFailure reports an object that could not be removed.
+type RemoveFailure struct {
+	Name  string `json:"name"`
+	Error string `json:"error"`
+}
+
+// handleDeletePrefix removes all the objects matching the requested
+// prefix, carrying on past failures so that as many as possible are
+// removed, and reports which were removed in a RemovePrefixResult.
+func (s *storageBackend) handleDeletePrefix(w http.ResponseWriter, req *http.Request) {
+	if req.Header.Get("If-Match") != "" || req.Header.Get("If-Unmodified-Since") != "" {
+		http.Error(w, "preconditions are not supported when removing a prefix", http.StatusBadRequest)
+		return
+	}
+	result := RemovePrefixResult{Removed: []string{}}
+	_, err := storage.RemovePrefixFunc(s.backend, s.listPrefix(req), func(name string) error {
+		if err := s.remove(name); err != nil {
+			logger.Warningf("cannot remove %q: %v", name, err)
+			result.Failed = append(result.Failed, RemoveFailure{name, err.Error()})
+			return err
+		}
+		result.Removed = append(result.Removed, name)
+		return nil
+	})
+	if err != nil && len(result.Removed) == 0 && len(result.Failed) == 0 {
+		// Nothing was removed as the objects could not be listed;
+		// see list for why a prefix may be reported as not found.
+		if !errors.IsNotFound(err) && !os.IsNotExist(errors.Cause(err)) {
+			http.Error(w, fmt.Sprint(err), http.StatusInternalServerError)
+			return
+		}
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		http.Error(w, fmt.Sprint(err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if len(result.Failed) > 0 {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+	w.Write(data)
+}
+
+// remove removes the named object, ordered with
+// other modifications of the object.
+func (s *storageBackend) remove(name string) error {
+	unlock := s.locks.lock(name)
+	defer unlock()
+	defer s.cache.invalidate(name)
+	return s.backend.Remove(name)
+}
+
+// handleMove moves an object to the name held in the request's
+// DestinationHeader. The request is rejected if the storage does not
+// implement MoveStorage, as the object could then not be moved
+// atomically.
+func (s *storageBackend) handleMove(w http.ResponseWriter, req *http.Request) {
+	mover, ok := s.backend.(MoveStorage)
+	if !ok {
+		http.Error(w, "move is not supported by this storage", http.StatusNotImplemented)
+		return
+	}
+	name := s.objectName(req)
+	dest := req.Header.Get(DestinationHeader)
+	if dest == "" || dest == name {
+		msg := fmt.Sprintf("invalid %s header %q", DestinationHeader, dest)
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	if isReserved(dest) {
+		http.Error(w, fmt.Sprintf("object name %q is reserved", dest), http.StatusBadRequest)
+		return
+	}
+	overwrite := false
+	switch overwriteMode := req.Header.Get(OverwriteHeader); overwriteMode {
+	case "", "false":
+	case "true":
+		overwrite = true
+	default:
+		msg := fmt.Sprintf("invalid %s header %q", OverwriteHeader, overwriteMode)
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	if !overwrite {
+		exists, err := s.exists(dest)
+		if err != nil {
+			http.Error(w, fmt.Sprint(err), statusOf(err))
+			return
+		}
+		if exists {
+			msg := fmt.Sprintf("%q already exists", dest)
+			http.Error(w, msg, http.StatusPreconditionFailed)
+			return
+		}
+	}
+	if err := s.move(mover, name, dest); err != nil {
+		http.Error(w, fmt.Sprint(err), statusOf(err))
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+// move moves the object named from to the name to.
+func (s *storageBackend) move(mover MoveStorage, from, to string) error {
+	exists, err := s.exists(from)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return &statusError{http.StatusNotFound, errors.NotFoundf("file %q", from)}
+	}
+	return mover.Move(from, to)
+}
+
+// exists reports whether the named object is stored.
+func (s *storageBackend) exists(name string) (bool, error) {
+	r, err := s.backend.Get(name)
+	if errors.IsNotFound(err) || os.IsNotExist(errors.Cause(err)) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	r.Close()
+	return true, nil
+}
+
+// checkPreconditions checks the conditions placed on a modifying
+// request for the named object by its If-Match, If-None-Match and
+// If-Unmodified-Since headers. If any does not hold, it returns an
+// error to be reported with 412 Precondition Failed. In particular,
+// "If-None-Match: *" only holds if the object does not exist.
+//
+// The entity tag of an object is the one sent when it is read; see
+// objectETag. If-Match compares tags strongly, so it never holds for
+// an object with a weak tag, while If-None-Match compares them weakly.
+// The modification time of an object is only known if the storage
+// implements ModTimeStorage; if it does not, an If-Unmodified-Since
+// condition never holds.
+func (s *storageBackend) checkPreconditions(req *http.Request, name string) error {
+	ifMatch := req.Header.Get("If-Match")
+	ifNoneMatch := req.Header.Get("If-None-Match")
+	if ifMatch != "" || ifNoneMatch != "" {
+		etag, exists := s.storedETag(name)
+		if ifMatch != "" && !exists {
+			return preconditionFailed("object %q not found", name)
+		}
+		if ifMatch != "" && !etagMatches(ifMatch, etag) {
+			return preconditionFailed("object %q does not match %s", name, ifMatch)
+		}
+		if ifNoneMatch != "" && exists && weakETagMatches(ifNoneMatch, etag) {
+			return preconditionFailed("object %q matches %s", name, ifNoneMatch)
+		}
+	}
+	if ifUnmodifiedSince := req.Header.Get("If-Unmodified-Since"); ifUnmodifiedSince != "" {
+		since, err := http.ParseTime(ifUnmodifiedSince)
+		if err != nil {
+			return &statusError{
+				http.StatusBadRequest,
+				fmt.Errorf("invalid If-Unmodified-Since header %q", ifUnmodifiedSince),
+			}
+		}
+		stat, ok := s.backend.(ModTimeStorage)
+		if !ok {
+			return preconditionFailed("modification time of object %q is not known", name)
+		}
+		modTime, err := stat.ModTime(name)
+		if errors.IsNotFound(err) || os.IsNotExist(errors.Cause(err)) {
+			return preconditionFailed("object %q not found", name)
+		} else if err != nil {
+			return err
+		}
+		// HTTP dates have a resolution of one second.
+		if modTime.Truncate(time.Second).After(since) {
+			return preconditionFailed("object %q modified since %s", name, ifUnmodifiedSince)
+		}
+	}
+	return nil
+}
+
+// storedETag returns the entity tag of the named object as held in
+// the storage, rather than in the cache or a mirror, reading its
+// contents if the storage cannot otherwise tag it. It returns false
+// if the object cannot be read.
+func (s *storageBackend) storedETag(name string) (string, bool) {
+	if etag, ok := s.objectETag(name); ok {
+		return etag, true
+	}
+	data, err := readObject(s.backend, name)
+	if err != nil {
+		return "", false
+	}
+	return dataETag(data), true
+}
+
+func preconditionFailed(format string, args ...interface{}) error {
+	return &statusError{http.StatusPreconditionFailed, fmt.Errorf(format, args...)}
+}
+
+// dataETag returns the entity tag of an object with the given contents.
+func dataETag(data []byte) string {
+	return fmt.Sprintf(`"%x"`, sha256.Sum256(data))
+}
+
+// hashETag returns the entity tag of an object whose contents
+// have the given hex-encoded SHA-256 hash.
+func hashETag(hash string) string {
+	return `"` + hash + `"`
+}
+
+// statETag returns the entity tag of an object with the given size
+// and modification time. The tag is weak, as objects with the same
+// size and modification time may have different contents.
+func statETag(size int64, modTime time.Time) string {
+	return fmt.Sprintf(`W/"%x-%x"`, size, modTime.UnixNano())
+}
+
+// objectETag returns the entity tag of the named object as held in
+// the storage, without reading its contents: the hash reported by the
+// storage if it implements HashStorage, or else a weak tag derived from
+// the object's size and modification time if it implements StatStorage
+// and ModTimeStorage. It returns false if the tag cannot be found so.
+func (s *storageBackend) objectETag(name string) (string, bool) {
+	if hasher, ok := s.backend.(HashStorage); ok {
+		hash, err := hasher.Hash(name)
+		if err != nil {
+			logger.Debugf("cannot get hash of %q: %v", name, err)
+			return "", false
+		}
+		return hashETag(hash), true
+	}
+	stat, hasSize := s.backend.(StatStorage)
+	modTimer, hasModTime := s.backend.(ModTimeStorage)
+	if !hasSize || !hasModTime {
+		return "", false
+	}
+	size, err := stat.Size(name)
+	if err != nil {
+		return "", false
+	}
+	modTime, err := modTimer.ModTime(name)
+	if err != nil {
+		return "", false
+	}
+	return statETag(size, modTime), true
+}
+
+// notModified sets the ETag header of the response. If the request's
+// If-None-Match header matches the entity tag, it also responds with
+// 304 Not Modified and returns true; nothing else should be written.
+func notModified(w http.ResponseWriter, req *http.Request, etag string) bool {
+	w.Header().Set("ETag", etag)
+	ifNoneMatch := req.Header.Get("If-None-Match")
+	if ifNoneMatch == "" || !weakETagMatches(ifNoneMatch, etag) {
+		return false
+	}
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// weakETagMatches reports whether the list of entity tags in an
+// If-None-Match header matches etag. Weak tags match, as the
+// comparison is weak.
+func weakETagMatches(header, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// etagMatches reports whether the list of entity tags in an If-Match
+// header matches etag. Weak tags never match, as a strong comparison
+// is required.
+func etagMatches(header, etag string) bool {
+	weak := strings.HasPrefix(etag, "W/")
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || !weak && tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// statusError is an error that should be reported
+// to the client with a specific HTTP status code.
+type statusError struct {
+	status int
+	err    error
+}
+
+func (e *statusError) Error() string {
+	return e.err.Error()
+}
+
+// statusOf returns the HTTP status code with which
+// err should be reported to the client. An error satisfying
+// errors.IsNotValid, such as one reporting an object name
+// the storage rejects, is reported with 400 Bad Request.
+func statusOf(err error) int {
+	if err, ok := errors.Cause(err).(*statusError); ok {
+		return err.status
+	}
+	if errors.IsNotValid(err) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// maxBytesReader reads from r, failing with an error to be reported
+// with 413 Request Entity Too Large if more than max bytes are read.
+type maxBytesReader struct {
+	r         io.Reader
+	remaining int64
+	max       int64
+}
+
+func (r *maxBytesReader) Read(p []byte) (int, error) {
+	if r.remaining < 0 {
+		return 0, r.tooLarge()
+	}
+	// Read one byte more than remains, to detect
+	// a body that is exactly the maximum size.
+	if int64(len(p)) > r.remaining+1 {
+		p = p[:r.remaining+1]
+	}
+	n, err := r.r.Read(p)
+	if int64(n) > r.remaining {
+		n = int(r.remaining)
+		r.remaining = -1
+		return n, r.tooLarge()
+	}
+	r.remaining -= int64(n)
+	return n, err
+}
+
+func (r *maxBytesReader) tooLarge() error {
+	return &statusError{
+		http.StatusRequestEntityTooLarge,
+		fmt.Errorf("body exceeds %d bytes", r.max),
+	}
+}
+
+// spool copies the data read from r into a temporary file in dir, and
+// returns the file, positioned at its start, and the length of the data.
+// If dir is empty, the default directory for temporary files is used.
+func spool(r io.Reader, dir string) (*os.File, int64, error) {
+	f, err := ioutil.TempFile(dir, "juju-httpstorage")
+	if err != nil {
+		return nil, 0, err
+	}
+	n, err := io.Copy(f, r)
+	if err == nil {
+		_, err = f.Seek(0, 0)
+	}
+	if err != nil {
+		removeTempFile(f)
+		return nil, 0, err
+	}
+	return f, n, nil
+}
+
+// decompress decompresses the gzip stream read from r into a temporary
+// file in dir, as spool does. It returns the file, positioned at its
+// start, and the length of the decompressed data. An error is returned
+// if the data decompresses to more than max bytes.
+func decompress(r io.Reader, max int64, dir string) (*os.File, int64, error) {
+	zr, err := gzip.NewReader(r)
+	if _, ok := err.(*statusError); ok {
+		return nil, 0, err
+	} else if err != nil {
+		return nil, 0, &statusError{http.StatusBadRequest, fmt.Errorf("cannot decompress body: %v", err)}
+	}
+	defer zr.Close()
+	f, err := ioutil.TempFile(dir, "juju-httpstorage")
+	if err != nil {
+		return nil, 0, err
+	}
+	n, err := io.Copy(f, io.LimitReader(zr, max+1))
+	switch err.(type) {
+	case nil, *os.PathError, *statusError:
+	default:
+		// The error did not come from writing the file
+		// or limiting the body, so the client sent
+		// invalid data.
+		err = &statusError{http.StatusBadRequest, fmt.Errorf("cannot decompress body: %v", err)}
+	}
+	if err == nil && n > max {
+		err = &statusError{
+			http.StatusRequestEntityTooLarge,
+			fmt.Errorf("decompressed body exceeds %d bytes", max),
+		}
+	} else if err == nil {
+		_, err = f.Seek(0, 0)
+	}
+	if err != nil {
+		removeTempFile(f)
+		return nil, 0, err
+	}
+	return f, n, nil
+}
+
+// removeTempFile closes and removes a temporary file.
+func removeTempFile(f *os.File) {
+	f.Close()
+	if err := os.Remove(f.Name()); err != nil {
+		logger.Warningf("cannot remove temporary file: %v", err)
+	}
+}
+
+// DefaultMaxDecompressedBytes is the default limit on the size to
+// which a gzip-encoded PUT body may decompress.
+const DefaultMaxDecompressedBytes = 1 << 30
+
+// ServeOpts holds optional configuration for a storage server.
+// The zero value is the configuration used by Serve and ServeTLS.
+type ServeOpts struct {
+	// MaxDecompressedBytes limits the size to which a gzip-encoded
+	// PUT body may decompress, guarding against decompression bombs.
+	// If it is zero, DefaultMaxDecompressedBytes is used.
+	MaxDecompressedBytes int64
+
+	// MaxUploadBytes limits the size of the body of a PUT request,
+	// before any decompression. A request whose Content-Length
+	// exceeds the limit is rejected outright, and a body of unknown
+	// length is rejected as soon as it is found to exceed the limit,
+	// without anything being stored. If it is zero, the size is not
+	// limited.
+	MaxUploadBytes int64
+
+	// CacheBytes limits the total size of the objects held in memory
+	// to answer GET requests without reading the storage, for each
+	// storage served. The cached copy of an object is discarded when
+	// it is written or removed. If CacheBytes is zero, nothing is
+	// cached.
+	CacheBytes int64
+
+	// CacheMaxObjectBytes limits the size of the objects that are
+	// cached. If it is zero, any object that fits in the cache is
+	// cached.
+	CacheMaxObjectBytes int64
+
+	// KeepAlivePeriod is the interval between TCP keep-alive probes
+	// on client connections, so that connections from clients that
+	// have gone away are closed. If it is zero, DefaultKeepAlivePeriod
+	// is used; if it is negative, keep-alives are not enabled.
+	KeepAlivePeriod time.Duration
+
+	// GzipResponses has the contents of objects gzip-encoded in
+	// responses to GET requests whose Accept-Encoding header allows
+	// it. Responses to requests for byte ranges are not encoded.
+	GzipResponses bool
+
+	// DisableContentTypes has all objects served with the content
+	// type application/octet-stream. Otherwise, objects whose names
+	// end in a known extension, such as ".json" or ".tar.gz", are
+	// served with the content type for the extension.
+	DisableContentTypes bool
+
+	// RateLimit limits the rate, in requests per second, at which
+	// each client, identified by its IP address, may make requests.
+	// Requests beyond the limit are rejected with 429 Too Many
+	// Requests, and a Retry-After header giving the number of
+	// seconds until the client may try again. If it is zero, the
+	// rate is not limited.
+	RateLimit float64
+
+	// RateLimitBurst is the number of requests a client may make at
+	// once, after having made none for a while, when RateLimit is
+	// set. If it is less than one, one is used.
+	RateLimitBurst int
+
+	// Metrics, if set, records every request served, and the metrics
+	// it holds are served at MetricsPath.
+	Metrics MetricsCollector
+
+	// CertExpiry is the time at which the certificate generated for a
+	// TLS server expires. It must be in the future. If it is zero, the
+	// certificate expires ten years after the server starts.
+	// It is ignored by servers that do not use TLS.
+	CertExpiry time.Time
+
+	// TempDir is the directory in which PUT bodies are buffered
+	// before they are stored: gzip-encoded bodies, and bodies of
+	// unknown length when the storage does not implement
+	// StreamStorage. If it is empty, the default directory for
+	// temporary files is used.
+	TempDir string
+}
+
+// tooManyRequests is the status code for 429 Too Many Requests,
+// which net/http does not define.
+const tooManyRequests = 429
+
+// DefaultKeepAlivePeriod is the interval between TCP keep-alive
+// probes used when ServeOpts.KeepAlivePeriod is zero.
+const DefaultKeepAlivePeriod = 3 * time.Minute
+
+func (opts ServeOpts) maxDecompressedBytes() int64 {
+	if opts.MaxDecompressedBytes > 0 {
+		return opts.MaxDecompressedBytes
+	}
+	return DefaultMaxDecompressedBytes
+}
+
+// Mount describes a storage served under a path prefix by ServeMulti
+// and ServeTLSMulti. If AuthKey is non-empty, requests modifying objects
+// under the prefix must specify it.
+//
+// If an object cannot be read from Storage, it is read from the first
+// of Mirrors that holds it. Objects are only ever listed, written and
+// removed in Storage.
+//
+// Defaults maps object name prefixes to the names of default objects.
+// If an object cannot be read at all, the default object for the
+// longest prefix of its name is served in its place, with the
+// FallbackHeader set to the default object's name. Objects whose
+// names match no prefix are reported as not found.
+type Mount struct {
+	Storage  storage.Storage
+	AuthKey  string
+	Mirrors  []storage.Storage
+	Defaults map[string]string
+}
+
 // Serve runs a storage server on the given network address, relaying
-// requests to the given storage implementation. It returns the network
-// listener. This can then be attached to with Client.
-func Serve(addr string, stor storage.Storage) (net.Listener, error) {
-	return serve(addr, stor, nil, "")
+// requests to the given storage implementation. It returns the server,
+// which is also its network listener. This can then be attached to with
+// Client.
+func Serve(addr string, stor storage.Storage) (*Server, error) {
+	return ServeWithOpts(addr, stor, ServeOpts{})
+}
+
+// ServeWithOpts runs a storage server as Serve does,
+// configured with the given options.
+func ServeWithOpts(addr string, stor storage.Storage, opts ServeOpts) (*Server, error) {
+	return serve(addr, map[string]Mount{"": {Storage: stor}}, nil, opts)
+}
+
+// ServeWithMirrors runs a storage server as ServeWithOpts does, falling
+// back to reading objects from each of the given mirrors, in order,
+// when they cannot be read from stor. Modifying requests always go
+// to stor.
+func ServeWithMirrors(addr string, stor storage.Storage, mirrors []storage.Storage, opts ServeOpts) (*Server, error) {
+	mounts := map[string]Mount{"": {Storage: stor, Mirrors: mirrors}}
+	if err := validateMounts(mounts); err != nil {
+		return nil, err
+	}
+	return serve(addr, mounts, nil, opts)
+}
+
+// ServeMulti runs a storage server on the given network address, relaying
+// requests for each path prefix in mounts to the associated storage, and
+// authorising modifying requests against that mount's auth key. Prefixes
+// must either be empty or end in a '/', and must not begin with one.
+// Requests for paths under no prefix are rejected. The server is
+// configured with the given options.
+//
+// It returns the server, which is also its network listener. A mount
+// can then be attached to with
+// Client, by appending its prefix to the listener's address.
+func ServeMulti(addr string, mounts map[string]Mount, opts ServeOpts) (*Server, error) {
+	if err := validateMounts(mounts); err != nil {
+		return nil, err
+	}
+	return serve(addr, mounts, nil, opts)
 }
 
 // ServeTLS runs a storage server on the given network address, relaying
 // requests to the given storage implementation. The server runs a TLS
 // listener, and verifies client certificates (if given) against the
 // specified CA certificate. A client certificate is only required for
-// PUT and DELETE methods.
+// PUT and DELETE methods. The certificate generated for the server
+// expires ten years after it starts; to choose when it expires, use
+// ServeTLSWithOpts.
 //
-// This method returns the network listener, which can then be attached
-// to with ClientTLS.
-func ServeTLS(addr string, stor storage.Storage, caCertPEM, caKeyPEM string, hostnames []string, authkey string) (net.Listener, error) {
-	expiry := time.Now().UTC().AddDate(10, 0, 0)
+// This method returns the server, which is also its network listener,
+// and can then be attached to with ClientTLS.
+func ServeTLS(addr string, stor storage.Storage, caCertPEM, caKeyPEM string, hostnames []string, authkey string) (*Server, error) {
+	return ServeTLSWithOpts(addr, stor, caCertPEM, caKeyPEM, hostnames, authkey, ServeOpts{})
+}
+
+// ServeTLSWithOpts runs a storage server as ServeTLS does,
+// configured with the given options.
+func ServeTLSWithOpts(addr string, stor storage.Storage, caCertPEM, caKeyPEM string, hostnames []string, authkey string, opts ServeOpts) (*Server, error) {
+	mounts := map[string]Mount{"": {Storage: stor, AuthKey: authkey}}
+	return serveTLS(addr, mounts, caCertPEM, caKeyPEM, hostnames, opts)
+}
+
+// ServeTLSMulti runs a storage server as ServeTLS does, relaying
+// requests for each path prefix in mounts to the associated storage
+// as ServeMulti does. Modifying requests must specify the auth key
+// of the mount they address. The server is configured with the given
+// options.
+func ServeTLSMulti(addr string, mounts map[string]Mount, caCertPEM, caKeyPEM string, hostnames []string, opts ServeOpts) (*Server, error) {
+	if err := validateMounts(mounts); err != nil {
+		return nil, err
+	}
+	return serveTLS(addr, mounts, caCertPEM, caKeyPEM, hostnames, opts)
+}
+
+func serveTLS(addr string, mounts map[string]Mount, caCertPEM, caKeyPEM string, hostnames []string, opts ServeOpts) (*Server, error) {
+	now := time.Now().UTC()
+	expiry := opts.CertExpiry
+	if expiry.IsZero() {
+		expiry = now.AddDate(10, 0, 0)
+	} else if !expiry.After(now) {
+		return nil, errors.Errorf("certificate expiry %v is not in the future", expiry.UTC())
+	}
 	certPEM, keyPEM, err := cert.NewServer(caCertPEM, caKeyPEM, expiry, hostnames)
 	if err != nil {
 		return nil, err
@@ -193,39 +1541,199 @@ func ServeTLS(addr string, stor storage.Storage, caCertPEM, caKeyPEM string, hos
 	if !caCerts.AppendCertsFromPEM([]byte(caCertPEM)) {
 		return nil, errors.New("error adding CA certificate to pool")
 	}
+	// Session tickets are left enabled, so that reconnecting
+	// clients can resume their sessions without a full handshake,
+	// which matters when many agents reconnect at once.
 	config := &tls.Config{
 		NextProtos:   []string{"http/1.1"},
 		Certificates: []tls.Certificate{serverCert},
 		ClientAuth:   tls.VerifyClientCertIfGiven,
 		ClientCAs:    caCerts,
 	}
-	return serve(addr, stor, config, authkey)
+	return serve(addr, mounts, config, opts)
 }
 
-func serve(addr string, stor storage.Storage, tlsConfig *tls.Config, authkey string) (net.Listener, error) {
-	listener, err := net.Listen("tcp", addr)
+// validateMounts checks that the mount prefixes are well formed.
+func validateMounts(mounts map[string]Mount) error {
+	if len(mounts) == 0 {
+		return errors.New("no storage mounts specified")
+	}
+	for prefix, mount := range mounts {
+		if mount.Storage == nil {
+			return fmt.Errorf("no storage specified for prefix %q", prefix)
+		}
+		for i, mirror := range mount.Mirrors {
+			if mirror == nil {
+				return fmt.Errorf("no storage specified for mirror %d of prefix %q", i, prefix)
+			}
+		}
+		for namePrefix, name := range mount.Defaults {
+			if name == "" {
+				return fmt.Errorf("no default object specified for %q in prefix %q", namePrefix, prefix)
+			}
+		}
+		if err := validatePrefix(prefix); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validatePrefix checks that a mount prefix is well formed.
+func validatePrefix(prefix string) error {
+	if strings.HasPrefix(prefix, "/") {
+		return fmt.Errorf("invalid prefix %q: must not begin with '/'", prefix)
+	}
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		return fmt.Errorf("invalid prefix %q: must end with '/'", prefix)
+	}
+	return nil
+}
+
+func serve(addr string, mounts map[string]Mount, tlsConfig *tls.Config, opts ServeOpts) (*Server, error) {
+	listener, err := listenTCP(addr, opts)
 	if err != nil {
 		return nil, fmt.Errorf("cannot start listener: %v", err)
 	}
-	backend := &storageBackend{backend: stor}
-	if tlsConfig != nil {
-		tlsBackend := &storageBackend{backend: stor, authkey: authkey}
-		tcpAddr := listener.Addr().(*net.TCPAddr)
-		tlsListener, err := tls.Listen("tcp", fmt.Sprintf("[%s]:0", tcpAddr.IP), tlsConfig)
-		if err != nil {
-			listener.Close()
-			return nil, fmt.Errorf("cannot start TLS listener: %v", err)
+	backends := make(map[string]*storageBackend)
+	limiter := newRateLimiter(opts.RateLimit, opts.RateLimitBurst)
+	if tlsConfig == nil {
+		for prefix, mount := range mounts {
+			backends[prefix] = &storageBackend{
+				backend:  mount.Storage,
+				mirrors:  mount.Mirrors,
+				defaults: mount.Defaults,
+				prefix:   prefix,
+				authkey:  mount.AuthKey,
+				opts:     opts,
+				locks:    newKeyLocker(),
+				cache:    newObjectCache(opts.CacheBytes, opts.CacheMaxObjectBytes),
+				limiter:  limiter,
+			}
 		}
-		backend.httpsPort = tlsListener.Addr().(*net.TCPAddr).Port
-		goServe(tlsListener, tlsBackend)
+		conns := newConnTracker()
+		return &Server{
+			Listener: listener,
+			servers:  []*http.Server{goServe(listener, backends, opts, conns)},
+			conns:    conns,
+		}, nil
+	}
+	tcpAddr := listener.Addr().(*net.TCPAddr)
+	tcpListener, err := listenTCP(fmt.Sprintf("[%s]:0", tcpAddr.IP), opts)
+	if err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("cannot start TLS listener: %v", err)
 	}
-	goServe(listener, backend)
-	return listener, nil
+	tlsListener := tls.NewListener(tcpListener, tlsConfig)
+	tlsBackends := make(map[string]*storageBackend)
+	httpsPort := tlsListener.Addr().(*net.TCPAddr).Port
+	for prefix, mount := range mounts {
+		locks := newKeyLocker()
+		cache := newObjectCache(opts.CacheBytes, opts.CacheMaxObjectBytes)
+		tlsBackends[prefix] = &storageBackend{
+			backend:  mount.Storage,
+			mirrors:  mount.Mirrors,
+			defaults: mount.Defaults,
+			prefix:   prefix,
+			authkey:  mount.AuthKey,
+			opts:     opts,
+			locks:    locks,
+			cache:    cache,
+			limiter:  limiter,
+		}
+		// Modifying requests are only accepted
+		// over HTTPS, so no auth key is needed.
+		backends[prefix] = &storageBackend{
+			backend:   mount.Storage,
+			mirrors:   mount.Mirrors,
+			defaults:  mount.Defaults,
+			prefix:    prefix,
+			httpsPort: httpsPort,
+			opts:      opts,
+			locks:     locks,
+			cache:     cache,
+			limiter:   limiter,
+		}
+	}
+	conns := newConnTracker()
+	return &Server{
+		Listener: &pairedListener{listener, tlsListener},
+		servers: []*http.Server{
+			goServe(tlsListener, tlsBackends, opts, conns),
+			goServe(listener, backends, opts, conns),
+		},
+		conns: conns,
+	}, nil
+}
+
+// listenTCP returns a listener on the given address which enables
+// TCP keep-alives on accepted connections as specified by opts.
+func listenTCP(addr string, opts ServeOpts) (net.Listener, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	period := opts.KeepAlivePeriod
+	if period == 0 {
+		period = DefaultKeepAlivePeriod
+	}
+	if period < 0 {
+		return listener, nil
+	}
+	return keepAliveListener{listener.(*net.TCPListener), period}, nil
+}
+
+// keepAliveListener enables TCP keep-alives
+// on the connections it accepts.
+type keepAliveListener struct {
+	*net.TCPListener
+	period time.Duration
 }
 
-func goServe(listener net.Listener, backend *storageBackend) {
+// Accept implements net.Listener.
+func (l keepAliveListener) Accept() (net.Conn, error) {
+	conn, err := l.AcceptTCP()
+	if err != nil {
+		return nil, err
+	}
+	conn.SetKeepAlive(true)
+	conn.SetKeepAlivePeriod(l.period)
+	return conn, nil
+}
+
+// pairedListener is the listener of a server serving over both HTTP
+// and HTTPS. It reports the HTTP listener's address, and closing it
+// closes both listeners, so that both serving goroutines exit.
+type pairedListener struct {
+	net.Listener
+	tlsListener net.Listener
+}
+
+// Close implements net.Listener.
+func (l *pairedListener) Close() error {
+	err := l.Listener.Close()
+	if tlsErr := l.tlsListener.Close(); err == nil {
+		err = tlsErr
+	}
+	return err
+}
+
+// goServe serves the backends on the listener in a new goroutine,
+// tracking connections with conns, and returns the HTTP server.
+func goServe(listener net.Listener, backends map[string]*storageBackend, opts ServeOpts, conns *connTracker) *http.Server {
 	// Construct a NewServeMux to sanitise request paths.
 	mux := http.NewServeMux()
-	mux.Handle("/", backend)
-	go http.Serve(listener, mux)
+	for prefix, backend := range backends {
+		mux.Handle("/"+prefix, backend)
+	}
+	mux.Handle(HealthPath, healthHandler{backends})
+	if opts.Metrics != nil {
+		mux.Handle(MetricsPath, metricsHandler{opts.Metrics})
+	}
+	server := &http.Server{
+		Handler:   mux,
+		ConnState: conns.connState,
+	}
+	go server.Serve(listener)
+	return server
 }
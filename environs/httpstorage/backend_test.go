@@ -5,23 +5,33 @@ package httpstorage_test
 
 import (
 	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"math/rand"
 	"net"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	stdtesting "testing"
+	"time"
 
+	"github.com/juju/errors"
 	jc "github.com/juju/testing/checkers"
 	"github.com/juju/utils"
 	gc "gopkg.in/check.v1"
 
 	"github.com/juju/juju/environs/filestorage"
 	"github.com/juju/juju/environs/httpstorage"
+	"github.com/juju/juju/environs/storage"
+	envtesting "github.com/juju/juju/environs/testing"
 	coretesting "github.com/juju/juju/testing"
 )
 
@@ -458,3 +468,2499 @@ func (b *backendSuite) TestTLSUnauthenticatedRemove(c *gc.C) {
 	createTestData(c, dataDir)
 	testRemove(c, client, url, dataDir, false)
 }
+
+// startServerMulti starts a new local storage server hosting
+// two storages, under the "one/" and "two/" prefixes, with
+// distinct auth keys. It returns the listener and the data
+// directory for each prefix.
+func startServerMulti(c *gc.C) (listener net.Listener, dataDirs map[string]string) {
+	mounts := make(map[string]httpstorage.Mount)
+	dataDirs = make(map[string]string)
+	for _, prefix := range []string{"one/", "two/"} {
+		dataDir := c.MkDir()
+		embedded, err := filestorage.NewFileStorageWriter(dataDir)
+		c.Assert(err, jc.ErrorIsNil)
+		mounts[prefix] = httpstorage.Mount{Storage: embedded, AuthKey: prefix + testAuthkey}
+		dataDirs[prefix] = dataDir
+	}
+	listener, err := httpstorage.ServeMulti("localhost:0", mounts, httpstorage.ServeOpts{})
+	c.Assert(err, jc.ErrorIsNil)
+	return listener, dataDirs
+}
+
+func (s *backendSuite) TestServeMultiGet(c *gc.C) {
+	listener, dataDirs := startServerMulti(c)
+	defer listener.Close()
+	for prefix, dataDir := range dataDirs {
+		createTestData(c, dataDir)
+		err := ioutil.WriteFile(filepath.Join(dataDir, "owner"), []byte(prefix), 0644)
+		c.Assert(err, jc.ErrorIsNil)
+		url := fmt.Sprintf("http://%s/%s", listener.Addr(), prefix)
+		testGet(c, http.DefaultClient, url)
+		testList(c, http.DefaultClient, url)
+
+		resp, err := http.Get(url + "owner")
+		c.Assert(err, jc.ErrorIsNil)
+		defer resp.Body.Close()
+		data, err := ioutil.ReadAll(resp.Body)
+		c.Assert(err, jc.ErrorIsNil)
+		c.Assert(string(data), gc.Equals, prefix)
+	}
+}
+
+func (s *backendSuite) TestGetDefaultObject(c *gc.C) {
+	dataDir := c.MkDir()
+	embedded, err := filestorage.NewFileStorageWriter(dataDir)
+	c.Assert(err, jc.ErrorIsNil)
+	createTestData(c, dataDir)
+	mounts := map[string]httpstorage.Mount{"": {
+		Storage: embedded,
+		Defaults: map[string]string{
+			"inner/":    "foo",
+			"inner/ba":  "bar",
+			"inner/bad": "missing",
+		},
+	}}
+	listener, err := httpstorage.ServeMulti("localhost:0", mounts, httpstorage.ServeOpts{})
+	c.Assert(err, jc.ErrorIsNil)
+	defer listener.Close()
+	url := fmt.Sprintf("http://%s/", listener.Addr())
+
+	for i, test := range []struct {
+		name     string
+		status   int
+		content  string
+		fallback string
+	}{
+		{"inner/fooin", http.StatusOK, "this is inner file 'fooin'", ""},
+		{"inner/nothing", http.StatusOK, "this is file 'foo'", "foo"},
+		{"inner/bananas", http.StatusOK, "this is file 'bar'", "bar"},
+		// A missing default object is not served.
+		{"inner/badger", http.StatusNotFound, "", ""},
+		// Objects with no default are not found, as usual.
+		{"nothing", http.StatusNotFound, "", ""},
+	} {
+		c.Logf("test %d: %s", i, test.name)
+		resp, err := http.Get(url + test.name)
+		c.Assert(err, jc.ErrorIsNil)
+		data, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		c.Assert(err, jc.ErrorIsNil)
+		c.Check(resp.StatusCode, gc.Equals, test.status)
+		c.Check(resp.Header.Get(httpstorage.FallbackHeader), gc.Equals, test.fallback)
+		if test.status == http.StatusOK {
+			c.Check(string(data), gc.Equals, test.content)
+		}
+	}
+}
+
+func (s *backendSuite) TestServeMultiInvalidDefault(c *gc.C) {
+	mounts := map[string]httpstorage.Mount{"": {
+		Storage:  unavailableStorage{},
+		Defaults: map[string]string{"inner/": ""},
+	}}
+	_, err := httpstorage.ServeMulti("localhost:0", mounts, httpstorage.ServeOpts{})
+	c.Assert(err, gc.ErrorMatches, `no default object specified for "inner/" in prefix ""`)
+}
+
+func (s *backendSuite) TestServeMultiUnknownPrefix(c *gc.C) {
+	listener, _ := startServerMulti(c)
+	defer listener.Close()
+	resp, err := http.Get(fmt.Sprintf("http://%s/three/foo", listener.Addr()))
+	c.Assert(err, jc.ErrorIsNil)
+	defer resp.Body.Close()
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusNotFound)
+}
+
+func (s *backendSuite) TestServeMultiAuthorisedPerPrefix(c *gc.C) {
+	listener, dataDirs := startServerMulti(c)
+	defer listener.Close()
+	put := func(prefix, authkey string) int {
+		url := fmt.Sprintf("http://%s/%sfile?authkey=%s", listener.Addr(), prefix, authkey)
+		req, err := http.NewRequest("PUT", url, bytes.NewBufferString("content"))
+		c.Assert(err, jc.ErrorIsNil)
+		resp, err := http.DefaultClient.Do(req)
+		c.Assert(err, jc.ErrorIsNil)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	c.Assert(put("one/", ""), gc.Equals, http.StatusUnauthorized)
+	c.Assert(put("one/", "two/"+testAuthkey), gc.Equals, http.StatusUnauthorized)
+	c.Assert(put("one/", "one/"+testAuthkey), gc.Equals, http.StatusCreated)
+
+	_, err := os.Stat(filepath.Join(dataDirs["one/"], "file"))
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = os.Stat(filepath.Join(dataDirs["two/"], "file"))
+	c.Assert(os.IsNotExist(err), jc.IsTrue)
+}
+
+func (s *backendSuite) TestServeMultiInvalidPrefix(c *gc.C) {
+	embedded, err := filestorage.NewFileStorageWriter(c.MkDir())
+	c.Assert(err, jc.ErrorIsNil)
+	for prefix, expect := range map[string]string{
+		"one":   `invalid prefix "one": must end with '/'`,
+		"/one/": `invalid prefix "/one/": must not begin with '/'`,
+	} {
+		mounts := map[string]httpstorage.Mount{prefix: {Storage: embedded}}
+		_, err := httpstorage.ServeMulti("localhost:0", mounts, httpstorage.ServeOpts{})
+		c.Check(err, gc.ErrorMatches, expect)
+	}
+	_, err = httpstorage.ServeMulti("localhost:0", nil, httpstorage.ServeOpts{})
+	c.Assert(err, gc.ErrorMatches, "no storage mounts specified")
+}
+
+func (s *backendSuite) TestServeExitsOnClose(c *gc.C) {
+	embedded, err := filestorage.NewFileStorageWriter(c.MkDir())
+	c.Assert(err, jc.ErrorIsNil)
+	envtesting.CheckHTTPStorageServerExits(c, func() (*httpstorage.Server, error) {
+		return httpstorage.Serve("localhost:0", embedded)
+	}, func(listener net.Listener) {
+		resp, err := http.Get(fmt.Sprintf("http://%s/*", listener.Addr()))
+		c.Assert(err, jc.ErrorIsNil)
+		resp.Body.Close()
+		c.Assert(resp.StatusCode, gc.Equals, http.StatusOK)
+	})
+}
+
+func (s *backendSuite) TestServeTLSExitsOnClose(c *gc.C) {
+	embedded, err := filestorage.NewFileStorageWriter(c.MkDir())
+	c.Assert(err, jc.ErrorIsNil)
+	envtesting.CheckHTTPStorageServerExits(c, func() (*httpstorage.Server, error) {
+		return httpstorage.ServeTLS(
+			"127.0.0.1:0",
+			embedded,
+			coretesting.CACert,
+			coretesting.CAKey,
+			[]string{"127.0.0.1"},
+			testAuthkey,
+		)
+	}, func(listener net.Listener) {
+		stor, err := httpstorage.ClientTLS(listener.Addr().String(), coretesting.CACert, testAuthkey)
+		c.Assert(err, jc.ErrorIsNil)
+		err = stor.Put("file", strings.NewReader("content"), 7)
+		c.Assert(err, jc.ErrorIsNil)
+	})
+}
+
+// slowGetStorage is a storage whose Get method blocks
+// until release is closed.
+type slowGetStorage struct {
+	storage.Storage
+	getStarted chan struct{}
+	release    chan struct{}
+}
+
+func (s *slowGetStorage) Get(name string) (io.ReadCloser, error) {
+	select {
+	case s.getStarted <- struct{}{}:
+	default:
+	}
+	<-s.release
+	return s.Storage.Get(name)
+}
+
+func startSlowGetServer(c *gc.C) (stor *slowGetStorage, server *httpstorage.Server, url string) {
+	embedded, err := filestorage.NewFileStorageWriter(c.MkDir())
+	c.Assert(err, jc.ErrorIsNil)
+	err = embedded.Put("obj", strings.NewReader("content"), 7)
+	c.Assert(err, jc.ErrorIsNil)
+	stor = &slowGetStorage{
+		Storage:    embedded,
+		getStarted: make(chan struct{}, 1),
+		release:    make(chan struct{}),
+	}
+	server, err = httpstorage.Serve("localhost:0", stor)
+	c.Assert(err, jc.ErrorIsNil)
+	return stor, server, fmt.Sprintf("http://%s/", server.Addr())
+}
+
+type getResult struct {
+	status int
+	body   string
+	err    error
+}
+
+// startGet starts a GET request for the url,
+// sending its result on the returned channel.
+func startGet(url string) <-chan getResult {
+	result := make(chan getResult, 1)
+	go func() {
+		resp, err := http.Get(url)
+		if err != nil {
+			result <- getResult{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		data, err := ioutil.ReadAll(resp.Body)
+		result <- getResult{resp.StatusCode, string(data), err}
+	}()
+	return result
+}
+
+func (s *backendSuite) TestShutdownWaitsForRequests(c *gc.C) {
+	stor, server, url := startSlowGetServer(c)
+	defer server.Close()
+	got := startGet(url + "obj")
+	select {
+	case <-stor.getStarted:
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("GET not started")
+	}
+
+	shutdown := make(chan error, 1)
+	go func() {
+		shutdown <- server.Shutdown(make(chan struct{}))
+	}()
+	select {
+	case err := <-shutdown:
+		c.Fatalf("Shutdown returned while a request was active: %v", err)
+	case <-time.After(coretesting.ShortWait):
+	}
+
+	// No new connections are accepted.
+	_, err := net.Dial("tcp", server.Addr().String())
+	c.Assert(err, gc.NotNil)
+
+	// The request completes, and then the shutdown does.
+	close(stor.release)
+	select {
+	case result := <-got:
+		c.Assert(result.err, jc.ErrorIsNil)
+		c.Assert(result.status, gc.Equals, http.StatusOK)
+		c.Assert(result.body, gc.Equals, "content")
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("GET did not complete")
+	}
+	select {
+	case err := <-shutdown:
+		c.Assert(err, jc.ErrorIsNil)
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("Shutdown did not return")
+	}
+}
+
+func (s *backendSuite) TestShutdownAborted(c *gc.C) {
+	stor, server, url := startSlowGetServer(c)
+	defer close(stor.release)
+	got := startGet(url + "obj")
+	select {
+	case <-stor.getStarted:
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("GET not started")
+	}
+
+	abort := make(chan struct{})
+	close(abort)
+	err := server.Shutdown(abort)
+	c.Assert(err, gc.Equals, httpstorage.ErrShutdownAborted)
+	select {
+	case result := <-got:
+		c.Assert(result.err, gc.NotNil)
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("GET was not cut short")
+	}
+}
+
+func (s *backendSuite) TestShutdownIdle(c *gc.C) {
+	for i, start := range []func() (*httpstorage.Server, error){
+		func() (*httpstorage.Server, error) {
+			embedded, err := filestorage.NewFileStorageWriter(c.MkDir())
+			c.Assert(err, jc.ErrorIsNil)
+			return httpstorage.Serve("localhost:0", embedded)
+		},
+		func() (*httpstorage.Server, error) {
+			embedded, err := filestorage.NewFileStorageWriter(c.MkDir())
+			c.Assert(err, jc.ErrorIsNil)
+			return httpstorage.ServeTLS(
+				"127.0.0.1:0",
+				embedded,
+				coretesting.CACert,
+				coretesting.CAKey,
+				[]string{"127.0.0.1"},
+				testAuthkey,
+			)
+		},
+	} {
+		c.Logf("test %d", i)
+		server, err := start()
+		c.Assert(err, jc.ErrorIsNil)
+		// Leave idle connections behind.
+		_, err = storage.List(httpstorage.Client(server.Addr().String()), "")
+		c.Assert(err, jc.ErrorIsNil)
+		if i == 1 {
+			stor, err := httpstorage.ClientTLS(server.Addr().String(), coretesting.CACert, testAuthkey)
+			c.Assert(err, jc.ErrorIsNil)
+			checkPutFile(c, stor, "file", []byte("content"))
+		}
+
+		shutdown := make(chan error, 1)
+		go func() {
+			shutdown <- server.Shutdown(make(chan struct{}))
+		}()
+		select {
+		case err := <-shutdown:
+			c.Assert(err, jc.ErrorIsNil)
+		case <-time.After(coretesting.LongWait):
+			c.Fatalf("Shutdown did not return")
+		}
+	}
+}
+
+func (s *backendSuite) TestShutdownSilentConnection(c *gc.C) {
+	s.PatchValue(httpstorage.NewConnGracePeriod, 50*time.Millisecond)
+	s.PatchValue(httpstorage.ShutdownPollInterval, 10*time.Millisecond)
+	embedded, err := filestorage.NewFileStorageWriter(c.MkDir())
+	c.Assert(err, jc.ErrorIsNil)
+	server, err := httpstorage.Serve("localhost:0", embedded)
+	c.Assert(err, jc.ErrorIsNil)
+	defer server.Close()
+
+	// A connection on which no request is ever sent
+	// does not hold up the shutdown for long.
+	conn, err := net.Dial("tcp", server.Addr().String())
+	c.Assert(err, jc.ErrorIsNil)
+	defer conn.Close()
+
+	shutdown := make(chan error, 1)
+	go func() {
+		shutdown <- server.Shutdown(make(chan struct{}))
+	}()
+	select {
+	case err := <-shutdown:
+		c.Assert(err, jc.ErrorIsNil)
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("Shutdown did not return")
+	}
+	// The server has closed the connection.
+	_, err = conn.Read(make([]byte, 1))
+	c.Assert(err, gc.NotNil)
+}
+
+// putGzip sends a gzip-encoded PUT request for the named file
+// with the given content, and returns the response status.
+func putGzip(c *gc.C, url, name string, content []byte) int {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err := zw.Write(content)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(zw.Close(), jc.ErrorIsNil)
+	req, err := http.NewRequest("PUT", url+name, &buf)
+	c.Assert(err, jc.ErrorIsNil)
+	req.Header.Set("Content-Encoding", "gzip")
+	resp, err := http.DefaultClient.Do(req)
+	c.Assert(err, jc.ErrorIsNil)
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func (s *backendSuite) TestPutGzip(c *gc.C) {
+	listener, url, dataDir := startServer(c)
+	defer listener.Close()
+	content := bytes.Repeat([]byte("compressible "), 1000)
+	status := putGzip(c, url, "file", content)
+	c.Assert(status, gc.Equals, http.StatusCreated)
+	data, err := ioutil.ReadFile(filepath.Join(dataDir, "file"))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(data, gc.DeepEquals, content)
+}
+
+func (s *backendSuite) TestPutGzipTooLarge(c *gc.C) {
+	dataDir := c.MkDir()
+	embedded, err := filestorage.NewFileStorageWriter(dataDir)
+	c.Assert(err, jc.ErrorIsNil)
+	listener, err := httpstorage.ServeWithOpts("localhost:0", embedded, httpstorage.ServeOpts{
+		MaxDecompressedBytes: 100,
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	defer listener.Close()
+	url := fmt.Sprintf("http://%s/", listener.Addr())
+
+	status := putGzip(c, url, "small", make([]byte, 100))
+	c.Assert(status, gc.Equals, http.StatusCreated)
+	status = putGzip(c, url, "large", make([]byte, 101))
+	c.Assert(status, gc.Equals, http.StatusRequestEntityTooLarge)
+	_, err = os.Stat(filepath.Join(dataDir, "large"))
+	c.Assert(os.IsNotExist(err), jc.IsTrue)
+}
+
+func (s *backendSuite) TestPutGzipInvalid(c *gc.C) {
+	listener, url, _ := startServer(c)
+	defer listener.Close()
+	req, err := http.NewRequest("PUT", url+"file", strings.NewReader("not gzip"))
+	c.Assert(err, jc.ErrorIsNil)
+	req.Header.Set("Content-Encoding", "gzip")
+	resp, err := http.DefaultClient.Do(req)
+	c.Assert(err, jc.ErrorIsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusBadRequest)
+}
+
+func (s *backendSuite) TestPutUnsupportedEncoding(c *gc.C) {
+	listener, url, _ := startServer(c)
+	defer listener.Close()
+	req, err := http.NewRequest("PUT", url+"file", strings.NewReader("content"))
+	c.Assert(err, jc.ErrorIsNil)
+	req.Header.Set("Content-Encoding", "compress")
+	resp, err := http.DefaultClient.Do(req)
+	c.Assert(err, jc.ErrorIsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusUnsupportedMediaType)
+}
+
+// putChunked sends a PUT request for the named file with the given
+// content, using chunked encoding if chunked is true, and returns the
+// response status.
+func putChunked(c *gc.C, url, name string, content []byte, chunked bool) int {
+	// Hide the reader's type, so that the request's
+	// length is not determined from it.
+	body := struct{ io.Reader }{bytes.NewReader(content)}
+	req, err := http.NewRequest("PUT", url+name, body)
+	c.Assert(err, jc.ErrorIsNil)
+	req.ContentLength = int64(len(content))
+	if chunked {
+		req.ContentLength = -1
+	}
+	resp, err := http.DefaultClient.Do(req)
+	c.Assert(err, jc.ErrorIsNil)
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func (s *backendSuite) TestPutChunked(c *gc.C) {
+	listener, url, dataDir := startServer(c)
+	defer listener.Close()
+	content := bytes.Repeat([]byte("chunky "), 10000)
+	status := putChunked(c, url, "file", content, true)
+	c.Assert(status, gc.Equals, http.StatusCreated)
+	data, err := ioutil.ReadFile(filepath.Join(dataDir, "file"))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(data, gc.DeepEquals, content)
+}
+
+func (s *backendSuite) TestPutMaxUploadBytes(c *gc.C) {
+	dataDir := c.MkDir()
+	embedded, err := filestorage.NewFileStorageWriter(dataDir)
+	c.Assert(err, jc.ErrorIsNil)
+	listener, err := httpstorage.ServeWithOpts("localhost:0", embedded, httpstorage.ServeOpts{
+		MaxUploadBytes: 100,
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	defer listener.Close()
+	url := fmt.Sprintf("http://%s/", listener.Addr())
+
+	for i, test := range []struct {
+		size    int
+		chunked bool
+		status  int
+	}{
+		{100, false, http.StatusCreated},
+		{101, false, http.StatusRequestEntityTooLarge},
+		{100, true, http.StatusCreated},
+		{101, true, http.StatusRequestEntityTooLarge},
+		{10000, true, http.StatusRequestEntityTooLarge},
+	} {
+		c.Logf("test %d: %d bytes, chunked %v", i, test.size, test.chunked)
+		name := fmt.Sprintf("file%d", i)
+		status := putChunked(c, url, name, make([]byte, test.size), test.chunked)
+		c.Check(status, gc.Equals, test.status)
+		_, err := os.Stat(filepath.Join(dataDir, name))
+		c.Check(os.IsNotExist(err), gc.Equals, test.status != http.StatusCreated)
+	}
+
+	// The limit applies to a gzip-encoded body before decompression.
+	status := putGzip(c, url, "compressible", make([]byte, 10000))
+	c.Assert(status, gc.Equals, http.StatusCreated)
+	random := rand.New(rand.NewSource(1))
+	incompressible := make([]byte, 1000)
+	for i := range incompressible {
+		incompressible[i] = byte(random.Intn(256))
+	}
+	status = putGzip(c, url, "incompressible", incompressible)
+	c.Assert(status, gc.Equals, http.StatusRequestEntityTooLarge)
+}
+
+// spoolCheckingStorage wraps a storage, recording the
+// names of the files from which objects are Put.
+type spoolCheckingStorage struct {
+	storage.Storage
+	spooled []string
+}
+
+func (s *spoolCheckingStorage) Put(name string, r io.Reader, length int64) error {
+	if f, ok := r.(*os.File); ok {
+		s.spooled = append(s.spooled, f.Name())
+	}
+	return s.Storage.Put(name, r, length)
+}
+
+func (s *backendSuite) TestPutTempDir(c *gc.C) {
+	dataDir := c.MkDir()
+	embedded, err := filestorage.NewFileStorageWriter(dataDir)
+	c.Assert(err, jc.ErrorIsNil)
+	stor := &spoolCheckingStorage{Storage: embedded}
+	tempDir := c.MkDir()
+	listener, err := httpstorage.ServeWithOpts("localhost:0", stor, httpstorage.ServeOpts{
+		TempDir: tempDir,
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	defer listener.Close()
+	url := fmt.Sprintf("http://%s/", listener.Addr())
+
+	content := bytes.Repeat([]byte("chunky "), 10000)
+	c.Assert(putChunked(c, url, "chunked", content, true), gc.Equals, http.StatusCreated)
+	c.Assert(putGzip(c, url, "gzipped", content), gc.Equals, http.StatusCreated)
+	c.Assert(stor.spooled, gc.HasLen, 2)
+	for _, name := range stor.spooled {
+		c.Assert(filepath.Dir(name), gc.Equals, tempDir)
+	}
+	// The buffered bodies are removed once stored.
+	infos, err := ioutil.ReadDir(tempDir)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(infos, gc.HasLen, 0)
+}
+
+// streamingStorage wraps a storage, implementing StreamStorage.
+type streamingStorage struct {
+	storage.Storage
+	streamed []string
+}
+
+func (s *streamingStorage) PutStream(name string, r io.Reader) error {
+	s.streamed = append(s.streamed, name)
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return s.Storage.Put(name, bytes.NewReader(data), int64(len(data)))
+}
+
+func (s *backendSuite) TestPutChunkedStream(c *gc.C) {
+	dataDir := c.MkDir()
+	embedded, err := filestorage.NewFileStorageWriter(dataDir)
+	c.Assert(err, jc.ErrorIsNil)
+	stor := &streamingStorage{Storage: embedded}
+	listener, err := httpstorage.ServeWithOpts("localhost:0", stor, httpstorage.ServeOpts{
+		MaxUploadBytes: 1000,
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	defer listener.Close()
+	url := fmt.Sprintf("http://%s/", listener.Addr())
+
+	content := bytes.Repeat([]byte("chunky "), 100)
+	c.Assert(putChunked(c, url, "chunked", content, true), gc.Equals, http.StatusCreated)
+	data, err := ioutil.ReadFile(filepath.Join(dataDir, "chunked"))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(data, gc.DeepEquals, content)
+
+	// A body of known length is Put as usual.
+	c.Assert(putChunked(c, url, "sized", content, false), gc.Equals, http.StatusCreated)
+	c.Assert(stor.streamed, jc.DeepEquals, []string{"chunked"})
+
+	// The upload limit still applies to a streamed body.
+	status := putChunked(c, url, "large", make([]byte, 1001), true)
+	c.Assert(status, gc.Equals, http.StatusRequestEntityTooLarge)
+	_, err = os.Stat(filepath.Join(dataDir, "large"))
+	c.Assert(err, jc.Satisfies, os.IsNotExist)
+}
+
+// unorderedStorage wraps a storage, listing names in reverse order.
+type unorderedStorage struct {
+	storage.Storage
+}
+
+func (s unorderedStorage) List(prefix string) ([]string, error) {
+	names, err := s.Storage.List(prefix)
+	for i, j := 0, len(names)-1; i < j; i, j = i+1, j-1 {
+		names[i], names[j] = names[j], names[i]
+	}
+	return names, err
+}
+
+func (s *backendSuite) TestListSorted(c *gc.C) {
+	dataDir := c.MkDir()
+	embedded, err := filestorage.NewFileStorageWriter(dataDir)
+	c.Assert(err, jc.ErrorIsNil)
+	listener, err := httpstorage.Serve("localhost:0", unorderedStorage{embedded})
+	c.Assert(err, jc.ErrorIsNil)
+	defer listener.Close()
+	createTestData(c, dataDir)
+
+	resp, err := http.Get(fmt.Sprintf("http://%s/*", listener.Addr()))
+	c.Assert(err, jc.ErrorIsNil)
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(strings.Split(string(data), "\n"), gc.DeepEquals, []string{
+		"bar", "baz", "foo", "inner/barin", "inner/bazin", "inner/fooin", "yadda",
+	})
+}
+
+func getListDetails(c *gc.C, url, accept string) (contentType string, objects []httpstorage.StorageObject, body string) {
+	req, err := http.NewRequest("GET", url, nil)
+	c.Assert(err, jc.ErrorIsNil)
+	req.Header.Set("Accept", accept)
+	resp, err := http.DefaultClient.Do(req)
+	c.Assert(err, jc.ErrorIsNil)
+	defer resp.Body.Close()
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusOK)
+	data, err := ioutil.ReadAll(resp.Body)
+	c.Assert(err, jc.ErrorIsNil)
+	contentType = resp.Header.Get("Content-Type")
+	if contentType == "application/json" {
+		err = json.Unmarshal(data, &objects)
+		c.Assert(err, jc.ErrorIsNil)
+	}
+	return contentType, objects, string(data)
+}
+
+func (s *backendSuite) TestListDetails(c *gc.C) {
+	listener, url, dataDir := startServer(c)
+	defer listener.Close()
+	createTestData(c, dataDir)
+	modTime := time.Date(2015, 6, 1, 12, 0, 0, 0, time.UTC)
+	for _, name := range []string{"inner/barin", "inner/bazin"} {
+		err := os.Chtimes(filepath.Join(dataDir, name), modTime, modTime)
+		c.Assert(err, jc.ErrorIsNil)
+	}
+
+	for i, accept := range []string{
+		"application/json",
+		"text/plain;q=0.5, application/json",
+	} {
+		c.Logf("test %d: %q", i, accept)
+		contentType, objects, _ := getListDetails(c, url+"inner/ba*", accept)
+		c.Assert(contentType, gc.Equals, "application/json")
+		c.Assert(objects, gc.HasLen, 2)
+		for j, name := range []string{"inner/barin", "inner/bazin"} {
+			c.Check(objects[j].Name, gc.Equals, name)
+			c.Check(objects[j].Size, gc.Equals, int64(len("this is inner file 'barin'")))
+			c.Check(objects[j].LastModified.Equal(modTime), jc.IsTrue)
+		}
+	}
+
+	// Names are listed as before unless JSON is accepted.
+	for i, accept := range []string{"", "*/*", "text/plain", "application/json;q=0"} {
+		c.Logf("test %d: %q", i, accept)
+		contentType, _, body := getListDetails(c, url+"inner/ba*", accept)
+		c.Check(contentType, gc.Equals, "application/octet-stream")
+		c.Check(body, gc.Equals, "inner/barin\ninner/bazin")
+	}
+}
+
+func (s *backendSuite) TestListDetailsUnknown(c *gc.C) {
+	dataDir := c.MkDir()
+	embedded, err := filestorage.NewFileStorageWriter(dataDir)
+	c.Assert(err, jc.ErrorIsNil)
+	listener, err := httpstorage.Serve("localhost:0", unorderedStorage{embedded})
+	c.Assert(err, jc.ErrorIsNil)
+	defer listener.Close()
+	createTestData(c, dataDir)
+
+	url := fmt.Sprintf("http://%s/ba*", listener.Addr())
+	_, objects, _ := getListDetails(c, url, "application/json")
+	c.Assert(objects, jc.DeepEquals, []httpstorage.StorageObject{
+		{Name: "bar", Size: -1},
+		{Name: "baz", Size: -1},
+	})
+
+	// A prefix matching nothing yields an empty array.
+	_, _, body := getListDetails(c, fmt.Sprintf("http://%s/missing*", listener.Addr()), "application/json")
+	c.Assert(body, gc.Equals, "[]")
+}
+
+func (s *backendSuite) TestHeadList(c *gc.C) {
+	listener, url, dataDir := startServer(c)
+	defer listener.Close()
+	createTestData(c, dataDir)
+	for i, test := range []struct {
+		prefix string
+		count  string
+		bytes  string
+	}{
+		{"", "7", "152"},
+		{"ba", "2", "36"},
+		{"inner/", "3", "78"},
+		{"missing", "0", "0"},
+	} {
+		c.Logf("test %d: %q", i, test.prefix)
+		resp, err := http.Head(url + test.prefix + "*")
+		c.Assert(err, jc.ErrorIsNil)
+		c.Assert(resp.StatusCode, gc.Equals, http.StatusOK)
+		c.Check(resp.Header.Get(httpstorage.ObjectCountHeader), gc.Equals, test.count)
+		c.Check(resp.Header.Get(httpstorage.ObjectBytesHeader), gc.Equals, test.bytes)
+	}
+}
+
+func (s *backendSuite) TestHeadListWithoutSizes(c *gc.C) {
+	dataDir := c.MkDir()
+	embedded, err := filestorage.NewFileStorageWriter(dataDir)
+	c.Assert(err, jc.ErrorIsNil)
+	// unorderedStorage does not implement StatStorage.
+	listener, err := httpstorage.Serve("localhost:0", unorderedStorage{embedded})
+	c.Assert(err, jc.ErrorIsNil)
+	defer listener.Close()
+	createTestData(c, dataDir)
+
+	resp, err := http.Head(fmt.Sprintf("http://%s/*", listener.Addr()))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusOK)
+	c.Assert(resp.Header.Get(httpstorage.ObjectCountHeader), gc.Equals, "7")
+	_, ok := resp.Header[httpstorage.ObjectBytesHeader]
+	c.Assert(ok, jc.IsFalse)
+}
+
+// blockingStorage wraps a storage, holding up any Put of the
+// "blocked" object until release is closed.
+type blockingStorage struct {
+	storage.Storage
+	putStarted chan struct{}
+	release    chan struct{}
+	removed    chan string
+}
+
+func (s *blockingStorage) Put(name string, r io.Reader, length int64) error {
+	if name == "blocked" {
+		close(s.putStarted)
+		<-s.release
+	}
+	return s.Storage.Put(name, r, length)
+}
+
+func (s *blockingStorage) Remove(name string) error {
+	s.removed <- name
+	return s.Storage.Remove(name)
+}
+
+func startBlockingServer(c *gc.C) (stor *blockingStorage, listener net.Listener, url, dataDir string) {
+	dataDir = c.MkDir()
+	embedded, err := filestorage.NewFileStorageWriter(dataDir)
+	c.Assert(err, jc.ErrorIsNil)
+	stor = &blockingStorage{
+		Storage:    embedded,
+		putStarted: make(chan struct{}),
+		release:    make(chan struct{}),
+		removed:    make(chan string, 1),
+	}
+	listener, err = httpstorage.Serve("localhost:0", stor)
+	c.Assert(err, jc.ErrorIsNil)
+	return stor, listener, fmt.Sprintf("http://%s/", listener.Addr()), dataDir
+}
+
+func doRequest(c *gc.C, method, url, body string, done chan<- int) {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	c.Check(err, jc.ErrorIsNil)
+	resp, err := http.DefaultClient.Do(req)
+	if !c.Check(err, jc.ErrorIsNil) {
+		done <- 0
+		return
+	}
+	resp.Body.Close()
+	done <- resp.StatusCode
+}
+
+func (s *backendSuite) TestConcurrentPutAndRemoveSameObject(c *gc.C) {
+	stor, listener, url, dataDir := startBlockingServer(c)
+	defer listener.Close()
+
+	putDone := make(chan int, 1)
+	go doRequest(c, "PUT", url+"blocked", "content", putDone)
+	select {
+	case <-stor.putStarted:
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("timed out waiting for PUT to start")
+	}
+
+	removeDone := make(chan int, 1)
+	go doRequest(c, "DELETE", url+"blocked", "", removeDone)
+	select {
+	case <-stor.removed:
+		c.Fatalf("DELETE reached the storage while PUT was in progress")
+	case <-time.After(coretesting.ShortWait):
+	}
+
+	close(stor.release)
+	for _, req := range []struct {
+		done   chan int
+		status int
+	}{
+		{putDone, http.StatusCreated},
+		{removeDone, http.StatusOK},
+	} {
+		select {
+		case status := <-req.done:
+			c.Assert(status, gc.Equals, req.status)
+		case <-time.After(coretesting.LongWait):
+			c.Fatalf("timed out waiting for request to complete")
+		}
+	}
+	c.Assert(<-stor.removed, gc.Equals, "blocked")
+
+	// The DELETE was ordered after the PUT, so the object is gone.
+	_, err := os.Stat(filepath.Join(dataDir, "blocked"))
+	c.Assert(err, jc.Satisfies, os.IsNotExist)
+}
+
+func (s *backendSuite) TestConcurrentOperationsDifferentObjects(c *gc.C) {
+	stor, listener, url, dataDir := startBlockingServer(c)
+	defer listener.Close()
+	defer close(stor.release)
+
+	putDone := make(chan int, 1)
+	go doRequest(c, "PUT", url+"blocked", "content", putDone)
+	select {
+	case <-stor.putStarted:
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("timed out waiting for PUT to start")
+	}
+
+	// Operations on other objects are not held up by the blocked PUT.
+	otherDone := make(chan int, 1)
+	go doRequest(c, "PUT", url+"other", "other content", otherDone)
+	select {
+	case status := <-otherDone:
+		c.Assert(status, gc.Equals, http.StatusCreated)
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("PUT of another object was blocked")
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dataDir, "other"))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(string(data), gc.Equals, "other content")
+}
+
+// TestConcurrentOperationsRace fires many concurrent requests at a
+// server with caching enabled, so that the race detector can find
+// any unsynchronised access to state shared between requests.
+func (s *backendSuite) TestConcurrentOperationsRace(c *gc.C) {
+	_, listener, url, _ := startCachingServer(c, httpstorage.ServeOpts{CacheBytes: 1024})
+	defer listener.Close()
+
+	const (
+		workers    = 10
+		iterations = 20
+	)
+	names := []string{"a", "b", "c"}
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for j := 0; j < iterations; j++ {
+				name := names[(worker+j)%len(names)]
+				var status int
+				var body string
+				switch (worker + j) % 3 {
+				case 0:
+					status, body = doConcurrentRequest(c, "PUT", url+name, fmt.Sprintf("%s %d %d", name, worker, j))
+					c.Check(status, gc.Equals, http.StatusCreated)
+				case 1:
+					status, body = doConcurrentRequest(c, "GET", url+name, "")
+					if status == http.StatusOK {
+						// The content must have been written whole
+						// by a single PUT of this object.
+						c.Check(body, gc.Matches, name+` \d+ \d+`)
+					} else {
+						c.Check(status, gc.Equals, http.StatusNotFound)
+					}
+				case 2:
+					status, _ = doConcurrentRequest(c, "DELETE", url+name, "")
+					c.Check(status, gc.Equals, http.StatusOK)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+// doConcurrentRequest makes an HTTP request, and returns the status
+// and body of the response. It is safe to call from any goroutine.
+func doConcurrentRequest(c *gc.C, method, url, body string) (int, string) {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if !c.Check(err, jc.ErrorIsNil) {
+		return 0, ""
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if !c.Check(err, jc.ErrorIsNil) {
+		return 0, ""
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	c.Check(err, jc.ErrorIsNil)
+	return resp.StatusCode, string(data)
+}
+
+func putAppend(c *gc.C, url, appendMode, content string) int {
+	req, err := http.NewRequest("PUT", url, strings.NewReader(content))
+	c.Assert(err, jc.ErrorIsNil)
+	req.Header.Set(httpstorage.AppendHeader, appendMode)
+	resp, err := http.DefaultClient.Do(req)
+	c.Assert(err, jc.ErrorIsNil)
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func (s *backendSuite) TestPutAppend(c *gc.C) {
+	listener, url, dataDir := startServer(c)
+	defer listener.Close()
+
+	c.Assert(putAppend(c, url+"log", "true", "first\n"), gc.Equals, http.StatusCreated)
+	c.Assert(putAppend(c, url+"log", "true", "second\n"), gc.Equals, http.StatusCreated)
+	data, err := ioutil.ReadFile(filepath.Join(dataDir, "log"))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(string(data), gc.Equals, "first\nsecond\n")
+
+	// Without the header, the object is replaced.
+	c.Assert(putAppend(c, url+"log", "false", "third\n"), gc.Equals, http.StatusCreated)
+	data, err = ioutil.ReadFile(filepath.Join(dataDir, "log"))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(string(data), gc.Equals, "third\n")
+}
+
+func (s *backendSuite) TestPutAppendInvalidHeader(c *gc.C) {
+	listener, url, _ := startServer(c)
+	defer listener.Close()
+	c.Assert(putAppend(c, url+"log", "yes", "data"), gc.Equals, http.StatusBadRequest)
+}
+
+func (s *backendSuite) TestPutAppendUnsupported(c *gc.C) {
+	dataDir := c.MkDir()
+	embedded, err := filestorage.NewFileStorageWriter(dataDir)
+	c.Assert(err, jc.ErrorIsNil)
+	// unorderedStorage does not implement AppendStorage.
+	listener, err := httpstorage.Serve("localhost:0", unorderedStorage{embedded})
+	c.Assert(err, jc.ErrorIsNil)
+	defer listener.Close()
+
+	url := fmt.Sprintf("http://%s/log", listener.Addr())
+	c.Assert(putAppend(c, url, "true", "data"), gc.Equals, http.StatusNotImplemented)
+	_, err = os.Stat(filepath.Join(dataDir, "log"))
+	c.Assert(err, jc.Satisfies, os.IsNotExist)
+}
+
+// unavailableStorage is a storage from which nothing can be read.
+type unavailableStorage struct {
+	storage.Storage
+}
+
+func (unavailableStorage) Get(name string) (io.ReadCloser, error) {
+	return nil, fmt.Errorf("storage is unavailable")
+}
+
+func (s *backendSuite) TestServeWithMirrors(c *gc.C) {
+	var dataDirs []string
+	var stors []storage.Storage
+	for i := 0; i < 3; i++ {
+		dataDir := c.MkDir()
+		stor, err := filestorage.NewFileStorageWriter(dataDir)
+		c.Assert(err, jc.ErrorIsNil)
+		dataDirs = append(dataDirs, dataDir)
+		stors = append(stors, stor)
+	}
+	writeFile := func(i int, name, content string) {
+		err := ioutil.WriteFile(filepath.Join(dataDirs[i], name), []byte(content), 0644)
+		c.Assert(err, jc.ErrorIsNil)
+	}
+	writeFile(0, "both", "primary")
+	writeFile(1, "both", "mirror")
+	writeFile(2, "mirrored", "second mirror")
+
+	mirrors := []storage.Storage{stors[1], unavailableStorage{}, stors[2]}
+	listener, err := httpstorage.ServeWithMirrors("localhost:0", stors[0], mirrors, httpstorage.ServeOpts{})
+	c.Assert(err, jc.ErrorIsNil)
+	defer listener.Close()
+	url := fmt.Sprintf("http://%s/", listener.Addr())
+
+	get := func(name string) (int, string) {
+		resp, err := http.Get(url + name)
+		c.Assert(err, jc.ErrorIsNil)
+		defer resp.Body.Close()
+		data, err := ioutil.ReadAll(resp.Body)
+		c.Assert(err, jc.ErrorIsNil)
+		return resp.StatusCode, string(data)
+	}
+	status, content := get("both")
+	c.Check(status, gc.Equals, http.StatusOK)
+	c.Check(content, gc.Equals, "primary")
+	status, content = get("mirrored")
+	c.Check(status, gc.Equals, http.StatusOK)
+	c.Check(content, gc.Equals, "second mirror")
+	status, _ = get("missing")
+	c.Check(status, gc.Equals, http.StatusNotFound)
+
+	// Objects are only listed from, and written to, the primary.
+	status, content = get("*")
+	c.Check(status, gc.Equals, http.StatusOK)
+	c.Check(content, gc.Equals, "both")
+	req, err := http.NewRequest("PUT", url+"written", bytes.NewBufferString("content"))
+	c.Assert(err, jc.ErrorIsNil)
+	resp, err := http.DefaultClient.Do(req)
+	c.Assert(err, jc.ErrorIsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusCreated)
+	_, err = os.Stat(filepath.Join(dataDirs[0], "written"))
+	c.Check(err, jc.ErrorIsNil)
+	for _, dataDir := range dataDirs[1:] {
+		_, err = os.Stat(filepath.Join(dataDir, "written"))
+		c.Check(os.IsNotExist(err), jc.IsTrue)
+	}
+}
+
+func (s *backendSuite) TestServeWithMirrorsInvalid(c *gc.C) {
+	embedded, err := filestorage.NewFileStorageWriter(c.MkDir())
+	c.Assert(err, jc.ErrorIsNil)
+	mirrors := []storage.Storage{embedded, nil}
+	_, err = httpstorage.ServeWithMirrors("localhost:0", embedded, mirrors, httpstorage.ServeOpts{})
+	c.Assert(err, gc.ErrorMatches, `no storage specified for mirror 1 of prefix ""`)
+}
+
+// countingStorage wraps a storage, counting the objects read from it.
+type countingStorage struct {
+	storage.Storage
+	mu   sync.Mutex
+	gets map[string]int
+}
+
+func (s *countingStorage) Get(name string) (io.ReadCloser, error) {
+	s.mu.Lock()
+	s.gets[name]++
+	s.mu.Unlock()
+	return s.Storage.Get(name)
+}
+
+func (s *countingStorage) count(name string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.gets[name]
+}
+
+func startCachingServer(c *gc.C, opts httpstorage.ServeOpts) (stor *countingStorage, listener net.Listener, url, dataDir string) {
+	dataDir = c.MkDir()
+	embedded, err := filestorage.NewFileStorageWriter(dataDir)
+	c.Assert(err, jc.ErrorIsNil)
+	stor = &countingStorage{Storage: embedded, gets: make(map[string]int)}
+	listener, err = httpstorage.ServeWithOpts("localhost:0", stor, opts)
+	c.Assert(err, jc.ErrorIsNil)
+	return stor, listener, fmt.Sprintf("http://%s/", listener.Addr()), dataDir
+}
+
+func putContent(c *gc.C, url, content string) int {
+	req, err := http.NewRequest("PUT", url, strings.NewReader(content))
+	c.Assert(err, jc.ErrorIsNil)
+	resp, err := http.DefaultClient.Do(req)
+	c.Assert(err, jc.ErrorIsNil)
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func getContent(c *gc.C, url string) string {
+	resp, err := http.Get(url)
+	c.Assert(err, jc.ErrorIsNil)
+	defer resp.Body.Close()
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusOK)
+	data, err := ioutil.ReadAll(resp.Body)
+	c.Assert(err, jc.ErrorIsNil)
+	return string(data)
+}
+
+func (s *backendSuite) TestGetDownload(c *gc.C) {
+	listener, url, dataDir := startServer(c)
+	defer listener.Close()
+	createTestData(c, dataDir)
+
+	for i, test := range []struct {
+		query       string
+		status      int
+		disposition string
+	}{
+		{"", http.StatusOK, ""},
+		{"?download=1", http.StatusOK, "attachment; filename=fooin"},
+		{"?download=true", http.StatusOK, "attachment; filename=fooin"},
+		{"?download=0", http.StatusOK, ""},
+		{"?download=please", http.StatusBadRequest, ""},
+	} {
+		c.Logf("test %d: %q", i, test.query)
+		resp, err := http.Get(url + "inner/fooin" + test.query)
+		c.Assert(err, jc.ErrorIsNil)
+		data, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		c.Assert(err, jc.ErrorIsNil)
+		c.Check(resp.StatusCode, gc.Equals, test.status)
+		c.Check(resp.Header.Get("Content-Disposition"), gc.Equals, test.disposition)
+		if test.status == http.StatusOK {
+			c.Check(string(data), gc.Equals, "this is inner file 'fooin'")
+		}
+	}
+
+	// Missing objects are reported as usual.
+	resp, err := http.Get(url + "missing?download=1")
+	c.Assert(err, jc.ErrorIsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusNotFound)
+	c.Assert(resp.Header.Get("Content-Disposition"), gc.Equals, "")
+}
+
+var contentTypeTests = []struct {
+	name        string
+	contentType string
+}{
+	{"tools/releases/juju-1.23.0-trusty-amd64.tgz", "application/x-compressed-tar"},
+	{"tools/releases/juju-1.23.0-trusty-amd64.tar.gz", "application/x-compressed-tar"},
+	{"tools/streams/v1/index.json", "application/json"},
+	{"tools/streams/v1/com.ubuntu.juju:released:tools.json", "application/json"},
+	{"tools/streams/v1/INDEX.JSON", "application/json"},
+	{"bootstrap/config.yaml", "text/yaml; charset=utf-8"},
+	{"bootstrap/config.yml", "text/yaml; charset=utf-8"},
+	{"notes.txt", "text/plain; charset=utf-8"},
+	{"charms/local.gz", "application/gzip"},
+	{"tools/streams/v1/index.sjson", "application/octet-stream"},
+	{"provider-state", "application/octet-stream"},
+}
+
+// testGetContentType checks the content types with which objects
+// are served by a server with the given options. If octetStream is
+// true, all objects are expected to be served as octet streams.
+func testGetContentType(c *gc.C, opts httpstorage.ServeOpts, octetStream bool) {
+	_, listener, url, _ := startCachingServer(c, opts)
+	defer listener.Close()
+	for i, test := range contentTypeTests {
+		c.Logf("test %d: %q", i, test.name)
+		c.Assert(putContent(c, url+test.name, "content"), gc.Equals, http.StatusCreated)
+		// Fetch the object twice, so that a cached copy is served too.
+		for j := 0; j < 2; j++ {
+			resp, err := http.Get(url + test.name)
+			c.Assert(err, jc.ErrorIsNil)
+			resp.Body.Close()
+			c.Assert(resp.StatusCode, gc.Equals, http.StatusOK)
+			expected := test.contentType
+			if octetStream {
+				expected = "application/octet-stream"
+			}
+			c.Check(resp.Header.Get("Content-Type"), gc.Equals, expected)
+		}
+	}
+}
+
+func (s *backendSuite) TestGetContentType(c *gc.C) {
+	testGetContentType(c, httpstorage.ServeOpts{}, false)
+}
+
+func (s *backendSuite) TestGetContentTypeCached(c *gc.C) {
+	testGetContentType(c, httpstorage.ServeOpts{CacheBytes: 1024}, false)
+}
+
+func (s *backendSuite) TestGetContentTypeDisabled(c *gc.C) {
+	testGetContentType(c, httpstorage.ServeOpts{DisableContentTypes: true}, true)
+}
+
+func (s *backendSuite) TestGetCached(c *gc.C) {
+	stor, listener, url, _ := startCachingServer(c, httpstorage.ServeOpts{CacheBytes: 1024})
+	defer listener.Close()
+
+	c.Assert(putContent(c, url+"obj", "old"), gc.Equals, http.StatusCreated)
+	c.Check(getContent(c, url+"obj"), gc.Equals, "old")
+	c.Check(getContent(c, url+"obj"), gc.Equals, "old")
+	c.Check(stor.count("obj"), gc.Equals, 1)
+
+	// Writing the object discards the cached copy.
+	c.Assert(putContent(c, url+"obj", "new"), gc.Equals, http.StatusCreated)
+	c.Check(getContent(c, url+"obj"), gc.Equals, "new")
+	c.Check(getContent(c, url+"obj"), gc.Equals, "new")
+	c.Check(stor.count("obj"), gc.Equals, 2)
+
+	// As does removing it.
+	req, err := http.NewRequest("DELETE", url+"obj", nil)
+	c.Assert(err, jc.ErrorIsNil)
+	resp, err := http.DefaultClient.Do(req)
+	c.Assert(err, jc.ErrorIsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusOK)
+	resp, err = http.Get(url + "obj")
+	c.Assert(err, jc.ErrorIsNil)
+	resp.Body.Close()
+	c.Check(resp.StatusCode, gc.Equals, http.StatusNotFound)
+}
+
+func (s *backendSuite) TestGetCachedEvictsLeastRecentlyUsed(c *gc.C) {
+	stor, listener, url, _ := startCachingServer(c, httpstorage.ServeOpts{CacheBytes: 10})
+	defer listener.Close()
+	for _, name := range []string{"a", "b", "c"} {
+		c.Assert(putContent(c, url+name, "1234"), gc.Equals, http.StatusCreated)
+	}
+
+	getContent(c, url+"a")
+	getContent(c, url+"b")
+	getContent(c, url+"a")
+	// There is only room for two objects, so reading c evicts b.
+	getContent(c, url+"c")
+	getContent(c, url+"a")
+	getContent(c, url+"b")
+	c.Check(stor.count("a"), gc.Equals, 1)
+	c.Check(stor.count("b"), gc.Equals, 2)
+	c.Check(stor.count("c"), gc.Equals, 1)
+}
+
+func (s *backendSuite) TestGetCachedObjectSizeLimit(c *gc.C) {
+	opts := httpstorage.ServeOpts{CacheBytes: 1024, CacheMaxObjectBytes: 4}
+	stor, listener, url, _ := startCachingServer(c, opts)
+	defer listener.Close()
+	c.Assert(putContent(c, url+"small", "1234"), gc.Equals, http.StatusCreated)
+	c.Assert(putContent(c, url+"large", "12345"), gc.Equals, http.StatusCreated)
+
+	for i := 0; i < 2; i++ {
+		c.Check(getContent(c, url+"small"), gc.Equals, "1234")
+		c.Check(getContent(c, url+"large"), gc.Equals, "12345")
+	}
+	c.Check(stor.count("small"), gc.Equals, 1)
+	c.Check(stor.count("large"), gc.Equals, 2)
+}
+
+func (s *backendSuite) TestGetNotCachedByDefault(c *gc.C) {
+	stor, listener, url, _ := startCachingServer(c, httpstorage.ServeOpts{})
+	defer listener.Close()
+	c.Assert(putContent(c, url+"obj", "data"), gc.Equals, http.StatusCreated)
+	getContent(c, url+"obj")
+	getContent(c, url+"obj")
+	c.Check(stor.count("obj"), gc.Equals, 2)
+}
+
+// listErrorStorage wraps a storage, failing to list the
+// "missing" prefix with a not found error, and the
+// "broken" prefix with any other error.
+type listErrorStorage struct {
+	storage.Storage
+}
+
+func (s listErrorStorage) List(prefix string) ([]string, error) {
+	switch prefix {
+	case "missing":
+		return nil, errors.NotFoundf("prefix %q", prefix)
+	case "broken":
+		return nil, fmt.Errorf("storage is broken")
+	}
+	return s.Storage.List(prefix)
+}
+
+func (s *backendSuite) TestListErrors(c *gc.C) {
+	dataDir := c.MkDir()
+	embedded, err := filestorage.NewFileStorageWriter(dataDir)
+	c.Assert(err, jc.ErrorIsNil)
+	listener, err := httpstorage.Serve("localhost:0", listErrorStorage{embedded})
+	c.Assert(err, jc.ErrorIsNil)
+	defer listener.Close()
+	createTestData(c, dataDir)
+
+	for i, test := range []struct {
+		prefix string
+		status int
+		body   string
+		count  string
+	}{
+		{"ba", http.StatusOK, "bar\nbaz", "2"},
+		{"missing", http.StatusOK, "", "0"},
+		{"broken", http.StatusInternalServerError, "storage is broken\n", ""},
+	} {
+		c.Logf("test %d: %q", i, test.prefix)
+		url := fmt.Sprintf("http://%s/%s*", listener.Addr(), test.prefix)
+		resp, err := http.Get(url)
+		c.Assert(err, jc.ErrorIsNil)
+		data, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		c.Assert(err, jc.ErrorIsNil)
+		c.Check(resp.StatusCode, gc.Equals, test.status)
+		c.Check(string(data), gc.Equals, test.body)
+
+		resp, err = http.Head(url)
+		c.Assert(err, jc.ErrorIsNil)
+		c.Check(resp.StatusCode, gc.Equals, test.status)
+		c.Check(resp.Header.Get(httpstorage.ObjectCountHeader), gc.Equals, test.count)
+	}
+
+	// The client sees an empty list.
+	names, err := storage.List(httpstorage.Client(listener.Addr().String()), "missing")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(names, gc.HasLen, 0)
+}
+
+// deletePrefix sends a DELETE request for the objects matching
+// the given prefix, and returns the status and decoded result.
+func deletePrefix(c *gc.C, client *http.Client, url string) (int, httpstorage.RemovePrefixResult) {
+	req, err := http.NewRequest("DELETE", url, nil)
+	c.Assert(err, jc.ErrorIsNil)
+	resp, err := client.Do(req)
+	c.Assert(err, jc.ErrorIsNil)
+	defer resp.Body.Close()
+	var result httpstorage.RemovePrefixResult
+	if resp.Header.Get("Content-Type") == "application/json" {
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		c.Assert(err, jc.ErrorIsNil)
+	}
+	return resp.StatusCode, result
+}
+
+func (s *backendSuite) TestRemovePrefix(c *gc.C) {
+	listener, url, dataDir := startServer(c)
+	defer listener.Close()
+	createTestData(c, dataDir)
+
+	status, result := deletePrefix(c, http.DefaultClient, url+"inner/ba*")
+	c.Assert(status, gc.Equals, http.StatusOK)
+	c.Assert(result, jc.DeepEquals, httpstorage.RemovePrefixResult{
+		Removed: []string{"inner/barin", "inner/bazin"},
+	})
+	for _, name := range []string{"barin", "bazin"} {
+		_, err := os.Stat(filepath.Join(dataDir, "inner", name))
+		c.Assert(err, jc.Satisfies, os.IsNotExist)
+	}
+	_, err := os.Stat(filepath.Join(dataDir, "inner", "fooin"))
+	c.Assert(err, jc.ErrorIsNil)
+
+	// A prefix matching nothing removes nothing.
+	status, result = deletePrefix(c, http.DefaultClient, url+"missing*")
+	c.Assert(status, gc.Equals, http.StatusOK)
+	c.Assert(result, jc.DeepEquals, httpstorage.RemovePrefixResult{Removed: []string{}})
+}
+
+func (s *backendSuite) TestRemovePrefixPreconditions(c *gc.C) {
+	listener, url, dataDir := startServer(c)
+	defer listener.Close()
+	createTestData(c, dataDir)
+
+	status := deleteWithHeader(c, url+"ba*", "If-Match", "*")
+	c.Assert(status, gc.Equals, http.StatusBadRequest)
+	_, err := os.Stat(filepath.Join(dataDir, "bar"))
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *backendSuite) TestRemovePrefixListErrors(c *gc.C) {
+	dataDir := c.MkDir()
+	embedded, err := filestorage.NewFileStorageWriter(dataDir)
+	c.Assert(err, jc.ErrorIsNil)
+	listener, err := httpstorage.Serve("localhost:0", listErrorStorage{embedded})
+	c.Assert(err, jc.ErrorIsNil)
+	defer listener.Close()
+	createTestData(c, dataDir)
+
+	url := fmt.Sprintf("http://%s/", listener.Addr())
+	status, result := deletePrefix(c, http.DefaultClient, url+"missing*")
+	c.Assert(status, gc.Equals, http.StatusOK)
+	c.Assert(result, jc.DeepEquals, httpstorage.RemovePrefixResult{Removed: []string{}})
+	status, _ = deletePrefix(c, http.DefaultClient, url+"broken*")
+	c.Assert(status, gc.Equals, http.StatusInternalServerError)
+}
+
+type removeErrorStorage struct {
+	storage.Storage
+}
+
+func (s removeErrorStorage) Remove(name string) error {
+	if name == "baz" {
+		return fmt.Errorf("baz is stuck")
+	}
+	return s.Storage.Remove(name)
+}
+
+func (s *backendSuite) TestRemovePrefixFailures(c *gc.C) {
+	dataDir := c.MkDir()
+	embedded, err := filestorage.NewFileStorageWriter(dataDir)
+	c.Assert(err, jc.ErrorIsNil)
+	stor := removeErrorStorage{embedded}
+	listener, err := httpstorage.Serve("localhost:0", stor)
+	c.Assert(err, jc.ErrorIsNil)
+	defer listener.Close()
+	createTestData(c, dataDir)
+
+	url := fmt.Sprintf("http://%s/", listener.Addr())
+	status, result := deletePrefix(c, http.DefaultClient, url+"*")
+	c.Assert(status, gc.Equals, http.StatusInternalServerError)
+	c.Assert(result, jc.DeepEquals, httpstorage.RemovePrefixResult{
+		Removed: []string{"bar", "foo", "inner/barin", "inner/bazin", "inner/fooin", "yadda"},
+		Failed:  []httpstorage.RemoveFailure{{"baz", "baz is stuck"}},
+	})
+
+	// The client removes what it can, and reports the failure.
+	checkPutFile(c, embedded, "foo", []byte("foo"))
+	removed, err := httpstorage.Client(listener.Addr().String()).RemovePrefix("")
+	c.Assert(err, gc.ErrorMatches, `cannot remove "baz": baz is stuck`)
+	c.Assert(removed, gc.Equals, 1)
+}
+
+func (b *backendSuite) TestTLSUnauthenticatedRemovePrefix(c *gc.C) {
+	client, url, dataDir := b.tlsServerAndClient(c)
+	createTestData(c, dataDir)
+	status, _ := deletePrefix(c, client, url+"*")
+	c.Assert(status, gc.Equals, http.StatusUnauthorized)
+	_, err := os.Stat(filepath.Join(dataDir, "foo"))
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func deleteWithHeader(c *gc.C, url, header, value string) int {
+	req, err := http.NewRequest("DELETE", url, nil)
+	c.Assert(err, jc.ErrorIsNil)
+	req.Header.Set(header, value)
+	resp, err := http.DefaultClient.Do(req)
+	c.Assert(err, jc.ErrorIsNil)
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func moveRequest(c *gc.C, url, dest, overwrite string) int {
+	req, err := http.NewRequest("MOVE", url, nil)
+	c.Assert(err, jc.ErrorIsNil)
+	req.Header.Set(httpstorage.DestinationHeader, dest)
+	req.Header.Set(httpstorage.OverwriteHeader, overwrite)
+	resp, err := http.DefaultClient.Do(req)
+	c.Assert(err, jc.ErrorIsNil)
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func (s *backendSuite) TestMoveInvalidHeaders(c *gc.C) {
+	listener, url, dataDir := startServer(c)
+	defer listener.Close()
+	err := ioutil.WriteFile(filepath.Join(dataDir, "foo"), []byte("foo"), 0644)
+	c.Assert(err, jc.ErrorIsNil)
+
+	c.Assert(moveRequest(c, url+"foo", "", "true"), gc.Equals, http.StatusBadRequest)
+	c.Assert(moveRequest(c, url+"foo", "foo", "true"), gc.Equals, http.StatusBadRequest)
+	c.Assert(moveRequest(c, url+"foo", "bar", "yes"), gc.Equals, http.StatusBadRequest)
+	_, err = os.Stat(filepath.Join(dataDir, "foo"))
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+// movingStorage wraps a storage, implementing MoveStorage.
+type movingStorage struct {
+	storage.Storage
+	moved []string
+}
+
+func (s *movingStorage) Move(from, to string) error {
+	s.moved = append(s.moved, from+" "+to)
+	r, err := s.Storage.Get(from)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if err := s.Storage.Put(to, bytes.NewReader(data), int64(len(data))); err != nil {
+		return err
+	}
+	return s.Storage.Remove(from)
+}
+
+func (s *backendSuite) TestMoveStorage(c *gc.C) {
+	dataDir := c.MkDir()
+	embedded, err := filestorage.NewFileStorageWriter(dataDir)
+	c.Assert(err, jc.ErrorIsNil)
+	stor := &movingStorage{Storage: embedded}
+	listener, err := httpstorage.Serve("localhost:0", stor)
+	c.Assert(err, jc.ErrorIsNil)
+	defer listener.Close()
+	url := fmt.Sprintf("http://%s/", listener.Addr())
+	err = ioutil.WriteFile(filepath.Join(dataDir, "foo"), []byte("foo"), 0644)
+	c.Assert(err, jc.ErrorIsNil)
+
+	c.Assert(moveRequest(c, url+"foo", "bar", "false"), gc.Equals, http.StatusCreated)
+	c.Assert(moveRequest(c, url+"missing", "bar", "true"), gc.Equals, http.StatusNotFound)
+	c.Assert(stor.moved, jc.DeepEquals, []string{"foo bar"})
+	data, err := ioutil.ReadFile(filepath.Join(dataDir, "bar"))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(string(data), gc.Equals, "foo")
+}
+
+func (s *backendSuite) TestRemoveIfMatch(c *gc.C) {
+	listener, url, dataDir := startServer(c)
+	defer listener.Close()
+	fp := filepath.Join(dataDir, "fox")
+	content := "the quick brown fox"
+	modTime := time.Date(2015, 4, 1, 12, 0, 0, 0, time.UTC)
+	opaque := fmt.Sprintf(`"%x-%x"`, len(content), modTime.UnixNano())
+
+	// The storage's entity tags are weak, so only "*" matches.
+	for i, test := range []struct {
+		ifMatch string
+		status  int
+	}{
+		{`"0123"`, http.StatusPreconditionFailed},
+		{"W/" + opaque, http.StatusPreconditionFailed},
+		{opaque, http.StatusPreconditionFailed},
+		{`"0123", W/` + opaque, http.StatusPreconditionFailed},
+		{"*", http.StatusOK},
+	} {
+		c.Logf("test %d: %s", i, test.ifMatch)
+		err := ioutil.WriteFile(fp, []byte(content), 0644)
+		c.Assert(err, jc.ErrorIsNil)
+		err = os.Chtimes(fp, modTime, modTime)
+		c.Assert(err, jc.ErrorIsNil)
+		status := deleteWithHeader(c, url+"fox", "If-Match", test.ifMatch)
+		c.Check(status, gc.Equals, test.status)
+		_, err = os.Stat(fp)
+		c.Check(os.IsNotExist(err), gc.Equals, test.status == http.StatusOK)
+	}
+
+	// A missing object matches nothing.
+	status := deleteWithHeader(c, url+"fox", "If-Match", "*")
+	c.Assert(status, gc.Equals, http.StatusPreconditionFailed)
+}
+
+func putWithHeader(c *gc.C, url, content, header, value string) int {
+	req, err := http.NewRequest("PUT", url, strings.NewReader(content))
+	c.Assert(err, jc.ErrorIsNil)
+	req.Header.Set(header, value)
+	resp, err := http.DefaultClient.Do(req)
+	c.Assert(err, jc.ErrorIsNil)
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func (s *backendSuite) TestPutIfMatch(c *gc.C) {
+	listener, url, dataDir := startServer(c)
+	defer listener.Close()
+	fp := filepath.Join(dataDir, "fox")
+	content := "the quick brown fox"
+	modTime := time.Date(2015, 4, 1, 12, 0, 0, 0, time.UTC)
+	opaque := fmt.Sprintf(`"%x-%x"`, len(content), modTime.UnixNano())
+
+	// A missing object matches nothing.
+	status := putWithHeader(c, url+"fox", "new", "If-Match", "*")
+	c.Assert(status, gc.Equals, http.StatusPreconditionFailed)
+
+	// The storage's entity tags are weak, so only "*" matches.
+	for i, test := range []struct {
+		ifMatch string
+		status  int
+	}{
+		{`"0123"`, http.StatusPreconditionFailed},
+		{"W/" + opaque, http.StatusPreconditionFailed},
+		{opaque, http.StatusPreconditionFailed},
+		{`"0123", W/` + opaque, http.StatusPreconditionFailed},
+		{"*", http.StatusCreated},
+	} {
+		c.Logf("test %d: %s", i, test.ifMatch)
+		err := ioutil.WriteFile(fp, []byte(content), 0644)
+		c.Assert(err, jc.ErrorIsNil)
+		err = os.Chtimes(fp, modTime, modTime)
+		c.Assert(err, jc.ErrorIsNil)
+		status := putWithHeader(c, url+"fox", "new", "If-Match", test.ifMatch)
+		c.Check(status, gc.Equals, test.status)
+		data, err := ioutil.ReadFile(fp)
+		c.Assert(err, jc.ErrorIsNil)
+		if test.status == http.StatusCreated {
+			c.Check(string(data), gc.Equals, "new")
+		} else {
+			c.Check(string(data), gc.Equals, content)
+		}
+	}
+}
+
+func (s *backendSuite) TestPutIfNoneMatch(c *gc.C) {
+	listener, url, dataDir := startServer(c)
+	defer listener.Close()
+	fp := filepath.Join(dataDir, "fox")
+	content := "the quick brown fox"
+
+	// Only a missing object is created with "If-None-Match: *".
+	c.Assert(putWithHeader(c, url+"fox", content, "If-None-Match", "*"), gc.Equals, http.StatusCreated)
+	c.Assert(putWithHeader(c, url+"fox", "new", "If-None-Match", "*"), gc.Equals, http.StatusPreconditionFailed)
+	data, err := ioutil.ReadFile(fp)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(string(data), gc.Equals, content)
+
+	// An object is only replaced if it does not match.
+	etag := fileETag(c, fp)
+	c.Assert(putWithHeader(c, url+"fox", "new", "If-None-Match", etag), gc.Equals, http.StatusPreconditionFailed)
+	c.Assert(putWithHeader(c, url+"fox", "new", "If-None-Match", `"0123"`), gc.Equals, http.StatusCreated)
+	data, err = ioutil.ReadFile(fp)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(string(data), gc.Equals, "new")
+}
+
+// hashingStorage wraps a storage, reporting
+// the hashes of its objects' contents.
+type hashingStorage struct {
+	storage.Storage
+}
+
+func (s *hashingStorage) Hash(name string) (string, error) {
+	r, err := s.Get(name)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, r); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
+
+// fileETag returns the weak entity tag of the file at the given path,
+// held in a storage that reports sizes and modification times.
+func fileETag(c *gc.C, path string) string {
+	info, err := os.Stat(path)
+	c.Assert(err, jc.ErrorIsNil)
+	return fmt.Sprintf(`W/"%x-%x"`, info.Size(), info.ModTime().UnixNano())
+}
+
+func (s *backendSuite) TestPutIfMatchHashStorage(c *gc.C) {
+	dataDir := c.MkDir()
+	embedded, err := filestorage.NewFileStorageWriter(dataDir)
+	c.Assert(err, jc.ErrorIsNil)
+	listener, err := httpstorage.Serve("localhost:0", &hashingStorage{embedded})
+	c.Assert(err, jc.ErrorIsNil)
+	defer listener.Close()
+	url := fmt.Sprintf("http://%s/", listener.Addr())
+
+	content := "the quick brown fox"
+	etag := fmt.Sprintf(`"%x"`, sha256.Sum256([]byte(content)))
+	c.Assert(putContent(c, url+"fox", content), gc.Equals, http.StatusCreated)
+	c.Assert(putWithHeader(c, url+"fox", "new", "If-Match", `"0123"`), gc.Equals, http.StatusPreconditionFailed)
+	c.Assert(putWithHeader(c, url+"fox", "new", "If-Match", etag), gc.Equals, http.StatusCreated)
+	c.Assert(getContent(c, url+"fox"), gc.Equals, "new")
+}
+
+func (s *backendSuite) TestRemoveIfUnmodifiedSince(c *gc.C) {
+	listener, url, dataDir := startServer(c)
+	defer listener.Close()
+	fp := filepath.Join(dataDir, "fox")
+	modTime := time.Date(2015, 4, 1, 12, 0, 0, 500e6, time.UTC)
+
+	for i, test := range []struct {
+		since  string
+		status int
+	}{
+		{modTime.Add(-time.Second).Format(http.TimeFormat), http.StatusPreconditionFailed},
+		{"yesterday", http.StatusBadRequest},
+		{modTime.Format(http.TimeFormat), http.StatusOK},
+		{modTime.Add(time.Hour).Format(http.TimeFormat), http.StatusOK},
+	} {
+		c.Logf("test %d: %s", i, test.since)
+		err := ioutil.WriteFile(fp, []byte("the quick brown fox"), 0644)
+		c.Assert(err, jc.ErrorIsNil)
+		err = os.Chtimes(fp, modTime, modTime)
+		c.Assert(err, jc.ErrorIsNil)
+		status := deleteWithHeader(c, url+"fox", "If-Unmodified-Since", test.since)
+		c.Check(status, gc.Equals, test.status)
+		_, err = os.Stat(fp)
+		c.Check(os.IsNotExist(err), gc.Equals, test.status == http.StatusOK)
+	}
+
+	status := deleteWithHeader(c, url+"fox", "If-Unmodified-Since", modTime.Format(http.TimeFormat))
+	c.Assert(status, gc.Equals, http.StatusPreconditionFailed)
+}
+
+func (s *backendSuite) TestRemoveIfUnmodifiedSinceUnknownModTime(c *gc.C) {
+	dataDir := c.MkDir()
+	embedded, err := filestorage.NewFileStorageWriter(dataDir)
+	c.Assert(err, jc.ErrorIsNil)
+	// Hide the storage's ModTime method.
+	listener, err := httpstorage.Serve("localhost:0", struct{ storage.Storage }{embedded})
+	c.Assert(err, jc.ErrorIsNil)
+	defer listener.Close()
+	fp := filepath.Join(dataDir, "fox")
+	err = ioutil.WriteFile(fp, []byte("the quick brown fox"), 0644)
+	c.Assert(err, jc.ErrorIsNil)
+
+	url := fmt.Sprintf("http://%s/fox", listener.Addr())
+	since := time.Now().Add(time.Hour).Format(http.TimeFormat)
+	status := deleteWithHeader(c, url, "If-Unmodified-Since", since)
+	c.Assert(status, gc.Equals, http.StatusPreconditionFailed)
+	_, err = os.Stat(fp)
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+var rangeTests = []struct {
+	about        string
+	rangeHeader  string
+	status       int
+	contentRange string
+	content      string
+}{{
+	about:   "no range",
+	status:  http.StatusOK,
+	content: "0123456789",
+}, {
+	about:        "closed range",
+	rangeHeader:  "bytes=2-5",
+	status:       http.StatusPartialContent,
+	contentRange: "bytes 2-5/10",
+	content:      "2345",
+}, {
+	about:        "closed range beyond end",
+	rangeHeader:  "bytes=7-20",
+	status:       http.StatusPartialContent,
+	contentRange: "bytes 7-9/10",
+	content:      "789",
+}, {
+	about:        "open-ended range",
+	rangeHeader:  "bytes=6-",
+	status:       http.StatusPartialContent,
+	contentRange: "bytes 6-9/10",
+	content:      "6789",
+}, {
+	about:        "suffix range",
+	rangeHeader:  "bytes=-3",
+	status:       http.StatusPartialContent,
+	contentRange: "bytes 7-9/10",
+	content:      "789",
+}, {
+	about:        "suffix range longer than object",
+	rangeHeader:  "bytes=-500",
+	status:       http.StatusPartialContent,
+	contentRange: "bytes 0-9/10",
+	content:      "0123456789",
+}, {
+	about:        "range starting at end",
+	rangeHeader:  "bytes=10-",
+	status:       http.StatusRequestedRangeNotSatisfiable,
+	contentRange: "bytes */10",
+}, {
+	about:        "range starting beyond end",
+	rangeHeader:  "bytes=500-600",
+	status:       http.StatusRequestedRangeNotSatisfiable,
+	contentRange: "bytes */10",
+}, {
+	about:        "empty suffix range",
+	rangeHeader:  "bytes=-0",
+	status:       http.StatusRequestedRangeNotSatisfiable,
+	contentRange: "bytes */10",
+}, {
+	about:       "multiple ranges",
+	rangeHeader: "bytes=0-1,4-5",
+	status:      http.StatusOK,
+	content:     "0123456789",
+}, {
+	about:       "reversed range",
+	rangeHeader: "bytes=5-2",
+	status:      http.StatusOK,
+	content:     "0123456789",
+}, {
+	about:       "malformed range",
+	rangeHeader: "bytes=two-five",
+	status:      http.StatusOK,
+	content:     "0123456789",
+}, {
+	about:       "unknown unit",
+	rangeHeader: "lines=1-2",
+	status:      http.StatusOK,
+	content:     "0123456789",
+}}
+
+func testGetRange(c *gc.C, url string) {
+	for i, test := range rangeTests {
+		c.Logf("test %d: %s", i, test.about)
+		req, err := http.NewRequest("GET", url, nil)
+		c.Assert(err, jc.ErrorIsNil)
+		if test.rangeHeader != "" {
+			req.Header.Set("Range", test.rangeHeader)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		c.Assert(err, jc.ErrorIsNil)
+		data, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		c.Assert(err, jc.ErrorIsNil)
+		c.Check(resp.StatusCode, gc.Equals, test.status)
+		c.Check(resp.Header.Get("Accept-Ranges"), gc.Equals, "bytes")
+		c.Check(resp.Header.Get("Content-Range"), gc.Equals, test.contentRange)
+		if test.status != http.StatusRequestedRangeNotSatisfiable {
+			c.Check(string(data), gc.Equals, test.content)
+		}
+	}
+}
+
+func (s *backendSuite) TestGetRange(c *gc.C) {
+	listener, url, dataDir := startServer(c)
+	defer listener.Close()
+	err := ioutil.WriteFile(filepath.Join(dataDir, "digits"), []byte("0123456789"), 0644)
+	c.Assert(err, jc.ErrorIsNil)
+	testGetRange(c, url+"digits")
+}
+
+func (s *backendSuite) TestGetRangeCached(c *gc.C) {
+	stor, listener, url, _ := startCachingServer(c, httpstorage.ServeOpts{CacheBytes: 1024})
+	defer listener.Close()
+	c.Assert(putContent(c, url+"digits", "0123456789"), gc.Equals, http.StatusCreated)
+	testGetRange(c, url+"digits")
+	c.Assert(stor.count("digits"), gc.Equals, 1)
+}
+
+func (s *backendSuite) TestGetRangeEmptyObject(c *gc.C) {
+	listener, url, dataDir := startServer(c)
+	defer listener.Close()
+	err := ioutil.WriteFile(filepath.Join(dataDir, "empty"), nil, 0644)
+	c.Assert(err, jc.ErrorIsNil)
+	for _, rangeHeader := range []string{"bytes=0-", "bytes=-1"} {
+		req, err := http.NewRequest("GET", url+"empty", nil)
+		c.Assert(err, jc.ErrorIsNil)
+		req.Header.Set("Range", rangeHeader)
+		resp, err := http.DefaultClient.Do(req)
+		c.Assert(err, jc.ErrorIsNil)
+		resp.Body.Close()
+		c.Check(resp.StatusCode, gc.Equals, http.StatusRequestedRangeNotSatisfiable)
+		c.Check(resp.Header.Get("Content-Range"), gc.Equals, "bytes */0")
+	}
+}
+
+// seekTrackingStorage wraps a storage, whose readers must seek,
+// counting the bytes read from the objects it returns.
+type seekTrackingStorage struct {
+	storage.Storage
+	mu   sync.Mutex
+	read int64
+}
+
+func (s *seekTrackingStorage) Get(name string) (io.ReadCloser, error) {
+	r, err := s.Storage.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	return &seekTrackingReader{r.(io.ReadSeeker), r, s}, nil
+}
+
+func (s *seekTrackingStorage) bytesRead() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.read
+}
+
+type seekTrackingReader struct {
+	io.ReadSeeker
+	io.Closer
+	stor *seekTrackingStorage
+}
+
+func (r *seekTrackingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadSeeker.Read(p)
+	r.stor.mu.Lock()
+	r.stor.read += int64(n)
+	r.stor.mu.Unlock()
+	return n, err
+}
+
+func (s *backendSuite) TestGetRangeSeekable(c *gc.C) {
+	dataDir := c.MkDir()
+	embedded, err := filestorage.NewFileStorageWriter(dataDir)
+	c.Assert(err, jc.ErrorIsNil)
+	stor := &seekTrackingStorage{Storage: embedded}
+	listener, err := httpstorage.Serve("localhost:0", stor)
+	c.Assert(err, jc.ErrorIsNil)
+	defer listener.Close()
+	url := fmt.Sprintf("http://%s/", listener.Addr())
+	content := strings.Repeat("0123456789", 100000)
+	err = ioutil.WriteFile(filepath.Join(dataDir, "digits"), []byte(content), 0644)
+	c.Assert(err, jc.ErrorIsNil)
+
+	req, err := http.NewRequest("GET", url+"digits", nil)
+	c.Assert(err, jc.ErrorIsNil)
+	req.Header.Set("Range", "bytes=500000-500003")
+	resp, err := http.DefaultClient.Do(req)
+	c.Assert(err, jc.ErrorIsNil)
+	data, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusPartialContent)
+	c.Assert(resp.Header.Get("Content-Range"), gc.Equals, "bytes 500000-500003/1000000")
+	c.Assert(string(data), gc.Equals, "0123")
+	// Only the bytes in the range were read.
+	c.Assert(stor.bytesRead(), gc.Equals, int64(4))
+}
+
+// failingReadStorage wraps a storage, reporting the sizes of its
+// objects but failing to read more than limit bytes of any of them.
+type failingReadStorage struct {
+	storage.Storage
+	limit int64
+}
+
+func (s *failingReadStorage) Size(name string) (int64, error) {
+	return s.Storage.(httpstorage.StatStorage).Size(name)
+}
+
+func (s *failingReadStorage) Get(name string) (io.ReadCloser, error) {
+	r, err := s.Storage.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(io.MultiReader(
+		io.LimitReader(r, s.limit),
+		&errorReader{errors.New("disk on fire")},
+	)), nil
+}
+
+type errorReader struct {
+	err error
+}
+
+func (r *errorReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
+
+func (s *backendSuite) TestGetStreams(c *gc.C) {
+	dataDir := c.MkDir()
+	embedded, err := filestorage.NewFileStorageWriter(dataDir)
+	c.Assert(err, jc.ErrorIsNil)
+	listener, err := httpstorage.Serve("localhost:0", &failingReadStorage{embedded, 1000})
+	c.Assert(err, jc.ErrorIsNil)
+	defer listener.Close()
+	url := fmt.Sprintf("http://%s/", listener.Addr())
+	content := strings.Repeat("0123456789", 100000)
+	err = ioutil.WriteFile(filepath.Join(dataDir, "digits"), []byte(content), 0644)
+	c.Assert(err, jc.ErrorIsNil)
+
+	// The response is started, with the object's length, before the
+	// object is read, so the client gets the bytes read before the
+	// failure and then finds the response cut short.
+	resp, err := http.Get(url + "digits")
+	c.Assert(err, jc.ErrorIsNil)
+	defer resp.Body.Close()
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusOK)
+	c.Assert(resp.ContentLength, gc.Equals, int64(len(content)))
+	data, err := ioutil.ReadAll(resp.Body)
+	c.Assert(err, gc.Equals, io.ErrUnexpectedEOF)
+	c.Assert(string(data), gc.Equals, content[:1000])
+}
+
+func (s *backendSuite) TestGetStreamsUnknownSizeCached(c *gc.C) {
+	dataDir := c.MkDir()
+	embedded, err := filestorage.NewFileStorageWriter(dataDir)
+	c.Assert(err, jc.ErrorIsNil)
+	// Embedding the storage hides its Size method.
+	stor := struct{ storage.Storage }{&failingReadStorage{embedded, 2000}}
+	listener, err := httpstorage.ServeWithOpts("localhost:0", stor, httpstorage.ServeOpts{CacheBytes: 1024})
+	c.Assert(err, jc.ErrorIsNil)
+	defer listener.Close()
+	url := fmt.Sprintf("http://%s/", listener.Addr())
+	content := strings.Repeat("0123456789", 100000)
+	err = ioutil.WriteFile(filepath.Join(dataDir, "digits"), []byte(content), 0644)
+	c.Assert(err, jc.ErrorIsNil)
+
+	// The object is too large to cache, so it is streamed
+	// rather than read whole; the client gets the bytes
+	// read before the failure.
+	resp, err := http.Get(url + "digits")
+	c.Assert(err, jc.ErrorIsNil)
+	defer resp.Body.Close()
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusOK)
+	c.Assert(resp.ContentLength, gc.Equals, int64(-1))
+	data, err := ioutil.ReadAll(resp.Body)
+	c.Assert(err, gc.Equals, io.ErrUnexpectedEOF)
+	c.Assert(string(data), gc.Equals, content[:2000])
+}
+
+func (s *backendSuite) TestGetContentLength(c *gc.C) {
+	listener, url, dataDir := startServer(c)
+	defer listener.Close()
+	err := ioutil.WriteFile(filepath.Join(dataDir, "digits"), []byte("0123456789"), 0644)
+	c.Assert(err, jc.ErrorIsNil)
+	resp, err := http.Get(url + "digits")
+	c.Assert(err, jc.ErrorIsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusOK)
+	c.Assert(resp.ContentLength, gc.Equals, int64(10))
+}
+
+// getEncoded gets the url with the given Accept-Encoding header,
+// returning the response and its body, which is not decoded.
+func getEncoded(c *gc.C, url, acceptEncoding, rangeHeader string) (*http.Response, []byte) {
+	req, err := http.NewRequest("GET", url, nil)
+	c.Assert(err, jc.ErrorIsNil)
+	// Setting the header stops the transport from decoding the body.
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+	if rangeHeader != "" {
+		req.Header.Set("Range", rangeHeader)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	c.Assert(err, jc.ErrorIsNil)
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	c.Assert(err, jc.ErrorIsNil)
+	return resp, data
+}
+
+func gunzip(c *gc.C, data []byte) string {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	c.Assert(err, jc.ErrorIsNil)
+	decoded, err := ioutil.ReadAll(zr)
+	c.Assert(err, jc.ErrorIsNil)
+	return string(decoded)
+}
+
+func (s *backendSuite) TestGetGzip(c *gc.C) {
+	content := strings.Repeat("0123456789", 1000)
+	for _, cached := range []bool{false, true} {
+		c.Logf("cached: %v", cached)
+		opts := httpstorage.ServeOpts{GzipResponses: true}
+		if cached {
+			opts.CacheBytes = 1 << 20
+		}
+		_, listener, url, dataDir := startCachingServer(c, opts)
+		err := ioutil.WriteFile(filepath.Join(dataDir, "digits"), []byte(content), 0644)
+		c.Assert(err, jc.ErrorIsNil)
+
+		resp, data := getEncoded(c, url+"digits", "deflate, gzip", "")
+		c.Check(resp.StatusCode, gc.Equals, http.StatusOK)
+		c.Check(resp.Header.Get("Content-Encoding"), gc.Equals, "gzip")
+		c.Check(resp.Header.Get("Vary"), gc.Equals, "Accept-Encoding")
+		c.Check(len(data) < len(content), jc.IsTrue)
+		c.Check(gunzip(c, data), gc.Equals, content)
+
+		// Clients that do not accept gzip get the object as it is.
+		for _, acceptEncoding := range []string{"", "deflate", "gzip;q=0"} {
+			resp, data = getEncoded(c, url+"digits", acceptEncoding, "")
+			c.Check(resp.StatusCode, gc.Equals, http.StatusOK)
+			c.Check(resp.Header.Get("Content-Encoding"), gc.Equals, "")
+			c.Check(string(data), gc.Equals, content)
+		}
+
+		// Byte ranges and errors are not encoded.
+		resp, data = getEncoded(c, url+"digits", "gzip", "bytes=2-5")
+		c.Check(resp.StatusCode, gc.Equals, http.StatusPartialContent)
+		c.Check(resp.Header.Get("Content-Encoding"), gc.Equals, "")
+		c.Check(string(data), gc.Equals, "2345")
+		resp, _ = getEncoded(c, url+"missing", "gzip", "")
+		c.Check(resp.StatusCode, gc.Equals, http.StatusNotFound)
+		c.Check(resp.Header.Get("Content-Encoding"), gc.Equals, "")
+		listener.Close()
+	}
+}
+
+func (s *backendSuite) TestGetGzipNotEnabled(c *gc.C) {
+	listener, url, dataDir := startServer(c)
+	defer listener.Close()
+	err := ioutil.WriteFile(filepath.Join(dataDir, "digits"), []byte("0123456789"), 0644)
+	c.Assert(err, jc.ErrorIsNil)
+	resp, data := getEncoded(c, url+"digits", "gzip", "")
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusOK)
+	c.Assert(resp.Header.Get("Content-Encoding"), gc.Equals, "")
+	c.Assert(string(data), gc.Equals, "0123456789")
+}
+
+func (s *backendSuite) TestGetGzipClient(c *gc.C) {
+	content := strings.Repeat("0123456789", 1000)
+	for _, gzipResponses := range []bool{false, true} {
+		c.Logf("gzip responses: %v", gzipResponses)
+		_, listener, _, dataDir := startCachingServer(c, httpstorage.ServeOpts{
+			GzipResponses: gzipResponses,
+		})
+		err := ioutil.WriteFile(filepath.Join(dataDir, "digits"), []byte(content), 0644)
+		c.Assert(err, jc.ErrorIsNil)
+		r, err := httpstorage.Client(listener.Addr().String()).Get("digits")
+		c.Assert(err, jc.ErrorIsNil)
+		data, err := ioutil.ReadAll(r)
+		c.Assert(err, jc.ErrorIsNil)
+		c.Assert(r.Close(), jc.ErrorIsNil)
+		c.Assert(string(data), gc.Equals, content)
+		listener.Close()
+	}
+}
+
+// getIfNoneMatch gets the url with the given If-None-Match
+// header, returning the response and its body.
+func getIfNoneMatch(c *gc.C, method, url, ifNoneMatch string) (*http.Response, string) {
+	req, err := http.NewRequest(method, url, nil)
+	c.Assert(err, jc.ErrorIsNil)
+	if ifNoneMatch != "" {
+		req.Header.Set("If-None-Match", ifNoneMatch)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	c.Assert(err, jc.ErrorIsNil)
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	c.Assert(err, jc.ErrorIsNil)
+	return resp, string(data)
+}
+
+func testGetETag(c *gc.C, url, etag string) {
+	content := "0123456789"
+	for i, test := range []struct {
+		ifNoneMatch string
+		status      int
+	}{
+		{"", http.StatusOK},
+		{`"0123"`, http.StatusOK},
+		{etag, http.StatusNotModified},
+		{"W/" + strings.TrimPrefix(etag, "W/"), http.StatusNotModified},
+		{strings.TrimPrefix(etag, "W/"), http.StatusNotModified},
+		{`"0123", ` + etag, http.StatusNotModified},
+		{"*", http.StatusNotModified},
+	} {
+		c.Logf("test %d: %s", i, test.ifNoneMatch)
+		resp, data := getIfNoneMatch(c, "GET", url, test.ifNoneMatch)
+		c.Check(resp.StatusCode, gc.Equals, test.status)
+		c.Check(resp.Header.Get("ETag"), gc.Equals, etag)
+		if test.status == http.StatusOK {
+			c.Check(data, gc.Equals, content)
+		} else {
+			c.Check(data, gc.Equals, "")
+		}
+	}
+}
+
+func (s *backendSuite) TestGetETag(c *gc.C) {
+	listener, url, dataDir := startServer(c)
+	defer listener.Close()
+	fp := filepath.Join(dataDir, "digits")
+	err := ioutil.WriteFile(fp, []byte("0123456789"), 0644)
+	c.Assert(err, jc.ErrorIsNil)
+	testGetETag(c, url+"digits", fileETag(c, fp))
+}
+
+func (s *backendSuite) TestGetETagFilesystemStorage(c *gc.C) {
+	dataDir := c.MkDir()
+	stor, err := httpstorage.NewFilesystemStorage(dataDir)
+	c.Assert(err, jc.ErrorIsNil)
+	listener, err := httpstorage.Serve("localhost:0", stor)
+	c.Assert(err, jc.ErrorIsNil)
+	defer listener.Close()
+	fp := filepath.Join(dataDir, "digits")
+	err = ioutil.WriteFile(fp, []byte("0123456789"), 0644)
+	c.Assert(err, jc.ErrorIsNil)
+	testGetETag(c, fmt.Sprintf("http://%s/digits", listener.Addr()), fileETag(c, fp))
+}
+
+func (s *backendSuite) TestGetETagHashStorage(c *gc.C) {
+	dataDir := c.MkDir()
+	embedded, err := filestorage.NewFileStorageWriter(dataDir)
+	c.Assert(err, jc.ErrorIsNil)
+	listener, err := httpstorage.Serve("localhost:0", &hashingStorage{embedded})
+	c.Assert(err, jc.ErrorIsNil)
+	defer listener.Close()
+	err = ioutil.WriteFile(filepath.Join(dataDir, "digits"), []byte("0123456789"), 0644)
+	c.Assert(err, jc.ErrorIsNil)
+	etag := fmt.Sprintf(`"%x"`, sha256.Sum256([]byte("0123456789")))
+	testGetETag(c, fmt.Sprintf("http://%s/digits", listener.Addr()), etag)
+}
+
+func (s *backendSuite) TestGetETagCached(c *gc.C) {
+	stor, listener, url, _ := startCachingServer(c, httpstorage.ServeOpts{CacheBytes: 1024})
+	defer listener.Close()
+	c.Assert(putContent(c, url+"digits", "0123456789"), gc.Equals, http.StatusCreated)
+	// The storage cannot tag its objects, so the
+	// tag is derived from the cached contents.
+	etag := fmt.Sprintf(`"%x"`, sha256.Sum256([]byte("0123456789")))
+	testGetETag(c, url+"digits", etag)
+	c.Assert(stor.count("digits"), gc.Equals, 1)
+}
+
+func (s *backendSuite) TestGetETagGzip(c *gc.C) {
+	_, listener, url, dataDir := startCachingServer(c, httpstorage.ServeOpts{
+		CacheBytes:    1024,
+		GzipResponses: true,
+	})
+	defer listener.Close()
+	err := ioutil.WriteFile(filepath.Join(dataDir, "digits"), []byte("0123456789"), 0644)
+	c.Assert(err, jc.ErrorIsNil)
+	etag := fmt.Sprintf(`"%x"`, sha256.Sum256([]byte("0123456789")))
+	resp, _ := getEncoded(c, url+"digits", "gzip", "")
+	c.Assert(resp.Header.Get("ETag"), gc.Equals, "W/"+etag)
+	resp, _ = getIfNoneMatch(c, "GET", url+"digits", "W/"+etag)
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusNotModified)
+	c.Assert(resp.Header.Get("ETag"), gc.Equals, "W/"+etag)
+}
+
+func (s *backendSuite) TestHeadETag(c *gc.C) {
+	listener, url, dataDir := startServerTLS(c)
+	defer listener.Close()
+	fp := filepath.Join(dataDir, "digits")
+	err := ioutil.WriteFile(fp, []byte("0123456789"), 0644)
+	c.Assert(err, jc.ErrorIsNil)
+	etag := fileETag(c, fp)
+
+	resp, _ := getIfNoneMatch(c, "HEAD", url+"digits", "")
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusOK)
+	c.Assert(resp.Header.Get("ETag"), gc.Equals, etag)
+	c.Assert(resp.Header.Get("Location"), gc.Matches, "https://.*/digits")
+	resp, _ = getIfNoneMatch(c, "HEAD", url+"digits", etag)
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusNotModified)
+	resp, _ = getIfNoneMatch(c, "HEAD", url+"missing", "")
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusOK)
+	c.Assert(resp.Header.Get("ETag"), gc.Equals, "")
+}
+
+func getHealth(c *gc.C, url string) (int, string) {
+	resp, err := http.Get(url + "_juju_health")
+	c.Assert(err, jc.ErrorIsNil)
+	defer resp.Body.Close()
+	c.Assert(resp.Header.Get("Content-Type"), gc.Equals, "application/json")
+	data, err := ioutil.ReadAll(resp.Body)
+	c.Assert(err, jc.ErrorIsNil)
+	return resp.StatusCode, string(data)
+}
+
+func (s *backendSuite) TestHealth(c *gc.C) {
+	listener, url, _ := startServer(c)
+	defer listener.Close()
+	status, body := getHealth(c, url)
+	c.Assert(status, gc.Equals, http.StatusOK)
+	c.Assert(body, gc.Equals, `{"status":"ok"}`)
+}
+
+func (s *backendSuite) TestHealthMulti(c *gc.C) {
+	listener, _ := startServerMulti(c)
+	defer listener.Close()
+	status, body := getHealth(c, fmt.Sprintf("http://%s/", listener.Addr()))
+	c.Assert(status, gc.Equals, http.StatusOK)
+	c.Assert(body, gc.Equals, `{"status":"ok"}`)
+}
+
+func (s *backendSuite) TestHealthListError(c *gc.C) {
+	dataDir := c.MkDir()
+	embedded, err := filestorage.NewFileStorageWriter(dataDir)
+	c.Assert(err, jc.ErrorIsNil)
+	listener, err := httpstorage.Serve("localhost:0", brokenListStorage{embedded})
+	c.Assert(err, jc.ErrorIsNil)
+	defer listener.Close()
+
+	status, body := getHealth(c, fmt.Sprintf("http://%s/", listener.Addr()))
+	c.Assert(status, gc.Equals, http.StatusServiceUnavailable)
+	c.Assert(body, gc.Equals, `{"status":"unavailable","error":"storage \"\": storage is broken"}`)
+}
+
+type brokenListStorage struct {
+	storage.Storage
+}
+
+func (brokenListStorage) List(prefix string) ([]string, error) {
+	return nil, fmt.Errorf("storage is broken")
+}
+
+type hangingListStorage struct {
+	storage.Storage
+	release chan struct{}
+}
+
+func (s hangingListStorage) List(prefix string) ([]string, error) {
+	<-s.release
+	return nil, nil
+}
+
+func (s *backendSuite) TestHealthTimeout(c *gc.C) {
+	s.PatchValue(httpstorage.HealthCheckTimeout, coretesting.ShortWait)
+	dataDir := c.MkDir()
+	embedded, err := filestorage.NewFileStorageWriter(dataDir)
+	c.Assert(err, jc.ErrorIsNil)
+	stor := hangingListStorage{embedded, make(chan struct{})}
+	defer close(stor.release)
+	listener, err := httpstorage.Serve("localhost:0", stor)
+	c.Assert(err, jc.ErrorIsNil)
+	defer listener.Close()
+
+	status, body := getHealth(c, fmt.Sprintf("http://%s/", listener.Addr()))
+	c.Assert(status, gc.Equals, http.StatusServiceUnavailable)
+	c.Assert(body, gc.Matches, `\{"status":"unavailable","error":"storage \\"\\": timed out after .*"\}`)
+}
+
+func (s *backendSuite) TestReservedNames(c *gc.C) {
+	listener, url, dataDir := startServer(c)
+	defer listener.Close()
+	createTestData(c, dataDir)
+
+	c.Assert(putContent(c, url+"_juju_health", "content"), gc.Equals, http.StatusBadRequest)
+	c.Assert(putContent(c, url+"_juju_other", "content"), gc.Equals, http.StatusBadRequest)
+	c.Assert(moveRequest(c, url+"foo", "_juju_health", "true"), gc.Equals, http.StatusBadRequest)
+	_, err := os.Stat(filepath.Join(dataDir, "foo"))
+	c.Assert(err, jc.ErrorIsNil)
+
+	// Names merely containing the prefix are not reserved.
+	c.Assert(putContent(c, url+"inner/_juju_health", "content"), gc.Equals, http.StatusCreated)
+}
+
+// recordingCollector is a MetricsCollector
+// that records the requests it observes.
+type recordingCollector struct {
+	mu       sync.Mutex
+	requests []string
+}
+
+func (m *recordingCollector) ObserveRequest(kind string, status int, duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests = append(m.requests, fmt.Sprintf("%s %d", kind, status))
+}
+
+func (m *recordingCollector) WriteMetrics(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, err := fmt.Fprintf(w, "requests %d\n", len(m.requests))
+	return err
+}
+
+func (m *recordingCollector) observed() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.requests...)
+}
+
+func (s *backendSuite) TestMetrics(c *gc.C) {
+	metrics := &recordingCollector{}
+	_, listener, url, _ := startCachingServer(c, httpstorage.ServeOpts{Metrics: metrics})
+	defer listener.Close()
+
+	c.Assert(putContent(c, url+"obj", "content"), gc.Equals, http.StatusCreated)
+	c.Assert(getContent(c, url+"obj"), gc.Equals, "content")
+	c.Assert(getContent(c, url+"ob*"), gc.Equals, "obj")
+	resp, err := http.Get(url + "missing")
+	c.Assert(err, jc.ErrorIsNil)
+	resp.Body.Close()
+	resp, err = http.Head(url + "obj")
+	c.Assert(err, jc.ErrorIsNil)
+	resp.Body.Close()
+	status, _ := doConcurrentRequest(c, "DELETE", url+"obj", "")
+	c.Assert(status, gc.Equals, http.StatusOK)
+	status, _ = doConcurrentRequest(c, "BOGUS", url+"obj", "")
+	c.Assert(status, gc.Equals, http.StatusMethodNotAllowed)
+
+	// Requests for the metrics themselves are not recorded.
+	c.Assert(getContent(c, url+"_juju_metrics"), gc.Equals, "requests 7\n")
+	c.Assert(metrics.observed(), jc.DeepEquals, []string{
+		"PUT 201",
+		"GET 200",
+		"LIST 200",
+		"GET 404",
+		"HEAD 405",
+		"DELETE 200",
+		"OTHER 405",
+	})
+}
+
+func (s *backendSuite) TestMetricsNotConfigured(c *gc.C) {
+	listener, url, _ := startServer(c)
+	defer listener.Close()
+	resp, err := http.Get(url + "_juju_metrics")
+	c.Assert(err, jc.ErrorIsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusNotFound)
+}
+
+func (s *backendSuite) TestRateLimit(c *gc.C) {
+	dataDir := c.MkDir()
+	embedded, err := filestorage.NewFileStorageWriter(dataDir)
+	c.Assert(err, jc.ErrorIsNil)
+	listener, err := httpstorage.ServeWithOpts("localhost:0", embedded, httpstorage.ServeOpts{
+		RateLimit:      0.01,
+		RateLimitBurst: 3,
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	defer listener.Close()
+	url := fmt.Sprintf("http://%s/", listener.Addr())
+	createTestData(c, dataDir)
+
+	// Every request counts towards the limit, whatever its outcome.
+	for _, name := range []string{"foo", "missing", "bar"} {
+		resp, err := http.Get(url + name)
+		c.Assert(err, jc.ErrorIsNil)
+		resp.Body.Close()
+		c.Assert(resp.StatusCode, gc.Not(gc.Equals), 429)
+	}
+	resp, err := http.Get(url + "foo")
+	c.Assert(err, jc.ErrorIsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, gc.Equals, 429)
+	c.Assert(resp.Header.Get("Retry-After"), gc.Equals, "100")
+}
+
+func (s *backendSuite) TestTLSSessionResumption(c *gc.C) {
+	listener, url, dataDir := startServerTLS(c)
+	defer listener.Close()
+	createTestData(c, dataDir)
+	resp, err := http.Head(url)
+	c.Assert(err, jc.ErrorIsNil)
+	resp.Body.Close()
+	httpsURL := resp.Header.Get("Location")
+	c.Assert(httpsURL, gc.Matches, "https://.*")
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{
+				InsecureSkipVerify: true,
+				ClientSessionCache: tls.NewLRUClientSessionCache(0),
+			},
+			// Make each request on a new connection.
+			DisableKeepAlives: true,
+		},
+	}
+	var resumed []bool
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(httpsURL + "foo")
+		c.Assert(err, jc.ErrorIsNil)
+		_, err = ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		c.Assert(err, jc.ErrorIsNil)
+		c.Assert(resp.TLS, gc.NotNil)
+		resumed = append(resumed, resp.TLS.DidResume)
+	}
+	c.Assert(resumed, jc.DeepEquals, []bool{false, true})
+}
+
+// serverCertExpiry starts a TLS server with the given options,
+// and returns the expiry time of the certificate it presents.
+func serverCertExpiry(c *gc.C, opts httpstorage.ServeOpts) time.Time {
+	embedded, err := filestorage.NewFileStorageWriter(c.MkDir())
+	c.Assert(err, jc.ErrorIsNil)
+	server, err := httpstorage.ServeTLSWithOpts(
+		"127.0.0.1:0",
+		embedded,
+		coretesting.CACert,
+		coretesting.CAKey,
+		[]string{"127.0.0.1"},
+		testAuthkey,
+		opts,
+	)
+	c.Assert(err, jc.ErrorIsNil)
+	defer server.Close()
+	resp, err := http.Head(fmt.Sprintf("http://%s/", server.Addr()))
+	c.Assert(err, jc.ErrorIsNil)
+	resp.Body.Close()
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+	}
+	resp, err = client.Get(resp.Header.Get("Location") + "*")
+	c.Assert(err, jc.ErrorIsNil)
+	resp.Body.Close()
+	c.Assert(resp.TLS, gc.NotNil)
+	return resp.TLS.PeerCertificates[0].NotAfter
+}
+
+func (s *backendSuite) TestServeTLSCertExpiry(c *gc.C) {
+	expiry := time.Now().Add(time.Hour).Truncate(time.Second)
+	notAfter := serverCertExpiry(c, httpstorage.ServeOpts{CertExpiry: expiry})
+	c.Assert(notAfter.Equal(expiry), jc.IsTrue, gc.Commentf("expiry %v", notAfter))
+}
+
+func (s *backendSuite) TestServeTLSCertExpiryDefault(c *gc.C) {
+	before := time.Now().AddDate(10, 0, 0).Add(-time.Second)
+	notAfter := serverCertExpiry(c, httpstorage.ServeOpts{})
+	after := time.Now().AddDate(10, 0, 0)
+	c.Assert(notAfter.Before(before), jc.IsFalse, gc.Commentf("expiry %v", notAfter))
+	c.Assert(notAfter.After(after), jc.IsFalse, gc.Commentf("expiry %v", notAfter))
+}
+
+func (s *backendSuite) TestServeTLSCertExpiryInPast(c *gc.C) {
+	embedded, err := filestorage.NewFileStorageWriter(c.MkDir())
+	c.Assert(err, jc.ErrorIsNil)
+	expiry := time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC)
+	_, err = httpstorage.ServeTLSWithOpts(
+		"127.0.0.1:0",
+		embedded,
+		coretesting.CACert,
+		coretesting.CAKey,
+		[]string{"127.0.0.1"},
+		testAuthkey,
+		httpstorage.ServeOpts{CertExpiry: expiry},
+	)
+	c.Assert(err, gc.ErrorMatches, `certificate expiry 2015-01-01 00:00:00 \+0000 UTC is not in the future`)
+}
+
+func (s *backendSuite) TestServeKeepAlivesDisabled(c *gc.C) {
+	embedded, err := filestorage.NewFileStorageWriter(c.MkDir())
+	c.Assert(err, jc.ErrorIsNil)
+	listener, err := httpstorage.ServeWithOpts("localhost:0", embedded, httpstorage.ServeOpts{
+		KeepAlivePeriod: -1,
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	defer listener.Close()
+	c.Assert(putContent(c, fmt.Sprintf("http://%s/obj", listener.Addr()), "data"), gc.Equals, http.StatusCreated)
+}
@@ -0,0 +1,124 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package httpstorage
+
+import (
+	"container/list"
+	"sync"
+)
+
+// objectCache holds the contents of recently read objects in memory,
+// discarding the least recently used objects to keep their total size
+// within a limit. A nil *objectCache caches nothing.
+type objectCache struct {
+	maxBytes       int64
+	maxObjectBytes int64
+
+	mu    sync.Mutex
+	bytes int64
+	// gen is incremented whenever an object is invalidated, so
+	// that contents read before the invalidation are not cached.
+	gen     uint64
+	lru     *list.List // of *cacheEntry, most recently used first
+	entries map[string]*list.Element
+}
+
+type cacheEntry struct {
+	name string
+	data []byte
+}
+
+// newObjectCache returns a cache holding up to maxBytes of objects, each
+// no larger than maxObjectBytes. If maxObjectBytes is zero, objects of
+// any size up to maxBytes are cached. If maxBytes is zero, newObjectCache
+// returns nil.
+func newObjectCache(maxBytes, maxObjectBytes int64) *objectCache {
+	if maxBytes <= 0 {
+		return nil
+	}
+	if maxObjectBytes <= 0 || maxObjectBytes > maxBytes {
+		maxObjectBytes = maxBytes
+	}
+	return &objectCache{
+		maxBytes:       maxBytes,
+		maxObjectBytes: maxObjectBytes,
+		lru:            list.New(),
+		entries:        make(map[string]*list.Element),
+	}
+}
+
+// get returns the cached contents of the named object, if any.
+func (c *objectCache) get(name string) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[name]
+	if !ok {
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+	return elem.Value.(*cacheEntry).data, true
+}
+
+// accepts reports whether an object of the given size may be cached.
+// An object of unknown size, given as a negative size, is not accepted
+// until its contents have been read.
+func (c *objectCache) accepts(size int64) bool {
+	return c != nil && size >= 0 && size <= c.maxObjectBytes
+}
+
+// generation returns a value to pass to add for
+// object contents about to be read.
+func (c *objectCache) generation() uint64 {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.gen
+}
+
+// add caches the contents of the named object, read after generation
+// returned gen. The contents are discarded if any object has been
+// invalidated since then, as they may be out of date.
+func (c *objectCache) add(name string, data []byte, gen uint64) {
+	if c == nil || int64(len(data)) > c.maxObjectBytes {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if gen != c.gen {
+		return
+	}
+	c.remove(name)
+	c.entries[name] = c.lru.PushFront(&cacheEntry{name, data})
+	c.bytes += int64(len(data))
+	for c.bytes > c.maxBytes {
+		c.remove(c.lru.Back().Value.(*cacheEntry).name)
+	}
+}
+
+// invalidate discards any cached contents of the named object.
+func (c *objectCache) invalidate(name string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	c.remove(name)
+}
+
+// remove discards the named object. It must be called with c.mu held.
+func (c *objectCache) remove(name string) {
+	elem, ok := c.entries[name]
+	if !ok {
+		return
+	}
+	c.lru.Remove(elem)
+	delete(c.entries, name)
+	c.bytes -= int64(len(elem.Value.(*cacheEntry).data))
+}
@@ -0,0 +1,13 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package httpstorage
+
+var ProgressInterval = &progressInterval
+
+var HealthCheckTimeout = &healthCheckTimeout
+
+var (
+	NewConnGracePeriod   = &newConnGracePeriod
+	ShutdownPollInterval = &shutdownPollInterval
+)
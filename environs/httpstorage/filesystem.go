@@ -0,0 +1,201 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package httpstorage
+
+import (
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/juju/utils"
+
+	"github.com/juju/juju/environs/filestorage"
+	"github.com/juju/juju/environs/storage"
+)
+
+// fileStorage is implemented by the storage returned by
+// filestorage.NewFileStorageWriter.
+type fileStorage interface {
+	storage.Storage
+	StatStorage
+	ModTimeStorage
+	AppendStorage
+}
+
+// FilesystemStorage is a storage.Storage holding its objects as files
+// in a directory of the local file system, so that Serve can front a
+// local mirror of the tools directly. Object names are slash-separated
+// paths relative to the directory; a name that could refer to a file
+// outside the directory, such as one that is absolute or that holds a
+// ".." element, is rejected with an error satisfying errors.IsNotValid.
+//
+// Names are only checked as written: symbolic links within the
+// directory are followed, wherever they point.
+type FilesystemStorage struct {
+	dir  string
+	stor fileStorage
+}
+
+var _ storage.Storage = (*FilesystemStorage)(nil)
+
+// NewFilesystemStorage returns a FilesystemStorage rooted at
+// the given directory, which must exist.
+func NewFilesystemStorage(dir string) (*FilesystemStorage, error) {
+	dir, err := utils.NormalizePath(dir)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if dir, err = filepath.Abs(dir); err != nil {
+		return nil, errors.Trace(err)
+	}
+	stor, err := filestorage.NewFileStorageWriter(dir)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &FilesystemStorage{dir: dir, stor: stor.(fileStorage)}, nil
+}
+
+// checkName returns an error if the object name, or name prefix,
+// could refer to a file outside the storage directory.
+func checkName(name string) error {
+	switch {
+	case path.IsAbs(name), filepath.IsAbs(name):
+		return errors.NotValidf("absolute object name %q", name)
+	case strings.ContainsAny(name, "\\\x00"):
+		return errors.NotValidf("object name %q", name)
+	}
+	for _, elem := range strings.Split(name, "/") {
+		if elem == ".." {
+			return errors.NotValidf("object name %q outside storage", name)
+		}
+	}
+	return nil
+}
+
+// checkObjectName is like checkName, but also rejects the empty name,
+// which refers to the storage directory itself.
+func checkObjectName(name string) error {
+	if name == "" {
+		return errors.NotValidf("empty object name")
+	}
+	return checkName(name)
+}
+
+// Get implements storage.StorageReader.Get.
+func (f *FilesystemStorage) Get(name string) (io.ReadCloser, error) {
+	if err := checkObjectName(name); err != nil {
+		return nil, err
+	}
+	return f.stor.Get(name)
+}
+
+// List implements storage.StorageReader.List.
+func (f *FilesystemStorage) List(prefix string) ([]string, error) {
+	if err := checkName(prefix); err != nil {
+		return nil, err
+	}
+	return f.stor.List(prefix)
+}
+
+// URL implements storage.StorageReader.URL.
+func (f *FilesystemStorage) URL(name string) (string, error) {
+	if err := checkObjectName(name); err != nil {
+		return "", err
+	}
+	return f.stor.URL(name)
+}
+
+// DefaultConsistencyStrategy implements
+// storage.StorageReader.DefaultConsistencyStrategy.
+func (f *FilesystemStorage) DefaultConsistencyStrategy() utils.AttemptStrategy {
+	return f.stor.DefaultConsistencyStrategy()
+}
+
+// ShouldRetry implements storage.StorageReader.ShouldRetry.
+func (f *FilesystemStorage) ShouldRetry(err error) bool {
+	return f.stor.ShouldRetry(err)
+}
+
+// Size implements StatStorage.Size.
+func (f *FilesystemStorage) Size(name string) (int64, error) {
+	if err := checkObjectName(name); err != nil {
+		return 0, err
+	}
+	return f.stor.Size(name)
+}
+
+// ModTime implements ModTimeStorage.ModTime.
+func (f *FilesystemStorage) ModTime(name string) (time.Time, error) {
+	if err := checkObjectName(name); err != nil {
+		return time.Time{}, err
+	}
+	return f.stor.ModTime(name)
+}
+
+// Put implements storage.StorageWriter.Put.
+func (f *FilesystemStorage) Put(name string, r io.Reader, length int64) error {
+	if err := checkObjectName(name); err != nil {
+		return err
+	}
+	return f.stor.Put(name, r, length)
+}
+
+// Append implements AppendStorage.Append.
+func (f *FilesystemStorage) Append(name string, r io.Reader, length int64) error {
+	if err := checkObjectName(name); err != nil {
+		return err
+	}
+	return f.stor.Append(name, r, length)
+}
+
+// Move implements MoveStorage.Move.
+func (f *FilesystemStorage) Move(from, to string) error {
+	for _, name := range []string{from, to} {
+		if err := checkObjectName(name); err != nil {
+			return err
+		}
+		// The staging directory used by Put is hidden.
+		if strings.HasPrefix(name, ".tmp") {
+			return errors.NotValidf("object name %q", name)
+		}
+	}
+	fromPath := filepath.Join(f.dir, filepath.FromSlash(from))
+	toPath := filepath.Join(f.dir, filepath.FromSlash(to))
+	if info, err := os.Stat(fromPath); os.IsNotExist(err) {
+		return errors.NotFoundf("file %q", from)
+	} else if err != nil {
+		return errors.Trace(err)
+	} else if info.IsDir() {
+		return errors.NotFoundf("file %q", from)
+	}
+	if err := os.MkdirAll(filepath.Dir(toPath), 0755); err != nil {
+		return errors.Trace(err)
+	}
+	return utils.ReplaceFile(fromPath, toPath)
+}
+
+// Remove implements storage.StorageWriter.Remove.
+func (f *FilesystemStorage) Remove(name string) error {
+	if err := checkObjectName(name); err != nil {
+		return err
+	}
+	return f.stor.Remove(name)
+}
+
+// RemoveAll implements storage.StorageWriter.RemoveAll.
+func (f *FilesystemStorage) RemoveAll() error {
+	return storage.RemoveAll(f)
+}
+
+// RemovePrefix implements storage.StorageWriter.RemovePrefix.
+func (f *FilesystemStorage) RemovePrefix(prefix string) (int, error) {
+	if err := checkName(prefix); err != nil {
+		return 0, err
+	}
+	return storage.RemovePrefix(f, prefix)
+}
@@ -0,0 +1,163 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package httpstorage_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/juju/errors"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/environs/httpstorage"
+	coretesting "github.com/juju/juju/testing"
+)
+
+type filesystemSuite struct {
+	coretesting.BaseSuite
+	outside string
+	dir     string
+	stor    *httpstorage.FilesystemStorage
+}
+
+var _ = gc.Suite(&filesystemSuite{})
+
+func (s *filesystemSuite) SetUpTest(c *gc.C) {
+	s.BaseSuite.SetUpTest(c)
+	// The storage is rooted beneath a directory
+	// holding a file it must not reach.
+	s.outside = c.MkDir()
+	err := ioutil.WriteFile(filepath.Join(s.outside, "secret"), []byte("secret"), 0644)
+	c.Assert(err, jc.ErrorIsNil)
+	s.dir = filepath.Join(s.outside, "tools")
+	err = os.MkdirAll(filepath.Join(s.dir, "releases"), 0755)
+	c.Assert(err, jc.ErrorIsNil)
+	err = ioutil.WriteFile(filepath.Join(s.dir, "releases", "juju.tgz"), []byte("tools"), 0644)
+	c.Assert(err, jc.ErrorIsNil)
+	s.stor, err = httpstorage.NewFilesystemStorage(s.dir)
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *filesystemSuite) TestNewFilesystemStorageMissingDir(c *gc.C) {
+	_, err := httpstorage.NewFilesystemStorage(filepath.Join(s.dir, "missing"))
+	c.Assert(err, gc.ErrorMatches, ".*no such file or directory")
+}
+
+func (s *filesystemSuite) TestReadWrite(c *gc.C) {
+	r, err := s.stor.Get("releases/juju.tgz")
+	c.Assert(err, jc.ErrorIsNil)
+	data, err := ioutil.ReadAll(r)
+	r.Close()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(string(data), gc.Equals, "tools")
+	size, err := s.stor.Size("releases/juju.tgz")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(size, gc.Equals, int64(5))
+
+	err = s.stor.Put("streams/index.json", strings.NewReader("{}"), 2)
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.stor.Move("streams/index.json", "streams/v1/index.json")
+	c.Assert(err, jc.ErrorIsNil)
+	names, err := s.stor.List("")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(names, jc.DeepEquals, []string{"releases/juju.tgz", "streams/v1/index.json"})
+}
+
+func (s *filesystemSuite) TestMissingFile(c *gc.C) {
+	_, err := s.stor.Get("releases/missing.tgz")
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+	_, err = s.stor.Get("releases")
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+	_, err = s.stor.Size("releases/missing.tgz")
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+	err = s.stor.Move("releases/missing.tgz", "releases/other.tgz")
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+	err = s.stor.Remove("releases/missing.tgz")
+	c.Assert(err, jc.ErrorIsNil)
+	names, err := s.stor.List("missing/")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(names, gc.HasLen, 0)
+}
+
+var escapingNames = []string{
+	"",
+	"..",
+	"../secret",
+	"releases/../../secret",
+	"/etc/passwd",
+	`..\secret`,
+	"secret\x00",
+}
+
+func (s *filesystemSuite) TestPathEscape(c *gc.C) {
+	for i, name := range escapingNames {
+		c.Logf("test %d: %q", i, name)
+		_, err := s.stor.Get(name)
+		c.Check(err, jc.Satisfies, errors.IsNotValid)
+		_, err = s.stor.Size(name)
+		c.Check(err, jc.Satisfies, errors.IsNotValid)
+		_, err = s.stor.URL(name)
+		c.Check(err, jc.Satisfies, errors.IsNotValid)
+		err = s.stor.Put(name, strings.NewReader("oops"), 4)
+		c.Check(err, jc.Satisfies, errors.IsNotValid)
+		err = s.stor.Append(name, strings.NewReader("oops"), 4)
+		c.Check(err, jc.Satisfies, errors.IsNotValid)
+		err = s.stor.Remove(name)
+		c.Check(err, jc.Satisfies, errors.IsNotValid)
+		err = s.stor.Move("releases/juju.tgz", name)
+		c.Check(err, jc.Satisfies, errors.IsNotValid)
+		err = s.stor.Move(name, "releases/stolen")
+		c.Check(err, jc.Satisfies, errors.IsNotValid)
+	}
+	_, err := s.stor.List("../")
+	c.Check(err, jc.Satisfies, errors.IsNotValid)
+	_, err = s.stor.RemovePrefix("../")
+	c.Check(err, jc.Satisfies, errors.IsNotValid)
+
+	// Nothing outside the storage directory was touched,
+	// and nothing within it was moved.
+	data, err := ioutil.ReadFile(filepath.Join(s.outside, "secret"))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(string(data), gc.Equals, "secret")
+	names, err := s.stor.List("")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(names, jc.DeepEquals, []string{"releases/juju.tgz"})
+}
+
+func (s *filesystemSuite) TestMoveRefusesTemp(c *gc.C) {
+	err := s.stor.Move("releases/juju.tgz", ".tmp/juju.tgz")
+	c.Assert(err, jc.Satisfies, errors.IsNotValid)
+}
+
+func (s *filesystemSuite) TestServe(c *gc.C) {
+	listener, err := httpstorage.Serve("localhost:0", s.stor)
+	c.Assert(err, jc.ErrorIsNil)
+	defer listener.Close()
+	url := fmt.Sprintf("http://%s/", listener.Addr())
+
+	resp, err := http.Get(url + "releases/juju.tgz")
+	c.Assert(err, jc.ErrorIsNil)
+	data, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusOK)
+	c.Assert(string(data), gc.Equals, "tools")
+
+	resp, err = http.Get(url + "releases/missing.tgz")
+	c.Assert(err, jc.ErrorIsNil)
+	resp.Body.Close()
+	c.Assert(resp.StatusCode, gc.Equals, http.StatusNotFound)
+
+	status := moveRequest(c, url+"releases/juju.tgz", "../stolen", "true")
+	c.Assert(status, gc.Equals, http.StatusBadRequest)
+	_, err = os.Stat(filepath.Join(s.outside, "stolen"))
+	c.Assert(err, jc.Satisfies, os.IsNotExist)
+	status = moveRequest(c, url+"releases/juju.tgz", "releases/old.tgz", "false")
+	c.Assert(status, gc.Equals, http.StatusCreated)
+}
@@ -0,0 +1,102 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package httpstorage
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// acceptsGzip reports whether the request's Accept-Encoding
+// header allows the response to be gzip-encoded.
+func acceptsGzip(req *http.Request) bool {
+	for _, coding := range strings.Split(req.Header.Get("Accept-Encoding"), ",") {
+		fields := strings.Split(coding, ";")
+		if name := strings.TrimSpace(fields[0]); name != "gzip" && name != "*" {
+			continue
+		}
+		accepted := true
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if !strings.HasPrefix(param, "q=") {
+				continue
+			}
+			q, err := strconv.ParseFloat(param[len("q="):], 64)
+			accepted = err == nil && q > 0
+		}
+		return accepted
+	}
+	return false
+}
+
+// gzipResponseWriter gzip-encodes the body of a 200 OK response. Other
+// responses, such as errors and partial content, are written as they
+// are. Close must be called once the response has been written.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	zw          *gzip.Writer
+	wroteHeader bool
+}
+
+func newGzipResponseWriter(w http.ResponseWriter) *gzipResponseWriter {
+	// The response depends on whether the client accepts gzip.
+	w.Header().Add("Vary", "Accept-Encoding")
+	return &gzipResponseWriter{ResponseWriter: w}
+}
+
+// WriteHeader implements http.ResponseWriter.WriteHeader.
+func (w *gzipResponseWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	if status == http.StatusOK || status == http.StatusNotModified {
+		// The encoded body is not the object's contents byte
+		// for byte, so its entity tag can only be weak.
+		if etag := w.Header().Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			w.Header().Set("ETag", "W/"+etag)
+		}
+	}
+	if status == http.StatusOK {
+		w.Header().Set("Content-Encoding", "gzip")
+		// The length of the encoded body is not known.
+		w.Header().Del("Content-Length")
+		w.zw = gzip.NewWriter(w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write implements http.ResponseWriter.Write.
+func (w *gzipResponseWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.zw != nil {
+		return w.zw.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Close writes any buffered data and the end of the gzip stream.
+func (w *gzipResponseWriter) Close() error {
+	if w.zw != nil {
+		return w.zw.Close()
+	}
+	return nil
+}
+
+// gzipReadCloser decodes a gzip-encoded response body.
+type gzipReadCloser struct {
+	*gzip.Reader
+	body io.Closer
+}
+
+// Close implements io.Closer.Close.
+func (r *gzipReadCloser) Close() error {
+	r.Reader.Close()
+	return r.body.Close()
+}
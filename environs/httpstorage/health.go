@@ -0,0 +1,106 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package httpstorage
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+
+	"github.com/juju/juju/environs/storage"
+)
+
+// ReservedPrefix begins the paths of the endpoints the server provides
+// besides its storages, such as HealthPath. Objects with names that
+// begin with it cannot be stored, so they never collide with them.
+const ReservedPrefix = "_juju_"
+
+// HealthPath is the path of the server's health endpoint. A GET request
+// for it lists each storage served. The response has status 200 OK if
+// all of them can be listed, within a short time, and 503 Service
+// Unavailable otherwise. Its body is a JSON object whose "status" is
+// "ok" or "unavailable"; if the latter, "error" describes the failure.
+const HealthPath = "/" + ReservedPrefix + "health"
+
+// healthCheckTimeout is how long the health endpoint
+// waits for each storage to be listed.
+var healthCheckTimeout = 5 * time.Second
+
+// healthStatus is the body of a response from the health endpoint.
+type healthStatus struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// healthHandler serves the health endpoint,
+// checking the storages of the given backends.
+type healthHandler struct {
+	backends map[string]*storageBackend
+}
+
+// ServeHTTP implements http.Handler.ServeHTTP.
+func (h healthHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "GET" && req.Method != "HEAD" {
+		http.Error(w, "method "+req.Method+" is not supported", http.StatusMethodNotAllowed)
+		return
+	}
+	status := healthStatus{Status: "ok"}
+	code := http.StatusOK
+	if err := h.check(); err != nil {
+		logger.Warningf("health check failed: %v", err)
+		status = healthStatus{Status: "unavailable", Error: err.Error()}
+		code = http.StatusServiceUnavailable
+	}
+	data, err := json.Marshal(status)
+	if err != nil {
+		http.Error(w, fmt.Sprint(err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(code)
+	w.Write(data)
+}
+
+// check returns an error if the storage of any backend
+// cannot be listed within healthCheckTimeout.
+func (h healthHandler) check() error {
+	for prefix, backend := range h.backends {
+		if err := checkStorage(backend.backend); err != nil {
+			return errors.Annotatef(err, "storage %q", prefix)
+		}
+	}
+	return nil
+}
+
+// checkStorage lists the storage, returning an error if that
+// fails or does not finish within healthCheckTimeout. A storage
+// that reports it holds nothing as not found is healthy.
+func checkStorage(stor storage.Storage) error {
+	done := make(chan error, 1)
+	go func() {
+		_, err := stor.List("")
+		if errors.IsNotFound(err) || os.IsNotExist(errors.Cause(err)) {
+			err = nil
+		}
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(healthCheckTimeout):
+		return errors.Errorf("timed out after %v", healthCheckTimeout)
+	}
+}
+
+// isReserved reports whether the object name begins with ReservedPrefix,
+// so that the object cannot be stored.
+func isReserved(name string) bool {
+	return strings.HasPrefix(name, ReservedPrefix)
+}
@@ -0,0 +1,50 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package httpstorage
+
+import (
+	"sync"
+)
+
+// keyLocker serialises operations on the same key, while allowing
+// operations on different keys to proceed concurrently.
+type keyLocker struct {
+	mu    sync.Mutex
+	locks map[string]*keyLock
+}
+
+// keyLock is the lock for a single key. It is removed
+// from its keyLocker once it has no more users.
+type keyLock struct {
+	sync.Mutex
+	users int
+}
+
+func newKeyLocker() *keyLocker {
+	return &keyLocker{locks: make(map[string]*keyLock)}
+}
+
+// lock blocks until no other operation holds the lock for key, and then
+// acquires it. The returned function must be called to release the lock.
+func (l *keyLocker) lock(key string) (unlock func()) {
+	l.mu.Lock()
+	kl, ok := l.locks[key]
+	if !ok {
+		kl = &keyLock{}
+		l.locks[key] = kl
+	}
+	kl.users++
+	l.mu.Unlock()
+
+	kl.Lock()
+	return func() {
+		kl.Unlock()
+		l.mu.Lock()
+		kl.users--
+		if kl.users == 0 {
+			delete(l.locks, key)
+		}
+		l.mu.Unlock()
+	}
+}
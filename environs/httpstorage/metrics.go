@@ -0,0 +1,213 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package httpstorage
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MetricsPath is the path of the server's metrics endpoint, served
+// when ServeOpts.Metrics is set. A GET request for it returns the
+// metrics recorded by the collector, as written by WriteMetrics.
+const MetricsPath = "/" + ReservedPrefix + "metrics"
+
+// MetricsCollector records the requests served by a storage server.
+type MetricsCollector interface {
+	// ObserveRequest records that a request of the given kind
+	// was answered with the given status code, taking the given
+	// time. The kind is the request's method, except that it is
+	// "LIST" for a GET request listing a prefix, and "OTHER" for
+	// a method the server does not support.
+	ObserveRequest(kind string, status int, duration time.Duration)
+
+	// WriteMetrics writes the metrics recorded to w,
+	// in the Prometheus text exposition format.
+	WriteMetrics(w io.Writer) error
+}
+
+// DefaultDurationBuckets holds the upper bounds, in seconds, of the
+// histogram buckets in which NewMetricsCollector counts requests.
+var DefaultDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// NewMetricsCollector returns a MetricsCollector that counts requests
+// by kind and status code, as the juju_httpstorage_requests_total
+// counter, and records the time taken by requests of each kind in the
+// juju_httpstorage_request_duration_seconds histogram, with the given
+// bucket bounds. The kind of a request is given by the "method" label.
+// If buckets is nil, DefaultDurationBuckets is used.
+func NewMetricsCollector(buckets []float64) MetricsCollector {
+	if buckets == nil {
+		buckets = DefaultDurationBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	return &metricsCollector{
+		buckets:   buckets,
+		requests:  make(map[requestKey]int64),
+		durations: make(map[string]*histogram),
+	}
+}
+
+type metricsCollector struct {
+	buckets []float64
+
+	mu        sync.Mutex
+	requests  map[requestKey]int64
+	durations map[string]*histogram
+}
+
+// requestKey identifies the requests of
+// a kind answered with a status code.
+type requestKey struct {
+	kind   string
+	status int
+}
+
+// histogram counts the durations observed that fall within
+// each bucket, and holds their total.
+type histogram struct {
+	counts []int64
+	sum    float64
+	count  int64
+}
+
+// ObserveRequest is specified in the MetricsCollector interface.
+func (m *metricsCollector) ObserveRequest(kind string, status int, duration time.Duration) {
+	seconds := duration.Seconds()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests[requestKey{kind, status}]++
+	h, ok := m.durations[kind]
+	if !ok {
+		h = &histogram{counts: make([]int64, len(m.buckets))}
+		m.durations[kind] = h
+	}
+	for i, bound := range m.buckets {
+		if seconds <= bound {
+			h.counts[i]++
+			break
+		}
+	}
+	h.sum += seconds
+	h.count++
+}
+
+// WriteMetrics is specified in the MetricsCollector interface.
+func (m *metricsCollector) WriteMetrics(w io.Writer) error {
+	var buf bytes.Buffer
+	m.mu.Lock()
+	keys := make([]requestKey, 0, len(m.requests))
+	for key := range m.requests {
+		keys = append(keys, key)
+	}
+	sort.Sort(requestKeys(keys))
+	fmt.Fprintln(&buf, "# HELP juju_httpstorage_requests_total Requests served, by method and status code.")
+	fmt.Fprintln(&buf, "# TYPE juju_httpstorage_requests_total counter")
+	for _, key := range keys {
+		fmt.Fprintf(&buf, "juju_httpstorage_requests_total{method=%q,code=\"%d\"} %d\n", key.kind, key.status, m.requests[key])
+	}
+	kinds := make([]string, 0, len(m.durations))
+	for kind := range m.durations {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	fmt.Fprintln(&buf, "# HELP juju_httpstorage_request_duration_seconds Time taken to serve requests, by method.")
+	fmt.Fprintln(&buf, "# TYPE juju_httpstorage_request_duration_seconds histogram")
+	for _, kind := range kinds {
+		h := m.durations[kind]
+		var cumulative int64
+		for i, bound := range m.buckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(&buf, "juju_httpstorage_request_duration_seconds_bucket{method=%q,le=%q} %d\n", kind, formatFloat(bound), cumulative)
+		}
+		fmt.Fprintf(&buf, "juju_httpstorage_request_duration_seconds_bucket{method=%q,le=\"+Inf\"} %d\n", kind, h.count)
+		fmt.Fprintf(&buf, "juju_httpstorage_request_duration_seconds_sum{method=%q} %s\n", kind, formatFloat(h.sum))
+		fmt.Fprintf(&buf, "juju_httpstorage_request_duration_seconds_count{method=%q} %d\n", kind, h.count)
+	}
+	m.mu.Unlock()
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// requestKeys sorts request keys by kind, then status code.
+type requestKeys []requestKey
+
+func (k requestKeys) Len() int      { return len(k) }
+func (k requestKeys) Swap(i, j int) { k[i], k[j] = k[j], k[i] }
+func (k requestKeys) Less(i, j int) bool {
+	if k[i].kind != k[j].kind {
+		return k[i].kind < k[j].kind
+	}
+	return k[i].status < k[j].status
+}
+
+// metricsHandler serves the metrics endpoint.
+type metricsHandler struct {
+	metrics MetricsCollector
+}
+
+// ServeHTTP implements http.Handler.ServeHTTP.
+func (h metricsHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "GET" {
+		http.Error(w, "method "+req.Method+" is not supported", http.StatusMethodNotAllowed)
+		return
+	}
+	var buf bytes.Buffer
+	if err := h.metrics.WriteMetrics(&buf); err != nil {
+		http.Error(w, fmt.Sprint(err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	buf.WriteTo(w)
+}
+
+// statusRecorder records the status code
+// with which a response is written.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader implements http.ResponseWriter.WriteHeader.
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Write implements http.ResponseWriter.Write.
+func (r *statusRecorder) Write(p []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(p)
+}
+
+// requestKind returns the kind of the request reported to a
+// MetricsCollector. Unsupported methods are all reported as
+// "OTHER", so that clients cannot create arbitrary kinds.
+func requestKind(req *http.Request) string {
+	switch req.Method {
+	case "GET":
+		if isPrefixRequest(req) {
+			return "LIST"
+		}
+		return "GET"
+	case "HEAD", "PUT", "DELETE", "MOVE":
+		return req.Method
+	}
+	return "OTHER"
+}
@@ -0,0 +1,61 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package httpstorage_test
+
+import (
+	"bytes"
+	"time"
+
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/environs/httpstorage"
+)
+
+type metricsSuite struct{}
+
+var _ = gc.Suite(&metricsSuite{})
+
+func (s *metricsSuite) TestWriteMetrics(c *gc.C) {
+	metrics := httpstorage.NewMetricsCollector([]float64{1, 0.1})
+	metrics.ObserveRequest("GET", 200, 62500*time.Microsecond)
+	metrics.ObserveRequest("GET", 200, 500*time.Millisecond)
+	metrics.ObserveRequest("GET", 404, 2*time.Second)
+	metrics.ObserveRequest("DELETE", 401, 0)
+
+	var buf bytes.Buffer
+	err := metrics.WriteMetrics(&buf)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(buf.String(), gc.Equals, `
+# HELP juju_httpstorage_requests_total Requests served, by method and status code.
+# TYPE juju_httpstorage_requests_total counter
+juju_httpstorage_requests_total{method="DELETE",code="401"} 1
+juju_httpstorage_requests_total{method="GET",code="200"} 2
+juju_httpstorage_requests_total{method="GET",code="404"} 1
+# HELP juju_httpstorage_request_duration_seconds Time taken to serve requests, by method.
+# TYPE juju_httpstorage_request_duration_seconds histogram
+juju_httpstorage_request_duration_seconds_bucket{method="DELETE",le="0.1"} 1
+juju_httpstorage_request_duration_seconds_bucket{method="DELETE",le="1"} 1
+juju_httpstorage_request_duration_seconds_bucket{method="DELETE",le="+Inf"} 1
+juju_httpstorage_request_duration_seconds_sum{method="DELETE"} 0
+juju_httpstorage_request_duration_seconds_count{method="DELETE"} 1
+juju_httpstorage_request_duration_seconds_bucket{method="GET",le="0.1"} 1
+juju_httpstorage_request_duration_seconds_bucket{method="GET",le="1"} 2
+juju_httpstorage_request_duration_seconds_bucket{method="GET",le="+Inf"} 3
+juju_httpstorage_request_duration_seconds_sum{method="GET"} 2.5625
+juju_httpstorage_request_duration_seconds_count{method="GET"} 3
+`[1:])
+}
+
+func (s *metricsSuite) TestWriteMetricsEmpty(c *gc.C) {
+	var buf bytes.Buffer
+	err := httpstorage.NewMetricsCollector(nil).WriteMetrics(&buf)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(buf.String(), gc.Equals, `
+# HELP juju_httpstorage_requests_total Requests served, by method and status code.
+# TYPE juju_httpstorage_requests_total counter
+# HELP juju_httpstorage_request_duration_seconds Time taken to serve requests, by method.
+# TYPE juju_httpstorage_request_duration_seconds histogram
+`[1:])
+}
@@ -0,0 +1,115 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package httpstorage
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// rateLimiter limits the rate of the requests made by each client,
+// identified by IP address, with a token bucket: a client may make
+// up to burst requests at once, and a further request each time
+// 1/rate seconds pass. A nil *rateLimiter allows every request.
+type rateLimiter struct {
+	rate  float64
+	burst float64
+	now   func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	// pruned holds when buckets were last pruned.
+	pruned time.Time
+}
+
+// tokenBucket holds the tokens available to a client
+// when last it made a request.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter returns a rate limiter allowing rate requests a second
+// from each client, with bursts of up to burst requests. If burst is
+// less than one, one is used. If rate is not positive, newRateLimiter
+// returns nil.
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		now:     time.Now,
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// allow reports whether the client may make a request now, taking
+// a token from its bucket if so. If not, it also returns how long
+// the client must wait until it may.
+func (l *rateLimiter) allow(client string) (bool, time.Duration) {
+	if l == nil {
+		return true, 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	l.prune(now)
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[client] = b
+	}
+	b.tokens = l.tokens(b, now)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	return false, wait
+}
+
+// tokens returns the tokens in the bucket at the given time.
+func (l *rateLimiter) tokens(b *tokenBucket, now time.Time) float64 {
+	elapsed := now.Sub(b.last).Seconds()
+	if elapsed < 0 {
+		// The clock went backwards.
+		elapsed = 0
+	}
+	return math.Min(l.burst, b.tokens+elapsed*l.rate)
+}
+
+// prune discards the buckets that have filled up, as a client
+// with no bucket is given a full one, at most once for each
+// period in which an empty bucket fills. It must be called
+// with l.mu held.
+func (l *rateLimiter) prune(now time.Time) {
+	fillTime := time.Duration(l.burst / l.rate * float64(time.Second))
+	if now.Sub(l.pruned) < fillTime {
+		return
+	}
+	l.pruned = now
+	for client, b := range l.buckets {
+		if l.tokens(b, now) >= l.burst {
+			delete(l.buckets, client)
+		}
+	}
+}
+
+// clientIP returns the IP address of the client making the request.
+func clientIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
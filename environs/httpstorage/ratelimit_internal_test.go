@@ -0,0 +1,89 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package httpstorage
+
+import (
+	"time"
+
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+)
+
+type rateLimiterSuite struct {
+	now time.Time
+}
+
+var _ = gc.Suite(&rateLimiterSuite{})
+
+func (s *rateLimiterSuite) newRateLimiter(rate float64, burst int) *rateLimiter {
+	s.now = time.Date(2015, 6, 1, 0, 0, 0, 0, time.UTC)
+	l := newRateLimiter(rate, burst)
+	l.now = func() time.Time { return s.now }
+	return l
+}
+
+// assertAllowed asserts that the client may make n requests at once,
+// and no more, and that it is then told to wait for the given duration.
+func (s *rateLimiterSuite) assertAllowed(c *gc.C, l *rateLimiter, client string, n int, wait time.Duration) {
+	for i := 0; i < n; i++ {
+		ok, _ := l.allow(client)
+		c.Assert(ok, jc.IsTrue, gc.Commentf("request %d", i))
+	}
+	ok, gotWait := l.allow(client)
+	c.Assert(ok, jc.IsFalse)
+	c.Assert(gotWait, gc.Equals, wait)
+}
+
+func (s *rateLimiterSuite) TestBurst(c *gc.C) {
+	l := s.newRateLimiter(2, 5)
+	s.assertAllowed(c, l, "10.0.0.1", 5, 500*time.Millisecond)
+
+	// Tokens are added at the given rate.
+	s.now = s.now.Add(time.Second)
+	s.assertAllowed(c, l, "10.0.0.1", 2, 500*time.Millisecond)
+	s.now = s.now.Add(250 * time.Millisecond)
+	s.assertAllowed(c, l, "10.0.0.1", 0, 250*time.Millisecond)
+
+	// The bucket holds no more than the burst.
+	s.now = s.now.Add(time.Hour)
+	s.assertAllowed(c, l, "10.0.0.1", 5, 500*time.Millisecond)
+}
+
+func (s *rateLimiterSuite) TestClientsLimitedSeparately(c *gc.C) {
+	l := s.newRateLimiter(1, 2)
+	s.assertAllowed(c, l, "10.0.0.1", 2, time.Second)
+	s.assertAllowed(c, l, "10.0.0.2", 2, time.Second)
+}
+
+func (s *rateLimiterSuite) TestMinimumBurst(c *gc.C) {
+	l := s.newRateLimiter(0.5, 0)
+	s.assertAllowed(c, l, "10.0.0.1", 1, 2*time.Second)
+}
+
+func (s *rateLimiterSuite) TestNotLimited(c *gc.C) {
+	var l *rateLimiter
+	c.Assert(newRateLimiter(0, 10), gc.IsNil)
+	for i := 0; i < 100; i++ {
+		ok, _ := l.allow("10.0.0.1")
+		c.Assert(ok, jc.IsTrue)
+	}
+}
+
+func (s *rateLimiterSuite) TestPrunesFullBuckets(c *gc.C) {
+	l := s.newRateLimiter(1, 2)
+	l.allow("10.0.0.1")
+	l.allow("10.0.0.2")
+	l.allow("10.0.0.2")
+	s.now = s.now.Add(time.Second)
+	l.allow("10.0.0.3")
+	l.allow("10.0.0.3")
+	c.Assert(l.buckets, gc.HasLen, 3)
+
+	// After the time it takes an empty bucket to fill,
+	// the buckets that have filled are discarded.
+	s.now = s.now.Add(time.Second)
+	l.allow("10.0.0.3")
+	c.Assert(l.buckets, gc.HasLen, 1)
+	c.Assert(l.buckets["10.0.0.3"], gc.NotNil)
+}
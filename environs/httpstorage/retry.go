@@ -0,0 +1,244 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package httpstorage
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+
+	"github.com/juju/juju/environs/storage"
+)
+
+// RetryingStorage is implemented by the storage returned by
+// Client and ClientTLS, allowing reads that fail transiently
+// to be retried.
+type RetryingStorage interface {
+	storage.Storage
+
+	// SetRetryPolicy has Get make up to attempts requests to read
+	// an object, rather than one, when a request fails with a
+	// network error or a 5xx status, or the object's contents are
+	// cut short. It waits for base before the second request, and
+	// twice as long before each further request. When the server
+	// supports it, a request after the contents were cut short
+	// asks only for the rest of them, with a Range header. If the
+	// object has changed since it was first requested, as shown
+	// by its ETag, the read fails.
+	//
+	// It must not be called while other operations are in progress.
+	SetRetryPolicy(attempts int, base time.Duration)
+}
+
+var _ RetryingStorage = (*localStorage)(nil)
+
+// SetRetryPolicy is specified in the RetryingStorage interface.
+func (s *localStorage) SetRetryPolicy(attempts int, base time.Duration) {
+	if attempts < 1 {
+		attempts = 1
+	}
+	s.retryAttempts = attempts
+	s.retryBase = base
+}
+
+// maxAttempts returns the number of requests
+// Get may make to read an object.
+func (s *localStorage) maxAttempts() int {
+	if s.retryAttempts < 1 {
+		return 1
+	}
+	return s.retryAttempts
+}
+
+// serverError is returned for a response with a 5xx status.
+type serverError struct {
+	status string
+}
+
+func (e *serverError) Error() string {
+	return "server error: " + e.status
+}
+
+// errNotResumable is returned when a request for the rest of
+// an object is answered with a different part of it, or the
+// object has changed since it was first requested.
+var errNotResumable = errors.New("cannot resume reading object")
+
+// retryingReader reads an object from the storage server, making
+// further requests as the storage's retry policy allows when a request
+// fails or the object's contents are cut short.
+type retryingReader struct {
+	stor *localStorage
+	name string
+	url  string
+
+	// requests holds the number of requests made.
+	requests int
+
+	// offset holds the number of bytes of the object read.
+	offset int64
+
+	// etag holds the ETag of the first response, if any, so that
+	// later requests can check that the object has not changed.
+	etag string
+
+	// body holds the body of the current response,
+	// or nil if there is none.
+	body io.ReadCloser
+}
+
+// open requests the object's contents from the current offset,
+// retrying as allowed.
+func (r *retryingReader) open() error {
+	for {
+		body, err := r.request()
+		if err == nil {
+			r.body = body
+			return nil
+		}
+		if !r.shouldRetry(err) {
+			return err
+		}
+		if err := r.wait(err); err != nil {
+			return err
+		}
+	}
+}
+
+// shouldRetry reports whether a request
+// that failed with err should be retried.
+func (r *retryingReader) shouldRetry(err error) bool {
+	if err == ErrAborted || err == errNotResumable || errors.IsNotFound(err) {
+		return false
+	}
+	return r.requests < r.stor.maxAttempts()
+}
+
+// wait waits before the next request, returning
+// ErrAborted if the storage's operations are aborted.
+func (r *retryingReader) wait(err error) error {
+	delay := r.stor.retryBase << uint(r.requests-1)
+	logger.Debugf("retrying get of %q in %v after attempt %d failed: %v", r.name, delay, r.requests, err)
+	select {
+	case <-time.After(delay):
+		return nil
+	case <-r.stor.abort:
+		return ErrAborted
+	}
+}
+
+// request requests the object's contents from the current offset.
+func (r *retryingReader) request() (io.ReadCloser, error) {
+	r.requests++
+	req, err := http.NewRequest("GET", r.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if r.offset == 0 {
+		// Servers that gzip-encode responses save bandwidth;
+		// the body is decoded as it is read.
+		req.Header.Set("Accept-Encoding", "gzip")
+	} else {
+		// The offset is in the object's contents as they are.
+		req.Header.Set("Accept-Encoding", "identity")
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", r.offset))
+		if r.etag != "" && !strings.HasPrefix(r.etag, "W/") {
+			// If the object has changed, the server
+			// sends all of it; that is detected below.
+			req.Header.Set("If-Range", r.etag)
+		}
+	}
+	resp, err := r.stor.do(req)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case resp.StatusCode >= 500:
+		resp.Body.Close()
+		return nil, &serverError{resp.Status}
+	case resp.StatusCode == http.StatusPartialContent && r.offset > 0:
+		prefix := fmt.Sprintf("bytes %d-", r.offset)
+		if !strings.HasPrefix(resp.Header.Get("Content-Range"), prefix) || r.changed(resp) {
+			resp.Body.Close()
+			return nil, errNotResumable
+		}
+		return resp.Body, nil
+	case resp.StatusCode != http.StatusOK:
+		resp.Body.Close()
+		return nil, errors.NotFoundf("file %q", r.name)
+	case r.offset > 0 && r.changed(resp):
+		// The part already read cannot be
+		// joined to the rest of the new object.
+		resp.Body.Close()
+		return nil, errNotResumable
+	}
+	if r.offset == 0 {
+		r.etag = resp.Header.Get("ETag")
+	}
+	body := resp.Body
+	if resp.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			resp.Body.Close()
+			return nil, errors.Annotatef(err, "cannot decode file %q", r.name)
+		}
+		body = &gzipReadCloser{zr, resp.Body}
+	}
+	if r.offset > 0 {
+		// The server sent the whole object; skip
+		// the part that has been read already.
+		if _, err := io.CopyN(ioutil.Discard, body, r.offset); err != nil {
+			body.Close()
+			return nil, err
+		}
+	}
+	return body, nil
+}
+
+// changed reports whether the ETag of the given response shows that
+// the object has changed since the first response. The ETags are
+// compared weakly, as a gzip-encoded response carries the weak form
+// of the object's ETag.
+func (r *retryingReader) changed(resp *http.Response) bool {
+	if r.etag == "" {
+		return false
+	}
+	etag := resp.Header.Get("ETag")
+	return strings.TrimPrefix(etag, "W/") != strings.TrimPrefix(r.etag, "W/")
+}
+
+// Read implements io.Reader.Read.
+func (r *retryingReader) Read(p []byte) (int, error) {
+	if r.body == nil {
+		return 0, errors.New("read of failed response")
+	}
+	n, err := r.body.Read(p)
+	r.offset += int64(n)
+	if err == nil || err == io.EOF || !r.shouldRetry(err) {
+		return n, err
+	}
+	r.body.Close()
+	r.body = nil
+	if err := r.wait(err); err != nil {
+		return n, err
+	}
+	if err := r.open(); err != nil {
+		return n, err
+	}
+	return n, nil
+}
+
+// Close implements io.Closer.Close.
+func (r *retryingReader) Close() error {
+	if r.body == nil {
+		return nil
+	}
+	return r.body.Close()
+}
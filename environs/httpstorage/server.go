@@ -0,0 +1,173 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package httpstorage
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+)
+
+// ErrShutdownAborted is returned by Server.Shutdown when its
+// abort channel is closed before all requests have finished.
+var ErrShutdownAborted = errors.New("storage server shutdown aborted")
+
+// newConnGracePeriod is how long a connection on which no request
+// has yet been read is left open by Shutdown, as for net/http's
+// Server.Shutdown; after that the connection is treated as idle.
+var newConnGracePeriod = 5 * time.Second
+
+// shutdownPollInterval is how often Shutdown looks for connections
+// that have become idle.
+var shutdownPollInterval = 500 * time.Millisecond
+
+// Server is a running storage server. It is also the server's network
+// listener, whose address is that of the HTTP listener, so that it can
+// be attached to with Client.
+//
+// Closing the server, as a listener, stops it accepting connections, but
+// leaves requests already being served to finish in their own time. Use
+// Shutdown to also wait for them.
+type Server struct {
+	net.Listener
+
+	servers []*http.Server
+	conns   *connTracker
+}
+
+// Shutdown stops the server accepting connections, as Close does, and
+// waits for the requests being served to finish. Idle connections are
+// closed at once, as are connections on which no request has been read
+// within newConnGracePeriod, and each other connection is closed once
+// the request on it has been answered. If abort is closed before then,
+// the remaining connections are closed, cutting their requests short,
+// and Shutdown returns ErrShutdownAborted.
+//
+// Shutdown may be called after Close.
+func (s *Server) Shutdown(abort <-chan struct{}) error {
+	if err := s.Listener.Close(); err != nil {
+		logger.Debugf("closing storage server listener: %v", err)
+	}
+	for _, server := range s.servers {
+		server.SetKeepAlivesEnabled(false)
+	}
+	done := s.conns.shutdown()
+	ticker := time.NewTicker(shutdownPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return nil
+		case <-abort:
+			s.conns.closeAll()
+			return ErrShutdownAborted
+		case <-ticker.C:
+			s.conns.closeIdle()
+		}
+	}
+}
+
+// connTracker tracks the state of the connections to a server,
+// so that the server can be shut down once they are all closed.
+type connTracker struct {
+	mu           sync.Mutex
+	conns        map[net.Conn]connInfo
+	shuttingDown bool
+	done         chan struct{}
+}
+
+// connInfo holds the state of a connection, and
+// the time the connection entered that state.
+type connInfo struct {
+	state http.ConnState
+	since time.Time
+}
+
+func newConnTracker() *connTracker {
+	return &connTracker{
+		conns: make(map[net.Conn]connInfo),
+		done:  make(chan struct{}),
+	}
+}
+
+// connState is called by a server as the state of each connection
+// changes. See http.Server.ConnState.
+func (t *connTracker) connState(conn net.Conn, state http.ConnState) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch state {
+	case http.StateNew, http.StateActive:
+		t.conns[conn] = connInfo{state, time.Now()}
+	case http.StateIdle:
+		t.conns[conn] = connInfo{state, time.Now()}
+		if t.shuttingDown {
+			conn.Close()
+		}
+	case http.StateHijacked, http.StateClosed:
+		delete(t.conns, conn)
+		t.checkDone()
+	}
+}
+
+// shutdown closes the idle connections, and returns a channel
+// that is closed once all connections have been closed.
+func (t *connTracker) shutdown() <-chan struct{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.shuttingDown {
+		t.shuttingDown = true
+		t.closeIdleLocked()
+		t.checkDone()
+	}
+	return t.done
+}
+
+// closeIdle closes the idle connections.
+func (t *connTracker) closeIdle() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.closeIdleLocked()
+}
+
+// closeIdleLocked closes the idle connections, including those on
+// which no request has been read within newConnGracePeriod. It must
+// be called with t.mu held.
+func (t *connTracker) closeIdleLocked() {
+	for conn, info := range t.conns {
+		switch info.state {
+		case http.StateIdle:
+			conn.Close()
+		case http.StateNew:
+			if time.Since(info.since) >= newConnGracePeriod {
+				conn.Close()
+			}
+		}
+	}
+}
+
+// closeAll closes all the connections.
+func (t *connTracker) closeAll() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for conn := range t.conns {
+		conn.Close()
+	}
+}
+
+// checkDone closes t.done if the server is shutting down and
+// all connections have been closed. It must be called with
+// t.mu held.
+func (t *connTracker) checkDone() {
+	if !t.shuttingDown || len(t.conns) > 0 {
+		return
+	}
+	select {
+	case <-t.done:
+	default:
+		close(t.done)
+	}
+}
@@ -6,14 +6,17 @@ package httpstorage
 import (
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"path"
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/juju/errors"
 	"github.com/juju/loggo"
@@ -30,9 +33,15 @@ type localStorage struct {
 	client *http.Client
 
 	authkey           string
+	abort             <-chan struct{}
 	httpsBaseURL      string
 	httpsBaseURLError error
 	httpsBaseURLOnce  sync.Once
+
+	// retryAttempts and retryBase hold the
+	// policy set with SetRetryPolicy.
+	retryAttempts int
+	retryBase     time.Duration
 }
 
 // Client returns a storage object that will talk to the
@@ -58,14 +67,23 @@ func ClientTLS(addr string, caCertPEM string, authkey string) (storage.Storage,
 		addr:    addr,
 		authkey: authkey,
 		client: &http.Client{
-			Transport: utils.NewHttpTLSTransport(&tls.Config{RootCAs: caCerts}),
+			Transport: utils.NewHttpTLSTransport(&tls.Config{
+				RootCAs: caCerts,
+				// Cache sessions so that reconnections
+				// can resume them.
+				ClientSessionCache: tls.NewLRUClientSessionCache(0),
+			}),
 		},
 	}, nil
 }
 
 func (s *localStorage) getHTTPSBaseURL() (string, error) {
 	url, _ := s.URL("") // never fails
-	resp, err := s.client.Head(url)
+	req, err := http.NewRequest("HEAD", url, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := s.do(req)
 	if err != nil {
 		return "", err
 	}
@@ -90,14 +108,14 @@ func (s *localStorage) Get(name string) (io.ReadCloser, error) {
 	if err != nil {
 		return nil, err
 	}
-	resp, err := s.client.Get(url)
-	if err != nil {
+	r := &retryingReader{stor: s, name: name, url: url}
+	if err := r.open(); err != nil {
+		if _, ok := err.(*serverError); ok {
+			return nil, errors.NotFoundf("file %q", name)
+		}
 		return nil, err
 	}
-	if resp.StatusCode != http.StatusOK {
-		return nil, errors.NotFoundf("file %q", name)
-	}
-	return resp.Body, nil
+	return r, nil
 }
 
 // List lists all names in the storage with the given prefix, in
@@ -110,10 +128,15 @@ func (s *localStorage) List(prefix string) ([]string, error) {
 	if err != nil {
 		return nil, err
 	}
-	resp, err := s.client.Get(url + "*")
+	req, err := http.NewRequest("GET", url+"*", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(req)
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
 		// If the path is not found, it's not an error
 		// because it's only created when the first
@@ -123,7 +146,6 @@ func (s *localStorage) List(prefix string) ([]string, error) {
 		}
 		return nil, fmt.Errorf("%s", resp.Status)
 	}
-	defer resp.Body.Close()
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		return nil, err
@@ -136,6 +158,66 @@ func (s *localStorage) List(prefix string) ([]string, error) {
 	return names, nil
 }
 
+// ListingStorage is implemented by the storage returned by Client
+// and ClientTLS, allowing the details of stored objects to be listed.
+type ListingStorage interface {
+	storage.Storage
+
+	// ListWithDetails returns the objects whose names begin
+	// with the given prefix, in alphabetical order of name.
+	// Sizes and modification times are reported as described
+	// for StorageObject; servers that cannot report them at
+	// all report sizes as -1.
+	ListWithDetails(prefix string) ([]StorageObject, error)
+}
+
+var _ ListingStorage = (*localStorage)(nil)
+
+// ListWithDetails is specified in the ListingStorage interface.
+func (s *localStorage) ListWithDetails(prefix string) ([]StorageObject, error) {
+	url, err := s.URL(prefix)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("GET", url+"*", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return []StorageObject{}, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s", resp.Status)
+	}
+	if resp.Header.Get("Content-Type") != "application/json" {
+		// The server can only list names.
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		var objects []StorageObject
+		if len(body) > 0 {
+			names := strings.Split(string(body), "\n")
+			sort.Strings(names)
+			for _, name := range names {
+				objects = append(objects, StorageObject{Name: name, Size: -1})
+			}
+		}
+		return objects, nil
+	}
+	var objects []StorageObject
+	if err := json.NewDecoder(resp.Body).Decode(&objects); err != nil {
+		return nil, errors.Annotate(err, "cannot read object list")
+	}
+	return objects, nil
+}
+
 // URL returns a URL that can be used to access the given storage file.
 func (s *localStorage) URL(name string) (string, error) {
 	return fmt.Sprintf("http://%s/%s", s.addr, name), nil
@@ -157,6 +239,62 @@ func (s *localStorage) modURL(name string) (string, error) {
 	return fmt.Sprintf("%s%s?%s", s.httpsBaseURL, name, v.Encode()), nil
 }
 
+// StorageURL holds the parts of a URL that addresses
+// an object held by a storage server.
+type StorageURL struct {
+	// Host holds the host and port of the server.
+	Host string
+
+	// Name holds the name of the object.
+	Name string
+
+	// HTTPS reports whether the URL is the HTTPS variant,
+	// through which objects are modified.
+	HTTPS bool
+
+	// AuthKey holds the auth key given in the URL, if any.
+	AuthKey string
+}
+
+// ParseStorageURL parses a URL produced by a storage returned by Client
+// or ClientTLS, for a storage server mount with the given prefix (see
+// ServeMulti), and returns its parts. An error is returned if the URL
+// does not address an object under the prefix.
+func ParseStorageURL(rawURL, prefix string) (*StorageURL, error) {
+	if err := validatePrefix(prefix); err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, errors.Annotate(err, "invalid storage URL")
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, errors.Errorf("invalid storage URL %q: unsupported scheme %q", rawURL, u.Scheme)
+	}
+	if u.Host == "" || u.Opaque != "" || u.User != nil || u.Fragment != "" {
+		return nil, errors.Errorf("invalid storage URL %q", rawURL)
+	}
+	if !strings.HasPrefix(u.Path, "/"+prefix) {
+		return nil, errors.Errorf("invalid storage URL %q: path is not under prefix %q", rawURL, prefix)
+	}
+	name := strings.TrimPrefix(u.Path, "/"+prefix)
+	if name == "" || strings.HasSuffix(name, "*") || path.Clean(u.Path) != u.Path {
+		return nil, errors.Errorf("invalid storage URL %q: invalid object name %q", rawURL, name)
+	}
+	query := u.Query()
+	authkey := query.Get("authkey")
+	query.Del("authkey")
+	if len(query) > 0 {
+		return nil, errors.Errorf("invalid storage URL %q: unexpected query", rawURL)
+	}
+	return &StorageURL{
+		Host:    u.Host,
+		Name:    name,
+		HTTPS:   u.Scheme == "https",
+		AuthKey: authkey,
+	}, nil
+}
+
 // DefaultConsistencyStrategy is specified in the StorageReader interface.
 func (s *localStorage) DefaultConsistencyStrategy() utils.AttemptStrategy {
 	return utils.AttemptStrategy{}
@@ -170,6 +308,105 @@ func (s *localStorage) ShouldRetry(err error) bool {
 // Put reads from r and writes to the given storage file.
 // The length must be set to the total length of the file.
 func (s *localStorage) Put(name string, r io.Reader, length int64) error {
+	return s.put(name, r, length, nil)
+}
+
+// ErrObjectExists is returned by ConditionalStorage.PutIfAbsent
+// when the object already exists.
+var ErrObjectExists = errors.New("object already exists")
+
+// ConditionalStorage is implemented by the storage returned by
+// Client and ClientTLS, allowing objects to be stored only if
+// they do not already exist.
+type ConditionalStorage interface {
+	storage.Storage
+
+	// PutIfAbsent works like Put, but returns ErrObjectExists,
+	// having stored nothing, if the named object exists.
+	PutIfAbsent(name string, r io.Reader, length int64) error
+}
+
+var _ ConditionalStorage = (*localStorage)(nil)
+
+// PutIfAbsent is specified in the ConditionalStorage interface.
+func (s *localStorage) PutIfAbsent(name string, r io.Reader, length int64) error {
+	err := s.put(name, r, length, http.Header{"If-None-Match": {"*"}})
+	if err == errPreconditionFailed {
+		return ErrObjectExists
+	}
+	return err
+}
+
+// ProgressStorage is implemented by the storage returned by
+// Client and ClientTLS, allowing uploads to be observed.
+type ProgressStorage interface {
+	storage.Storage
+
+	// PutWithProgress works like Put, but calls progress with the
+	// total number of bytes sent so far, at most once every
+	// progressInterval and once more when the upload completes.
+	PutWithProgress(name string, r io.Reader, length int64, progress func(bytesSent int64)) error
+}
+
+var _ ProgressStorage = (*localStorage)(nil)
+
+// progressInterval is the minimum time between
+// calls to a PutWithProgress progress callback.
+var progressInterval = 250 * time.Millisecond
+
+// PutWithProgress is specified in the ProgressStorage interface.
+func (s *localStorage) PutWithProgress(name string, r io.Reader, length int64, progress func(bytesSent int64)) error {
+	if progress == nil {
+		return s.Put(name, r, length)
+	}
+	pr := &progressReader{
+		reader:   r,
+		progress: progress,
+		last:     time.Now(),
+	}
+	if err := s.put(name, pr, length, nil); err != nil {
+		return err
+	}
+	pr.flush()
+	return nil
+}
+
+// progressReader counts the bytes read through it,
+// periodically reporting the total to a callback.
+type progressReader struct {
+	reader   io.Reader
+	progress func(int64)
+	sent     int64
+	reported int64
+	last     time.Time
+}
+
+func (r *progressReader) Read(buf []byte) (int, error) {
+	n, err := r.reader.Read(buf)
+	r.sent += int64(n)
+	if n > 0 && time.Since(r.last) >= progressInterval {
+		r.flush()
+	}
+	return n, err
+}
+
+// flush reports the bytes sent, if they have not been reported already.
+func (r *progressReader) flush() {
+	if r.sent == r.reported {
+		return
+	}
+	r.progress(r.sent)
+	r.reported = r.sent
+	r.last = time.Now()
+}
+
+// errPreconditionFailed is returned by put when
+// a precondition in the request's header fails.
+var errPreconditionFailed = errors.New("precondition failed")
+
+// put stores the data read from r as the named object, adding
+// the given header fields, if any, to the request.
+func (s *localStorage) put(name string, r io.Reader, length int64, header http.Header) error {
 	logger.Debugf("putting %q (len %d) to storage", name, length)
 	url, err := s.modURL(name)
 	if err != nil {
@@ -187,11 +424,18 @@ func (s *localStorage) Put(name string, r io.Reader, length int64) error {
 		return err
 	}
 	req.Header.Set("Content-Type", "application/octet-stream")
+	for key, values := range header {
+		req.Header[key] = values
+	}
 	req.ContentLength = length
-	resp, err := s.client.Do(req)
+	resp, err := s.do(req)
 	if err != nil {
 		return err
 	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusPreconditionFailed {
+		return errPreconditionFailed
+	}
 	if resp.StatusCode != 201 {
 		return fmt.Errorf("%d %s", resp.StatusCode, resp.Status)
 	}
@@ -210,16 +454,239 @@ func (s *localStorage) Remove(name string) error {
 	if err != nil {
 		return err
 	}
-	resp, err := s.client.Do(req)
+	resp, err := s.do(req)
 	if err != nil {
 		return err
 	}
+	resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("%d %s", resp.StatusCode, resp.Status)
 	}
 	return nil
 }
 
+// ErrDestinationExists is returned by MovingStorage.Move when the
+// destination exists and overwriting it was not requested.
+var ErrDestinationExists = errors.New("destination already exists")
+
+// MovingStorage is implemented by the storage returned by
+// Client and ClientTLS, allowing objects to be moved on the
+// storage server without transferring their contents.
+type MovingStorage interface {
+	storage.Storage
+
+	// Move moves the object named from to the name to. If
+	// overwrite is true, any object already stored under that
+	// name is replaced; otherwise ErrDestinationExists is
+	// returned if there is one.
+	Move(from, to string, overwrite bool) error
+}
+
+var _ MovingStorage = (*localStorage)(nil)
+
+// Move is specified in the MovingStorage interface.
+func (s *localStorage) Move(from, to string, overwrite bool) error {
+	logger.Debugf("moving %q to %q in storage", from, to)
+	url, err := s.modURL(from)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("MOVE", url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(DestinationHeader, to)
+	req.Header.Set(OverwriteHeader, fmt.Sprint(overwrite))
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusCreated:
+		return nil
+	case http.StatusNotFound:
+		return errors.NotFoundf("file %q", from)
+	case http.StatusPreconditionFailed:
+		return ErrDestinationExists
+	case http.StatusNotImplemented:
+		return errors.NotSupportedf("moving objects in this storage")
+	}
+	return fmt.Errorf("%d %s", resp.StatusCode, resp.Status)
+}
+
+// RemoveAll removes all the files in the storage.
 func (s *localStorage) RemoveAll() error {
-	return storage.RemoveAll(s)
+	_, err := s.RemovePrefix("")
+	return err
+}
+
+// RemovePrefix removes all the files in the storage whose names begin
+// with the given prefix, with a single request. It returns the number
+// of files removed and the first error encountered, if any.
+func (s *localStorage) RemovePrefix(prefix string) (int, error) {
+	logger.Debugf("removing prefix %q from storage", prefix)
+	url, err := s.modURL(prefix + "*")
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequest("DELETE", url, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Type") != "application/json" {
+		if resp.StatusCode != http.StatusOK {
+			return 0, fmt.Errorf("%d %s", resp.StatusCode, resp.Status)
+		}
+		// Servers that cannot remove a prefix treat the
+		// request as one for a single object, so remove
+		// the files one at a time instead.
+		return storage.RemovePrefix(s, prefix)
+	}
+	var result RemovePrefixResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return 0, errors.Annotate(err, "cannot read removal result")
+	}
+	if len(result.Failed) > 0 {
+		failure := result.Failed[0]
+		return len(result.Removed), fmt.Errorf("cannot remove %q: %s", failure.Name, failure.Error)
+	}
+	return len(result.Removed), nil
+}
+
+// ErrAborted is returned by storage operations
+// that were abandoned because their abort channel was closed.
+var ErrAborted = errors.New("storage request aborted")
+
+// AbortableStorage is implemented by the storage returned by
+// Client and ClientTLS, allowing in-flight requests to be abandoned.
+type AbortableStorage interface {
+	storage.Storage
+
+	// WithAbort returns a storage that talks to the same server,
+	// but whose operations fail with ErrAborted as soon as abort
+	// is closed, cancelling any outstanding HTTP request. The
+	// returned storage also implements ProgressStorage and
+	// AbortableStorage.
+	WithAbort(abort <-chan struct{}) storage.Storage
+}
+
+var _ AbortableStorage = (*localStorage)(nil)
+
+// WithAbort is specified in the AbortableStorage interface.
+func (s *localStorage) WithAbort(abort <-chan struct{}) storage.Storage {
+	return &localStorage{
+		addr:          s.addr,
+		client:        s.client,
+		authkey:       s.authkey,
+		abort:         abort,
+		retryAttempts: s.retryAttempts,
+		retryBase:     s.retryBase,
+	}
+}
+
+// requestCanceler is implemented by http transports
+// that can cancel in-flight requests.
+type requestCanceler interface {
+	CancelRequest(*http.Request)
+}
+
+// cancel cancels the given request, if the client's transport allows it.
+func (s *localStorage) cancel(req *http.Request) {
+	if c, ok := s.client.Transport.(requestCanceler); ok {
+		c.CancelRequest(req)
+	}
+}
+
+// do sends the request, returning ErrAborted if the storage's
+// abort channel is closed before the response arrives. Reading
+// the body of a successful response is also abandoned on abort.
+func (s *localStorage) do(req *http.Request) (*http.Response, error) {
+	if s.abort == nil {
+		return s.client.Do(req)
+	}
+	select {
+	case <-s.abort:
+		return nil, ErrAborted
+	default:
+	}
+	type result struct {
+		resp *http.Response
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		resp, err := s.client.Do(req)
+		done <- result{resp, err}
+	}()
+	select {
+	case r := <-done:
+		if r.err != nil {
+			select {
+			case <-s.abort:
+				return nil, ErrAborted
+			default:
+			}
+			return nil, r.err
+		}
+		body := &abortableBody{
+			ReadCloser: r.resp.Body,
+			abort:      s.abort,
+			closed:     make(chan struct{}),
+		}
+		go body.watch(func() { s.cancel(req) })
+		r.resp.Body = body
+		return r.resp, nil
+	case <-s.abort:
+		s.cancel(req)
+		go func() {
+			if r := <-done; r.err == nil {
+				r.resp.Body.Close()
+			}
+		}()
+		return nil, ErrAborted
+	}
+}
+
+// abortableBody wraps a response body, reporting
+// ErrAborted for reads that fail after an abort.
+type abortableBody struct {
+	io.ReadCloser
+	abort     <-chan struct{}
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// watch calls cancel if the abort channel is
+// closed before the body is.
+func (b *abortableBody) watch(cancel func()) {
+	select {
+	case <-b.abort:
+		cancel()
+	case <-b.closed:
+	}
+}
+
+func (b *abortableBody) Read(buf []byte) (int, error) {
+	n, err := b.ReadCloser.Read(buf)
+	if err != nil && err != io.EOF {
+		select {
+		case <-b.abort:
+			return n, ErrAborted
+		default:
+		}
+	}
+	return n, err
+}
+
+func (b *abortableBody) Close() error {
+	b.closeOnce.Do(func() {
+		close(b.closed)
+	})
+	return b.ReadCloser.Close()
 }
@@ -8,13 +8,19 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"path/filepath"
+	"sync/atomic"
+	"testing/iotest"
+	"time"
 
 	"github.com/juju/errors"
+	gitjujutesting "github.com/juju/testing"
 	jc "github.com/juju/testing/checkers"
 	gc "gopkg.in/check.v1"
 
+	"github.com/juju/juju/environs/filestorage"
 	"github.com/juju/juju/environs/httpstorage"
 	"github.com/juju/juju/environs/storage"
 	coretesting "github.com/juju/juju/testing"
@@ -140,6 +146,393 @@ func checkList(c *gc.C, stor storage.StorageReader, prefix string, names []strin
 	}
 }
 
+func (s *storageSuite) TestParseStorageURLRoundTrip(c *gc.C) {
+	stor := httpstorage.Client("10.0.0.1:8040/one/")
+	url, err := stor.URL("tools/releases/juju-1.2.3-trusty-amd64.tgz")
+	c.Assert(err, jc.ErrorIsNil)
+	parsed, err := httpstorage.ParseStorageURL(url, "one/")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(parsed, jc.DeepEquals, &httpstorage.StorageURL{
+		Host: "10.0.0.1:8040",
+		Name: "tools/releases/juju-1.2.3-trusty-amd64.tgz",
+	})
+}
+
+var parseStorageURLTests = []struct {
+	url    string
+	prefix string
+	expect *httpstorage.StorageURL
+	err    string
+}{{
+	url:    "http://10.0.0.1:8040/foo/bar",
+	expect: &httpstorage.StorageURL{Host: "10.0.0.1:8040", Name: "foo/bar"},
+}, {
+	url:    "https://10.0.0.1:8041/one/foo?authkey=" + testAuthkey,
+	prefix: "one/",
+	expect: &httpstorage.StorageURL{Host: "10.0.0.1:8041", Name: "foo", HTTPS: true, AuthKey: testAuthkey},
+}, {
+	url: "ftp://10.0.0.1/foo",
+	err: `invalid storage URL "ftp://10.0.0.1/foo": unsupported scheme "ftp"`,
+}, {
+	url: "http:foo",
+	err: `invalid storage URL "http:foo"`,
+}, {
+	url: "http://10.0.0.1/foo#frag",
+	err: `invalid storage URL "http://10.0.0.1/foo#frag"`,
+}, {
+	url:    "http://10.0.0.1/two/foo",
+	prefix: "one/",
+	err:    `invalid storage URL "http://10.0.0.1/two/foo": path is not under prefix "one/"`,
+}, {
+	url:    "http://10.0.0.1/one/",
+	prefix: "one/",
+	err:    `invalid storage URL "http://10.0.0.1/one/": invalid object name ""`,
+}, {
+	url: "http://10.0.0.1/foo*",
+	err: `invalid storage URL "http://10.0.0.1/foo\*": invalid object name "foo\*"`,
+}, {
+	url: "http://10.0.0.1/foo/../bar",
+	err: `invalid storage URL "http://10.0.0.1/foo/../bar": invalid object name "foo/../bar"`,
+}, {
+	url: "http://10.0.0.1/foo?bar=baz",
+	err: `invalid storage URL "http://10.0.0.1/foo\?bar=baz": unexpected query`,
+}, {
+	url:    "http://10.0.0.1/foo",
+	prefix: "one",
+	err:    `invalid prefix "one": must end with '/'`,
+}}
+
+func (s *storageSuite) TestParseStorageURL(c *gc.C) {
+	for i, test := range parseStorageURLTests {
+		c.Logf("test %d: %q", i, test.url)
+		parsed, err := httpstorage.ParseStorageURL(test.url, test.prefix)
+		if test.err != "" {
+			c.Check(err, gc.ErrorMatches, test.err)
+			continue
+		}
+		c.Check(err, jc.ErrorIsNil)
+		c.Check(parsed, jc.DeepEquals, test.expect)
+	}
+}
+
+func (s *storageSuite) TestMove(c *gc.C) {
+	listener, _, _ := startServer(c)
+	defer listener.Close()
+	stor := httpstorage.Client(listener.Addr().String()).(httpstorage.MovingStorage)
+	checkPutFile(c, stor, "staged", []byte("new"))
+	checkPutFile(c, stor, "released", []byte("old"))
+
+	// Without overwrite, an existing destination is left alone.
+	err := stor.Move("staged", "released", false)
+	c.Assert(err, gc.Equals, httpstorage.ErrDestinationExists)
+	checkFileHasContents(c, stor, "staged", []byte("new"))
+	checkFileHasContents(c, stor, "released", []byte("old"))
+
+	// With overwrite, it is replaced.
+	err = stor.Move("staged", "released", true)
+	c.Assert(err, jc.ErrorIsNil)
+	checkFileHasContents(c, stor, "released", []byte("new"))
+	_, err = storage.Get(stor, "staged")
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+
+	// Without overwrite, a missing destination is created.
+	err = stor.Move("released", "promoted", false)
+	c.Assert(err, jc.ErrorIsNil)
+	checkFileHasContents(c, stor, "promoted", []byte("new"))
+
+	err = stor.Move("staged", "elsewhere", true)
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *storageSuite) TestMoveNotSupported(c *gc.C) {
+	embedded, err := filestorage.NewFileStorageWriter(c.MkDir())
+	c.Assert(err, jc.ErrorIsNil)
+	// Hide the storage's Move method.
+	listener, err := httpstorage.Serve("localhost:0", struct{ storage.Storage }{embedded})
+	c.Assert(err, jc.ErrorIsNil)
+	defer listener.Close()
+	stor := httpstorage.Client(listener.Addr().String()).(httpstorage.MovingStorage)
+	checkPutFile(c, stor, "staged", []byte("new"))
+
+	err = stor.Move("staged", "released", true)
+	c.Assert(err, jc.Satisfies, errors.IsNotSupported)
+	checkFileHasContents(c, stor, "staged", []byte("new"))
+}
+
+func (s *storageSuite) TestPutWithProgress(c *gc.C) {
+	defer gitjujutesting.PatchValue(httpstorage.ProgressInterval, time.Duration(0)).Restore()
+	listener, _, _ := startServer(c)
+	defer listener.Close()
+	stor := httpstorage.Client(listener.Addr().String()).(httpstorage.ProgressStorage)
+
+	data := []byte("some data to upload")
+	var sent []int64
+	err := stor.PutWithProgress("progress", iotest.OneByteReader(bytes.NewReader(data)), int64(len(data)), func(n int64) {
+		sent = append(sent, n)
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	checkFileHasContents(c, stor, "progress", data)
+
+	// Progress is reported for every read, and always ends
+	// with the total length.
+	c.Assert(len(sent) > 1, jc.IsTrue)
+	for i := 1; i < len(sent); i++ {
+		c.Assert(sent[i] > sent[i-1], jc.IsTrue, gc.Commentf("%v", sent))
+	}
+	c.Assert(sent[len(sent)-1], gc.Equals, int64(len(data)))
+}
+
+func (s *storageSuite) TestPutWithProgressReportsCompletion(c *gc.C) {
+	listener, _, _ := startServer(c)
+	defer listener.Close()
+	stor := httpstorage.Client(listener.Addr().String()).(httpstorage.ProgressStorage)
+
+	// However quickly the upload finishes, the final total is reported.
+	data := []byte("hello")
+	var sent []int64
+	err := stor.PutWithProgress("progress", bytes.NewReader(data), int64(len(data)), func(n int64) {
+		sent = append(sent, n)
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(sent, gc.DeepEquals, []int64{int64(len(data))})
+}
+
+func (s *storageSuite) TestWithAbortNotAborted(c *gc.C) {
+	listener, _, _ := startServer(c)
+	defer listener.Close()
+	stor := httpstorage.Client(listener.Addr().String()).(httpstorage.AbortableStorage)
+	abortable := stor.WithAbort(make(chan struct{}))
+
+	data := []byte("hello")
+	checkPutFile(c, abortable, "filename", data)
+	checkFileHasContents(c, abortable, "filename", data)
+	checkList(c, abortable, "", []string{"filename"})
+	c.Assert(abortable.Remove("filename"), jc.ErrorIsNil)
+	checkFileDoesNotExist(c, abortable, "filename")
+}
+
+// startHangingServer starts a server that accepts requests but
+// never responds to them until the returned channel is closed.
+func startHangingServer(c *gc.C) (listener net.Listener, release chan struct{}) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, jc.ErrorIsNil)
+	release = make(chan struct{})
+	go http.Serve(listener, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		<-release
+	}))
+	return listener, release
+}
+
+func (s *storageSuite) TestWithAbortAbortsGet(c *gc.C) {
+	listener, release := startHangingServer(c)
+	defer listener.Close()
+	defer close(release)
+	stor := httpstorage.Client(listener.Addr().String()).(httpstorage.AbortableStorage)
+	abort := make(chan struct{})
+	abortable := stor.WithAbort(abort)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := abortable.Get("filename")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		c.Fatalf("Get returned before abort: %v", err)
+	case <-time.After(coretesting.ShortWait):
+	}
+	close(abort)
+	select {
+	case err := <-done:
+		c.Assert(err, gc.Equals, httpstorage.ErrAborted)
+	case <-time.After(coretesting.LongWait):
+		c.Fatalf("Get was not aborted")
+	}
+}
+
+func (s *storageSuite) TestWithAbortAlreadyAborted(c *gc.C) {
+	listener, _, _ := startServer(c)
+	defer listener.Close()
+	stor := httpstorage.Client(listener.Addr().String()).(httpstorage.AbortableStorage)
+	abort := make(chan struct{})
+	close(abort)
+	abortable := stor.WithAbort(abort)
+
+	_, err := abortable.List("")
+	c.Assert(err, gc.Equals, httpstorage.ErrAborted)
+	err = abortable.Put("filename", bytes.NewReader(nil), 0)
+	c.Assert(err, gc.Equals, httpstorage.ErrAborted)
+}
+
+// startFlakyServer starts a server that serves the given contents
+// once the first failures requests for them have failed, alternately
+// with a 503 Service Unavailable status and by closing the connection
+// after sending half of the contents. It returns a channel that receives
+// each request's Range header.
+func startFlakyServer(c *gc.C, contents []byte, failures int) (listener net.Listener, ranges chan string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, jc.ErrorIsNil)
+	ranges = make(chan string, 10)
+	var requests int32
+	go http.Serve(listener, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ranges <- req.Header.Get("Range")
+		n := atomic.AddInt32(&requests, 1)
+		switch {
+		case int(n) > failures:
+			http.ServeContent(w, req, "", time.Time{}, bytes.NewReader(contents))
+		case n%2 == 1:
+			http.Error(w, "try again later", http.StatusServiceUnavailable)
+		default:
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				return
+			}
+			fmt.Fprintf(conn, "HTTP/1.1 200 OK\r\nContent-Length: %d\r\n\r\n", len(contents))
+			conn.Write(contents[:len(contents)/2])
+			conn.Close()
+		}
+	}))
+	return listener, ranges
+}
+
+func (s *storageSuite) TestGetRetries(c *gc.C) {
+	contents := []byte("some contents to be read in two parts")
+	listener, ranges := startFlakyServer(c, contents, 2)
+	defer listener.Close()
+	stor := httpstorage.Client(listener.Addr().String()).(httpstorage.RetryingStorage)
+	stor.SetRetryPolicy(3, time.Millisecond)
+
+	checkFileHasContents(c, stor, "filename", contents)
+	c.Assert(ranges, gc.HasLen, 3)
+	c.Assert(<-ranges, gc.Equals, "")
+	c.Assert(<-ranges, gc.Equals, "")
+	// The last request resumes where the connection was closed.
+	c.Assert(<-ranges, gc.Equals, fmt.Sprintf("bytes=%d-", len(contents)/2))
+}
+
+func (s *storageSuite) TestGetRetriesObjectChanged(c *gc.C) {
+	contents := []byte("some contents to be read in two parts")
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, jc.ErrorIsNil)
+	defer listener.Close()
+	ifRanges := make(chan string, 10)
+	var requests int32
+	go http.Serve(listener, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ifRanges <- req.Header.Get("If-Range")
+		if atomic.AddInt32(&requests, 1) > 1 {
+			// The object has changed since the first request.
+			w.Header().Set("ETag", `"v2"`)
+			http.ServeContent(w, req, "", time.Time{}, bytes.NewReader(contents))
+			return
+		}
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		fmt.Fprintf(conn, "HTTP/1.1 200 OK\r\nETag: \"v1\"\r\nContent-Length: %d\r\n\r\n", len(contents))
+		conn.Write(contents[:len(contents)/2])
+		conn.Close()
+	}))
+	stor := httpstorage.Client(listener.Addr().String()).(httpstorage.RetryingStorage)
+	stor.SetRetryPolicy(3, time.Millisecond)
+
+	r, err := stor.Get("filename")
+	c.Assert(err, jc.ErrorIsNil)
+	defer r.Close()
+	_, err = ioutil.ReadAll(r)
+	c.Assert(err, gc.ErrorMatches, "cannot resume reading object")
+	c.Assert(ifRanges, gc.HasLen, 2)
+	c.Assert(<-ifRanges, gc.Equals, "")
+	c.Assert(<-ifRanges, gc.Equals, `"v1"`)
+}
+
+func (s *storageSuite) TestGetRetriesExhausted(c *gc.C) {
+	contents := []byte("some contents")
+	listener, ranges := startFlakyServer(c, contents, 2)
+	defer listener.Close()
+	stor := httpstorage.Client(listener.Addr().String()).(httpstorage.RetryingStorage)
+	stor.SetRetryPolicy(2, time.Millisecond)
+
+	r, err := stor.Get("filename")
+	c.Assert(err, jc.ErrorIsNil)
+	defer r.Close()
+	_, err = ioutil.ReadAll(r)
+	c.Assert(err, gc.Equals, io.ErrUnexpectedEOF)
+	c.Assert(ranges, gc.HasLen, 2)
+}
+
+func (s *storageSuite) TestGetNoRetriesByDefault(c *gc.C) {
+	listener, ranges := startFlakyServer(c, []byte("some contents"), 2)
+	defer listener.Close()
+	stor := httpstorage.Client(listener.Addr().String())
+
+	_, err := stor.Get("filename")
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+	c.Assert(ranges, gc.HasLen, 1)
+}
+
+func (s *storageSuite) TestGetRetriesWithAbort(c *gc.C) {
+	contents := []byte("some contents")
+	listener, ranges := startFlakyServer(c, contents, 2)
+	defer listener.Close()
+	stor := httpstorage.Client(listener.Addr().String())
+	stor.(httpstorage.RetryingStorage).SetRetryPolicy(3, time.Millisecond)
+	abortable := stor.(httpstorage.AbortableStorage).WithAbort(make(chan struct{}))
+
+	// The retry policy is kept.
+	checkFileHasContents(c, abortable, "filename", contents)
+	c.Assert(ranges, gc.HasLen, 3)
+}
+
+func (s *storageSuite) TestRemovePrefix(c *gc.C) {
+	listener, _, _ := startServer(c)
+	defer listener.Close()
+	stor := httpstorage.Client(listener.Addr().String())
+	for _, name := range []string{"tools/a", "tools/b", "other"} {
+		checkPutFile(c, stor, name, []byte(name))
+	}
+
+	removed, err := stor.RemovePrefix("tools/")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(removed, gc.Equals, 2)
+	checkList(c, stor, "", []string{"other"})
+}
+
+func (s *storageSuite) TestPutIfAbsent(c *gc.C) {
+	listener, _, _ := startServer(c)
+	defer listener.Close()
+	stor := httpstorage.Client(listener.Addr().String()).(httpstorage.ConditionalStorage)
+
+	err := stor.PutIfAbsent("filename", bytes.NewReader([]byte("first")), 5)
+	c.Assert(err, jc.ErrorIsNil)
+	err = stor.PutIfAbsent("filename", bytes.NewReader([]byte("second")), 6)
+	c.Assert(err, gc.Equals, httpstorage.ErrObjectExists)
+	checkFileHasContents(c, stor, "filename", []byte("first"))
+}
+
+func (s *storageSuite) TestListWithDetails(c *gc.C) {
+	listener, _, _ := startServer(c)
+	defer listener.Close()
+	stor := httpstorage.Client(listener.Addr().String()).(httpstorage.ListingStorage)
+
+	objects, err := stor.ListWithDetails("tools/")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(objects, gc.HasLen, 0)
+
+	before := time.Now().Add(-time.Minute)
+	checkPutFile(c, stor, "tools/b", []byte("bb"))
+	checkPutFile(c, stor, "tools/a", []byte("a"))
+	checkPutFile(c, stor, "other", []byte("other"))
+	objects, err = stor.ListWithDetails("tools/")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(objects, gc.HasLen, 2)
+	for i, name := range []string{"tools/a", "tools/b"} {
+		c.Check(objects[i].Name, gc.Equals, name)
+		c.Check(objects[i].Size, gc.Equals, int64(i+1))
+		c.Check(objects[i].LastModified.After(before), jc.IsTrue)
+	}
+}
+
 type readerWithClose struct {
 	*bytes.Buffer
 	closeCalled bool
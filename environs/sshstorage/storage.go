@@ -19,6 +19,7 @@ import (
 	"github.com/juju/loggo"
 	"github.com/juju/utils"
 
+	"github.com/juju/juju/environs/storage"
 	"github.com/juju/juju/utils/ssh"
 )
 
@@ -345,3 +346,8 @@ func (s *SSHStorage) RemoveAll() error {
 	_, err := s.runf(flockExclusive, "rm -fr %s/*", utils.ShQuote(s.remotepath))
 	return err
 }
+
+// RemovePrefix implements storage.StorageWriter.RemovePrefix
+func (s *SSHStorage) RemovePrefix(prefix string) (int, error) {
+	return storage.RemovePrefix(s, prefix)
+}
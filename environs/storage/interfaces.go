@@ -59,6 +59,11 @@ type StorageWriter interface {
 	// Nevertheless, use with care!  This method is only mean
 	// for cleaning up an environment that's being destroyed.
 	RemoveAll() error
+
+	// RemovePrefix removes all files whose names begin with the
+	// given prefix. It returns the number of files removed and
+	// the first error encountered, if any.
+	RemovePrefix(prefix string) (int, error)
 }
 
 // Storage represents storage that can be both
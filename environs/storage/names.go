@@ -0,0 +1,79 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package storage
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/juju/errors"
+)
+
+// ArtifactKind identifies a kind of artifact, other than tools,
+// held in environment storage.
+type ArtifactKind string
+
+const (
+	// CharmResourceArtifact is the kind of charm resources;
+	// their owner is the charm or service they belong to.
+	CharmResourceArtifact ArtifactKind = "resources"
+
+	// BackupArtifact is the kind of environment backups;
+	// their owner is the UUID of the backed up environment.
+	BackupArtifact ArtifactKind = "backups"
+)
+
+// StorageName returns the name that is used to store and retrieve
+// the given revision of the named artifact, of the given kind and
+// belonging to the given owner. Names are of the form
+// "<kind>/<owner>/<name>-<revision>"; the owner and artifact name
+// must be non-empty and must not contain slashes, so that names for
+// different artifacts never collide.
+func StorageName(kind ArtifactKind, owner, name string, revision int) (string, error) {
+	if err := validateArtifact(kind, owner, name); err != nil {
+		return "", errors.Trace(err)
+	}
+	if revision < 0 {
+		return "", errors.NotValidf("artifact revision %d", revision)
+	}
+	return fmt.Sprintf("%s/%s/%s-%d", kind, owner, name, revision), nil
+}
+
+// ParseStorageName returns the kind, owner, name and revision
+// of the artifact stored with the given name by StorageName.
+func ParseStorageName(storageName string) (kind ArtifactKind, owner, name string, revision int, err error) {
+	parts := strings.Split(storageName, "/")
+	if len(parts) != 3 {
+		return "", "", "", 0, errors.NotValidf("artifact storage name %q", storageName)
+	}
+	i := strings.LastIndex(parts[2], "-")
+	if i < 0 {
+		return "", "", "", 0, errors.NotValidf("artifact storage name %q", storageName)
+	}
+	revision, err = strconv.Atoi(parts[2][i+1:])
+	if err != nil || revision < 0 {
+		return "", "", "", 0, errors.NotValidf("artifact storage name %q", storageName)
+	}
+	kind, owner, name = ArtifactKind(parts[0]), parts[1], parts[2][:i]
+	if err := validateArtifact(kind, owner, name); err != nil {
+		return "", "", "", 0, errors.NotValidf("artifact storage name %q", storageName)
+	}
+	return kind, owner, name, revision, nil
+}
+
+func validateArtifact(kind ArtifactKind, owner, name string) error {
+	switch kind {
+	case CharmResourceArtifact, BackupArtifact:
+	default:
+		return errors.NotValidf("artifact kind %q", kind)
+	}
+	if owner == "" || strings.Contains(owner, "/") {
+		return errors.NotValidf("artifact owner %q", owner)
+	}
+	if name == "" || strings.Contains(name, "/") {
+		return errors.NotValidf("artifact name %q", name)
+	}
+	return nil
+}
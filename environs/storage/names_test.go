@@ -0,0 +1,91 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package storage_test
+
+import (
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/environs/storage"
+	"github.com/juju/juju/testing"
+)
+
+type namesSuite struct {
+	testing.BaseSuite
+}
+
+var _ = gc.Suite(&namesSuite{})
+
+func (*namesSuite) TestStorageName(c *gc.C) {
+	for i, test := range []struct {
+		kind     storage.ArtifactKind
+		owner    string
+		name     string
+		revision int
+		expect   string
+		err      string
+	}{{
+		kind:     storage.CharmResourceArtifact,
+		owner:    "wordpress",
+		name:     "theme.tgz",
+		revision: 3,
+		expect:   "resources/wordpress/theme.tgz-3",
+	}, {
+		kind:   storage.BackupArtifact,
+		owner:  "deadbeef-0bad-400d-8000-4b1d0d06f00d",
+		name:   "20150401-120000",
+		expect: "backups/deadbeef-0bad-400d-8000-4b1d0d06f00d/20150401-120000-0",
+	}, {
+		kind:  "tools",
+		owner: "x",
+		name:  "y",
+		err:   `artifact kind "tools" not valid`,
+	}, {
+		kind:  storage.BackupArtifact,
+		owner: "a/b",
+		name:  "y",
+		err:   `artifact owner "a/b" not valid`,
+	}, {
+		kind:  storage.BackupArtifact,
+		owner: "x",
+		err:   `artifact name "" not valid`,
+	}, {
+		kind:     storage.BackupArtifact,
+		owner:    "x",
+		name:     "y",
+		revision: -1,
+		err:      `artifact revision -1 not valid`,
+	}} {
+		c.Logf("test %d", i)
+		name, err := storage.StorageName(test.kind, test.owner, test.name, test.revision)
+		if test.err != "" {
+			c.Check(err, gc.ErrorMatches, test.err)
+			continue
+		}
+		c.Assert(err, jc.ErrorIsNil)
+		c.Check(name, gc.Equals, test.expect)
+
+		kind, owner, artifactName, revision, err := storage.ParseStorageName(name)
+		c.Assert(err, jc.ErrorIsNil)
+		c.Check(kind, gc.Equals, test.kind)
+		c.Check(owner, gc.Equals, test.owner)
+		c.Check(artifactName, gc.Equals, test.name)
+		c.Check(revision, gc.Equals, test.revision)
+	}
+}
+
+func (*namesSuite) TestParseStorageNameInvalid(c *gc.C) {
+	for _, name := range []string{
+		"",
+		"tools/released/juju-1.23.0-trusty-amd64.tgz",
+		"backups/x",
+		"backups/x/y",
+		"backups/x/y-z",
+		"backups/x/-1",
+		"backups/x/y/z-1",
+	} {
+		_, _, _, _, err := storage.ParseStorageName(name)
+		c.Check(err, gc.ErrorMatches, `artifact storage name ".*" not valid`)
+	}
+}
@@ -4,10 +4,14 @@
 package storage
 
 import (
+	"crypto/sha256"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"path"
+	"sort"
 
+	"github.com/juju/errors"
 	"github.com/juju/utils"
 
 	"github.com/juju/juju/environs/simplestreams"
@@ -33,6 +37,38 @@ func RemoveAll(stor Storage) error {
 	return err
 }
 
+// RemovePrefix is a default implementation for StorageWriter.RemovePrefix.
+// It lists the files matching prefix and removes each in turn, carrying on
+// past failures so that as much as possible is removed. It returns the
+// number of files removed and the first error encountered.
+func RemovePrefix(stor Storage, prefix string) (int, error) {
+	return RemovePrefixFunc(stor, prefix, stor.Remove)
+}
+
+// RemovePrefixFunc is like RemovePrefix, but removes each file, in
+// lexical order, by calling remove with its name. If the files cannot
+// be listed, remove is not called and the error returned has the
+// listing error as its cause.
+func RemovePrefixFunc(stor StorageReader, prefix string, remove func(name string) error) (int, error) {
+	files, err := List(stor, prefix)
+	if err != nil {
+		return 0, errors.Annotate(err, "unable to list files for deletion")
+	}
+	sort.Strings(files)
+	var firstErr error
+	removed := 0
+	for _, file := range files {
+		if err := remove(file); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		removed++
+	}
+	return removed, firstErr
+}
+
 // Get gets the named file from stor using the stor's default consistency strategy.
 func Get(stor StorageReader, name string) (io.ReadCloser, error) {
 	return GetWithRetry(stor, name, stor.DefaultConsistencyStrategy())
@@ -65,6 +101,39 @@ func ListWithRetry(stor StorageReader, prefix string, attempt utils.AttemptStrat
 	return list, err
 }
 
+// Copy streams the named file from src into dst, under the same name,
+// without holding the whole file in memory or on disk. The file must be
+// size bytes long and, if sha256hash is not empty, have that hex-encoded
+// SHA-256 hash, as recorded in tools metadata. If it does not, the copy
+// is removed from dst and an error is returned.
+func Copy(dst StorageWriter, src StorageReader, name string, size int64, sha256hash string) error {
+	r, err := Get(src, name)
+	if err != nil {
+		return fmt.Errorf("cannot read %q: %v", name, err)
+	}
+	defer r.Close()
+	hash := sha256.New()
+	if err := dst.Put(name, io.TeeReader(r, hash), size); err != nil {
+		return fmt.Errorf("cannot write %q: %v", name, err)
+	}
+	// Put reads only size bytes, so make sure there are no more.
+	n, err := io.CopyN(ioutil.Discard, r, 1)
+	switch {
+	case err != nil && err != io.EOF:
+		err = fmt.Errorf("cannot read %q: %v", name, err)
+	case n > 0:
+		err = fmt.Errorf("%q is larger than %d bytes", name, size)
+	case sha256hash != "" && fmt.Sprintf("%x", hash.Sum(nil)) != sha256hash:
+		err = fmt.Errorf("%q has SHA-256 hash %x, expected %s", name, hash.Sum(nil), sha256hash)
+	default:
+		return nil
+	}
+	if removeErr := dst.Remove(name); removeErr != nil {
+		return fmt.Errorf("%v; cannot remove copy: %v", err, removeErr)
+	}
+	return err
+}
+
 // BaseToolsPath is the container where tools tarballs and metadata are found.
 var BaseToolsPath = "tools"
 
@@ -5,6 +5,7 @@ package storage_test
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -107,6 +108,107 @@ func (s *datasourceSuite) TestURLWithBasePath(c *gc.C) {
 	c.Assert(url, gc.Equals, expectedURL)
 }
 
+func (s *datasourceSuite) putFiles(c *gc.C, names ...string) {
+	for _, name := range names {
+		err := s.stor.Put(name, bytes.NewReader([]byte(name)), int64(len(name)))
+		c.Assert(err, jc.ErrorIsNil)
+	}
+}
+
+func (s *datasourceSuite) TestRemovePrefix(c *gc.C) {
+	s.putFiles(c, "tools/a", "tools/b", "other")
+	removed, err := storage.RemovePrefix(s.stor, "tools/")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(removed, gc.Equals, 2)
+	names, err := storage.List(s.stor, "")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(names, gc.DeepEquals, []string{"other"})
+}
+
+type failingRemoveStorage struct {
+	storage.Storage
+	fail string
+}
+
+func (s *failingRemoveStorage) Remove(name string) error {
+	if name == s.fail {
+		return fmt.Errorf("cannot remove %q", name)
+	}
+	return s.Storage.Remove(name)
+}
+
+func (s *datasourceSuite) TestRemovePrefixReturnsFirstError(c *gc.C) {
+	s.putFiles(c, "tools/a", "tools/b", "tools/c")
+	stor := &failingRemoveStorage{Storage: s.stor, fail: "tools/b"}
+	removed, err := storage.RemovePrefix(stor, "tools/")
+	c.Assert(err, gc.ErrorMatches, `cannot remove "tools/b"`)
+	c.Assert(removed, gc.Equals, 2)
+	names, err := storage.List(s.stor, "")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(names, gc.DeepEquals, []string{"tools/b"})
+}
+
+func (s *datasourceSuite) TestRemovePrefixFunc(c *gc.C) {
+	s.putFiles(c, "tools/b", "tools/a", "tools/c", "other")
+	var called []string
+	removed, err := storage.RemovePrefixFunc(s.stor, "tools/", func(name string) error {
+		called = append(called, name)
+		if name == "tools/b" {
+			return fmt.Errorf("cannot remove %q", name)
+		}
+		return s.stor.Remove(name)
+	})
+	c.Assert(err, gc.ErrorMatches, `cannot remove "tools/b"`)
+	c.Assert(removed, gc.Equals, 2)
+	c.Assert(called, gc.DeepEquals, []string{"tools/a", "tools/b", "tools/c"})
+	names, err := storage.List(s.stor, "")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(names, gc.DeepEquals, []string{"other", "tools/b"})
+}
+
+func (s *datasourceSuite) TestCopy(c *gc.C) {
+	s.putFiles(c, "tools/a")
+	dst, err := filestorage.NewFileStorageWriter(c.MkDir())
+	c.Assert(err, jc.ErrorIsNil)
+	hash := fmt.Sprintf("%x", sha256.Sum256([]byte("tools/a")))
+	err = storage.Copy(dst, s.stor, "tools/a", int64(len("tools/a")), hash)
+	c.Assert(err, jc.ErrorIsNil)
+	r, err := storage.Get(dst, "tools/a")
+	c.Assert(err, jc.ErrorIsNil)
+	defer r.Close()
+	data, err := ioutil.ReadAll(r)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(string(data), gc.Equals, "tools/a")
+}
+
+func (s *datasourceSuite) TestCopyMismatch(c *gc.C) {
+	s.putFiles(c, "tools/a")
+	for i, test := range []struct {
+		size int64
+		hash string
+		err  string
+	}{{
+		size: 3,
+		err:  `"tools/a" is larger than 3 bytes`,
+	}, {
+		size: 10,
+		err:  `cannot write "tools/a": .*`,
+	}, {
+		size: 7,
+		hash: "0123",
+		err:  `"tools/a" has SHA-256 hash [0-9a-f]+, expected 0123`,
+	}} {
+		c.Logf("test %d", i)
+		dst, err := filestorage.NewFileStorageWriter(c.MkDir())
+		c.Assert(err, jc.ErrorIsNil)
+		err = storage.Copy(dst, s.stor, "tools/a", test.size, test.hash)
+		c.Check(err, gc.ErrorMatches, test.err)
+		names, err := storage.List(dst, "")
+		c.Assert(err, jc.ErrorIsNil)
+		c.Check(names, gc.HasLen, 0)
+	}
+}
+
 var _ = gc.Suite(&storageSuite{})
 
 type storageSuite struct{}
@@ -4,7 +4,10 @@
 package testing
 
 import (
+	"bytes"
 	"io"
+	"net"
+	"runtime"
 
 	jc "github.com/juju/testing/checkers"
 	gc "gopkg.in/check.v1"
@@ -12,6 +15,7 @@ import (
 	"github.com/juju/juju/environs/filestorage"
 	"github.com/juju/juju/environs/httpstorage"
 	"github.com/juju/juju/environs/storage"
+	coretesting "github.com/juju/juju/testing"
 )
 
 // CreateLocalTestStorage returns the listener, which needs to be closed, and
@@ -27,3 +31,50 @@ func CreateLocalTestStorage(c *gc.C) (closer io.Closer, stor storage.Storage, da
 	closer = listener
 	return
 }
+
+// httpStorageServeCreator is the stack frame that identifies
+// goroutines started to serve an httpstorage listener.
+const httpStorageServeCreator = "created by github.com/juju/juju/environs/httpstorage.goServe"
+
+// countHTTPStorageServers returns the number of running goroutines
+// serving httpstorage listeners.
+func countHTTPStorageServers() int {
+	buf := make([]byte, 1<<20)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return bytes.Count(buf[:n], []byte(httpStorageServeCreator))
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// CheckHTTPStorageServerExits calls start to start an httpstorage
+// server, calls f with the resulting server's listener, and then
+// closes the server and asserts that all the goroutines started to
+// serve it have exited.
+//
+// It relies on no other httpstorage servers being started or stopped
+// concurrently, which holds for tests within a single package.
+func CheckHTTPStorageServerExits(c *gc.C, start func() (*httpstorage.Server, error), f func(net.Listener)) {
+	before := countHTTPStorageServers()
+	server, err := start()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(server, gc.NotNil)
+	closed := false
+	defer func() {
+		if !closed {
+			server.Close()
+		}
+	}()
+	c.Assert(countHTTPStorageServers(), jc.GreaterThan, before)
+	f(server)
+	closed = true
+	c.Assert(server.Close(), jc.ErrorIsNil)
+	for a := coretesting.LongAttempt.Start(); a.Next(); {
+		if countHTTPStorageServers() <= before {
+			return
+		}
+	}
+	c.Fatalf("httpstorage server goroutines did not exit after closing listener")
+}
@@ -5,8 +5,11 @@ package testing
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"fmt"
+	"hash"
 	"os"
+	"path"
 	"path/filepath"
 
 	jc "github.com/juju/testing/checkers"
@@ -80,12 +83,8 @@ func (s *ToolsFixture) UploadFakeTools(c *gc.C, stor storage.Storage, toolsDir,
 
 // RemoveFakeToolsMetadata deletes the fake simplestreams tools metadata from the supplied storage.
 func RemoveFakeToolsMetadata(c *gc.C, stor storage.Storage) {
-	files, err := stor.List("tools/streams")
-	c.Assert(err, jc.ErrorIsNil)
-	for _, file := range files {
-		err = stor.Remove(file)
-		c.Check(err, jc.ErrorIsNil)
-	}
+	_, err := stor.RemovePrefix("tools/streams")
+	c.Check(err, jc.ErrorIsNil)
 }
 
 // CheckTools ensures the obtained and expected tools are equal, allowing for the fact that
@@ -128,19 +127,102 @@ func PrimeTools(c *gc.C, stor storage.Storage, dataDir, toolsDir string, vers ve
 	return agentTools
 }
 
+// FakeToolsChecksum specifies how the checksum recorded
+// for fake tools is computed.
+type FakeToolsChecksum struct {
+	// Hash returns the hash used to compute the checksum.
+	// If it is nil, sha256.New is used.
+	Hash func() hash.Hash
+
+	// Wrong specifies that the recorded checksum should not
+	// match the contents of the tools, so that code verifying
+	// downloaded tools can be tested against a mismatch.
+	Wrong bool
+}
+
+// sum returns the checksum of the given data, as a hex string.
+func (cs FakeToolsChecksum) sum(data []byte) string {
+	newHash := cs.Hash
+	if newHash == nil {
+		newHash = sha256.New
+	}
+	h := newHash()
+	h.Write(data)
+	if cs.Wrong {
+		h.Write([]byte("wrong"))
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
 func uploadFakeToolsVersion(stor storage.Storage, toolsDir string, vers version.Binary) (*coretools.Tools, error) {
+	return uploadFakeToolsVersionWithChecksum(stor, toolsDir, vers, FakeToolsChecksum{})
+}
+
+func uploadFakeToolsVersionWithChecksum(stor storage.Storage, toolsDir string, vers version.Binary, cs FakeToolsChecksum) (*coretools.Tools, error) {
 	logger.Infof("uploading FAKE tools %s", vers)
-	tgz, checksum := makeFakeTools(vers)
-	size := int64(len(tgz))
-	name := envtools.StorageName(vers, toolsDir)
-	if err := stor.Put(name, bytes.NewReader(tgz), size); err != nil {
+	tgz, upload, err := fakeToolsUpload(stor, toolsDir, vers, cs)
+	if err != nil {
+		return nil, err
+	}
+	if err := stor.Put(upload.Name, bytes.NewReader(tgz), upload.Tools.Size); err != nil {
 		return nil, err
 	}
+	return upload.Tools, nil
+}
+
+// FakeToolsUpload describes fake tools put in storage
+// by the fake tools upload helpers.
+type FakeToolsUpload struct {
+	// Name holds the name of the tools in storage.
+	Name string
+
+	// Tools describes the tools, including the URL
+	// from which they can be read.
+	Tools *coretools.Tools
+}
+
+// fakeToolsUpload returns the contents of fake tools with the given
+// version, and a description of them as they would be uploaded to
+// stor.
+func fakeToolsUpload(stor storage.StorageReader, toolsDir string, vers version.Binary, cs FakeToolsChecksum) ([]byte, FakeToolsUpload, error) {
+	tgz, _ := makeFakeTools(vers)
+	name := envtools.StorageName(vers, toolsDir)
 	url, err := stor.URL(name)
 	if err != nil {
-		return nil, err
+		return nil, FakeToolsUpload{}, err
 	}
-	return &coretools.Tools{URL: url, Version: vers, Size: size, SHA256: checksum}, nil
+	return tgz, FakeToolsUpload{
+		Name: name,
+		Tools: &coretools.Tools{
+			URL:     url,
+			Version: vers,
+			Size:    int64(len(tgz)),
+			SHA256:  cs.sum(tgz),
+		},
+	}, nil
+}
+
+// PlanFakeToolsVersions returns descriptions of the fake tools that
+// UploadFakeToolsVersions would put in the supplied storage for the
+// supplied versions, without uploading anything. This lets tests check
+// the names and URLs of tools without writing to a real storage.
+func PlanFakeToolsVersions(stor storage.StorageReader, toolsDir string, versions ...version.Binary) ([]FakeToolsUpload, error) {
+	uploads := make([]FakeToolsUpload, len(versions))
+	for i, vers := range versions {
+		_, upload, err := fakeToolsUpload(stor, toolsDir, vers, FakeToolsChecksum{})
+		if err != nil {
+			return nil, err
+		}
+		uploads[i] = upload
+	}
+	return uploads, nil
+}
+
+// PlanFakeTools returns descriptions of the fake tools that
+// UploadFakeTools would put in the supplied storage, without
+// uploading anything.
+func PlanFakeTools(stor storage.StorageReader, toolsDir string) ([]FakeToolsUpload, error) {
+	return PlanFakeToolsVersions(stor, toolsDir, fakeToolsVersions()...)
 }
 
 // InstallFakeDownloadedTools creates and unpacks fake tools of the
@@ -188,6 +270,24 @@ func UploadFakeToolsVersions(stor storage.Storage, toolsDir, stream string, vers
 	return agentTools, nil
 }
 
+// UploadFakeToolsVersionsWithChecksum acts as UploadFakeToolsVersions,
+// but records checksums for the uploaded tools as specified by cs.
+// Any existing tools for the supplied versions are replaced.
+func UploadFakeToolsVersionsWithChecksum(stor storage.Storage, toolsDir, stream string, cs FakeToolsChecksum, versions ...version.Binary) ([]*coretools.Tools, error) {
+	var agentTools coretools.List = make(coretools.List, len(versions))
+	for i, version := range versions {
+		t, err := uploadFakeToolsVersionWithChecksum(stor, toolsDir, version, cs)
+		if err != nil {
+			return nil, err
+		}
+		agentTools[i] = t
+	}
+	if err := envtools.MergeAndWriteMetadata(stor, toolsDir, stream, agentTools, envtools.DoNotWriteMirrors); err != nil {
+		return nil, err
+	}
+	return agentTools, nil
+}
+
 // AssertUploadFakeToolsVersions puts fake tools in the supplied storage for the supplied versions.
 func AssertUploadFakeToolsVersions(c *gc.C, stor storage.Storage, toolsDir, stream string, versions ...version.Binary) []*coretools.Tools {
 	agentTools, err := UploadFakeToolsVersions(stor, toolsDir, stream, versions...)
@@ -214,7 +314,32 @@ func MustUploadFakeToolsVersions(stor storage.Storage, stream string, versions .
 	return agentTools
 }
 
-func uploadFakeTools(stor storage.Storage, toolsDir, stream string) error {
+// BuildVersions returns count consecutive versions for the given series
+// and architecture, starting at start and incrementing the build number.
+func BuildVersions(start version.Number, count int, series, arch string) []version.Binary {
+	versions := make([]version.Binary, count)
+	for i := range versions {
+		number := start
+		number.Build += i
+		versions[i] = version.Binary{Number: number, Series: series, Arch: arch}
+	}
+	return versions
+}
+
+// GenerateToolsVersions puts fake tools in the supplied storage for count
+// consecutive versions, as returned by BuildVersions, and returns them.
+// It lets tests stage a ladder of versions without listing each one.
+func GenerateToolsVersions(
+	c *gc.C, stor storage.Storage, toolsDir, stream string,
+	start version.Number, count int, series, arch string,
+) coretools.List {
+	versions := BuildVersions(start, count, series, arch)
+	return AssertUploadFakeToolsVersions(c, stor, toolsDir, stream, versions...)
+}
+
+// fakeToolsVersions returns the versions of the tools
+// uploaded by UploadFakeTools.
+func fakeToolsVersions() []version.Binary {
 	toolsSeries := set.NewStrings(toolsLtsSeries...)
 	toolsSeries.Add(version.Current.Series)
 	var versions []version.Binary
@@ -223,7 +348,11 @@ func uploadFakeTools(stor storage.Storage, toolsDir, stream string) error {
 		vers.Series = series
 		versions = append(versions, vers)
 	}
-	if _, err := UploadFakeToolsVersions(stor, toolsDir, stream, versions...); err != nil {
+	return versions
+}
+
+func uploadFakeTools(stor storage.Storage, toolsDir, stream string) error {
+	if _, err := UploadFakeToolsVersions(stor, toolsDir, stream, fakeToolsVersions()...); err != nil {
 		return err
 	}
 	return nil
@@ -262,15 +391,36 @@ func RemoveFakeTools(c *gc.C, stor storage.Storage, toolsDir string) {
 	RemoveFakeToolsMetadata(c, stor)
 }
 
-// RemoveTools deletes all tools from the supplied storage.
-func RemoveTools(c *gc.C, stor storage.Storage, toolsDir string) {
-	names, err := storage.List(stor, fmt.Sprintf("tools/%s/juju-", toolsDir))
-	c.Assert(err, jc.ErrorIsNil)
-	c.Logf("removing files: %v", names)
-	for _, name := range names {
-		err = stor.Remove(name)
+// RemoveFakeToolsInStream removes the fake tools written by UploadFakeTools
+// with the same toolsDir and stream, along with the stream's metadata. The
+// metadata for other streams is left in place, so tests that upload into
+// several streams can clean up exactly what they wrote.
+func RemoveFakeToolsInStream(c *gc.C, stor storage.Storage, toolsDir, stream string) {
+	c.Logf("removing fake tools in stream %q", stream)
+	for _, vers := range fakeToolsVersions() {
+		err := stor.Remove(envtools.StorageName(vers, toolsDir))
 		c.Check(err, jc.ErrorIsNil)
 	}
+	metadata, err := envtools.ReadAllMetadata(stor)
+	c.Assert(err, jc.ErrorIsNil)
+	delete(metadata, stream)
+	if len(metadata) == 0 {
+		RemoveFakeToolsMetadata(c, stor)
+		return
+	}
+	err = stor.Remove(path.Join(storage.BaseToolsPath, envtools.ProductMetadataPath(stream)))
+	c.Check(err, jc.ErrorIsNil)
+	// Rewrite the indexes so they no longer refer to the stream.
+	err = envtools.WriteMetadata(stor, metadata, nil, envtools.DoNotWriteMirrors)
+	c.Check(err, jc.ErrorIsNil)
+}
+
+// RemoveTools deletes all tools from the supplied storage.
+func RemoveTools(c *gc.C, stor storage.Storage, toolsDir string) {
+	prefix := fmt.Sprintf("tools/%s/juju-", toolsDir)
+	removed, err := stor.RemovePrefix(prefix)
+	c.Check(err, jc.ErrorIsNil)
+	c.Logf("removed %d files matching %q", removed, prefix)
 	RemoveFakeToolsMetadata(c, stor)
 }
 
@@ -0,0 +1,169 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package testing
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	agenttools "github.com/juju/juju/agent/tools"
+	"github.com/juju/juju/environs/filestorage"
+	"github.com/juju/juju/environs/storage"
+	envtools "github.com/juju/juju/environs/tools"
+	"github.com/juju/juju/version"
+)
+
+type toolsSuite struct{}
+
+var _ = gc.Suite(&toolsSuite{})
+
+func (*toolsSuite) TestBuildVersions(c *gc.C) {
+	versions := BuildVersions(version.MustParse("1.2.3.4"), 3, "trusty", "amd64")
+	c.Assert(versions, jc.DeepEquals, []version.Binary{
+		version.MustParseBinary("1.2.3.4-trusty-amd64"),
+		version.MustParseBinary("1.2.3.5-trusty-amd64"),
+		version.MustParseBinary("1.2.3.6-trusty-amd64"),
+	})
+}
+
+func (*toolsSuite) TestGenerateToolsVersions(c *gc.C) {
+	stor, err := filestorage.NewFileStorageWriter(c.MkDir())
+	c.Assert(err, jc.ErrorIsNil)
+	list := GenerateToolsVersions(c, stor, "releases", "released", version.MustParse("1.2.0"), 3, "trusty", "amd64")
+	var versions []version.Binary
+	for _, tools := range list {
+		versions = append(versions, tools.Version)
+	}
+	c.Assert(versions, jc.DeepEquals, BuildVersions(version.MustParse("1.2.0"), 3, "trusty", "amd64"))
+
+	found, err := envtools.ReadList(stor, "releases", 1, 2)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(found, gc.HasLen, 3)
+}
+
+func (*toolsSuite) TestRemoveFakeToolsInStream(c *gc.C) {
+	stor, err := filestorage.NewFileStorageWriter(c.MkDir())
+	c.Assert(err, jc.ErrorIsNil)
+	UploadFakeTools(c, stor, "released", "released")
+	UploadFakeTools(c, stor, "proposed", "proposed")
+
+	RemoveFakeToolsInStream(c, stor, "proposed", "proposed")
+	_, err = envtools.ReadList(stor, "proposed", version.Current.Major, -1)
+	c.Assert(err, gc.Equals, envtools.ErrNoTools)
+	metadata, err := envtools.ReadMetadata(stor, "proposed")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(metadata, gc.HasLen, 0)
+
+	// The released stream is untouched.
+	released, err := envtools.ReadList(stor, "released", version.Current.Major, -1)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(released, gc.HasLen, len(fakeToolsVersions()))
+	metadata, err = envtools.ReadMetadata(stor, "released")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(metadata, gc.HasLen, len(fakeToolsVersions()))
+}
+
+func (*toolsSuite) TestRemoveFakeToolsInLastStream(c *gc.C) {
+	stor, err := filestorage.NewFileStorageWriter(c.MkDir())
+	c.Assert(err, jc.ErrorIsNil)
+	UploadFakeTools(c, stor, "devel", "devel")
+
+	RemoveFakeToolsInStream(c, stor, "devel", "devel")
+	names, err := stor.List("tools/")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(names, gc.HasLen, 0)
+}
+
+func (*toolsSuite) TestUploadFakeToolsVersionsWithChecksum(c *gc.C) {
+	vers := version.MustParseBinary("1.2.3-trusty-amd64")
+	for i, test := range []struct {
+		about string
+		cs    FakeToolsChecksum
+		sum   func([]byte) string
+	}{{
+		about: "default hash",
+		sum: func(data []byte) string {
+			return fmt.Sprintf("%x", sha256.Sum256(data))
+		},
+	}, {
+		about: "specified hash",
+		cs:    FakeToolsChecksum{Hash: md5.New},
+		sum: func(data []byte) string {
+			return fmt.Sprintf("%x", md5.Sum(data))
+		},
+	}} {
+		c.Logf("test %d: %s", i, test.about)
+		stor, err := filestorage.NewFileStorageWriter(c.MkDir())
+		c.Assert(err, jc.ErrorIsNil)
+		list, err := UploadFakeToolsVersionsWithChecksum(stor, "released", "released", test.cs, vers)
+		c.Assert(err, jc.ErrorIsNil)
+		c.Assert(list, gc.HasLen, 1)
+
+		data := readFakeTools(c, stor, vers)
+		c.Assert(list[0].Size, gc.Equals, int64(len(data)))
+		c.Assert(list[0].SHA256, gc.Equals, test.sum(data))
+	}
+}
+
+func (*toolsSuite) TestUploadFakeToolsVersionsWithWrongChecksum(c *gc.C) {
+	vers := version.MustParseBinary("1.2.3-trusty-amd64")
+	stor, err := filestorage.NewFileStorageWriter(c.MkDir())
+	c.Assert(err, jc.ErrorIsNil)
+	list, err := UploadFakeToolsVersionsWithChecksum(stor, "released", "released", FakeToolsChecksum{Wrong: true}, vers)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(list, gc.HasLen, 1)
+
+	data := readFakeTools(c, stor, vers)
+	c.Assert(list[0].SHA256, gc.Not(gc.Equals), fmt.Sprintf("%x", sha256.Sum256(data)))
+	err = agenttools.UnpackTools(c.MkDir(), list[0], bytes.NewReader(data))
+	c.Assert(err, gc.ErrorMatches, "tarball sha256 mismatch, expected .*, got .*")
+}
+
+func readFakeTools(c *gc.C, stor storage.StorageReader, vers version.Binary) []byte {
+	r, err := stor.Get(envtools.StorageName(vers, "released"))
+	c.Assert(err, jc.ErrorIsNil)
+	defer r.Close()
+	data, err := ioutil.ReadAll(r)
+	c.Assert(err, jc.ErrorIsNil)
+	return data
+}
+
+func (*toolsSuite) TestPlanFakeToolsVersions(c *gc.C) {
+	stor, err := filestorage.NewFileStorageWriter(c.MkDir())
+	c.Assert(err, jc.ErrorIsNil)
+	versions := BuildVersions(version.MustParse("1.2.3"), 2, "trusty", "amd64")
+	planned, err := PlanFakeToolsVersions(stor, "released", versions...)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(planned, gc.HasLen, 2)
+
+	// Nothing is written to storage.
+	names, err := stor.List("")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(names, gc.HasLen, 0)
+
+	// The plan matches what is uploaded.
+	uploaded, err := UploadFakeToolsVersions(stor, "released", "released", versions...)
+	c.Assert(err, jc.ErrorIsNil)
+	for i, upload := range planned {
+		c.Check(upload.Name, gc.Equals, envtools.StorageName(versions[i], "released"))
+		c.Check(upload.Tools, jc.DeepEquals, uploaded[i])
+	}
+}
+
+func (*toolsSuite) TestPlanFakeTools(c *gc.C) {
+	stor, err := filestorage.NewFileStorageWriter(c.MkDir())
+	c.Assert(err, jc.ErrorIsNil)
+	planned, err := PlanFakeTools(stor, "released")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(planned, gc.HasLen, len(fakeToolsVersions()))
+	for i, upload := range planned {
+		c.Check(upload.Tools.Version, gc.Equals, fakeToolsVersions()[i])
+	}
+}
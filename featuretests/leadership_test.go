@@ -133,7 +133,10 @@ func (s *leadershipSuite) TestClaimLeadership(c *gc.C) {
 	duration, err := client.ClaimLeadership(s.serviceId, s.unitId)
 
 	c.Assert(err, gc.IsNil)
-	c.Check(duration, gc.Equals, 30*time.Second)
+	// The client renews ahead of the 30s lease to allow for
+	// the round trip and any clock skew.
+	c.Check(duration > 0, gc.Equals, true)
+	c.Check(duration < 30*time.Second, gc.Equals, true)
 }
 
 func (s *leadershipSuite) TestReleaseLeadership(c *gc.C) {
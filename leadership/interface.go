@@ -28,6 +28,11 @@ type LeadershipManager interface {
 	// BlockUntilLeadershipReleased blocks the caller until leadership is
 	// released for the given serviceId.
 	BlockUntilLeadershipReleased(serviceId string) (err error)
+
+	// TransferLeadership hands leadership of the given serviceId from
+	// fromUnitId, which must hold it, directly to toUnitId, so that no
+	// other unit can claim it in between.
+	TransferLeadership(serviceId, fromUnitId, toUnitId string) (err error)
 }
 
 type LeadershipLeaseManager interface {
@@ -40,6 +45,10 @@ type LeadershipLeaseManager interface {
 	// ReleaseLease releases the lease held for namespace by id.
 	ReleaseLease(namespace, id string) (err error)
 
+	// TransferLease hands the lease held for namespace by fromId to
+	// toId, for the given duration.
+	TransferLease(namespace, fromId, toId string, forDur time.Duration) (err error)
+
 	// RetrieveLease retrieves the current lease token for a given
 	// namespace. This is not intended to be exposed to clients, and is
 	// only available within a server-process.
@@ -55,6 +55,12 @@ func (m *Manager) ReleaseLeadership(sid, uid string) error {
 	return m.leaseMgr.ReleaseLease(leadershipNamespace(sid), uid)
 }
 
+// TransferLeadership implements the LeadershipManager interface.
+func (m *Manager) TransferLeadership(sid, fromUid, toUid string) error {
+	err := m.leaseMgr.TransferLease(leadershipNamespace(sid), fromUid, toUid, leadershipDuration)
+	return errors.Annotatef(err, "cannot transfer leadership of %q to %q", sid, toUid)
+}
+
 // BlockUntilLeadershipReleased implements the LeadershipManager interface.
 func (m *Manager) BlockUntilLeadershipReleased(serviceId string) error {
 	notifier := m.leaseMgr.LeaseReleasedNotifier(leadershipNamespace(serviceId))
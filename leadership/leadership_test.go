@@ -7,6 +7,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/juju/errors"
 	gc "gopkg.in/check.v1"
 
 	"github.com/juju/juju/lease"
@@ -29,6 +30,7 @@ type leadershipSuite struct{}
 type leaseStub struct {
 	ClaimLeaseFn            func(string, string, time.Duration) (string, error)
 	ReleaseLeaseFn          func(string, string) error
+	TransferLeaseFn         func(string, string, string, time.Duration) error
 	LeaseReleasedNotifierFn func(string) <-chan struct{}
 	RetrieveLeaseFn         func(string) lease.Token
 }
@@ -47,6 +49,13 @@ func (s *leaseStub) ReleaseLease(namespace, id string) error {
 	return nil
 }
 
+func (s *leaseStub) TransferLease(namespace, fromId, toId string, forDur time.Duration) error {
+	if s.TransferLeaseFn != nil {
+		return s.TransferLeaseFn(namespace, fromId, toId, forDur)
+	}
+	return nil
+}
+
 func (s *leaseStub) LeaseReleasedNotifier(namespace string) <-chan struct{} {
 	if s.LeaseReleasedNotifierFn != nil {
 		return s.LeaseReleasedNotifierFn(namespace)
@@ -97,6 +106,41 @@ func (s *leadershipSuite) TestReleaseLeadershipTranslation(c *gc.C) {
 	c.Check(err, gc.IsNil)
 }
 
+func (s *leadershipSuite) TestTransferLeadershipTranslation(c *gc.C) {
+
+	numStubCalls := 0
+	stub := &leaseStub{
+		TransferLeaseFn: func(namespace, fromId, toId string, forDur time.Duration) error {
+			numStubCalls++
+			c.Check(namespace, gc.Equals, leadershipNamespace(StubServiceNm))
+			c.Check(fromId, gc.Equals, StubUnitNm)
+			c.Check(toId, gc.Equals, "stub-unit/1")
+			c.Check(forDur, gc.Equals, leadershipDuration)
+			return nil
+		},
+	}
+
+	leaderMgr := NewLeadershipManager(stub)
+	err := leaderMgr.TransferLeadership(StubServiceNm, StubUnitNm, "stub-unit/1")
+
+	c.Check(numStubCalls, gc.Equals, 1)
+	c.Check(err, gc.IsNil)
+}
+
+func (s *leadershipSuite) TestTransferLeadershipNotLeader(c *gc.C) {
+	stub := &leaseStub{
+		TransferLeaseFn: func(namespace, fromId, toId string, forDur time.Duration) error {
+			return lease.NotLeaseOwnerErr
+		},
+	}
+
+	leaderMgr := NewLeadershipManager(stub)
+	err := leaderMgr.TransferLeadership(StubServiceNm, StubUnitNm, "stub-unit/1")
+
+	c.Check(err, gc.ErrorMatches, `cannot transfer leadership of "stub-service" to "stub-unit/1": .*`)
+	c.Check(errors.Cause(err), gc.Equals, lease.NotLeaseOwnerErr)
+}
+
 func (s *leadershipSuite) TestBlockUntilLeadershipReleasedTranslation(c *gc.C) {
 
 	numStubCalls := 0
@@ -32,6 +32,7 @@ func init() {
 	singleton = &leaseManager{
 		claimLease:       make(chan Token),
 		releaseLease:     make(chan releaseLeaseMsg),
+		transferLease:    make(chan transferLeaseMsg),
 		leaseReleasedSub: make(chan leaseReleasedMsg),
 		copyOfTokens:     make(chan []Token),
 	}
@@ -71,6 +72,11 @@ type releaseLeaseMsg struct {
 	Token *Token
 	Err   error
 }
+type transferLeaseMsg struct {
+	Token  Token
+	FromId string
+	Err    error
+}
 type leaseReleasedMsg struct {
 	Watcher      chan<- struct{}
 	ForNamespace string
@@ -81,6 +87,7 @@ type leaseManager struct {
 	retrieveLease    chan Token
 	claimLease       chan Token
 	releaseLease     chan releaseLeaseMsg
+	transferLease    chan transferLeaseMsg
 	leaseReleasedSub chan leaseReleasedMsg
 	copyOfTokens     chan []Token
 }
@@ -145,6 +152,22 @@ func (m *leaseManager) ReleaseLease(namespace, id string) (err error) {
 	return nil
 }
 
+// TransferLease hands the lease held for namespace by fromId to toId,
+// for the given duration. The lease is never released in between, so
+// no other id can claim it. NotLeaseOwnerErr is returned if fromId
+// does not hold the lease.
+func (m *leaseManager) TransferLease(namespace, fromId, toId string, forDur time.Duration) error {
+
+	token := Token{namespace, toId, time.Now().Add(forDur)}
+	m.transferLease <- transferLeaseMsg{Token: token, FromId: fromId}
+	response := <-m.transferLease
+
+	if response.Err != nil {
+		return errors.Annotatef(response.Err, `could not transfer lease for namespace "%s" from "%s" to "%s"`, namespace, fromId, toId)
+	}
+	return nil
+}
+
 // LeaseReleasedNotifier returns a channel a caller can block on to be
 // notified of when a lease is released for namespace. This channel is
 // reusable, but will be closed if it does not respond within
@@ -198,6 +221,17 @@ func (m *leaseManager) workerLoop(stop <-chan struct{}) error {
 			m.releaseLease <- response
 			notifyOfRelease(releaseSubs[claim.Token.Namespace], claim.Token.Namespace)
 
+		case transfer := <-m.transferLease:
+			var response transferLeaseMsg
+			response.Err = transferLease(leaseCache, transfer.FromId, transfer.Token)
+			if response.Err == nil {
+				response.Err = m.leasePersistor.WriteToken(transfer.Token.Namespace, transfer.Token)
+				if transfer.Token.Expiration.Before(nextExpiration) {
+					nextExpiration = transfer.Token.Expiration
+				}
+			}
+			m.transferLease <- response
+
 		case subscription := <-m.leaseReleasedSub:
 			subscribe(releaseSubs, subscription)
 		case <-m.copyOfTokens:
@@ -266,6 +300,15 @@ func releaseLease(cache map[string]Token, claim *Token) error {
 	return nil
 }
 
+func transferLease(cache map[string]Token, fromId string, claim Token) error {
+	if active, ok := cache[claim.Namespace]; !ok || active.Id != fromId {
+		return NotLeaseOwnerErr
+	}
+	cache[claim.Namespace] = claim
+	logger.Infof(`"%s" transferred lease for "%s" to "%s"`, fromId, claim.Namespace, claim.Id)
+	return nil
+}
+
 func subscribe(subMap map[string][]chan<- struct{}, subscription leaseReleasedMsg) {
 	subList := subMap[subscription.ForNamespace]
 	subList = append(subList, subscription.Watcher)
@@ -7,6 +7,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/juju/errors"
 	gc "gopkg.in/check.v1"
 
 	coretesting "github.com/juju/juju/testing"
@@ -126,6 +127,46 @@ func (s *leaseSuite) TestReleaseLease(c *gc.C) {
 	c.Assert(toks, gc.HasLen, 0)
 }
 
+func (s *leaseSuite) TestTransferLease(c *gc.C) {
+	var written []Token
+	persistor := &stubLeasePersistor{
+		WriteTokenFn: func(id string, tok Token) error {
+			written = append(written, tok)
+			return nil
+		},
+	}
+	stop := make(chan struct{})
+	go WorkerLoop(persistor)(stop)
+	defer func() { stop <- struct{}{} }()
+
+	mgr := Manager()
+
+	_, err := mgr.ClaimLease(testNamespace, testId, testDuration)
+	c.Assert(err, gc.IsNil)
+
+	err = mgr.TransferLease(testNamespace, testId, "stub-unit/1", testDuration)
+	c.Assert(err, gc.IsNil)
+
+	tok := mgr.RetrieveLease(testNamespace)
+	c.Check(tok.Id, gc.Equals, "stub-unit/1")
+	c.Assert(written, gc.HasLen, 2)
+	c.Check(written[1].Id, gc.Equals, "stub-unit/1")
+
+	// The previous owner can no longer transfer the lease.
+	err = mgr.TransferLease(testNamespace, testId, "stub-unit/2", testDuration)
+	c.Check(errors.Cause(err), gc.Equals, NotLeaseOwnerErr)
+}
+
+func (s *leaseSuite) TestTransferUnownedLease(c *gc.C) {
+	stop := make(chan struct{})
+	go WorkerLoop(&stubLeasePersistor{})(stop)
+	defer func() { stop <- struct{}{} }()
+
+	err := Manager().TransferLease(testNamespace, testId, "stub-unit/1", testDuration)
+	c.Check(errors.Cause(err), gc.Equals, NotLeaseOwnerErr)
+	c.Check(Manager().CopyOfLeaseTokens(), gc.HasLen, 0)
+}
+
 func (s *leaseSuite) TestRetrieveLease(c *gc.C) {
 	stop := make(chan struct{})
 	go WorkerLoop(&stubLeasePersistor{})(stop)
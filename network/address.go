@@ -446,6 +446,41 @@ func SortAddresses(addrs []Address, preferIPv6 bool) {
 	}
 }
 
+// scopeSpecificity ranks scopes from the least specific, unknown,
+// to the most specific, link-local.
+var scopeSpecificity = map[Scope]int{
+	ScopeUnknown:      0,
+	ScopePublic:       1,
+	ScopeCloudLocal:   2,
+	ScopeMachineLocal: 3,
+	ScopeLinkLocal:    4,
+}
+
+// UniqueAddresses returns the given addresses with their IP values
+// normalised and duplicates removed, in the order each value first
+// appears. The same address may be reported more than once, for
+// example when it is configured on several interfaces; when the
+// duplicates' scopes differ, the most specific scope is kept.
+func UniqueAddresses(addrs []Address) []Address {
+	var unique []Address
+	seen := make(map[string]int)
+	for _, addr := range addrs {
+		if ip := net.ParseIP(addr.Value); ip != nil {
+			addr.Value = ip.String()
+		}
+		i, ok := seen[addr.Value]
+		if !ok {
+			seen[addr.Value] = len(unique)
+			unique = append(unique, addr)
+			continue
+		}
+		if scopeSpecificity[addr.Scope] > scopeSpecificity[unique[i].Scope] {
+			unique[i].Scope = addr.Scope
+		}
+	}
+	return unique
+}
+
 // DecimalToIPv4 converts a decimal to the dotted quad IP address format.
 func DecimalToIPv4(addr uint32) net.IP {
 	bytes := make([]byte, 4)
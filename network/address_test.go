@@ -687,6 +687,26 @@ func (*AddressSuite) TestSortAddresses(c *gc.C) {
 	))
 }
 
+func (*AddressSuite) TestUniqueAddresses(c *gc.C) {
+	addrs := []network.Address{
+		network.NewAddress("10.0.0.1", network.ScopeUnknown),
+		network.NewAddress("::1", network.ScopeMachineLocal),
+		network.NewAddress("10.0.0.1", network.ScopeCloudLocal),
+		network.NewAddress("2001:0db8:0000::1", network.ScopePublic),
+		network.NewAddress("example.com", network.ScopeUnknown),
+		network.NewAddress("2001:db8::1", network.ScopeUnknown),
+		network.NewAddress("10.0.0.1", network.ScopePublic),
+		network.NewAddress("example.com", network.ScopePublic),
+	}
+	c.Assert(network.UniqueAddresses(addrs), jc.DeepEquals, []network.Address{
+		network.NewAddress("10.0.0.1", network.ScopeCloudLocal),
+		network.NewAddress("::1", network.ScopeMachineLocal),
+		network.NewAddress("2001:db8::1", network.ScopePublic),
+		network.NewAddress("example.com", network.ScopePublic),
+	})
+	c.Assert(network.UniqueAddresses(nil), gc.HasLen, 0)
+}
+
 func (*AddressSuite) TestIPv4ToDecimal(c *gc.C) {
 	zeroIP, err := network.IPv4ToDecimal(net.ParseIP("0.0.0.0"))
 	c.Assert(err, jc.ErrorIsNil)
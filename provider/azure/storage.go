@@ -143,6 +143,11 @@ func (storage *azureStorage) RemoveAll() error {
 	return context.DeleteContainer(storage.getContainer())
 }
 
+// RemovePrefix is specified in the StorageWriter interface.
+func (azStorage *azureStorage) RemovePrefix(prefix string) (int, error) {
+	return storage.RemovePrefix(azStorage, prefix)
+}
+
 // createContainer makes a private container in the storage account.
 // It can be called when the container already exists and returns with no error
 // if it does.  To avoid unnecessary HTTP requests, we do this only once for
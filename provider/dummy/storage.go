@@ -256,6 +256,10 @@ func (s *dummyStorage) RemoveAll() error {
 	return srv.RemoveAll()
 }
 
+func (s *dummyStorage) RemovePrefix(prefix string) (int, error) {
+	return storage.RemovePrefix(s, prefix)
+}
+
 func (s *dummyStorage) List(prefix string) ([]string, error) {
 	srv, err := s.server()
 	if err != nil {
@@ -204,6 +204,10 @@ func (s *ec2storage) RemoveAll() error {
 	return err
 }
 
+func (s *ec2storage) RemovePrefix(prefix string) (int, error) {
+	return storage.RemovePrefix(s, prefix)
+}
+
 func deleteBucket(s *ec2storage) (err error) {
 	for a := s.DefaultConsistencyStrategy().Start(); a.Next(); {
 		err = s.bucket.DelBucket()
@@ -272,6 +272,10 @@ func (s *JoyentStorage) RemoveAll() error {
 	return nil
 }
 
+func (s *JoyentStorage) RemovePrefix(prefix string) (int, error) {
+	return storage.RemovePrefix(s, prefix)
+}
+
 func (s *JoyentStorage) DefaultConsistencyStrategy() utils.AttemptStrategy {
 	return utils.AttemptStrategy{}
 }
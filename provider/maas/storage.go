@@ -245,3 +245,8 @@ func (stor *maasStorage) RemoveAll() error {
 	}
 	return nil
 }
+
+// RemovePrefix is specified in the StorageWriter interface.
+func (stor *maasStorage) RemovePrefix(prefix string) (int, error) {
+	return storage.RemovePrefix(stor, prefix)
+}
@@ -178,6 +178,11 @@ func (s *openstackstorage) RemoveAll() error {
 	return err
 }
 
+// RemovePrefix is specified in the StorageWriter interface.
+func (s *openstackstorage) RemovePrefix(prefix string) (int, error) {
+	return storage.RemovePrefix(s, prefix)
+}
+
 // maybeNotFound returns a errors.NotFoundError if the root cause of the specified error is due to a file or
 // container not being found.
 func maybeNotFound(err error) (error, bool) {
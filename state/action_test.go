@@ -686,6 +686,50 @@ func (s *ActionSuite) TestActionStatusWatcher(c *gc.C) {
 	watchCancelledOrCompleted.AssertNoChange()
 }
 
+func (s *ActionSuite) TestWatchActionResultsFor(c *gc.C) {
+	done, err := s.unit.AddAction("snapshot", nil)
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = done.Finish(state.ActionResults{Status: state.ActionCompleted})
+	c.Assert(err, jc.ErrorIsNil)
+	watched, err := s.unit2.AddAction("snapshot", nil)
+	c.Assert(err, jc.ErrorIsNil)
+	unwatched, err := s.unit.AddAction("snapshot", nil)
+	c.Assert(err, jc.ErrorIsNil)
+
+	w := s.State.WatchActionResultsFor(done.ActionTag(), watched.ActionTag())
+	defer statetesting.AssertStop(c, w)
+	wc := statetesting.NewStringsWatcherC(c, s.State, w)
+	// Actions that have already finished are reported at once.
+	wc.AssertChange(done.Id())
+	wc.AssertNoChange()
+
+	_, err = unwatched.Finish(state.ActionResults{Status: state.ActionCompleted})
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertNoChange()
+
+	_, err = watched.Begin()
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertNoChange()
+	_, err = watched.Finish(state.ActionResults{Status: state.ActionFailed, Message: "oops"})
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertChange(watched.Id())
+	wc.AssertNoChange()
+}
+
+func (s *ActionSuite) TestWatchActionResultsForNoActions(c *gc.C) {
+	w := s.State.WatchActionResultsFor()
+	defer statetesting.AssertStop(c, w)
+	wc := statetesting.NewStringsWatcherC(c, s.State, w)
+	wc.AssertChange()
+	wc.AssertNoChange()
+
+	action, err := s.unit.AddAction("snapshot", nil)
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = action.Finish(state.ActionResults{Status: state.ActionCompleted})
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertNoChange()
+}
+
 func expectActionIds(actions ...*state.Action) []string {
 	ids := make([]string, len(actions))
 	for i, action := range actions {
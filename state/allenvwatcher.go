@@ -0,0 +1,192 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"github.com/juju/errors"
+	"github.com/juju/names"
+	"launchpad.net/tomb"
+
+	"github.com/juju/juju/state/multiwatcher"
+	"github.com/juju/juju/state/watcher"
+)
+
+// AllEnvWatcher watches changes to all the environments managed by
+// the state server, reporting them as the deltas a Multiwatcher
+// reports for each environment, tagged with the environment's UUID.
+type AllEnvWatcher struct {
+	tomb tomb.Tomb
+	st   *State
+	in   chan []multiwatcher.EnvironDelta
+	out  chan []multiwatcher.EnvironDelta
+
+	// envs holds the watchers of the environments currently
+	// watched, by environment UUID. It is only accessed by
+	// the loop goroutine.
+	envs map[string]*envMultiwatcher
+}
+
+// envMultiwatcher watches a single environment for an AllEnvWatcher.
+type envMultiwatcher struct {
+	st      *State
+	w       *Multiwatcher
+	ownsSt  bool
+	stopped chan struct{}
+	done    chan struct{}
+}
+
+// WatchAllEnvs returns an AllEnvWatcher watching every environment
+// managed by the state server. The first call to Next returns the
+// current state of each environment.
+func (st *State) WatchAllEnvs() *AllEnvWatcher {
+	w := &AllEnvWatcher{
+		st:   st,
+		in:   make(chan []multiwatcher.EnvironDelta),
+		out:  make(chan []multiwatcher.EnvironDelta),
+		envs: make(map[string]*envMultiwatcher),
+	}
+	go func() {
+		defer w.tomb.Done()
+		w.tomb.Kill(w.loop())
+	}()
+	return w
+}
+
+// Next retrieves all changes that have happened since the last
+// time it was called, blocking until there are some changes available.
+func (w *AllEnvWatcher) Next() ([]multiwatcher.EnvironDelta, error) {
+	select {
+	case deltas := <-w.out:
+		return deltas, nil
+	case <-w.tomb.Dead():
+	}
+	if err := w.tomb.Err(); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return nil, errors.Trace(ErrStopped)
+}
+
+// Stop stops the watcher.
+func (w *AllEnvWatcher) Stop() error {
+	w.tomb.Kill(nil)
+	return w.tomb.Wait()
+}
+
+func (w *AllEnvWatcher) loop() error {
+	envWatcher := w.st.WatchEnvironments()
+	defer watcher.Stop(envWatcher, &w.tomb)
+	defer w.stopAll()
+
+	var pending []multiwatcher.EnvironDelta
+	for {
+		var out chan []multiwatcher.EnvironDelta
+		if len(pending) > 0 {
+			out = w.out
+		}
+		select {
+		case <-w.tomb.Dying():
+			return tomb.ErrDying
+		case uuids, ok := <-envWatcher.Changes():
+			if !ok {
+				return watcher.EnsureErr(envWatcher)
+			}
+			for _, uuid := range uuids {
+				if err := w.envChanged(uuid); err != nil {
+					return errors.Trace(err)
+				}
+			}
+		case deltas := <-w.in:
+			pending = append(pending, deltas...)
+		case out <- pending:
+			pending = nil
+		}
+	}
+}
+
+// envChanged starts or stops watching the environment with the given
+// UUID, according to whether it still exists.
+func (w *AllEnvWatcher) envChanged(uuid string) error {
+	tag := names.NewEnvironTag(uuid)
+	env, err := w.st.GetEnvironment(tag)
+	if errors.IsNotFound(err) || err == nil && env.Life() == Dead {
+		w.stopEnv(uuid)
+		return nil
+	} else if err != nil {
+		return errors.Trace(err)
+	}
+	if _, ok := w.envs[uuid]; ok {
+		return nil
+	}
+	st := w.st
+	if uuid != w.st.EnvironUUID() {
+		if st, err = w.st.ForEnviron(tag); err != nil {
+			return errors.Annotatef(err, "cannot open state for environment %s", uuid)
+		}
+	}
+	ew := &envMultiwatcher{
+		st:      st,
+		w:       st.Watch(),
+		ownsSt:  st != w.st,
+		stopped: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	w.envs[uuid] = ew
+	go w.forward(uuid, ew)
+	return nil
+}
+
+// forward sends the changes reported by the environment's
+// Multiwatcher to the loop goroutine, tagged with its UUID.
+func (w *AllEnvWatcher) forward(uuid string, ew *envMultiwatcher) {
+	defer close(ew.done)
+	for {
+		deltas, err := ew.w.Next()
+		if err != nil {
+			select {
+			case <-ew.stopped:
+			default:
+				w.tomb.Kill(errors.Annotatef(err, "watching environment %s", uuid))
+			}
+			return
+		}
+		envDeltas := make([]multiwatcher.EnvironDelta, len(deltas))
+		for i, delta := range deltas {
+			envDeltas[i] = multiwatcher.EnvironDelta{EnvUUID: uuid, Delta: delta}
+		}
+		select {
+		case w.in <- envDeltas:
+		case <-ew.stopped:
+			return
+		case <-w.tomb.Dying():
+			return
+		}
+	}
+}
+
+// stopEnv stops watching the environment with the given UUID,
+// if it is being watched.
+func (w *AllEnvWatcher) stopEnv(uuid string) {
+	ew, ok := w.envs[uuid]
+	if !ok {
+		return
+	}
+	delete(w.envs, uuid)
+	close(ew.stopped)
+	if err := ew.w.Stop(); err != nil {
+		logger.Errorf("cannot stop watching environment %s: %v", uuid, err)
+	}
+	<-ew.done
+	if ew.ownsSt {
+		if err := ew.st.Close(); err != nil {
+			logger.Errorf("cannot close state for environment %s: %v", uuid, err)
+		}
+	}
+}
+
+// stopAll stops watching all environments.
+func (w *AllEnvWatcher) stopAll() {
+	for uuid := range w.envs {
+		w.stopEnv(uuid)
+	}
+}
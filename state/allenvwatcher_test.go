@@ -0,0 +1,82 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state_test
+
+import (
+	"time"
+
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/state"
+	"github.com/juju/juju/state/multiwatcher"
+	"github.com/juju/juju/testing"
+)
+
+type AllEnvWatcherSuite struct {
+	ConnSuite
+}
+
+var _ = gc.Suite(&AllEnvWatcherSuite{})
+
+// machinesSeen calls Next on the watcher until it has reported the
+// given number of machines, and returns the ids of the machines
+// reported, by environment UUID.
+func machinesSeen(c *gc.C, w *state.AllEnvWatcher, count int) map[string][]string {
+	seen := make(map[string][]string)
+	timeout := time.After(testing.LongWait)
+	for n := 0; n < count; {
+		result := make(chan []multiwatcher.EnvironDelta, 1)
+		go func() {
+			deltas, err := w.Next()
+			c.Check(err, jc.ErrorIsNil)
+			result <- deltas
+		}()
+		select {
+		case deltas := <-result:
+			for _, d := range deltas {
+				if m, ok := d.Delta.Entity.(*multiwatcher.MachineInfo); ok {
+					seen[d.EnvUUID] = append(seen[d.EnvUUID], m.Id)
+					n++
+				}
+			}
+		case <-timeout:
+			c.Fatalf("timed out waiting for %d machines; saw %v", count, seen)
+		}
+	}
+	return seen
+}
+
+func (s *AllEnvWatcherSuite) TestWatchAllEnvs(c *gc.C) {
+	st1 := s.factory.MakeEnvironment(c, nil)
+	defer st1.Close()
+	_, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = st1.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+
+	w := s.State.WatchAllEnvs()
+	defer func() {
+		c.Check(w.Stop(), jc.ErrorIsNil)
+	}()
+	c.Assert(machinesSeen(c, w, 2), jc.DeepEquals, map[string][]string{
+		s.State.EnvironUUID(): {"0"},
+		st1.EnvironUUID():     {"0"},
+	})
+
+	// Changes made after the initial state are reported too.
+	_, err = st1.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(machinesSeen(c, w, 1), jc.DeepEquals, map[string][]string{
+		st1.EnvironUUID(): {"1"},
+	})
+}
+
+func (s *AllEnvWatcherSuite) TestNextAfterStop(c *gc.C) {
+	w := s.State.WatchAllEnvs()
+	err := w.Stop()
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = w.Next()
+	c.Assert(err, gc.ErrorMatches, state.ErrStopped.Error())
+}
@@ -85,6 +85,7 @@ var multiEnvCollections = set.NewStrings(
 	settingsC,
 	settingsrefsC,
 	statusesC,
+	statusesHistoryC,
 	storageAttachmentsC,
 	storageConstraintsC,
 	storageInstancesC,
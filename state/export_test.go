@@ -46,6 +46,8 @@ var (
 	MultiEnvCollections    = multiEnvCollections
 	PickAddress            = &pickAddress
 	AddVolumeOp            = (*State).addVolumeOp
+
+	MaxStatusHistoryEntries = &maxStatusHistoryEntries
 )
 
 type (
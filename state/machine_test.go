@@ -1563,6 +1563,21 @@ func (s *MachineSuite) TestGetSetStatusWhileAlive(c *gc.C) {
 	c.Assert(data, gc.DeepEquals, map[string]interface{}{
 		"foo": "bar",
 	})
+
+	err = s.machine.SetStatus(state.StatusStarted, "", map[string]interface{}{
+		"kernel-version": "3.13.0-46-generic",
+	})
+	c.Assert(err, jc.ErrorIsNil)
+	_, _, data, err = s.machine.Status()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(data, gc.DeepEquals, map[string]interface{}{
+		"kernel-version": "3.13.0-46-generic",
+	})
+
+	err = s.machine.SetStatus(state.StatusStopped, "", map[string]interface{}{
+		"foo": "bar",
+	})
+	c.Assert(err, gc.ErrorMatches, `cannot set status data when status is "stopped"`)
 }
 
 func (s *MachineSuite) TestSetStatusPending(c *gc.C) {
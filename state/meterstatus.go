@@ -7,6 +7,7 @@ import (
 	"github.com/juju/errors"
 	"github.com/juju/loggo"
 	jujutxn "github.com/juju/txn"
+	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
 	"gopkg.in/mgo.v2/txn"
 )
@@ -112,7 +113,9 @@ func (u *Unit) getMeterStatusDoc() (*meterStatusDoc, error) {
 	defer closer()
 	var status meterStatusDoc
 	err := meterStatuses.FindId(u.globalKey()).One(&status)
-	if err != nil {
+	if err == mgo.ErrNotFound {
+		return nil, errors.NewNotFound(err, "")
+	} else if err != nil {
 		return nil, errors.Trace(err)
 	}
 	return &status, nil
@@ -4,6 +4,7 @@
 package state_test
 
 import (
+	"github.com/juju/errors"
 	jc "github.com/juju/testing/checkers"
 	gc "gopkg.in/check.v1"
 	"gopkg.in/mgo.v2/bson"
@@ -90,6 +91,7 @@ func (s *UnitSuite) TestMeterStatusRemovedWithUnit(c *gc.C) {
 	c.Assert(err, jc.ErrorIsNil)
 	code, info, err := s.unit.GetMeterStatus()
 	c.Assert(err, gc.ErrorMatches, "cannot retrieve meter status for unit .*: not found")
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
 	c.Assert(code, gc.Equals, "NOT AVAILABLE")
 	c.Assert(info, gc.Equals, "")
 }
@@ -45,6 +45,15 @@ type Delta struct {
 	Entity EntityInfo
 }
 
+// EnvironDelta holds details of a change to one of
+// the environments managed by a state server.
+type EnvironDelta struct {
+	// EnvUUID holds the UUID of the environment
+	// holding the changed entity.
+	EnvUUID string
+	Delta   Delta
+}
+
 // MarshalJSON implements json.Marshaler.
 func (d *Delta) MarshalJSON() ([]byte, error) {
 	b, err := json.Marshal(d.Entity)
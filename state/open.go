@@ -173,6 +173,7 @@ var indexes = []struct {
 	{ipaddressesC, []string{"env-uuid", "state"}, false, false},
 	{ipaddressesC, []string{"env-uuid", "subnetid"}, false, false},
 	{storageInstancesC, []string{"env-uuid", "owner"}, false, false},
+	{statusesHistoryC, []string{"env-uuid", "entityid", "-updated"}, false, false},
 }
 
 // The capped collection used for transaction logs defaults to 10MB.
@@ -723,6 +723,14 @@ func (s *Service) removeUnitOps(u *Unit, asserts bson.D) ([]txn.Op, error) {
 	if err != nil {
 		return nil, err
 	}
+	historyOps, err := removeStatusHistoryOps(s.st, u.globalKey(), 0)
+	if err != nil {
+		return nil, err
+	}
+	agentHistoryOps, err := removeStatusHistoryOps(s.st, u.globalAgentKey(), 0)
+	if err != nil {
+		return nil, err
+	}
 
 	observedFieldsMatch := bson.D{
 		{"charmurl", u.doc.CharmURL},
@@ -743,6 +751,8 @@ func (s *Service) removeUnitOps(u *Unit, asserts bson.D) ([]txn.Op, error) {
 	)
 	ops = append(ops, portsOps...)
 	ops = append(ops, storageInstanceOps...)
+	ops = append(ops, historyOps...)
+	ops = append(ops, agentHistoryOps...)
 	if u.doc.CharmURL != nil {
 		decOps, err := settingsDecRefOps(s.st, s.doc.Name, u.doc.CharmURL)
 		if errors.IsNotFound(err) {
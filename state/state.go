@@ -73,6 +73,7 @@ const (
 	cleanupsC           = "cleanups"
 	annotationsC        = "annotations"
 	statusesC           = "statuses"
+	statusesHistoryC    = "statuseshistory"
 	stateServersC       = "stateServers"
 	openedPortsC        = "openedPorts"
 	metricsC            = "metrics"
@@ -384,23 +385,10 @@ func IsUpgradeInProgressError(err error) bool {
 // stable state (all agents are running the current version).
 func (st *State) SetEnvironAgentVersion(newVersion version.Number) (err error) {
 	buildTxn := func(attempt int) ([]txn.Op, error) {
-		settings, err := readSettings(st, environGlobalKey)
+		currentVersion, settingsOp, err := st.agentVersionOp(newVersion)
 		if err != nil {
-			return nil, errors.Trace(err)
-		}
-		agentVersion, ok := settings.Get("agent-version")
-		if !ok {
-			return nil, errors.Errorf("no agent version set in the environment")
-		}
-		currentVersion, ok := agentVersion.(string)
-		if !ok {
-			return nil, errors.Errorf("invalid agent version format: expected string, got %v", agentVersion)
-		}
-		if newVersion.String() == currentVersion {
-			// Nothing to do.
-			return nil, jujutxn.ErrNoOperations
+			return nil, err
 		}
-
 		if err := st.checkCanUpgrade(currentVersion, newVersion.String()); err != nil {
 			return nil, errors.Trace(err)
 		}
@@ -411,12 +399,8 @@ func (st *State) SetEnvironAgentVersion(newVersion version.Number) (err error) {
 				C:      upgradeInfoC,
 				Id:     currentUpgradeId,
 				Assert: txn.DocMissing,
-			}, {
-				C:      settingsC,
-				Id:     st.docID(environGlobalKey),
-				Assert: bson.D{{"txn-revno", settings.txnRevno}},
-				Update: bson.D{{"$set", bson.D{{"agent-version", newVersion.String()}}}},
 			},
+			settingsOp,
 		}
 		return ops, nil
 	}
@@ -433,6 +417,34 @@ func (st *State) SetEnvironAgentVersion(newVersion version.Number) (err error) {
 	return errors.Trace(err)
 }
 
+// agentVersionOp returns the environment's current agent version and
+// an operation that sets it to newVersion, or jujutxn.ErrNoOperations
+// if it is already set to newVersion.
+func (st *State) agentVersionOp(newVersion version.Number) (string, txn.Op, error) {
+	settings, err := readSettings(st, environGlobalKey)
+	if err != nil {
+		return "", txn.Op{}, errors.Trace(err)
+	}
+	agentVersion, ok := settings.Get("agent-version")
+	if !ok {
+		return "", txn.Op{}, errors.Errorf("no agent version set in the environment")
+	}
+	currentVersion, ok := agentVersion.(string)
+	if !ok {
+		return "", txn.Op{}, errors.Errorf("invalid agent version format: expected string, got %v", agentVersion)
+	}
+	if newVersion.String() == currentVersion {
+		// Nothing to do.
+		return "", txn.Op{}, jujutxn.ErrNoOperations
+	}
+	return currentVersion, txn.Op{
+		C:      settingsC,
+		Id:     st.docID(environGlobalKey),
+		Assert: bson.D{{"txn-revno", settings.txnRevno}},
+		Update: bson.D{{"$set", bson.D{{"agent-version", newVersion.String()}}}},
+	}, nil
+}
+
 func (st *State) buildAndValidateEnvironConfig(updateAttrs map[string]interface{}, removeAttrs []string, oldConfig *config.Config) (validCfg *config.Config, err error) {
 	newConfig, err := oldConfig.Apply(updateAttrs)
 	if err != nil {
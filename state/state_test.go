@@ -4244,6 +4244,51 @@ func (s *StateSuite) TestWatchMachineAddresses(c *gc.C) {
 	c.Assert(w.Err(), jc.Satisfies, errors.IsNotFound)
 }
 
+func (s *StateSuite) TestWatchAllMachineAddresses(c *gc.C) {
+	machine0, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	machine1, err := s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+
+	w := s.State.WatchAllMachineAddresses()
+	defer statetesting.AssertStop(c, w)
+	wc := statetesting.NewStringsWatcherC(c, s.State, w)
+	wc.AssertChangeInSingleEvent("0", "1")
+	wc.AssertNoChange()
+
+	// Change a machine without changing its addresses: not reported.
+	err = machine0.SetProvisioned(instance.Id("i-blah"), "fake-nonce", nil)
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertNoChange()
+
+	// Set machine addresses: reported.
+	err = machine0.SetMachineAddresses(network.NewAddress("abc", network.ScopeUnknown))
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertChange("0")
+	wc.AssertNoChange()
+
+	// Set provider addresses eclipsing machine addresses: reported.
+	err = machine0.SetAddresses(network.NewAddress("abc", network.ScopePublic))
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertChange("0")
+	wc.AssertNoChange()
+
+	// Add a machine: reported.
+	_, err = s.State.AddMachine("quantal", state.JobHostUnits)
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertChange("2")
+	wc.AssertNoChange()
+
+	// Remove a machine: reported.
+	err = machine1.EnsureDead()
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertNoChange()
+	err = machine1.Remove()
+	c.Assert(err, jc.ErrorIsNil)
+	wc.AssertChange("1")
+	wc.AssertNoChange()
+}
+
 func (s *StateSuite) TestNowToTheSecond(c *gc.C) {
 	t := state.NowToTheSecond()
 	rounded := t.Round(time.Second)
@@ -217,7 +217,8 @@ func (doc machineStatusDoc) validateSet(allowPending bool) error {
 			return errors.Errorf("cannot set status %q without info", doc.Status)
 		}
 	}
-	if doc.StatusData != nil && doc.Status != StatusError {
+	// A started machine's status data describes the host.
+	if doc.StatusData != nil && doc.Status != StatusError && doc.Status != StatusStarted {
 		return errors.Errorf("cannot set status data when status is %q", doc.Status)
 	}
 	return nil
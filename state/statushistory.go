@@ -0,0 +1,120 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package state
+
+import (
+	"time"
+
+	"github.com/juju/errors"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+)
+
+// historicalStatusDoc records a status set for an entity. A document
+// is written whenever the status of a unit or its agent is set.
+type historicalStatusDoc struct {
+	DocID      string                 `bson:"_id"`
+	EnvUUID    string                 `bson:"env-uuid"`
+	EntityId   string                 `bson:"entityid"`
+	Status     Status                 `bson:"status"`
+	StatusInfo string                 `bson:"statusinfo"`
+	StatusData map[string]interface{} `bson:"statusdata"`
+	Updated    time.Time              `bson:"updated"`
+}
+
+// maxStatusHistoryEntries is the number of statuses kept in the status
+// history of each entity. The oldest are removed as new ones are set.
+var maxStatusHistoryEntries = 100
+
+// statusHistoryOps returns the operations needed to record the given
+// status document, set for the entity with the given globalKey, in
+// the status history, and to remove the entity's oldest statuses so
+// that no more than maxStatusHistoryEntries are kept.
+func statusHistoryOps(st *State, globalKey string, doc statusDoc) ([]txn.Op, error) {
+	ops, err := removeStatusHistoryOps(st, globalKey, maxStatusHistoryEntries-1)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return append(ops, txn.Op{
+		C:      statusesHistoryC,
+		Id:     st.docID(bson.NewObjectId().Hex()),
+		Assert: txn.DocMissing,
+		Insert: &historicalStatusDoc{
+			EntityId:   globalKey,
+			Status:     doc.Status,
+			StatusInfo: doc.StatusInfo,
+			StatusData: doc.StatusData,
+			Updated:    time.Now().UTC(),
+		},
+	}), nil
+}
+
+// removeStatusHistoryOps returns the operations needed to remove the
+// statuses in the history of the entity with the given globalKey,
+// other than the keep most recently set.
+func removeStatusHistoryOps(st *State, globalKey string, keep int) ([]txn.Op, error) {
+	history, closer := st.getCollection(statusesHistoryC)
+	defer closer()
+
+	var docs []struct {
+		DocID string `bson:"_id"`
+	}
+	sel := bson.D{{"entityid", globalKey}}
+	err := history.Find(sel).Sort("-updated", "-_id").Skip(keep).Select(bson.D{{"_id", 1}}).All(&docs)
+	if err != nil {
+		return nil, errors.Annotatef(err, "cannot get status history of %q", globalKey)
+	}
+	ops := make([]txn.Op, len(docs))
+	for i, doc := range docs {
+		ops[i] = txn.Op{
+			C:      statusesHistoryC,
+			Id:     doc.DocID,
+			Remove: true,
+		}
+	}
+	return ops, nil
+}
+
+// StatusHistoryEntry holds a status that a unit
+// or its agent had since some time.
+type StatusHistoryEntry struct {
+	// Agent reports whether the status is that of the
+	// unit's agent, rather than the unit itself.
+	Agent bool
+
+	Status Status
+	Info   string
+	Data   map[string]interface{}
+	Since  time.Time
+}
+
+// StatusHistory returns up to size of the statuses most recently set
+// for the unit and its agent, most recent first. Fewer entries are
+// returned if fewer statuses have been set.
+func (u *Unit) StatusHistory(size int) ([]StatusHistoryEntry, error) {
+	if size <= 0 {
+		return nil, errors.NotValidf("status history size %d", size)
+	}
+	history, closer := u.st.getCollection(statusesHistoryC)
+	defer closer()
+
+	var docs []historicalStatusDoc
+	agentKey := u.globalAgentKey()
+	sel := bson.D{{"entityid", bson.D{{"$in", []string{u.globalKey(), agentKey}}}}}
+	err := history.Find(sel).Sort("-updated", "-_id").Limit(size).All(&docs)
+	if err != nil {
+		return nil, errors.Annotatef(err, "cannot get status history of unit %q", u)
+	}
+	entries := make([]StatusHistoryEntry, len(docs))
+	for i, doc := range docs {
+		entries[i] = StatusHistoryEntry{
+			Agent:  doc.EntityId == agentKey,
+			Status: doc.Status,
+			Info:   doc.StatusInfo,
+			Data:   doc.StatusData,
+			Since:  doc.Updated,
+		}
+	}
+	return entries, nil
+}
@@ -801,6 +801,10 @@ func (u *Unit) SetStatus(status Status, info string, data map[string]interface{}
 	if err != nil {
 		return err
 	}
+	historyOps, err := statusHistoryOps(u.st, u.globalKey(), doc.statusDoc)
+	if err != nil {
+		return err
+	}
 	ops := []txn.Op{{
 		C:      unitsC,
 		Id:     u.doc.DocID,
@@ -808,6 +812,7 @@ func (u *Unit) SetStatus(status Status, info string, data map[string]interface{}
 	},
 		updateStatusOp(u.st, u.globalKey(), doc.statusDoc),
 	}
+	ops = append(ops, historyOps...)
 	err = u.st.runTransaction(ops)
 	if err != nil {
 		return fmt.Errorf("cannot set status of unit %q: %v", u, onAbort(err, ErrDead))
@@ -664,6 +664,80 @@ func (s *UnitSuite) TestGetSetStatusDataChange(c *gc.C) {
 	c.Assert(data, gc.HasLen, 0)
 }
 
+func (s *UnitSuite) TestStatusHistory(c *gc.C) {
+	history, err := s.unit.StatusHistory(10)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(history, gc.HasLen, 0)
+
+	err = s.unit.SetStatus(state.StatusBusy, "installing", nil)
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.unit.SetAgentStatus(state.StatusError, "hook failed", map[string]interface{}{"hook": "install"})
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.unit.SetStatus(state.StatusRunning, "", nil)
+	c.Assert(err, jc.ErrorIsNil)
+	// A status that cannot be set is not recorded.
+	err = s.unit.SetStatus(state.StatusActive, "", nil)
+	c.Assert(err, gc.NotNil)
+
+	history, err = s.unit.StatusHistory(10)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(history, gc.HasLen, 3)
+	c.Check(history[0].Agent, jc.IsFalse)
+	c.Check(history[0].Status, gc.Equals, state.StatusRunning)
+	c.Check(history[1].Agent, jc.IsTrue)
+	c.Check(history[1].Status, gc.Equals, state.StatusError)
+	c.Check(history[1].Info, gc.Equals, "hook failed")
+	c.Check(history[1].Data, gc.DeepEquals, map[string]interface{}{"hook": "install"})
+	c.Check(history[2].Agent, jc.IsFalse)
+	c.Check(history[2].Status, gc.Equals, state.StatusBusy)
+	c.Check(history[2].Info, gc.Equals, "installing")
+	for i := 1; i < len(history); i++ {
+		c.Check(history[i].Since.After(history[i-1].Since), jc.IsFalse)
+	}
+
+	history, err = s.unit.StatusHistory(2)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(history, gc.HasLen, 2)
+	c.Check(history[0].Status, gc.Equals, state.StatusRunning)
+	c.Check(history[1].Status, gc.Equals, state.StatusError)
+
+	_, err = s.unit.StatusHistory(0)
+	c.Assert(err, gc.ErrorMatches, "status history size 0 not valid")
+}
+
+func (s *UnitSuite) TestStatusHistoryPruned(c *gc.C) {
+	s.PatchValue(state.MaxStatusHistoryEntries, 2)
+	for _, info := range []string{"one", "two", "three"} {
+		err := s.unit.SetStatus(state.StatusBusy, info, nil)
+		c.Assert(err, jc.ErrorIsNil)
+		err = s.unit.SetAgentStatus(state.StatusIdle, info, nil)
+		c.Assert(err, jc.ErrorIsNil)
+	}
+	history, err := s.unit.StatusHistory(10)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(history, gc.HasLen, 4)
+	for _, entry := range history {
+		c.Check(entry.Info, gc.Not(gc.Equals), "one")
+	}
+}
+
+func (s *UnitSuite) TestStatusHistoryRemovedWithUnit(c *gc.C) {
+	err := s.unit.SetStatus(state.StatusBusy, "installing", nil)
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.unit.SetAgentStatus(state.StatusIdle, "", nil)
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.unit.EnsureDead()
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.unit.Remove()
+	c.Assert(err, jc.ErrorIsNil)
+
+	history, closer := state.GetCollection(s.State, "statuseshistory")
+	defer closer()
+	count, err := history.Find(nil).Count()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(count, gc.Equals, 0)
+}
+
 func (s *UnitSuite) TestSetCharmURLSuccess(c *gc.C) {
 	preventUnitDestroyRemove(c, s.unit)
 	curl, ok := s.unit.CharmURL()
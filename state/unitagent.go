@@ -50,9 +50,14 @@ func (u *UnitAgent) SetStatus(status Status, info string, data map[string]interf
 	if err != nil {
 		return errors.Trace(err)
 	}
+	historyOps, err := statusHistoryOps(u.st, u.globalKey(), doc.statusDoc)
+	if err != nil {
+		return errors.Trace(err)
+	}
 	ops := []txn.Op{
 		updateStatusOp(u.st, u.globalKey(), doc.statusDoc),
 	}
+	ops = append(ops, historyOps...)
 	err = u.st.runTransaction(ops)
 	if err != nil {
 		return errors.Errorf("cannot set status of unit agent %q: %v", u, onAbort(err, ErrDead))
@@ -480,6 +480,43 @@ func (st *State) AbortCurrentUpgrade() error {
 
 }
 
+// RollbackCurrentUpgrade aborts the current upgrade, as
+// AbortCurrentUpgrade does, and sets the environment's agent version
+// back to the version being upgraded from in the same transaction, so
+// that agents which have already upgraded will downgrade again. An
+// error satisfying errors.IsNotFound is returned if no upgrade is in
+// progress.
+func (st *State) RollbackCurrentUpgrade() error {
+	buildTxn := func(attempt int) ([]txn.Op, error) {
+		doc, err := currentUpgradeInfoDoc(st)
+		if errors.IsNotFound(err) {
+			return nil, errors.NotFoundf("upgrade in progress")
+		} else if err != nil {
+			return nil, errors.Trace(err)
+		}
+		previousVersion := doc.PreviousVersion
+		info := &UpgradeInfo{st: st, doc: *doc}
+		ops := info.makeArchiveOps(doc, UpgradeAborted)
+		// The agents may already be running the target version,
+		// so the upgrade checks of SetEnvironAgentVersion are
+		// not made here.
+		_, settingsOp, err := st.agentVersionOp(previousVersion)
+		if err == jujutxn.ErrNoOperations {
+			return ops, nil
+		} else if err != nil {
+			return nil, errors.Trace(err)
+		}
+		return append(ops, settingsOp), nil
+	}
+	if err := st.run(buildTxn); err != nil {
+		if errors.IsNotFound(err) {
+			return err
+		}
+		return errors.Annotate(err, "cannot roll back upgrade")
+	}
+	return nil
+}
+
 func currentUpgradeInfoDoc(st *State) (*upgradeInfoDoc, error) {
 	var doc upgradeInfoDoc
 	upgradeInfo, closer := st.getCollection(upgradeInfoC)
@@ -668,6 +668,37 @@ func (s *UpgradeSuite) TestAbortCurrentUpgrade(c *gc.C) {
 	c.Check(err, jc.ErrorIsNil)
 }
 
+func (s *UpgradeSuite) TestRollbackCurrentUpgrade(c *gc.C) {
+	err := s.State.RollbackCurrentUpgrade()
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+	c.Assert(err, gc.ErrorMatches, "upgrade in progress not found")
+
+	cfg, err := s.State.EnvironConfig()
+	c.Assert(err, jc.ErrorIsNil)
+	current, ok := cfg.AgentVersion()
+	c.Assert(ok, jc.IsTrue)
+	target := current
+	target.Minor++
+	stateServer, err := s.State.Machine(s.serverIdA)
+	c.Assert(err, jc.ErrorIsNil)
+	err = stateServer.SetAgentVersion(version.Binary{Number: current, Series: "quantal", Arch: "amd64"})
+	c.Assert(err, jc.ErrorIsNil)
+	err = s.State.SetEnvironAgentVersion(target)
+	c.Assert(err, jc.ErrorIsNil)
+	_, err = s.State.EnsureUpgradeInfo(s.serverIdA, current, target)
+	c.Assert(err, jc.ErrorIsNil)
+
+	err = s.State.RollbackCurrentUpgrade()
+	c.Assert(err, jc.ErrorIsNil)
+	s.assertUpgrading(c, false)
+	info := s.getOneUpgradeInfo(c)
+	c.Check(info.Status(), gc.Equals, state.UpgradeAborted)
+	cfg, err = s.State.EnvironConfig()
+	c.Assert(err, jc.ErrorIsNil)
+	agentVersion, _ := cfg.AgentVersion()
+	c.Check(agentVersion, gc.Equals, current)
+}
+
 func (s *UpgradeSuite) TestClearUpgradeInfo(c *gc.C) {
 	v111 := vers("1.1.1")
 	v123 := vers("1.2.3")
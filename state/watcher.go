@@ -20,6 +20,7 @@ import (
 
 	"github.com/juju/juju/environs/config"
 	"github.com/juju/juju/instance"
+	"github.com/juju/juju/network"
 	"github.com/juju/juju/state/multiwatcher"
 	"github.com/juju/juju/state/watcher"
 )
@@ -1584,6 +1585,124 @@ func (w *machineAddressesWatcher) loop() error {
 	}
 }
 
+// allMachineAddressesWatcher notifies about changes
+// to the addresses of all machines in the environment.
+type allMachineAddressesWatcher struct {
+	commonWatcher
+	known map[string][]network.Address
+	out   chan []string
+}
+
+var _ Watcher = (*allMachineAddressesWatcher)(nil)
+
+// WatchAllMachineAddresses returns a StringsWatcher that notifies of
+// changes to the addresses of any machine in the environment. The first
+// event contains the ids of all machines; after that, the ids of
+// machines whose addresses have changed, or that have been removed,
+// are reported.
+func (st *State) WatchAllMachineAddresses() StringsWatcher {
+	w := &allMachineAddressesWatcher{
+		commonWatcher: commonWatcher{st: st},
+		known:         make(map[string][]network.Address),
+		out:           make(chan []string),
+	}
+	go func() {
+		defer w.tomb.Done()
+		defer close(w.out)
+		w.tomb.Kill(w.loop())
+	}()
+	return w
+}
+
+// Changes returns the event channel for w.
+func (w *allMachineAddressesWatcher) Changes() <-chan []string {
+	return w.out
+}
+
+// machineAddressesFields selects the fields of a
+// machine document needed to determine its addresses.
+var machineAddressesFields = bson.D{{"machineid", 1}, {"addresses", 1}, {"machineaddresses", 1}}
+
+func (w *allMachineAddressesWatcher) initial() (set.Strings, error) {
+	machines, closer := w.st.getCollection(machinesC)
+	defer closer()
+
+	ids := set.NewStrings()
+	var doc machineDoc
+	iter := machines.Find(nil).Select(machineAddressesFields).Iter()
+	for iter.Next(&doc) {
+		w.known[doc.Id] = mergedAddresses(doc.MachineAddresses, doc.Addresses)
+		ids.Add(doc.Id)
+	}
+	return ids, errors.Trace(iter.Close())
+}
+
+func (w *allMachineAddressesWatcher) loop() error {
+	in := make(chan watcher.Change)
+	changes, err := w.initial()
+	if err != nil {
+		return errors.Trace(err)
+	}
+	w.st.watcher.WatchCollectionWithFilter(machinesC, in, w.st.isForStateEnv)
+	defer w.st.watcher.UnwatchCollection(machinesC, in)
+
+	out := w.out
+	for {
+		select {
+		case <-w.tomb.Dying():
+			return tomb.ErrDying
+		case <-w.st.watcher.Dead():
+			return stateWatcherDeadError(w.st.watcher.Err())
+		case ch := <-in:
+			if err := w.merge(changes, ch); err != nil {
+				return errors.Trace(err)
+			}
+			if !changes.IsEmpty() {
+				out = w.out
+			}
+		case out <- changes.SortedValues():
+			out = nil
+			changes = set.NewStrings()
+		}
+	}
+}
+
+func (w *allMachineAddressesWatcher) merge(ids set.Strings, change watcher.Change) error {
+	docID, ok := change.Id.(string)
+	if !ok {
+		return errors.Errorf("id %v is not of type string, got %T", change.Id, change.Id)
+	}
+	machines, closer := w.st.getCollection(machinesC)
+	defer closer()
+
+	var doc machineDoc
+	if change.Revno != -1 {
+		err := machines.FindId(docID).Select(machineAddressesFields).One(&doc)
+		if err != nil && err != mgo.ErrNotFound {
+			return errors.Trace(err)
+		}
+		if err == nil {
+			addresses := mergedAddresses(doc.MachineAddresses, doc.Addresses)
+			known, isKnown := w.known[doc.Id]
+			if !isKnown || !addressesEqual(addresses, known) {
+				w.known[doc.Id] = addresses
+				ids.Add(doc.Id)
+			}
+			return nil
+		}
+	}
+	// The machine has been removed.
+	id, err := w.st.strictLocalID(docID)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if _, isKnown := w.known[id]; isKnown {
+		delete(w.known, id)
+		ids.Add(id)
+	}
+	return nil
+}
+
 // cleanupWatcher notifies of changes in the cleanups collection.
 type cleanupWatcher struct {
 	commonWatcher
@@ -1647,6 +1766,10 @@ type actionStatusWatcher struct {
 	sink           chan []string
 	receiverFilter bson.D
 	statusFilter   bson.D
+
+	// actionFilter further restricts the Actions
+	// matched, if it is not empty.
+	actionFilter bson.D
 }
 
 var _ StringsWatcher = (*actionStatusWatcher)(nil)
@@ -1656,14 +1779,18 @@ var _ StringsWatcher = (*actionStatusWatcher)(nil)
 // filters.
 func newActionStatusWatcher(st *State, receivers []ActionReceiver, statusSet ...ActionStatus) StringsWatcher {
 	watchLogger.Debugf("newActionStatusWatcher receivers:'%+v', statuses'%+v'", receivers, statusSet)
-	w := &actionStatusWatcher{
+	return startActionStatusWatcher(&actionStatusWatcher{
 		commonWatcher:  commonWatcher{st: st},
 		source:         make(chan watcher.Change),
 		sink:           make(chan []string),
 		receiverFilter: actionReceiverInCollectionOp(receivers...),
 		statusFilter:   statusInCollectionOp(statusSet...),
-	}
+	})
+}
 
+// startActionStatusWatcher starts the loop of the given watcher
+// and returns it.
+func startActionStatusWatcher(w *actionStatusWatcher) StringsWatcher {
 	go func() {
 		defer w.tomb.Done()
 		defer close(w.sink)
@@ -1741,7 +1868,11 @@ func (w *actionStatusWatcher) matchingIds(ids ...string) ([]string, error) {
 	defer closer()
 
 	idFilter := localIdInCollectionOp(w.st, ids...)
-	query := bson.D{{"$and", []bson.D{idFilter, w.receiverFilter, w.statusFilter}}}
+	filters := []bson.D{idFilter, w.receiverFilter, w.statusFilter}
+	if len(w.actionFilter) > 0 {
+		filters = append(filters, w.actionFilter)
+	}
+	query := bson.D{{"$and", filters}}
 	iter := coll.Find(query).Iter()
 	var found []string
 	var doc actionDoc
@@ -2048,6 +2179,27 @@ func (st *State) WatchActionResultsFilteredBy(receivers ...ActionReceiver) Strin
 	return newActionStatusWatcher(st, receivers, []ActionStatus{ActionCompleted, ActionCancelled, ActionFailed}...)
 }
 
+// WatchActionResultsFor starts and returns a StringsWatcher that
+// notifies when any of the given Actions is completed, cancelled or
+// fails. Its first event holds the ids of those that already have.
+func (st *State) WatchActionResultsFor(tags ...names.ActionTag) StringsWatcher {
+	ids := make([]string, len(tags))
+	for i, tag := range tags {
+		ids[i] = st.docID(tag.Id())
+	}
+	watchLogger.Debugf("WatchActionResultsFor actions:'%+v'", tags)
+	return startActionStatusWatcher(&actionStatusWatcher{
+		commonWatcher:  commonWatcher{st: st},
+		source:         make(chan watcher.Change),
+		sink:           make(chan []string),
+		receiverFilter: actionReceiverInCollectionOp(),
+		statusFilter:   statusInCollectionOp(ActionCompleted, ActionCancelled, ActionFailed),
+		// Match the given ids explicitly, so that no
+		// Actions match if none are given.
+		actionFilter: bson.D{{"_id", bson.D{{"$in", ids}}}},
+	})
+}
+
 // machineInterfacesWatcher notifies about changes to all network interfaces
 // of a machine. Changes include adding, removing enabling or disabling interfaces.
 type machineInterfacesWatcher struct {
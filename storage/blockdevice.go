@@ -3,6 +3,8 @@
 
 package storage
 
+import "strings"
+
 // BlockDevice describes a block device discovered on a machine.
 type BlockDevice struct {
 	// DeviceName is the block device's OS-specific name (e.g. "sdb").
@@ -29,6 +31,11 @@ type BlockDevice struct {
 	// is immutable.
 	Serial string `yaml:"serial,omitempty"`
 
+	// WWN is the block device's World Wide Name. Not all block devices
+	// have a WWN. Like the serial, the WWN is immutable and may be used
+	// to identify a block device.
+	WWN string `yaml:"wwn,omitempty"`
+
 	// Size is the size of the block device, in MiB.
 	Size uint64 `yaml:"size"`
 
@@ -38,4 +45,114 @@ type BlockDevice struct {
 
 	// InUse indicates that the block device is in use (e.g. mounted).
 	InUse bool `yaml:"inuse"`
+
+	// BackingFile is the path of the file backing the block device,
+	// if it is a loop device. If it is set, the device's path is
+	// that of the loop device attached to the file; see
+	// ResolveBlockDevicePath.
+	BackingFile string `yaml:"backingfile,omitempty"`
+}
+
+// busPrefixes holds the bus types with which /dev/disk/by-id
+// prefixes the links it creates for a disk.
+var busPrefixes = map[string]bool{
+	"ata":    true,
+	"ide":    true,
+	"mmc":    true,
+	"nvme":   true,
+	"scsi":   true,
+	"usb":    true,
+	"virtio": true,
+	"xen":    true,
+}
+
+// MatchBlockDevice reports whether the candidate block device refers to
+// the same physical device as any of the present block devices.
+//
+// Devices are compared by their hardware identities (WWN and serial),
+// accounting for the different aliases that /dev/disk/by-id may expose
+// for a single disk. If either device has no hardware identity, they
+// are compared by filesystem UUID and then by filesystem label.
+func MatchBlockDevice(candidate BlockDevice, present []BlockDevice) bool {
+	for _, dev := range present {
+		if SameBlockDevice(candidate, dev) {
+			return true
+		}
+	}
+	return false
+}
+
+// SameBlockDevice reports whether a and b refer to the same physical
+// device, comparing their identities as MatchBlockDevice does. Devices
+// with no identity at all are never the same.
+func SameBlockDevice(a, b BlockDevice) bool {
+	aIDs, bIDs := hardwareIDs(a), hardwareIDs(b)
+	for _, aID := range aIDs {
+		for _, bID := range bIDs {
+			if sameHardwareID(aID, bID) {
+				return true
+			}
+		}
+	}
+	if len(aIDs) > 0 && len(bIDs) > 0 {
+		// Hardware identities are immutable, so devices
+		// that have them but do not share one differ.
+		return false
+	}
+	if a.UUID != "" && b.UUID != "" {
+		return a.UUID == b.UUID
+	}
+	if a.Label != "" && b.Label != "" {
+		return a.Label == b.Label
+	}
+	return false
+}
+
+// hardwareID is a normalised hardware identity of a block device.
+type hardwareID struct {
+	wwn   bool
+	value string
+}
+
+// hardwareIDs returns the normalised hardware identities of a
+// block device. The serial may be a /dev/disk/by-id name, in which
+// case the bus prefix is dropped, and a "wwn-" name is treated as
+// the device's WWN.
+func hardwareIDs(dev BlockDevice) []hardwareID {
+	var ids []hardwareID
+	if dev.WWN != "" {
+		ids = append(ids, hardwareID{wwn: true, value: normaliseWWN(dev.WWN)})
+	}
+	if serial := dev.Serial; serial != "" {
+		if strings.HasPrefix(serial, "wwn-") {
+			ids = append(ids, hardwareID{wwn: true, value: normaliseWWN(serial[len("wwn-"):])})
+		} else {
+			if i := strings.Index(serial, "-"); i > 0 && busPrefixes[serial[:i]] {
+				serial = serial[i+1:]
+			}
+			ids = append(ids, hardwareID{value: serial})
+		}
+	}
+	return ids
+}
+
+// sameHardwareID reports whether a and b identify the same device.
+// Serial identities match if they are equal, or if one is an
+// underscore-separated suffix of the other; the same disk may be
+// exposed both as "VENDOR_MODEL_SERIAL" and as "MODEL_SERIAL".
+func sameHardwareID(a, b hardwareID) bool {
+	if a.wwn != b.wwn {
+		return false
+	}
+	if a.value == b.value {
+		return true
+	}
+	if a.wwn {
+		return false
+	}
+	return strings.HasSuffix(a.value, "_"+b.value) || strings.HasSuffix(b.value, "_"+a.value)
+}
+
+func normaliseWWN(wwn string) string {
+	return strings.TrimPrefix(strings.ToLower(wwn), "0x")
 }
@@ -0,0 +1,103 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package storage_test
+
+import (
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/storage"
+)
+
+type MatchBlockDeviceSuite struct{}
+
+var _ = gc.Suite(&MatchBlockDeviceSuite{})
+
+var matchBlockDeviceTests = []struct {
+	about     string
+	candidate storage.BlockDevice
+	present   storage.BlockDevice
+	match     bool
+}{{
+	about:     "same serial",
+	candidate: storage.BlockDevice{Serial: "ST3500418AS_9VMJ1PYR"},
+	present:   storage.BlockDevice{DeviceName: "sdb", Serial: "ST3500418AS_9VMJ1PYR"},
+	match:     true,
+}, {
+	about:     "serial with bus prefix",
+	candidate: storage.BlockDevice{Serial: "ST3500418AS_9VMJ1PYR"},
+	present:   storage.BlockDevice{Serial: "ata-ST3500418AS_9VMJ1PYR"},
+	match:     true,
+}, {
+	about:     "serial with bus and vendor prefix",
+	candidate: storage.BlockDevice{Serial: "ata-ST3500418AS_9VMJ1PYR"},
+	present:   storage.BlockDevice{Serial: "scsi-SATA_ST3500418AS_9VMJ1PYR"},
+	match:     true,
+}, {
+	about:     "serial partially overlapping",
+	candidate: storage.BlockDevice{Serial: "ST3500418AS_9VMJ1PYR"},
+	present:   storage.BlockDevice{Serial: "ST3500418AS_19VMJ1PYR"},
+	match:     false,
+}, {
+	about:     "same WWN",
+	candidate: storage.BlockDevice{WWN: "0x5000C5002DE3B9C4"},
+	present:   storage.BlockDevice{WWN: "0x5000c5002de3b9c4"},
+	match:     true,
+}, {
+	about:     "WWN by-id alias",
+	candidate: storage.BlockDevice{WWN: "0x5000c5002de3b9c4"},
+	present:   storage.BlockDevice{Serial: "wwn-0x5000c5002de3b9c4"},
+	match:     true,
+}, {
+	about:     "WWN and serial of the same device",
+	candidate: storage.BlockDevice{WWN: "0x5000c5002de3b9c4", Serial: "ST3500418AS_9VMJ1PYR"},
+	present:   storage.BlockDevice{Serial: "ata-ST3500418AS_9VMJ1PYR"},
+	match:     true,
+}, {
+	about:     "different serials, same UUID",
+	candidate: storage.BlockDevice{Serial: "one", UUID: "deadbeef"},
+	present:   storage.BlockDevice{Serial: "two", UUID: "deadbeef"},
+	match:     false,
+}, {
+	about:     "no hardware identity, same UUID",
+	candidate: storage.BlockDevice{UUID: "deadbeef"},
+	present:   storage.BlockDevice{Serial: "one", UUID: "deadbeef"},
+	match:     true,
+}, {
+	about:     "no hardware identity, different UUID",
+	candidate: storage.BlockDevice{UUID: "deadbeef", Label: "data"},
+	present:   storage.BlockDevice{UUID: "cafebabe", Label: "data"},
+	match:     false,
+}, {
+	about:     "label only",
+	candidate: storage.BlockDevice{Label: "data"},
+	present:   storage.BlockDevice{Label: "data"},
+	match:     true,
+}, {
+	about:     "no identity",
+	candidate: storage.BlockDevice{DeviceName: "sdb"},
+	present:   storage.BlockDevice{DeviceName: "sdb"},
+	match:     false,
+}}
+
+func (s *MatchBlockDeviceSuite) TestMatchBlockDevice(c *gc.C) {
+	for i, test := range matchBlockDeviceTests {
+		c.Logf("test %d: %s", i, test.about)
+		present := []storage.BlockDevice{{Serial: "other"}, test.present}
+		c.Check(storage.MatchBlockDevice(test.candidate, present), gc.Equals, test.match)
+	}
+}
+
+func (s *MatchBlockDeviceSuite) TestSameBlockDevice(c *gc.C) {
+	for i, test := range matchBlockDeviceTests {
+		c.Logf("test %d: %s", i, test.about)
+		c.Check(storage.SameBlockDevice(test.candidate, test.present), gc.Equals, test.match)
+		c.Check(storage.SameBlockDevice(test.present, test.candidate), gc.Equals, test.match)
+	}
+}
+
+func (s *MatchBlockDeviceSuite) TestMatchBlockDeviceNonePresent(c *gc.C) {
+	candidate := storage.BlockDevice{Serial: "ST3500418AS_9VMJ1PYR"}
+	c.Assert(storage.MatchBlockDevice(candidate, nil), jc.IsFalse)
+}
@@ -0,0 +1,172 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package storage
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/juju/errors"
+)
+
+var (
+	// mountsFile lists the mounted filesystems.
+	mountsFile = "/proc/mounts"
+
+	// sysBlockDir holds the kernel's view of the block devices.
+	sysBlockDir = "/sys/block"
+)
+
+// resolvePath returns the path with any symbolic links resolved. It
+// is a variable so it can be replaced in tests.
+var resolvePath = filepath.EvalSymlinks
+
+// unmount unmounts the filesystem at the given mount point. It is a
+// variable so it can be replaced in tests.
+var unmount = func(mountPoint string) error {
+	logger.Debugf("running: umount %s", mountPoint)
+	output, err := exec.Command("umount", mountPoint).CombinedOutput()
+	if err != nil {
+		return errors.Annotatef(err, "umount failed (%q)", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// flushBuffers flushes the buffers of the block device at the given
+// path. It is a variable so it can be replaced in tests.
+var flushBuffers = func(path string) error {
+	logger.Debugf("running: blockdev --flushbufs %s", path)
+	output, err := exec.Command("blockdev", "--flushbufs", path).CombinedOutput()
+	if err != nil {
+		return errors.Annotatef(err, "blockdev failed (%q)", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// PrepareForDetach prepares the block device to be detached from the
+// machine, so that no data is lost when it is. Any filesystems mounted
+// from the device or its partitions are unmounted, and the device's
+// buffers are flushed. If deleteDevice is true, the kernel is then told
+// to delete the device, so that it is no longer used.
+//
+// An error is returned if any filesystem on the device cannot be
+// unmounted, for example because it is busy; the device must not be
+// detached in that case.
+func PrepareForDetach(device BlockDevice, deleteDevice bool) error {
+	path, err := BlockDevicePath(device)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	name := device.DeviceName
+	if name == "" {
+		resolved, err := resolvePath(path)
+		if err != nil {
+			return errors.Annotatef(err, "resolving block device %q", path)
+		}
+		name = filepath.Base(resolved)
+	}
+
+	mountPoints, err := deviceMountPoints(name)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	// Unmount in reverse order, so that filesystems mounted
+	// beneath others are unmounted first.
+	for i := len(mountPoints) - 1; i >= 0; i-- {
+		if err := unmount(mountPoints[i]); err != nil {
+			return errors.Annotatef(err, "unmounting %q from block device %q", mountPoints[i], path)
+		}
+	}
+	if mountPoints, err = deviceMountPoints(name); err != nil {
+		return errors.Trace(err)
+	} else if len(mountPoints) > 0 {
+		return errors.Errorf(
+			"block device %q is busy: still mounted at %s",
+			path, strings.Join(mountPoints, ", "),
+		)
+	}
+
+	if err := flushBuffers(path); err != nil {
+		return errors.Annotatef(err, "flushing block device %q", path)
+	}
+	if !deleteDevice {
+		return nil
+	}
+	deletePath := filepath.Join(sysBlockDir, name, "device", "delete")
+	logger.Debugf("deleting block device %q", name)
+	if err := ioutil.WriteFile(deletePath, []byte("1"), 0200); err != nil {
+		return errors.Annotatef(err, "deleting block device %q", path)
+	}
+	return nil
+}
+
+// deviceMountPoints returns the mount points, in mount order, of the
+// filesystems mounted from the named block device or its partitions.
+func deviceMountPoints(name string) ([]string, error) {
+	f, err := os.Open(mountsFile)
+	if err != nil {
+		return nil, errors.Annotate(err, "reading mounted filesystems")
+	}
+	defer f.Close()
+	var mountPoints []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || !strings.HasPrefix(fields[0], "/dev/") {
+			continue
+		}
+		source, err := resolvePath(fields[0])
+		if err != nil {
+			// The source is not a device that exists.
+			continue
+		}
+		if isDeviceOrPartition(filepath.Base(source), name) {
+			mountPoints = append(mountPoints, unescapeMountPath(fields[1]))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Annotate(err, "reading mounted filesystems")
+	}
+	return mountPoints, nil
+}
+
+// isDeviceOrPartition reports whether the block device with the given
+// name is the named device or one of its partitions. Partition names
+// are the device name followed by a number, separated by "p" if the
+// device name ends in a digit (e.g. "sdb1", "nvme0n1p1" or "loop1p1"),
+// so that "loop10" is not taken for a partition of "loop1".
+func isDeviceOrPartition(candidate, name string) bool {
+	if !strings.HasPrefix(candidate, name) {
+		return false
+	}
+	suffix := candidate[len(name):]
+	if suffix == "" {
+		return true
+	}
+	if last := name[len(name)-1]; last >= '0' && last <= '9' {
+		if !strings.HasPrefix(suffix, "p") {
+			return false
+		}
+		suffix = suffix[1:]
+	}
+	if suffix == "" {
+		return false
+	}
+	for _, r := range suffix {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// unescapeMountPath reverses the octal escaping of
+// whitespace and backslashes in mount points.
+var unescapeMountPath = strings.NewReplacer(
+	`\040`, " ", `\011`, "\t", `\012`, "\n", `\134`, `\`,
+).Replace
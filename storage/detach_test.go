@@ -0,0 +1,178 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package storage_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/storage"
+)
+
+type PrepareForDetachSuite struct {
+	testing.IsolationSuite
+	mountsFile string
+	sysBlock   string
+	mounts     []string
+	unmounted  []string
+	flushed    []string
+	busy       map[string]bool
+}
+
+var _ = gc.Suite(&PrepareForDetachSuite{})
+
+func (s *PrepareForDetachSuite) SetUpTest(c *gc.C) {
+	s.IsolationSuite.SetUpTest(c)
+	dir := c.MkDir()
+	s.mountsFile = filepath.Join(dir, "mounts")
+	s.sysBlock = filepath.Join(dir, "block")
+	s.mounts = nil
+	s.unmounted = nil
+	s.flushed = nil
+	s.busy = make(map[string]bool)
+	s.PatchValue(storage.MountsFile, s.mountsFile)
+	s.PatchValue(storage.SysBlockDir, s.sysBlock)
+	s.PatchValue(storage.ResolvePath, func(path string) (string, error) {
+		if path == "/dev/disk/by-id/serial-1" {
+			return "/dev/sdb", nil
+		}
+		return path, nil
+	})
+	s.PatchValue(storage.Unmount, func(mountPoint string) error {
+		if s.busy[mountPoint] {
+			return errors.New("target is busy")
+		}
+		s.unmounted = append(s.unmounted, mountPoint)
+		var remaining []string
+		for _, line := range s.mounts {
+			if strings.Fields(line)[1] != mountPoint {
+				remaining = append(remaining, line)
+			}
+		}
+		s.writeMounts(c, remaining...)
+		return nil
+	})
+	s.PatchValue(storage.FlushBuffers, func(path string) error {
+		s.flushed = append(s.flushed, path)
+		return nil
+	})
+	s.writeMounts(c,
+		"/dev/sda1 / ext4 rw 0 0",
+		"/dev/sdb1 /srv/data ext4 rw 0 0",
+		"/dev/sdb2 /srv/data/logs ext4 rw 0 0",
+		"/dev/sdba /srv/other ext4 rw 0 0",
+		"proc /proc proc rw 0 0",
+	)
+}
+
+func (s *PrepareForDetachSuite) writeMounts(c *gc.C, lines ...string) {
+	s.mounts = lines
+	err := ioutil.WriteFile(s.mountsFile, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *PrepareForDetachSuite) TestUnmountsAndFlushes(c *gc.C) {
+	err := storage.PrepareForDetach(sdb, false)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.unmounted, jc.DeepEquals, []string{"/srv/data/logs", "/srv/data"})
+	c.Assert(s.flushed, jc.DeepEquals, []string{"/dev/sdb"})
+	c.Assert(s.mounts, jc.DeepEquals, []string{
+		"/dev/sda1 / ext4 rw 0 0",
+		"/dev/sdba /srv/other ext4 rw 0 0",
+		"proc /proc proc rw 0 0",
+	})
+}
+
+func (s *PrepareForDetachSuite) TestResolvesDeviceName(c *gc.C) {
+	err := storage.PrepareForDetach(storage.BlockDevice{Serial: "serial-1"}, false)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.unmounted, jc.DeepEquals, []string{"/srv/data/logs", "/srv/data"})
+	c.Assert(s.flushed, jc.DeepEquals, []string{"/dev/disk/by-id/serial-1"})
+}
+
+func (s *PrepareForDetachSuite) TestDeletesDevice(c *gc.C) {
+	deviceDir := filepath.Join(s.sysBlock, "sdb", "device")
+	err := os.MkdirAll(deviceDir, 0755)
+	c.Assert(err, jc.ErrorIsNil)
+
+	err = storage.PrepareForDetach(sdb, true)
+	c.Assert(err, jc.ErrorIsNil)
+	data, err := ioutil.ReadFile(filepath.Join(deviceDir, "delete"))
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(string(data), gc.Equals, "1")
+}
+
+func (s *PrepareForDetachSuite) TestDeleteDeviceError(c *gc.C) {
+	err := storage.PrepareForDetach(sdb, true)
+	c.Assert(err, gc.ErrorMatches, `deleting block device "/dev/sdb": .*`)
+	c.Assert(s.flushed, jc.DeepEquals, []string{"/dev/sdb"})
+}
+
+func (s *PrepareForDetachSuite) TestBusy(c *gc.C) {
+	s.busy["/srv/data"] = true
+	err := storage.PrepareForDetach(sdb, true)
+	c.Assert(err, gc.ErrorMatches, `unmounting "/srv/data" from block device "/dev/sdb": target is busy`)
+	c.Assert(s.unmounted, jc.DeepEquals, []string{"/srv/data/logs"})
+	c.Assert(s.flushed, gc.HasLen, 0)
+}
+
+func (s *PrepareForDetachSuite) TestStillMounted(c *gc.C) {
+	s.PatchValue(storage.Unmount, func(string) error { return nil })
+	err := storage.PrepareForDetach(sdb, false)
+	c.Assert(err, gc.ErrorMatches, `block device "/dev/sdb" is busy: still mounted at /srv/data, /srv/data/logs`)
+	c.Assert(s.flushed, gc.HasLen, 0)
+}
+
+func (s *PrepareForDetachSuite) TestFlushError(c *gc.C) {
+	s.PatchValue(storage.FlushBuffers, func(string) error {
+		return errors.New("blockdev failed")
+	})
+	err := storage.PrepareForDetach(sdb, false)
+	c.Assert(err, gc.ErrorMatches, `flushing block device "/dev/sdb": blockdev failed`)
+}
+
+func (s *PrepareForDetachSuite) TestNumberedDeviceNames(c *gc.C) {
+	s.writeMounts(c,
+		"/dev/loop1 /srv/loop1 ext4 rw 0 0",
+		"/dev/loop1p1 /srv/loop1p1 ext4 rw 0 0",
+		"/dev/loop10 /srv/loop10 ext4 rw 0 0",
+		"/dev/loop12 /srv/loop12 ext4 rw 0 0",
+		"/dev/nvme0n1p1 /srv/nvme0n1p1 ext4 rw 0 0",
+		"/dev/nvme0n10 /srv/nvme0n10 ext4 rw 0 0",
+		"/dev/nvme0n10p1 /srv/nvme0n10p1 ext4 rw 0 0",
+	)
+	err := storage.PrepareForDetach(storage.BlockDevice{DeviceName: "loop1"}, false)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.unmounted, jc.DeepEquals, []string{"/srv/loop1p1", "/srv/loop1"})
+
+	s.unmounted = nil
+	err = storage.PrepareForDetach(storage.BlockDevice{DeviceName: "nvme0n1"}, false)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.unmounted, jc.DeepEquals, []string{"/srv/nvme0n1p1"})
+	c.Assert(s.mounts, jc.DeepEquals, []string{
+		"/dev/loop10 /srv/loop10 ext4 rw 0 0",
+		"/dev/loop12 /srv/loop12 ext4 rw 0 0",
+		"/dev/nvme0n10 /srv/nvme0n10 ext4 rw 0 0",
+		"/dev/nvme0n10p1 /srv/nvme0n10p1 ext4 rw 0 0",
+	})
+}
+
+func (s *PrepareForDetachSuite) TestLetteredDeviceNames(c *gc.C) {
+	s.writeMounts(c,
+		"/dev/sdb1 /srv/sdb1 ext4 rw 0 0",
+		"/dev/sdbp1 /srv/sdbp1 ext4 rw 0 0",
+		"/dev/sdba /srv/sdba ext4 rw 0 0",
+		"/dev/sdba1 /srv/sdba1 ext4 rw 0 0",
+	)
+	err := storage.PrepareForDetach(sdb, false)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.unmounted, jc.DeepEquals, []string{"/srv/sdb1"})
+}
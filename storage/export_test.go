@@ -0,0 +1,23 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package storage
+
+var OpenBlockDevice = &openBlockDevice
+
+var StatBlockDevice = &statBlockDevice
+
+var (
+	ProbeFilesystem = &probeFilesystem
+	MakeFilesystem  = &makeFilesystem
+)
+
+var (
+	MountsFile   = &mountsFile
+	SysBlockDir  = &sysBlockDir
+	ResolvePath  = &resolvePath
+	Unmount      = &unmount
+	FlushBuffers = &flushBuffers
+)
+
+var RunLosetup = &runLosetup
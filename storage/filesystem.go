@@ -0,0 +1,88 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package storage
+
+import (
+	"os/exec"
+	"strings"
+	"syscall"
+
+	"github.com/juju/errors"
+)
+
+// blkidNotFound is the exit status of blkid when
+// the device has no recognisable filesystem.
+const blkidNotFound = 2
+
+// probeFilesystem returns the type and label of the filesystem on the
+// block device at the given path, or empty strings if there is none.
+// It is a variable so it can be replaced in tests.
+var probeFilesystem = func(path string) (fsType, label string, err error) {
+	output, err := exec.Command("blkid", "-p", "-o", "export", path).Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			status, ok := exitErr.Sys().(syscall.WaitStatus)
+			if ok && status.ExitStatus() == blkidNotFound {
+				return "", "", nil
+			}
+		}
+		return "", "", errors.Annotatef(err, "probing block device %q", path)
+	}
+	for _, line := range strings.Split(string(output), "\n") {
+		if strings.HasPrefix(line, "TYPE=") {
+			fsType = strings.TrimPrefix(line, "TYPE=")
+		} else if strings.HasPrefix(line, "LABEL=") {
+			label = strings.TrimPrefix(line, "LABEL=")
+		}
+	}
+	return fsType, label, nil
+}
+
+// makeFilesystem creates a filesystem of the given type and label
+// on the block device at the given path. It is a variable so it
+// can be replaced in tests.
+var makeFilesystem = func(path, fsType, label string) error {
+	mkfscmd := "mkfs." + fsType
+	logger.Debugf("running: %s -L %s %s", mkfscmd, label, path)
+	output, err := exec.Command(mkfscmd, "-L", label, path).CombinedOutput()
+	if err != nil {
+		return errors.Annotatef(err, "%s failed (%q)", mkfscmd, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// EnsureFilesystem ensures that the block device has a filesystem of
+// the given type and label, creating one only if the device has no
+// filesystem at all, and returns the path of the device. If the device
+// already has the expected filesystem, for example because an earlier
+// attempt formatted it before failing, it is left untouched; this makes
+// formatting safe to retry. An error is returned if the device has a
+// different filesystem, rather than destroying its contents.
+func EnsureFilesystem(device BlockDevice, fsType, label string) (string, error) {
+	if fsType == "" || label == "" {
+		return "", errors.New("filesystem type and label must be specified")
+	}
+	path, err := BlockDevicePath(device)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	existingType, existingLabel, err := probeFilesystem(path)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	switch {
+	case existingType == fsType && existingLabel == label:
+		logger.Debugf("block device %q already has %s filesystem %q", path, fsType, label)
+		return path, nil
+	case existingType != "":
+		return "", errors.Errorf(
+			"block device %q has %s filesystem %q, expected %s filesystem %q",
+			path, existingType, existingLabel, fsType, label,
+		)
+	}
+	if err := makeFilesystem(path, fsType, label); err != nil {
+		return "", errors.Annotatef(err, "creating filesystem on block device %q", path)
+	}
+	return path, nil
+}
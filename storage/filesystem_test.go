@@ -0,0 +1,96 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package storage_test
+
+import (
+	"errors"
+
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/storage"
+)
+
+type EnsureFilesystemSuite struct {
+	testing.IsolationSuite
+	made []string
+}
+
+var _ = gc.Suite(&EnsureFilesystemSuite{})
+
+func (s *EnsureFilesystemSuite) SetUpTest(c *gc.C) {
+	s.IsolationSuite.SetUpTest(c)
+	s.made = nil
+	s.PatchValue(storage.MakeFilesystem, func(path, fsType, label string) error {
+		s.made = append(s.made, path+" "+fsType+" "+label)
+		return nil
+	})
+}
+
+// patchProbe arranges for the block device at /dev/sdb to
+// report the given filesystem type and label.
+func (s *EnsureFilesystemSuite) patchProbe(c *gc.C, fsType, label string) {
+	s.PatchValue(storage.ProbeFilesystem, func(path string) (string, string, error) {
+		c.Assert(path, gc.Equals, "/dev/sdb")
+		return fsType, label, nil
+	})
+}
+
+var sdb = storage.BlockDevice{DeviceName: "sdb"}
+
+func (s *EnsureFilesystemSuite) TestCreatesFilesystem(c *gc.C) {
+	s.patchProbe(c, "", "")
+	path, err := storage.EnsureFilesystem(sdb, "ext4", "juju-data")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(path, gc.Equals, "/dev/sdb")
+	c.Assert(s.made, jc.DeepEquals, []string{"/dev/sdb ext4 juju-data"})
+}
+
+func (s *EnsureFilesystemSuite) TestExistingFilesystemUntouched(c *gc.C) {
+	s.patchProbe(c, "ext4", "juju-data")
+	path, err := storage.EnsureFilesystem(sdb, "ext4", "juju-data")
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(path, gc.Equals, "/dev/sdb")
+	c.Assert(s.made, gc.HasLen, 0)
+}
+
+func (s *EnsureFilesystemSuite) TestDifferentLabel(c *gc.C) {
+	s.patchProbe(c, "ext4", "other")
+	_, err := storage.EnsureFilesystem(sdb, "ext4", "juju-data")
+	c.Assert(err, gc.ErrorMatches, `block device "/dev/sdb" has ext4 filesystem "other", expected ext4 filesystem "juju-data"`)
+	c.Assert(s.made, gc.HasLen, 0)
+}
+
+func (s *EnsureFilesystemSuite) TestDifferentType(c *gc.C) {
+	s.patchProbe(c, "xfs", "juju-data")
+	_, err := storage.EnsureFilesystem(sdb, "ext4", "juju-data")
+	c.Assert(err, gc.ErrorMatches, `block device "/dev/sdb" has xfs filesystem "juju-data", expected ext4 filesystem "juju-data"`)
+	c.Assert(s.made, gc.HasLen, 0)
+}
+
+func (s *EnsureFilesystemSuite) TestProbeError(c *gc.C) {
+	s.PatchValue(storage.ProbeFilesystem, func(path string) (string, string, error) {
+		return "", "", errors.New("blkid failed")
+	})
+	_, err := storage.EnsureFilesystem(sdb, "ext4", "juju-data")
+	c.Assert(err, gc.ErrorMatches, "blkid failed")
+	c.Assert(s.made, gc.HasLen, 0)
+}
+
+func (s *EnsureFilesystemSuite) TestMakeFilesystemError(c *gc.C) {
+	s.patchProbe(c, "", "")
+	s.PatchValue(storage.MakeFilesystem, func(path, fsType, label string) error {
+		return errors.New("mkfs.ext4 failed")
+	})
+	_, err := storage.EnsureFilesystem(sdb, "ext4", "juju-data")
+	c.Assert(err, gc.ErrorMatches, `creating filesystem on block device "/dev/sdb": mkfs.ext4 failed`)
+}
+
+func (s *EnsureFilesystemSuite) TestMissingArguments(c *gc.C) {
+	_, err := storage.EnsureFilesystem(sdb, "", "juju-data")
+	c.Assert(err, gc.ErrorMatches, "filesystem type and label must be specified")
+	_, err = storage.EnsureFilesystem(sdb, "ext4", "")
+	c.Assert(err, gc.ErrorMatches, "filesystem type and label must be specified")
+}
@@ -0,0 +1,97 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package storage
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/juju/errors"
+)
+
+// runLosetup runs losetup with the given arguments, and returns its
+// output. It is a variable so it can be replaced in tests.
+var runLosetup = func(args ...string) (string, error) {
+	logger.Debugf("running: losetup %s", strings.Join(args, " "))
+	output, err := exec.Command("losetup", args...).CombinedOutput()
+	if err != nil {
+		return "", errors.Annotatef(err, "losetup failed (%q)", strings.TrimSpace(string(output)))
+	}
+	return string(output), nil
+}
+
+// ResolveBlockDevicePath returns the path to a block device, as
+// BlockDevicePath does, except that a device backed by a file is
+// resolved to the loop device attached to that file. If no loop
+// device is attached to the file, one is attached if attach is
+// true; otherwise an error satisfying errors.IsNotFound is returned.
+func ResolveBlockDevicePath(device BlockDevice, attach bool) (string, error) {
+	if device.BackingFile == "" {
+		return BlockDevicePath(device)
+	}
+	deviceNames, err := loopDevices(device.BackingFile)
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	if len(deviceNames) > 0 {
+		if len(deviceNames) > 1 {
+			logger.Warningf("expected 1 loop device for %q, got %d", device.BackingFile, len(deviceNames))
+		}
+		return filepath.Join(diskByDeviceName, deviceNames[0]), nil
+	}
+	if !attach {
+		return "", errors.NotFoundf("loop device for %q", device.BackingFile)
+	}
+	// -f finds the first unused loop device, creating
+	// one if necessary; --show reports its path.
+	output, err := runLosetup("-f", "--show", device.BackingFile)
+	if err != nil {
+		return "", errors.Annotatef(err, "attaching loop device to %q", device.BackingFile)
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// DetachLoopDevices detaches any loop devices attached to the file
+// backing the block device. It does nothing if the device is not
+// backed by a file, or no loop devices are attached to it.
+func DetachLoopDevices(device BlockDevice) error {
+	if device.BackingFile == "" {
+		return nil
+	}
+	deviceNames, err := loopDevices(device.BackingFile)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for _, deviceName := range deviceNames {
+		path := filepath.Join(diskByDeviceName, deviceName)
+		if _, err := runLosetup("-d", path); err != nil {
+			return errors.Annotatef(err, "detaching loop device %q", deviceName)
+		}
+	}
+	return nil
+}
+
+// loopDevices returns the names of the loop devices
+// attached to the file with the given path.
+func loopDevices(filePath string) ([]string, error) {
+	output, err := runLosetup("-j", filePath)
+	if err != nil {
+		return nil, errors.Annotatef(err, "locating loop devices for %q", filePath)
+	}
+	// The output has zero or more lines of the form:
+	//    "/dev/loop0: [0021]:7504142 (/tmp/test.dat)"
+	var deviceNames []string
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		if line == "" {
+			continue
+		}
+		pos := strings.IndexRune(line, ':')
+		if pos == -1 || !strings.HasPrefix(line, "/dev/") {
+			return nil, errors.Errorf("unexpected losetup output %q", line)
+		}
+		deviceNames = append(deviceNames, line[len("/dev/"):pos])
+	}
+	return deviceNames, nil
+}
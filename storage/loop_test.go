@@ -0,0 +1,104 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package storage_test
+
+import (
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/juju/testing"
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/storage"
+)
+
+type LoopSuite struct {
+	testing.IsolationSuite
+	commands []string
+	attached map[string][]string
+}
+
+var _ = gc.Suite(&LoopSuite{})
+
+func (s *LoopSuite) SetUpTest(c *gc.C) {
+	s.IsolationSuite.SetUpTest(c)
+	s.commands = nil
+	s.attached = make(map[string][]string)
+	s.PatchValue(storage.RunLosetup, func(args ...string) (string, error) {
+		s.commands = append(s.commands, strings.Join(args, " "))
+		switch args[0] {
+		case "-j":
+			var lines []string
+			for _, name := range s.attached[args[1]] {
+				lines = append(lines, "/dev/"+name+": [0021]:7504142 ("+args[1]+")")
+			}
+			return strings.Join(lines, "\n"), nil
+		case "-f":
+			s.attached[args[2]] = append(s.attached[args[2]], "loop3")
+			return "/dev/loop3\n", nil
+		case "-d":
+			return "", nil
+		}
+		return "", errors.Errorf("unexpected losetup %v", args)
+	})
+}
+
+var fileBacked = storage.BlockDevice{BackingFile: "/var/lib/juju/storage/disk-0"}
+
+func (s *LoopSuite) TestResolveBlockDevicePathNotFileBacked(c *gc.C) {
+	path, err := storage.ResolveBlockDevicePath(storage.BlockDevice{DeviceName: "sdb"}, true)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(path, gc.Equals, "/dev/sdb")
+	c.Assert(s.commands, gc.HasLen, 0)
+}
+
+func (s *LoopSuite) TestResolveBlockDevicePathAttached(c *gc.C) {
+	s.attached[fileBacked.BackingFile] = []string{"loop1"}
+	path, err := storage.ResolveBlockDevicePath(fileBacked, true)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(path, gc.Equals, "/dev/loop1")
+	c.Assert(s.commands, jc.DeepEquals, []string{"-j /var/lib/juju/storage/disk-0"})
+}
+
+func (s *LoopSuite) TestResolveBlockDevicePathAttaches(c *gc.C) {
+	path, err := storage.ResolveBlockDevicePath(fileBacked, true)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(path, gc.Equals, "/dev/loop3")
+	c.Assert(s.commands, jc.DeepEquals, []string{
+		"-j /var/lib/juju/storage/disk-0",
+		"-f --show /var/lib/juju/storage/disk-0",
+	})
+}
+
+func (s *LoopSuite) TestResolveBlockDevicePathNotAttached(c *gc.C) {
+	_, err := storage.ResolveBlockDevicePath(fileBacked, false)
+	c.Assert(err, gc.ErrorMatches, `loop device for "/var/lib/juju/storage/disk-0" not found`)
+	c.Assert(err, jc.Satisfies, errors.IsNotFound)
+}
+
+func (s *LoopSuite) TestResolveBlockDevicePathUnexpectedOutput(c *gc.C) {
+	s.PatchValue(storage.RunLosetup, func(args ...string) (string, error) {
+		return "rubbish", nil
+	})
+	_, err := storage.ResolveBlockDevicePath(fileBacked, true)
+	c.Assert(err, gc.ErrorMatches, `unexpected losetup output "rubbish"`)
+}
+
+func (s *LoopSuite) TestDetachLoopDevices(c *gc.C) {
+	s.attached[fileBacked.BackingFile] = []string{"loop1", "loop2"}
+	err := storage.DetachLoopDevices(fileBacked)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.commands, jc.DeepEquals, []string{
+		"-j /var/lib/juju/storage/disk-0",
+		"-d /dev/loop1",
+		"-d /dev/loop2",
+	})
+}
+
+func (s *LoopSuite) TestDetachLoopDevicesNotFileBacked(c *gc.C) {
+	err := storage.DetachLoopDevices(storage.BlockDevice{DeviceName: "sdb"})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(s.commands, gc.HasLen, 0)
+}
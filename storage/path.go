@@ -4,6 +4,9 @@
 package storage
 
 import (
+	"bytes"
+	"io"
+	"os"
 	"path/filepath"
 
 	"github.com/juju/errors"
@@ -28,3 +31,116 @@ func BlockDevicePath(device BlockDevice) (string, error) {
 	}
 	return "", errors.Errorf("could not determine path for block device")
 }
+
+// PathPreference determines which kind of path BlockDevicePathPreferring
+// resolves a block device to when more than one is available.
+type PathPreference int
+
+const (
+	// PreferStablePath prefers the /dev/disk/by-id path derived from the
+	// device's serial. The path is stable across reboots and device
+	// reordering, so it is the right choice wherever udev creates the
+	// by-id links reliably. This is the behaviour of BlockDevicePath.
+	PreferStablePath PathPreference = iota
+
+	// PreferDeviceName prefers the /dev path derived from the device's
+	// name. Device names may change across reboots, or when devices are
+	// attached and detached, so this should only be used where by-id
+	// links are missing or unreliable; for example, under some nested
+	// virtualisation stacks, where the emulated disks report serials
+	// that do not correspond to the links udev creates.
+	PreferDeviceName
+)
+
+// statBlockDevice is called to check that a block device path exists.
+// It is a variable so it can be replaced in tests.
+var statBlockDevice = os.Stat
+
+// BlockDevicePathPreferring returns the path to a block device according
+// to the given preference, or an error if a path cannot be determined.
+//
+// With PreferStablePath, it behaves exactly as BlockDevicePath. With
+// PreferDeviceName, the device-name path is returned if the device has a
+// name and the path exists; if the device has no name, the stable path is
+// returned instead. An error is returned if the device is named but its
+// path does not exist, rather than silently resolving to another device.
+func BlockDevicePathPreferring(device BlockDevice, pref PathPreference) (string, error) {
+	if pref != PreferDeviceName || device.DeviceName == "" {
+		return BlockDevicePath(device)
+	}
+	path := filepath.Join(diskByDeviceName, device.DeviceName)
+	if _, err := statBlockDevice(path); err != nil {
+		return "", errors.Annotatef(err, "validating block device path")
+	}
+	return path, nil
+}
+
+// signature identifies a partition table or filesystem by the
+// magic bytes found at a fixed offset from the start of a device.
+type signature struct {
+	name   string
+	offset int
+	magic  []byte
+}
+
+var signatures = []signature{
+	{"MBR partition table", 510, []byte{0x55, 0xaa}},
+	{"GPT partition table", 512, []byte("EFI PART")},
+	{"LVM physical volume", 512 + 24, []byte("LVM2 001")},
+	{"ext2/3/4 filesystem", 1024 + 56, []byte{0x53, 0xef}},
+	{"XFS filesystem", 0, []byte("XFSB")},
+	{"btrfs filesystem", 0x10040, []byte("_BHRfS_M")},
+	{"swap space", 4096 - 10, []byte("SWAPSPACE2")},
+}
+
+// probeSize is the number of bytes that must be read from the
+// start of a device to check for all known signatures.
+var probeSize = func() int {
+	var size int
+	for _, sig := range signatures {
+		if end := sig.offset + len(sig.magic); end > size {
+			size = end
+		}
+	}
+	return size
+}()
+
+// openBlockDevice opens the block device at the given path
+// for reading. It is a variable so it can be replaced in tests.
+var openBlockDevice = func(path string) (io.ReadCloser, error) {
+	return os.Open(path)
+}
+
+// HasExistingData reports whether the block device appears to already
+// contain a partition table or filesystem, and so should not be
+// formatted without explicit confirmation. Devices known to have a
+// filesystem are reported without being probed; otherwise the first
+// sectors of the device are read and checked for well-known signatures.
+func HasExistingData(device BlockDevice) (bool, error) {
+	if device.FilesystemType != "" || device.UUID != "" || device.Label != "" {
+		return true, nil
+	}
+	path, err := BlockDevicePath(device)
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	f, err := openBlockDevice(path)
+	if err != nil {
+		return false, errors.Annotatef(err, "opening block device %q", path)
+	}
+	defer f.Close()
+	buf := make([]byte, probeSize)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return false, errors.Annotatef(err, "reading block device %q", path)
+	}
+	buf = buf[:n]
+	for _, sig := range signatures {
+		end := sig.offset + len(sig.magic)
+		if end <= len(buf) && bytes.Equal(buf[sig.offset:end], sig.magic) {
+			logger.Debugf("found %s on block device %q", sig.name, path)
+			return true, nil
+		}
+	}
+	return false, nil
+}
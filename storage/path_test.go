@@ -4,6 +4,13 @@
 package storage_test
 
 import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/juju/testing"
 	jc "github.com/juju/testing/checkers"
 	gc "gopkg.in/check.v1"
 
@@ -37,3 +44,131 @@ func testBlockDevicePath(c *gc.C, dev storage.BlockDevice, expect string) {
 	c.Assert(err, jc.ErrorIsNil)
 	c.Assert(path, jc.SamePath, expect)
 }
+
+type HasExistingDataSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&HasExistingDataSuite{})
+
+// patchDevice arranges for the block device at /dev/sdb
+// to have the given content.
+func (s *HasExistingDataSuite) patchDevice(c *gc.C, content []byte) {
+	s.PatchValue(storage.OpenBlockDevice, func(path string) (io.ReadCloser, error) {
+		c.Assert(path, gc.Equals, "/dev/sdb")
+		return ioutil.NopCloser(bytes.NewReader(content)), nil
+	})
+}
+
+func (s *HasExistingDataSuite) TestKnownFilesystem(c *gc.C) {
+	s.PatchValue(storage.OpenBlockDevice, func(string) (io.ReadCloser, error) {
+		c.Fatalf("unexpected probe")
+		return nil, nil
+	})
+	hasData, err := storage.HasExistingData(storage.BlockDevice{DeviceName: "sdb", FilesystemType: "ext4"})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(hasData, jc.IsTrue)
+}
+
+func (s *HasExistingDataSuite) TestEmptyDevice(c *gc.C) {
+	s.patchDevice(c, make([]byte, 1<<20))
+	hasData, err := storage.HasExistingData(storage.BlockDevice{DeviceName: "sdb"})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(hasData, jc.IsFalse)
+}
+
+func (s *HasExistingDataSuite) TestSmallDevice(c *gc.C) {
+	s.patchDevice(c, make([]byte, 100))
+	hasData, err := storage.HasExistingData(storage.BlockDevice{DeviceName: "sdb"})
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(hasData, jc.IsFalse)
+}
+
+func (s *HasExistingDataSuite) TestSignatures(c *gc.C) {
+	for i, test := range []struct {
+		offset int
+		magic  string
+	}{
+		{510, "\x55\xaa"},
+		{512, "EFI PART"},
+		{536, "LVM2 001"},
+		{1080, "\x53\xef"},
+		{0, "XFSB"},
+		{0x10040, "_BHRfS_M"},
+		{4086, "SWAPSPACE2"},
+	} {
+		c.Logf("test %d: %q", i, test.magic)
+		content := make([]byte, 1<<20)
+		copy(content[test.offset:], test.magic)
+		s.patchDevice(c, content)
+		hasData, err := storage.HasExistingData(storage.BlockDevice{DeviceName: "sdb"})
+		c.Assert(err, jc.ErrorIsNil)
+		c.Check(hasData, jc.IsTrue)
+	}
+}
+
+func (s *HasExistingDataSuite) TestOpenError(c *gc.C) {
+	s.PatchValue(storage.OpenBlockDevice, func(string) (io.ReadCloser, error) {
+		return nil, errors.New("no such device")
+	})
+	_, err := storage.HasExistingData(storage.BlockDevice{DeviceName: "sdb"})
+	c.Assert(err, gc.ErrorMatches, `opening block device "/dev/sdb": no such device`)
+}
+
+func (s *HasExistingDataSuite) TestNoPath(c *gc.C) {
+	_, err := storage.HasExistingData(storage.BlockDevice{})
+	c.Assert(err, gc.ErrorMatches, `could not determine path for block device`)
+}
+
+type BlockDevicePathPreferringSuite struct {
+	testing.IsolationSuite
+}
+
+var _ = gc.Suite(&BlockDevicePathPreferringSuite{})
+
+func (s *BlockDevicePathPreferringSuite) patchStat(exists bool) {
+	s.PatchValue(storage.StatBlockDevice, func(path string) (os.FileInfo, error) {
+		if !exists {
+			return nil, &os.PathError{Op: "stat", Path: path, Err: os.ErrNotExist}
+		}
+		return nil, nil
+	})
+}
+
+func (s *BlockDevicePathPreferringSuite) TestPreferStablePath(c *gc.C) {
+	s.patchStat(false)
+	path, err := storage.BlockDevicePathPreferring(storage.BlockDevice{
+		Serial:     "SPR_OSUM_123",
+		DeviceName: "name",
+	}, storage.PreferStablePath)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(path, jc.SamePath, "/dev/disk/by-id/SPR_OSUM_123")
+}
+
+func (s *BlockDevicePathPreferringSuite) TestPreferDeviceName(c *gc.C) {
+	s.patchStat(true)
+	path, err := storage.BlockDevicePathPreferring(storage.BlockDevice{
+		Serial:     "SPR_OSUM_123",
+		DeviceName: "name",
+	}, storage.PreferDeviceName)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(path, jc.SamePath, "/dev/name")
+}
+
+func (s *BlockDevicePathPreferringSuite) TestPreferDeviceNameMissing(c *gc.C) {
+	s.patchStat(false)
+	_, err := storage.BlockDevicePathPreferring(storage.BlockDevice{
+		Serial:     "SPR_OSUM_123",
+		DeviceName: "name",
+	}, storage.PreferDeviceName)
+	c.Assert(err, gc.ErrorMatches, `validating block device path: stat /dev/name: file does not exist`)
+}
+
+func (s *BlockDevicePathPreferringSuite) TestPreferDeviceNameNoName(c *gc.C) {
+	s.patchStat(false)
+	path, err := storage.BlockDevicePathPreferring(storage.BlockDevice{
+		Serial: "SPR_OSUM_123",
+	}, storage.PreferDeviceName)
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(path, jc.SamePath, "/dev/disk/by-id/SPR_OSUM_123")
+}
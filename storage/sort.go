@@ -23,3 +23,46 @@ func (b byDeviceName) Swap(i, j int) {
 func (b byDeviceName) Less(i, j int) bool {
 	return b[i].DeviceName < b[j].DeviceName
 }
+
+// SortBlockDevicesByIdentity sorts block devices by their identities:
+// by WWN, then by serial, filesystem UUID and filesystem label, and
+// finally by device name. WWNs and serials are normalised as they are
+// by SameBlockDevice, so that the order does not depend on the aliases
+// under which the devices were discovered.
+func SortBlockDevicesByIdentity(devices []BlockDevice) {
+	sort.Sort(byIdentity(devices))
+}
+
+type byIdentity []BlockDevice
+
+func (b byIdentity) Len() int {
+	return len(b)
+}
+
+func (b byIdentity) Swap(i, j int) {
+	b[i], b[j] = b[j], b[i]
+}
+
+func (b byIdentity) Less(i, j int) bool {
+	ki, kj := identityKey(b[i]), identityKey(b[j])
+	for n := range ki {
+		if ki[n] != kj[n] {
+			return ki[n] < kj[n]
+		}
+	}
+	return false
+}
+
+// identityKey returns the fields by which
+// block devices are ordered by identity.
+func identityKey(dev BlockDevice) [5]string {
+	var wwn, serial string
+	for _, id := range hardwareIDs(dev) {
+		if id.wwn && wwn == "" {
+			wwn = id.value
+		} else if !id.wwn {
+			serial = id.value
+		}
+	}
+	return [5]string{wwn, serial, dev.UUID, dev.Label, dev.DeviceName}
+}
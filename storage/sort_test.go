@@ -0,0 +1,70 @@
+// Copyright 2015 Canonical Ltd.
+// Licensed under the AGPLv3, see LICENCE file for details.
+
+package storage_test
+
+import (
+	jc "github.com/juju/testing/checkers"
+	gc "gopkg.in/check.v1"
+
+	"github.com/juju/juju/storage"
+)
+
+type SortBlockDevicesSuite struct{}
+
+var _ = gc.Suite(&SortBlockDevicesSuite{})
+
+func (s *SortBlockDevicesSuite) TestSortBlockDevices(c *gc.C) {
+	devices := []storage.BlockDevice{
+		{DeviceName: "sdc"},
+		{DeviceName: "sda"},
+		{DeviceName: "sdb"},
+	}
+	storage.SortBlockDevices(devices)
+	c.Assert(devices, jc.DeepEquals, []storage.BlockDevice{
+		{DeviceName: "sda"},
+		{DeviceName: "sdb"},
+		{DeviceName: "sdc"},
+	})
+}
+
+func (s *SortBlockDevicesSuite) TestSortBlockDevicesByIdentity(c *gc.C) {
+	devices := []storage.BlockDevice{
+		{DeviceName: "sdf", Label: "logs"},
+		{DeviceName: "sde", UUID: "deadbeef"},
+		{DeviceName: "sdd", Serial: "ata-ST3500418AS_9VMJ1PYR"},
+		{DeviceName: "sdc", Serial: "wwn-0x5000C5002DE3B9C4"},
+		{DeviceName: "sdb", WWN: "0x5000a"},
+		{DeviceName: "sda", Serial: "QM00001"},
+		{DeviceName: "sdg"},
+	}
+	storage.SortBlockDevicesByIdentity(devices)
+	var names []string
+	for _, dev := range devices {
+		names = append(names, dev.DeviceName)
+	}
+	c.Assert(names, jc.DeepEquals, []string{
+		"sdg", "sdf", "sde", "sda", "sdd", "sdb", "sdc",
+	})
+}
+
+func (s *SortBlockDevicesSuite) TestSortBlockDevicesByIdentityIgnoresAliases(c *gc.C) {
+	// The same devices, discovered under different aliases and
+	// in different orders, are sorted into the same order.
+	first := []storage.BlockDevice{
+		{DeviceName: "sdb", Serial: "ata-ST3500418AS_9VMJ1PYR"},
+		{DeviceName: "sda", WWN: "0x5000C5002DE3B9C4"},
+		{DeviceName: "sdc", Serial: "ST3500418AS_1ABC"},
+	}
+	second := []storage.BlockDevice{
+		{DeviceName: "sda", WWN: "0x5000c5002de3b9c4"},
+		{DeviceName: "sdc", Serial: "scsi-ST3500418AS_1ABC"},
+		{DeviceName: "sdb", Serial: "ST3500418AS_9VMJ1PYR"},
+	}
+	storage.SortBlockDevicesByIdentity(first)
+	storage.SortBlockDevicesByIdentity(second)
+	c.Assert(first, gc.HasLen, len(second))
+	for i := range first {
+		c.Check(storage.SameBlockDevice(first[i], second[i]), jc.IsTrue)
+	}
+}
@@ -106,6 +106,44 @@ func (src List) NewestCompatible(base version.Number) (newest version.Number, fo
 	return newest, found
 }
 
+// Preferred returns the tools in src to use when tools for several
+// series would do, or nil if src is empty. Tools for preferredSeries
+// are chosen if there are any; otherwise tools for the newest series
+// are. Ties are broken by choosing the greatest version number, and
+// then the alphabetically first binary version, so that the same
+// tools are always chosen from the same list, whatever its order.
+func (src List) Preferred(preferredSeries string) *Tools {
+	var best *Tools
+	for _, tools := range src {
+		if best == nil || preferredTo(tools, best, preferredSeries) {
+			best = tools
+		}
+	}
+	return best
+}
+
+// preferredTo reports whether tools a are preferred to
+// tools b, as described by List.Preferred.
+func preferredTo(a, b *Tools, preferredSeries string) bool {
+	aSeries, bSeries := a.Version.Series, b.Version.Series
+	if aSeries != bSeries {
+		if aSeries == preferredSeries || bSeries == preferredSeries {
+			return aSeries == preferredSeries
+		}
+		// Series with unknown versions are the least preferred.
+		aVersion, _ := version.SeriesVersion(aSeries)
+		bVersion, _ := version.SeriesVersion(bSeries)
+		if aVersion != bVersion {
+			return aVersion > bVersion
+		}
+		return aSeries > bSeries
+	}
+	if cmp := a.Version.Number.Compare(b.Version.Number); cmp != 0 {
+		return cmp > 0
+	}
+	return a.Version.String() < b.Version.String()
+}
+
 // Exclude returns the tools in src that are not in excluded.
 func (src List) Exclude(excluded List) List {
 	ignore := make(map[version.Binary]bool, len(excluded))
@@ -149,6 +149,51 @@ func (s *ListSuite) TestNewest(c *gc.C) {
 	}
 }
 
+var preferredTests = []struct {
+	about  string
+	src    tools.List
+	series string
+	expect *tools.Tools
+}{{
+	about:  "no tools",
+	src:    nil,
+	series: "precise",
+	expect: nil,
+}, {
+	about:  "preferred series",
+	src:    tools.List{t100quantal, t100precise32, t100precise},
+	series: "precise",
+	expect: t100precise,
+}, {
+	about:  "no preferred series, newest series",
+	src:    tools.List{t100precise, t100quantal32, t100quantal},
+	series: "",
+	expect: t100quantal,
+}, {
+	about:  "preferred series unavailable, newest series",
+	src:    tools.List{t100quantal32, t100precise, t100quantal},
+	series: "trusty",
+	expect: t100quantal,
+}, {
+	about:  "newest version within series",
+	src:    tools.List{t190precise, t200precise, t100precise},
+	series: "precise",
+	expect: t200precise,
+}}
+
+func (s *ListSuite) TestPreferred(c *gc.C) {
+	for i, test := range preferredTests {
+		c.Logf("test %d: %s", i, test.about)
+		c.Check(test.src.Preferred(test.series), gc.Equals, test.expect)
+		// The choice does not depend on the order of the list.
+		reversed := make(tools.List, len(test.src))
+		for j, t := range test.src {
+			reversed[len(test.src)-1-j] = t
+		}
+		c.Check(reversed.Preferred(test.series), gc.Equals, test.expect)
+	}
+}
+
 var newestCompatibleTests = []struct {
 	src    tools.List
 	base   version.Number
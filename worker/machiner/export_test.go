@@ -4,3 +4,16 @@
 package machiner
 
 var InterfaceAddrs = &interfaceAddrs
+
+var (
+	HostStatusData    = &hostStatusData
+	KernelReleaseFile = &kernelReleaseFile
+)
+
+var MachineEnsureDead = &machineEnsureDead
+
+var (
+	MachineSetAddresses  = &machineSetAddresses
+	SetAddressesAttempts = &setAddressesAttempts
+	SetAddressesDelay    = &setAddressesDelay
+)
@@ -4,16 +4,23 @@ package machiner
 
 import (
 	"fmt"
+	"io/ioutil"
+	"math/rand"
 	"net"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/juju/loggo"
 	"github.com/juju/names"
+	"launchpad.net/tomb"
 
 	"github.com/juju/juju/agent"
 	"github.com/juju/juju/api/machiner"
 	"github.com/juju/juju/api/watcher"
 	"github.com/juju/juju/apiserver/params"
 	"github.com/juju/juju/network"
+	"github.com/juju/juju/version"
 	"github.com/juju/juju/worker"
 )
 
@@ -24,6 +31,15 @@ type Machiner struct {
 	st      *machiner.State
 	tag     names.MachineTag
 	machine *machiner.Machine
+
+	// stopped records whether the machine's status has been set to
+	// stopped, so that it can be set back to started if the machine
+	// becomes Alive again.
+	stopped bool
+
+	// dying is closed when the worker running
+	// the machiner is killed.
+	dying chan struct{}
 }
 
 // NewMachiner returns a Worker that will wait for the identified machine
@@ -31,8 +47,28 @@ type Machiner struct {
 // other means.
 func NewMachiner(st *machiner.State, agentConfig agent.Config) worker.Worker {
 	// TODO(dfc) clearly agentConfig.Tag() can _only_ return a machine tag
-	mr := &Machiner{st: st, tag: agentConfig.Tag().(names.MachineTag)}
-	return worker.NewNotifyWorker(mr)
+	mr := &Machiner{
+		st:    st,
+		tag:   agentConfig.Tag().(names.MachineTag),
+		dying: make(chan struct{}),
+	}
+	return &machinerWorker{Worker: worker.NewNotifyWorker(mr), dying: mr.dying}
+}
+
+// machinerWorker runs a Machiner, closing its dying channel when
+// killed, so that it stops waiting to retry as it is set up.
+type machinerWorker struct {
+	worker.Worker
+	once  sync.Once
+	dying chan struct{}
+}
+
+// Kill implements worker.Worker.Kill.
+func (w *machinerWorker) Kill() {
+	// The worker must be dying before the
+	// machiner can stop with tomb.ErrDying.
+	w.Worker.Kill()
+	w.once.Do(func() { close(w.dying) })
 }
 
 func (mr *Machiner) SetUp() (watcher.NotifyWatcher, error) {
@@ -46,12 +82,14 @@ func (mr *Machiner) SetUp() (watcher.NotifyWatcher, error) {
 	mr.machine = m
 
 	// Set the addresses in state to the host's addresses.
-	if err := setMachineAddresses(m); err != nil {
+	if err := setMachineAddresses(m, mr.dying); params.IsCodeNotFoundOrCodeUnauthorized(err) {
+		return nil, worker.ErrTerminateAgent
+	} else if err != nil {
 		return nil, err
 	}
 
 	// Mark the machine as started and log it.
-	if err := m.SetStatus(params.StatusStarted, "", nil); err != nil {
+	if err := m.SetStatus(params.StatusStarted, "", hostStatusData()); err != nil {
 		return nil, fmt.Errorf("%s failed to set status started: %v", mr.tag, err)
 	}
 	logger.Infof("%q started", mr.tag)
@@ -61,9 +99,47 @@ func (mr *Machiner) SetUp() (watcher.NotifyWatcher, error) {
 
 var interfaceAddrs = net.InterfaceAddrs
 
+// kernelReleaseFile holds the release of the running kernel.
+var kernelReleaseFile = "/proc/sys/kernel/osrelease"
+
+// hostStatusData returns the status data set when the machine is
+// started, describing the host's operating system and kernel versions.
+// Versions that cannot be found on the host are omitted. It is a
+// variable so it can be replaced in tests.
+var hostStatusData = func() map[string]interface{} {
+	data := make(map[string]interface{})
+	if osVersion := version.ReleaseVersion(); osVersion != "" {
+		data["os-version"] = osVersion
+	}
+	kernel, err := ioutil.ReadFile(kernelReleaseFile)
+	if err != nil {
+		logger.Debugf("cannot read kernel version: %v", err)
+	} else if kernelVersion := strings.TrimSpace(string(kernel)); kernelVersion != "" {
+		data["kernel-version"] = kernelVersion
+	}
+	return data
+}
+
+// machineSetAddresses sets the machine's addresses in state. It is a
+// variable so it can be replaced in tests.
+var machineSetAddresses = (*machiner.Machine).SetMachineAddresses
+
+var (
+	// setAddressesAttempts is the number of times setting the
+	// machine's addresses is attempted before giving up.
+	setAddressesAttempts = 5
+
+	// setAddressesDelay is the delay before setting the machine's
+	// addresses is first retried. It is doubled for each further
+	// retry, and up to half as much again is added at random, so
+	// that many machines failing together do not retry in step.
+	setAddressesDelay = time.Second
+)
+
 // setMachineAddresses sets the addresses for this machine to all of the
-// host's non-loopback interface IP addresses.
-func setMachineAddresses(m *machiner.Machine) error {
+// host's non-loopback interface IP addresses. If dying is closed while
+// it waits to retry, it gives up at once with tomb.ErrDying.
+func setMachineAddresses(m *machiner.Machine, dying <-chan struct{}) error {
 	addrs, err := interfaceAddrs()
 	if err != nil {
 		return err
@@ -86,11 +162,34 @@ func setMachineAddresses(m *machiner.Machine) error {
 		}
 		hostAddresses = append(hostAddresses, address)
 	}
+	// The same address may be configured on several interfaces.
+	hostAddresses = network.UniqueAddresses(hostAddresses)
 	if len(hostAddresses) == 0 {
 		return nil
 	}
 	logger.Infof("setting addresses for %v to %q", m.Tag(), hostAddresses)
-	return m.SetMachineAddresses(hostAddresses)
+	delay := setAddressesDelay
+	for attempt := 1; ; attempt++ {
+		err := machineSetAddresses(m, hostAddresses)
+		if err == nil || params.IsCodeNotFoundOrCodeUnauthorized(err) {
+			return err
+		}
+		if attempt == setAddressesAttempts {
+			// The addresses are not essential to the machine's
+			// operation, so a persistent failure to set them is
+			// not allowed to stop the machiner.
+			logger.Errorf("cannot set addresses for %v, giving up: %v", m.Tag(), err)
+			return nil
+		}
+		jitter := time.Duration(rand.Int63n(int64(delay)/2 + 1))
+		logger.Warningf("cannot set addresses for %v, retrying in %v: %v", m.Tag(), delay+jitter, err)
+		select {
+		case <-time.After(delay + jitter):
+		case <-dying:
+			return tomb.ErrDying
+		}
+		delay *= 2
+	}
 }
 
 func (mr *Machiner) Handle() error {
@@ -100,21 +199,46 @@ func (mr *Machiner) Handle() error {
 		return err
 	}
 	if mr.machine.Life() == params.Alive {
-		return nil
+		return mr.restart()
 	}
 	logger.Debugf("%q is now %s", mr.tag, mr.machine.Life())
 	if err := mr.machine.SetStatus(params.StatusStopped, "", nil); err != nil {
 		return fmt.Errorf("%s failed to set status stopped: %v", mr.tag, err)
 	}
+	mr.stopped = true
 
 	// If the machine is Dying, it has no units,
 	// and can be safely set to Dead.
-	if err := mr.machine.EnsureDead(); err != nil {
+	if err := machineEnsureDead(mr.machine); err != nil {
+		// The machine may have become Alive again since it
+		// was refreshed, in which case it should keep running.
+		if refreshErr := mr.machine.Refresh(); refreshErr == nil && mr.machine.Life() == params.Alive {
+			logger.Debugf("%q is Alive again: %v", mr.tag, err)
+			return mr.restart()
+		}
 		return fmt.Errorf("%s failed to set machine to dead: %v", mr.tag, err)
 	}
 	return worker.ErrTerminateAgent
 }
 
+// restart sets the machine's status back to started if it was
+// set to stopped when the machine stopped being Alive.
+func (mr *Machiner) restart() error {
+	if !mr.stopped {
+		return nil
+	}
+	if err := mr.machine.SetStatus(params.StatusStarted, "", hostStatusData()); err != nil {
+		return fmt.Errorf("%s failed to set status started: %v", mr.tag, err)
+	}
+	mr.stopped = false
+	logger.Infof("%q restarted", mr.tag)
+	return nil
+}
+
+// machineEnsureDead sets the machine's life to Dead. It is a
+// variable so it can be replaced in tests.
+var machineEnsureDead = (*machiner.Machine).EnsureDead
+
 func (mr *Machiner) TearDown() error {
 	// Nothing to do here.
 	return nil
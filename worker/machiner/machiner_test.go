@@ -4,13 +4,17 @@
 package machiner_test
 
 import (
+	"errors"
+	"io/ioutil"
 	"net"
+	"path/filepath"
 	stdtesting "testing"
 	"time"
 
 	"github.com/juju/names"
 	jc "github.com/juju/testing/checkers"
 	gc "gopkg.in/check.v1"
+	"gopkg.in/mgo.v2/bson"
 
 	"github.com/juju/juju/agent"
 	"github.com/juju/juju/api"
@@ -121,6 +125,40 @@ func (s *MachinerSuite) TestStartSetsStatus(c *gc.C) {
 	s.waitMachineStatus(c, s.machine, state.StatusStarted)
 }
 
+func (s *MachinerSuite) TestStartSetsHostStatusData(c *gc.C) {
+	s.PatchValue(machiner.HostStatusData, func() map[string]interface{} {
+		return map[string]interface{}{
+			"os-version":     "14.04",
+			"kernel-version": "3.13.0-46-generic",
+		}
+	})
+	mr := s.makeMachiner()
+	defer worker.Stop(mr)
+
+	s.waitMachineStatus(c, s.machine, state.StatusStarted)
+	_, _, data, err := s.machine.Status()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(data, jc.DeepEquals, map[string]interface{}{
+		"os-version":     "14.04",
+		"kernel-version": "3.13.0-46-generic",
+	})
+}
+
+func (s *MachinerSuite) TestHostStatusDataKernelVersion(c *gc.C) {
+	kernelFile := filepath.Join(c.MkDir(), "osrelease")
+	err := ioutil.WriteFile(kernelFile, []byte("3.13.0-46-generic\n"), 0644)
+	c.Assert(err, jc.ErrorIsNil)
+	s.PatchValue(machiner.KernelReleaseFile, kernelFile)
+	data := (*machiner.HostStatusData)()
+	c.Assert(data["kernel-version"], gc.Equals, "3.13.0-46-generic")
+
+	// A missing file is skipped.
+	s.PatchValue(machiner.KernelReleaseFile, filepath.Join(c.MkDir(), "missing"))
+	data = (*machiner.HostStatusData)()
+	_, ok := data["kernel-version"]
+	c.Assert(ok, jc.IsFalse)
+}
+
 func (s *MachinerSuite) TestSetsStatusWhenDying(c *gc.C) {
 	mr := s.makeMachiner()
 	defer worker.Stop(mr)
@@ -138,6 +176,43 @@ func (s *MachinerSuite) TestSetDead(c *gc.C) {
 	c.Assert(s.machine.Life(), gc.Equals, state.Dead)
 }
 
+func (s *MachinerSuite) TestSetsStatusStartedWhenAliveAgain(c *gc.C) {
+	// Simulate the machine becoming Alive again while the machiner
+	// is trying to make it Dead: Alive -> Dying -> Alive.
+	undone := make(chan struct{})
+	s.PatchValue(machiner.MachineEnsureDead, func(*apimachiner.Machine) error {
+		defer close(undone)
+		status, _, _, err := s.machine.Status()
+		c.Check(err, jc.ErrorIsNil)
+		c.Check(status, gc.Equals, state.StatusStopped)
+		machines := s.State.MongoSession().DB("juju").C("machines")
+		err = machines.Update(
+			bson.D{{"machineid", s.machine.Id()}},
+			bson.D{{"$set", bson.D{{"life", state.Alive}}}},
+		)
+		c.Check(err, jc.ErrorIsNil)
+		return errors.New("machine is not dying")
+	})
+	mr := s.makeMachiner()
+	defer worker.Stop(mr)
+	s.waitMachineStatus(c, s.machine, state.StatusStarted)
+
+	err := s.machine.Destroy()
+	c.Assert(err, jc.ErrorIsNil)
+	s.State.StartSync()
+	select {
+	case <-undone:
+	case <-time.After(worstCase):
+		c.Fatalf("timed out waiting for the machiner to make the machine Dead")
+	}
+	s.waitMachineStatus(c, s.machine, state.StatusStarted)
+
+	// The machiner is still running.
+	c.Assert(s.machine.Refresh(), jc.ErrorIsNil)
+	c.Assert(s.machine.Life(), gc.Equals, state.Alive)
+	c.Assert(worker.Stop(mr), jc.ErrorIsNil)
+}
+
 func (s *MachinerSuite) TestMachineAddresses(c *gc.C) {
 	s.PatchValue(machiner.InterfaceAddrs, func() ([]net.Addr, error) {
 		addrs := []net.Addr{
@@ -164,3 +239,116 @@ func (s *MachinerSuite) TestMachineAddresses(c *gc.C) {
 		network.NewAddress("127.0.0.1", network.ScopeMachineLocal),
 	})
 }
+
+func (s *MachinerSuite) TestMachineAddressesDuplicated(c *gc.C) {
+	s.PatchValue(machiner.InterfaceAddrs, func() ([]net.Addr, error) {
+		// The same address configured on two interfaces.
+		addrs := []net.Addr{
+			&net.IPAddr{IP: net.IPv4(10, 0, 0, 1)},
+			&net.IPNet{IP: net.ParseIP("2001:db8::1")},
+			&net.IPNet{IP: net.IPv4(10, 0, 0, 1), Mask: net.CIDRMask(24, 32)},
+		}
+		return addrs, nil
+	})
+	mr := s.makeMachiner()
+	defer worker.Stop(mr)
+	c.Assert(s.machine.Destroy(), gc.IsNil)
+	s.State.StartSync()
+	c.Assert(mr.Wait(), gc.Equals, worker.ErrTerminateAgent)
+	c.Assert(s.machine.Refresh(), gc.IsNil)
+	c.Assert(s.machine.MachineAddresses(), jc.DeepEquals, []network.Address{
+		network.NewAddress("2001:db8::1", network.ScopeUnknown),
+		network.NewAddress("10.0.0.1", network.ScopeCloudLocal),
+	})
+}
+
+// patchSetAddresses arranges for setting the machine's addresses
+// to fail with the given errors, in order, before succeeding. It
+// returns a pointer to the number of attempts made.
+func (s *MachinerSuite) patchSetAddresses(c *gc.C, errs ...error) *int {
+	s.PatchValue(machiner.InterfaceAddrs, func() ([]net.Addr, error) {
+		return []net.Addr{&net.IPAddr{IP: net.IPv4(10, 0, 0, 1)}}, nil
+	})
+	s.PatchValue(machiner.SetAddressesDelay, time.Millisecond)
+	var calls int
+	s.PatchValue(machiner.MachineSetAddresses, func(m *apimachiner.Machine, addrs []network.Address) error {
+		calls++
+		if calls <= len(errs) {
+			return errs[calls-1]
+		}
+		return m.SetMachineAddresses(addrs)
+	})
+	return &calls
+}
+
+func (s *MachinerSuite) TestSetMachineAddressesRetriesTransientFailure(c *gc.C) {
+	transient := errors.New("connection reset")
+	calls := s.patchSetAddresses(c, transient, transient)
+	mr := s.makeMachiner()
+	defer worker.Stop(mr)
+
+	s.waitMachineStatus(c, s.machine, state.StatusStarted)
+	c.Assert(*calls, gc.Equals, 3)
+	c.Assert(s.machine.Refresh(), gc.IsNil)
+	c.Assert(s.machine.MachineAddresses(), jc.DeepEquals, []network.Address{
+		network.NewAddress("10.0.0.1", network.ScopeCloudLocal),
+	})
+}
+
+func (s *MachinerSuite) TestSetMachineAddressesGivesUp(c *gc.C) {
+	s.PatchValue(machiner.SetAddressesAttempts, 3)
+	transient := errors.New("connection reset")
+	calls := s.patchSetAddresses(c, transient, transient, transient, transient)
+	mr := s.makeMachiner()
+	defer worker.Stop(mr)
+
+	// The machiner carries on without the addresses.
+	s.waitMachineStatus(c, s.machine, state.StatusStarted)
+	c.Assert(*calls, gc.Equals, 3)
+	c.Assert(s.machine.Refresh(), gc.IsNil)
+	c.Assert(s.machine.MachineAddresses(), gc.HasLen, 0)
+	c.Assert(worker.Stop(mr), gc.IsNil)
+}
+
+func (s *MachinerSuite) TestSetMachineAddressesStopsWhenKilled(c *gc.C) {
+	s.PatchValue(machiner.InterfaceAddrs, func() ([]net.Addr, error) {
+		return []net.Addr{&net.IPAddr{IP: net.IPv4(10, 0, 0, 1)}}, nil
+	})
+	s.PatchValue(machiner.SetAddressesDelay, time.Hour)
+	attempted := make(chan struct{}, 1)
+	s.PatchValue(machiner.MachineSetAddresses, func(*apimachiner.Machine, []network.Address) error {
+		attempted <- struct{}{}
+		return errors.New("connection reset")
+	})
+	mr := s.makeMachiner()
+	select {
+	case <-attempted:
+	case <-time.After(worstCase):
+		c.Fatalf("timed out waiting for addresses to be set")
+	}
+
+	// The machiner stops without waiting to retry.
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- worker.Stop(mr)
+	}()
+	select {
+	case err := <-stopped:
+		c.Assert(err, jc.ErrorIsNil)
+	case <-time.After(worstCase):
+		c.Fatalf("timed out waiting for the machiner to stop")
+	}
+	status, _, _, err := s.machine.Status()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(status, gc.Not(gc.Equals), state.StatusStarted)
+}
+
+func (s *MachinerSuite) TestSetMachineAddressesNotFoundOrUnauthorized(c *gc.C) {
+	for i, code := range []string{params.CodeNotFound, params.CodeUnauthorized} {
+		c.Logf("test %d: %s", i, code)
+		calls := s.patchSetAddresses(c, &params.Error{Code: code, Message: code})
+		mr := s.makeMachiner()
+		c.Assert(mr.Wait(), gc.Equals, worker.ErrTerminateAgent)
+		c.Assert(*calls, gc.Equals, 1)
+	}
+}
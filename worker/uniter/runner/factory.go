@@ -205,20 +205,33 @@ func (f *factory) NewActionRunner(actionId string) (Runner, error) {
 	}
 
 	name := action.Name()
-	spec, ok := ch.Actions().ActionSpecs[name]
-	if !ok {
-		return nil, &badActionError{name, "not defined"}
-	}
-	params := action.Params()
-	if err := spec.ValidateParams(params); err != nil {
-		return nil, &badActionError{name, err.Error()}
+	// The predefined collect-metrics action is not declared by the
+	// charm; it runs the collect-metrics hook, which may only add the
+	// metrics the charm declares.
+	collectMetrics := name == params.CollectMetricsAction
+	if collectMetrics {
+		if ch.Metrics() == nil {
+			return nil, &badActionError{name, "charm declares no metrics"}
+		}
+	} else {
+		spec, ok := ch.Actions().ActionSpecs[name]
+		if !ok {
+			return nil, &badActionError{name, "not defined"}
+		}
+		if err := spec.ValidateParams(action.Params()); err != nil {
+			return nil, &badActionError{name, err.Error()}
+		}
 	}
 
 	ctx, err := f.coreContext()
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
-	ctx.actionData = newActionData(name, &tag, params)
+	ctx.actionData = newActionData(name, &tag, action.Params())
+	if collectMetrics {
+		ctx.canAddMetrics = true
+		ctx.definedMetrics = ch.Metrics()
+	}
 	ctx.id = f.newId(name)
 	runner := NewRunner(ctx, f.paths)
 	return runner, nil
@@ -558,6 +558,31 @@ func (s *FactorySuite) TestNewActionRunnerGood(c *gc.C) {
 	c.Assert(combined, gc.Matches, `(^|.*\|)JUJU_ACTION_TAG=`+action.Tag().String()+`(\|.*|$)`)
 }
 
+func (s *FactorySuite) TestNewActionRunnerCollectMetrics(c *gc.C) {
+	s.SetCharm(c, "metered")
+	action, err := s.State.EnqueueAction(s.unit.Tag(), params.CollectMetricsAction, nil)
+	c.Assert(err, jc.ErrorIsNil)
+	rnr, err := s.factory.NewActionRunner(action.Id())
+	c.Assert(err, jc.ErrorIsNil)
+	s.AssertPaths(c, rnr)
+	ctx := rnr.Context()
+	data, err := ctx.ActionData()
+	c.Assert(err, jc.ErrorIsNil)
+	c.Assert(data.ActionName, gc.Equals, params.CollectMetricsAction)
+	err = ctx.AddMetric("pings", "0.5", time.Now())
+	c.Assert(err, jc.ErrorIsNil)
+}
+
+func (s *FactorySuite) TestNewActionRunnerCollectMetricsUndeclared(c *gc.C) {
+	s.SetCharm(c, "dummy")
+	action, err := s.State.EnqueueAction(s.unit.Tag(), params.CollectMetricsAction, nil)
+	c.Assert(err, jc.ErrorIsNil)
+	rnr, err := s.factory.NewActionRunner(action.Id())
+	c.Check(rnr, gc.IsNil)
+	c.Check(err, gc.ErrorMatches, `cannot run "juju-collect-metrics" action: charm declares no metrics`)
+	c.Check(err, jc.Satisfies, runner.IsBadActionError)
+}
+
 func (s *FactorySuite) TestNewActionRunnerBadCharm(c *gc.C) {
 	rnr, err := s.factory.NewActionRunner("irrelevant")
 	c.Assert(rnr, gc.IsNil)
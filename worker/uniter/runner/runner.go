@@ -13,7 +13,9 @@ import (
 	"github.com/juju/errors"
 	"github.com/juju/loggo"
 	utilexec "github.com/juju/utils/exec"
+	"gopkg.in/juju/charm.v4/hooks"
 
+	"github.com/juju/juju/apiserver/params"
 	"github.com/juju/juju/version"
 	"github.com/juju/juju/worker/uniter/runner/debug"
 	"github.com/juju/juju/worker/uniter/runner/jujuc"
@@ -107,6 +109,9 @@ func (runner *runner) RunAction(actionName string) error {
 	if _, err := runner.context.ActionData(); err != nil {
 		return errors.Trace(err)
 	}
+	if actionName == params.CollectMetricsAction {
+		return runner.runCharmHookWithLocation(string(hooks.CollectMetrics), "hooks")
+	}
 	return runner.runCharmHookWithLocation(actionName, "actions")
 }
 